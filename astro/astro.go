@@ -0,0 +1,161 @@
+// Package astro provides low precision algorithms to calculate the
+// position (azimuth / elevation) of the Sun and the Moon for an observer
+// on earth. The accuracy (~0.01° for the Sun, ~0.3° for the Moon) is
+// well within the beamwidth of typical EME and solar noise antennas.
+//
+// The algorithms are taken from the "Astronomical Almanac" low precision
+// formulas, as published e.g. in Jean Meeus, "Astronomical Algorithms".
+package astro
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Body is a celestial object which can be tracked.
+type Body string
+
+const (
+	// Sun is the sun
+	Sun Body = "sun"
+	// Moon is the earth's moon
+	Moon Body = "moon"
+)
+
+// Bodies returns all supported celestial bodies.
+func Bodies() []Body {
+	return []Body{Sun, Moon}
+}
+
+// ParseBody converts a string into a Body.
+func ParseBody(s string) (Body, error) {
+	for _, b := range Bodies() {
+		if strings.ToLower(s) == string(b) {
+			return b, nil
+		}
+	}
+	return "", fmt.Errorf("unknown celestial body (%s)", s)
+}
+
+// Position returns the azimuth (0° = North, clockwise) and elevation
+// (0° = horizon) in degrees of a celestial body at time t for an observer
+// at the given latitude and longitude (degrees, North and East positive).
+func Position(b Body, t time.Time, lat, lon float64) (az, el float64, err error) {
+	switch b {
+	case Sun:
+		az, el = SunPosition(t, lat, lon)
+	case Moon:
+		az, el = MoonPosition(t, lat, lon)
+	default:
+		return 0, 0, fmt.Errorf("unknown celestial body (%s)", b)
+	}
+	return az, el, nil
+}
+
+// SunPosition returns the azimuth and elevation of the sun in degrees.
+func SunPosition(t time.Time, lat, lon float64) (az, el float64) {
+	d := daysSinceJ2000(t)
+
+	// mean anomaly and mean longitude
+	g := 357.529 + 0.98560028*d
+	q := 280.459 + 0.98564736*d
+
+	// geocentric apparent ecliptic longitude
+	l := q + 1.915*sin(g) + 0.020*sin(2*g)
+
+	ra, dec := eclipticToEquatorial(d, l, 0)
+
+	return horizontal(d, ra, dec, lat, lon)
+}
+
+// MoonPosition returns the topocentric azimuth and elevation of the moon
+// in degrees.
+func MoonPosition(t time.Time, lat, lon float64) (az, el float64) {
+	d := daysSinceJ2000(t)
+	T := d / 36525
+
+	// ecliptic longitude
+	l := 218.32 + 481267.881*T +
+		6.29*sin(135.0+477198.87*T) -
+		1.27*sin(259.3-413335.36*T) +
+		0.66*sin(235.7+890534.22*T) +
+		0.21*sin(269.9+954397.74*T) -
+		0.19*sin(357.5+35999.05*T) -
+		0.11*sin(186.5+966404.03*T)
+
+	// ecliptic latitude
+	b := 5.13*sin(93.3+483202.02*T) +
+		0.28*sin(228.2+960400.89*T) -
+		0.28*sin(318.3+6003.15*T) -
+		0.17*sin(217.6-407332.21*T)
+
+	// horizontal parallax
+	p := 0.9508 +
+		0.0518*cos(135.0+477198.87*T) +
+		0.0095*cos(259.3-413335.36*T) +
+		0.0078*cos(235.7+890534.22*T) +
+		0.0028*cos(269.9+954397.74*T)
+
+	ra, dec := eclipticToEquatorial(d, l, b)
+
+	az, el = horizontal(d, ra, dec, lat, lon)
+
+	// the moon is close enough that the observer's position on the
+	// earth's surface lowers the apparent elevation by up to ~1°
+	el -= asin(sin(p) * cos(el))
+
+	return az, el
+}
+
+// daysSinceJ2000 returns the (fractional) days since 2000-01-01 12:00 UTC
+func daysSinceJ2000(t time.Time) float64 {
+	j2000 := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	return t.Sub(j2000).Hours() / 24
+}
+
+// eclipticToEquatorial converts ecliptic coordinates into right ascension
+// and declination (all in degrees).
+func eclipticToEquatorial(d, lambda, beta float64) (ra, dec float64) {
+	// obliquity of the ecliptic
+	e := 23.439 - 0.00000036*d
+
+	ra = atan2(sin(lambda)*cos(e)-tan(beta)*sin(e), cos(lambda))
+	dec = asin(sin(beta)*cos(e) + cos(beta)*sin(e)*sin(lambda))
+
+	return ra, dec
+}
+
+// horizontal converts right ascension and declination into azimuth and
+// elevation for an observer at lat / lon (all in degrees).
+func horizontal(d, ra, dec, lat, lon float64) (az, el float64) {
+	// greenwich mean sidereal time & local hour angle
+	gmst := 280.46061837 + 360.98564736629*d
+	ha := gmst + lon - ra
+
+	el = asin(sin(lat)*sin(dec) + cos(lat)*cos(dec)*cos(ha))
+	az = atan2(-sin(ha)*cos(dec), cos(lat)*sin(dec)-sin(lat)*cos(dec)*cos(ha))
+
+	return normalize(az), el
+}
+
+// normalize maps an angle into the range 0...360°
+func normalize(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+// trigonometric helper functions working with degrees
+
+func rad(deg float64) float64 { return deg * math.Pi / 180 }
+func deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+func sin(d float64) float64      { return math.Sin(rad(d)) }
+func cos(d float64) float64      { return math.Cos(rad(d)) }
+func tan(d float64) float64      { return math.Tan(rad(d)) }
+func asin(x float64) float64     { return deg(math.Asin(x)) }
+func atan2(y, x float64) float64 { return deg(math.Atan2(y, x)) }
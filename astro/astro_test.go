@@ -0,0 +1,84 @@
+package astro
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// separation returns the angular distance between two horizontal positions
+func separation(az1, el1, az2, el2 float64) float64 {
+	return deg(math.Acos(sin(el1)*sin(el2) + cos(el1)*cos(el2)*cos(az1-az2)))
+}
+
+func TestSolarEclipse(t *testing.T) {
+	// greatest total solar eclipse on 2017-08-21 near Hopkinsville, KY;
+	// the sun was at an elevation of 64° and the moon covered it.
+	ts := time.Date(2017, 8, 21, 18, 25, 32, 0, time.UTC)
+	lat, lon := 36.97, -87.67
+
+	sunAz, sunEl := SunPosition(ts, lat, lon)
+	moonAz, moonEl := MoonPosition(ts, lat, lon)
+
+	if math.Abs(sunEl-64) > 1 {
+		t.Fatalf("expected sun elevation of 64°, got %.2f°", sunEl)
+	}
+	if math.Abs(sunAz-198) > 2 {
+		t.Fatalf("expected sun azimuth of 198°, got %.2f°", sunAz)
+	}
+	if sep := separation(sunAz, sunEl, moonAz, moonEl); sep > 0.5 {
+		t.Fatalf("expected sun and moon to coincide, but they are %.2f° apart", sep)
+	}
+}
+
+func TestLunarEclipse(t *testing.T) {
+	// greatest total lunar eclipse on 2018-01-31, seen from Tokyo;
+	// the moon stood opposite of the sun.
+	ts := time.Date(2018, 1, 31, 13, 30, 0, 0, time.UTC)
+	lat, lon := 35.68, 139.69
+
+	sunAz, sunEl := SunPosition(ts, lat, lon)
+	moonAz, moonEl := MoonPosition(ts, lat, lon)
+
+	if moonEl < 45 {
+		t.Fatalf("expected the moon high above the horizon, got %.2f°", moonEl)
+	}
+	if sep := separation(sunAz, sunEl, moonAz, moonEl); sep < 178 {
+		t.Fatalf("expected sun and moon to be opposite, but they are %.2f° apart", sep)
+	}
+}
+
+func TestParseLocator(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		loc    string
+		expLat float64
+		expLon float64
+	}{
+		{"square", "JN58", 48.5, 11},
+		{"subsquare", "JN58td", 48.146, 11.625},
+		{"lowercase", "jn58TD", 48.146, 11.625},
+		{"south west", "GF15", -34.5, -57},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			lat, lon, err := ParseLocator(tc.loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(lat-tc.expLat) > 0.01 || math.Abs(lon-tc.expLon) > 0.01 {
+				t.Fatalf("expected %.3f/%.3f, got %.3f/%.3f", tc.expLat, tc.expLon, lat, lon)
+			}
+		})
+	}
+}
+
+func TestParseLocatorInvalid(t *testing.T) {
+	for _, loc := range []string{"", "JN5", "ZZ00", "JN58zz", "JNAB"} {
+		if _, _, err := ParseLocator(loc); err == nil {
+			t.Fatalf("expected error for %q", loc)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package astro
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseLocator converts a Maidenhead grid locator (e.g. "JN58" or "JN58td")
+// into latitude and longitude (degrees, North and East positive). The
+// returned position is the center of the locator's square / subsquare.
+func ParseLocator(locator string) (lat, lon float64, err error) {
+
+	l := strings.ToUpper(strings.TrimSpace(locator))
+
+	if len(l) != 4 && len(l) != 6 {
+		return 0, 0, fmt.Errorf("invalid locator %q (expected 4 or 6 characters)", locator)
+	}
+
+	if l[0] < 'A' || l[0] > 'R' || l[1] < 'A' || l[1] > 'R' ||
+		l[2] < '0' || l[2] > '9' || l[3] < '0' || l[3] > '9' {
+		return 0, 0, fmt.Errorf("invalid locator %q", locator)
+	}
+
+	lon = float64(l[0]-'A')*20 + float64(l[2]-'0')*2 - 180
+	lat = float64(l[1]-'A')*10 + float64(l[3]-'0') - 90
+
+	if len(l) == 4 {
+		// center of the square
+		return lat + 0.5, lon + 1, nil
+	}
+
+	if l[4] < 'A' || l[4] > 'X' || l[5] < 'A' || l[5] > 'X' {
+		return 0, 0, fmt.Errorf("invalid locator %q", locator)
+	}
+
+	lon += float64(l[4]-'A') * 2 / 24
+	lat += float64(l[5]-'A') * 1 / 24
+
+	// center of the subsquare
+	return lat + 1.0/48, lon + 1.0/24, nil
+}
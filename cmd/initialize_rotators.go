@@ -15,7 +15,8 @@ func initRotator(rType string, eventHdlr rotator.EventHandler, errorCh chan stru
 
 	switch strings.ToUpper(rType) {
 
-	case "YAESU":
+	// the EA4TX ARS controllers emulate the Yaesu GS232A protocol
+	case "YAESU", "ARS":
 		evHandler := yaesu.EventHandler(eventHdlr)
 		name := yaesu.Name(viper.GetString("rotator.name"))
 		interval := yaesu.UpdateInterval(viper.GetDuration("rotator.pollingrate"))
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"net"
@@ -10,9 +11,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dh1tw/remoteRotator/astro"
+	"github.com/dh1tw/remoteRotator/hass"
 	"github.com/dh1tw/remoteRotator/hub"
+	"github.com/dh1tw/remoteRotator/influx"
+	"github.com/dh1tw/remoteRotator/metrics"
 	"github.com/dh1tw/remoteRotator/rotator"
-	"github.com/micro/mdns"
+	"github.com/dh1tw/remoteRotator/rotator/offset"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	// _ "net/http/pprof"
@@ -32,7 +37,8 @@ remoteRotator supports access via TCP, emulating the Yaesu GS232 protocol
 
 You can select the following rotator types:
 1. Yaesu (GS232 compatible)
-2. Dummy (great for testing)
+2. ARS (EA4TX ARS-USB / ARS-RCI, same as Yaesu)
+3. Dummy (great for testing)
 
 remoteRotator allows to assign a series of meta data to a rotator:
 1. Name
@@ -52,24 +58,80 @@ func init() {
 	serverCmd.AddCommand(lanServerCmd)
 
 	lanServerCmd.Flags().BoolP("tcp-enabled", "", false, "enable TCP Server")
-	lanServerCmd.Flags().StringP("tcp-host", "u", "127.0.0.1", "Host (use '0.0.0.0' to listen on all network adapters)")
+	lanServerCmd.Flags().StringSliceP("tcp-host", "u", []string{"127.0.0.1"}, "Host(s) to listen on; IPv4 or IPv6 (use '' or '::' to listen on all network adapters)")
 	lanServerCmd.Flags().IntP("tcp-port", "p", 7373, "TCP Port")
+	lanServerCmd.Flags().StringP("tcp-dialect", "", "arsvcom", fmt.Sprintf("TCP protocol dialect (supported: %s)", dialectNames()))
+	lanServerCmd.Flags().StringP("tcp-frame", "", "auto", "format of the TCP heading updates (auto: only the rotator's axes, azel: always +0aaa+0eee as required by ARSVCOM)")
+	lanServerCmd.Flags().IntP("tcp-digits", "", hub.DefaultDigits, "number of digits of each value in the TCP heading updates (e.g. 4: +0aaa)")
+	lanServerCmd.Flags().BoolP("tcp-readonly", "", false, "reject all commands from TCP clients (queries only)")
+	lanServerCmd.Flags().DurationP("tcp-idle-timeout", "", 0, "disconnect TCP clients which haven't sent anything within this time (0 to disable)")
+	lanServerCmd.Flags().BoolP("tcp-nodelay", "", true, "disable Nagle's algorithm on TCP connections (lower latency)")
+	lanServerCmd.Flags().DurationP("tcp-keepalive", "", time.Second*30, "TCP keep-alive period for idle clients (0 to disable)")
+	lanServerCmd.Flags().DurationP("tcp-write-timeout", "", time.Second*5, "maximum time for writing to a TCP client before it is disconnected (0 for unlimited)")
+	lanServerCmd.Flags().IntP("tcp-max-clients", "", 0, "maximum number of simultaneous TCP clients (0 for unlimited)")
+	lanServerCmd.Flags().IntP("hub-broadcast-rate", "", 0, "maximum number of heading updates per second sent to the clients (0 for unlimited)")
+	lanServerCmd.Flags().DurationP("hub-duplicate-keepalive", "", 0, "skip heading updates identical to the last one and repeat them only after this interval (0 to disable)")
+	lanServerCmd.Flags().Float64P("hub-max-slew-rate", "", 0, "drop headings implying a movement faster than this rate in deg/s (0 to disable)")
+	lanServerCmd.Flags().Float64P("hub-command-rate", "", 0, "maximum number of commands per second and client (0 for unlimited)")
+	lanServerCmd.Flags().BoolP("hub-stop-on-last-disconnect", "", false, "stop the rotator when the last controlling client disconnects")
+	lanServerCmd.Flags().BoolP("hub-dry-run", "", false, "simulate the commands instead of moving the rotator (for testing client software)")
+	lanServerCmd.Flags().BoolP("hub-shortest-path", "", false, "let rotators with overlap take the shortest path to the azimuth (same as --hub-azimuth-path=shortest)")
+	lanServerCmd.Flags().StringP("hub-azimuth-path", "", "direct", "path of rotators with overlap to an azimuth within the overlap (direct, shortest, clockwise)")
+	lanServerCmd.Flags().IntP("hub-direct-path-within", "", 0, "move rotators with overlap straight to azimuths at most this many degrees away, ignoring the azimuth path (0 to disable)")
+	lanServerCmd.Flags().IntP("hub-ramp-step", "", 0, "break large azimuth movements into steps of this size in degrees (0 to disable)")
+	lanServerCmd.Flags().IntP("hub-ramp-threshold", "", 90, "minimum azimuth movement in degrees to which the ramp is applied")
+	lanServerCmd.Flags().DurationP("hub-ramp-dwell", "", time.Second*5, "time to wait after each step of a ramp")
+	lanServerCmd.Flags().IntP("hub-preset-tolerance", "", 1, "deviation in degrees within which a rotator has reached its preset")
+	lanServerCmd.Flags().StringSliceP("hub-keep-out", "", []string{}, "azimuth ranges to which the rotator must not be commanded (e.g. 100-130,350-10)")
+	lanServerCmd.Flags().StringP("hub-preset-file", "", "", "file in which the last known presets are stored to restore them after a restart (disabled if empty)")
 	lanServerCmd.Flags().BoolP("http-enabled", "", true, "enable HTTP Server")
-	lanServerCmd.Flags().StringP("http-host", "w", "127.0.0.1", "Host (use '0.0.0.0' to listen on all network adapters)")
+	lanServerCmd.Flags().StringSliceP("http-host", "w", []string{"127.0.0.1"}, "Host(s) to listen on; IPv4 or IPv6 (use '' or '::' to listen on all network adapters)")
 	lanServerCmd.Flags().IntP("http-port", "k", 7070, "Port for the HTTP access to the rotator")
+	lanServerCmd.Flags().BoolP("http-tcp", "", false, "also serve the TCP protocol (see --tcp-dialect) on the HTTP port, e.g. if only one port can be forwarded")
+	lanServerCmd.Flags().StringP("http-tls-cert", "", "", "TLS certificate (PEM); enables HTTPS / WSS together with --http-tls-key")
+	lanServerCmd.Flags().StringP("http-tls-key", "", "", "TLS private key (PEM)")
+	lanServerCmd.Flags().StringP("http-auth-token", "", "", "token required to access the API and websocket (open if empty)")
+	lanServerCmd.Flags().DurationP("http-keepalive", "", time.Second*30, "period of the pings sent to websocket clients; unresponsive clients are disconnected (0 to disable)")
+	lanServerCmd.Flags().BoolP("http-ws-binary", "", false, "send the events to websocket clients as binary instead of text messages")
+	lanServerCmd.Flags().BoolP("http-ws-compression", "", false, "compress the messages sent to websocket clients (permessage-deflate)")
+	lanServerCmd.Flags().StringP("http-ws-path", "", "/ws", "path of the websocket endpoint")
+	lanServerCmd.Flags().IntP("http-max-clients", "", 0, "maximum number of simultaneous websocket clients (0 for unlimited)")
+	lanServerCmd.Flags().StringP("http-config-token", "", "", "token to access the config export / import API (disabled if empty)")
+	lanServerCmd.Flags().BoolP("http-metrics", "", false, "expose Prometheus metrics on /metrics")
+	lanServerCmd.Flags().StringSliceP("http-allowed-origins", "", []string{}, "origins from which browsers may access the API and websocket (e.g. https://panel.example.com; * for all)")
 	lanServerCmd.Flags().BoolP("discovery-enabled", "", true, "make rotator discoverable on the network")
 	lanServerCmd.Flags().StringP("portname", "P", "/dev/ttyACM0", "portname / path to the rotator (e.g. COM1)")
 	lanServerCmd.Flags().IntP("baudrate", "b", 9600, "baudrate")
-	lanServerCmd.Flags().StringP("type", "t", "yaesu", "Rotator type (supported: yaesu, dummy")
+	lanServerCmd.Flags().StringP("type", "t", "yaesu", "Rotator type (supported: yaesu, ars, dummy)")
 	lanServerCmd.Flags().StringP("name", "n", "myRotator", "Name tag for the rotator")
+	lanServerCmd.Flags().StringP("description", "", "", "description of the rotator / antenna for the clients (e.g. Tribander)")
+	lanServerCmd.Flags().StringP("location", "", "", "location of the rotator for the clients (e.g. Tower 1)")
+	lanServerCmd.Flags().StringSliceP("tags", "", []string{}, "tags of the rotator for the clients (e.g. hf,20m)")
 	lanServerCmd.Flags().BoolP("has-azimuth", "", true, "rotator supports Azimuth")
 	lanServerCmd.Flags().BoolP("has-elevation", "", false, "rotator supports Elevation")
 	lanServerCmd.Flags().DurationP("pollingrate", "", time.Second*1, "rotator polling rate")
 	lanServerCmd.Flags().IntP("azimuth-min", "", 0, "metadata: minimum azimuth (in deg)")
 	lanServerCmd.Flags().IntP("azimuth-max", "", 360, "metadata: maximum azimuth (in deg)")
 	lanServerCmd.Flags().IntP("azimuth-stop", "", 0, "metadata: mechanical azimuth stop (in deg)")
+	lanServerCmd.Flags().IntP("azimuth-offset", "", 0, "direction of the rotator's 0° azimuth (e.g. 180 for South-Center rotators)")
 	lanServerCmd.Flags().IntP("elevation-min", "", 0, "metadata: minimum elevation (in deg)")
 	lanServerCmd.Flags().IntP("elevation-max", "", 180, "metadata: maximum elevation (in deg)")
+	lanServerCmd.Flags().StringP("locator", "", "", "station location as Maidenhead locator (e.g. JN58td)")
+	lanServerCmd.Flags().Float64P("latitude", "", 0, "station latitude (in deg, North positive)")
+	lanServerCmd.Flags().Float64P("longitude", "", 0, "station longitude (in deg, East positive)")
+	lanServerCmd.Flags().StringP("park-at", "", "", "park the rotator daily at this local time (hh:mm)")
+	lanServerCmd.Flags().IntP("park-azimuth", "", 0, "park azimuth (in deg)")
+	lanServerCmd.Flags().IntP("park-elevation", "", 0, "park elevation (in deg)")
+	lanServerCmd.Flags().StringP("operating-from", "", "", "reject movement commands before this local time (hh:mm)")
+	lanServerCmd.Flags().StringP("operating-to", "", "", "reject movement commands after this local time (hh:mm)")
+	lanServerCmd.Flags().BoolP("influx-enabled", "", false, "export the rotator headings to InfluxDB")
+	lanServerCmd.Flags().StringP("influx-address", "", "udp://localhost:8089", "InfluxDB address (udp://host:port or http://host:port)")
+	lanServerCmd.Flags().StringP("influx-database", "", "rotators", "InfluxDB database (http only)")
+	lanServerCmd.Flags().BoolP("hass-enabled", "", false, "expose the rotator to Home Assistant through MQTT discovery")
+	lanServerCmd.Flags().StringP("hass-broker", "", "tcp://localhost:1883", "MQTT broker used by Home Assistant")
+	lanServerCmd.Flags().StringP("hass-username", "", "", "MQTT username")
+	lanServerCmd.Flags().StringP("hass-password", "", "", "MQTT password")
+	lanServerCmd.Flags().StringP("hass-discovery-prefix", "", "homeassistant", "Home Assistant MQTT discovery prefix")
 }
 
 func lanServer(cmd *cobra.Command, args []string) {
@@ -91,22 +153,78 @@ func lanServer(cmd *cobra.Command, args []string) {
 	viper.BindPFlag("tcp.enabled", cmd.Flags().Lookup("tcp-enabled"))
 	viper.BindPFlag("tcp.host", cmd.Flags().Lookup("tcp-host"))
 	viper.BindPFlag("tcp.port", cmd.Flags().Lookup("tcp-port"))
+	viper.BindPFlag("tcp.dialect", cmd.Flags().Lookup("tcp-dialect"))
+	viper.BindPFlag("tcp.frame", cmd.Flags().Lookup("tcp-frame"))
+	viper.BindPFlag("tcp.digits", cmd.Flags().Lookup("tcp-digits"))
+	viper.BindPFlag("tcp.readonly", cmd.Flags().Lookup("tcp-readonly"))
+	viper.BindPFlag("tcp.keepalive", cmd.Flags().Lookup("tcp-keepalive"))
+	viper.BindPFlag("tcp.nodelay", cmd.Flags().Lookup("tcp-nodelay"))
+	viper.BindPFlag("tcp.idle-timeout", cmd.Flags().Lookup("tcp-idle-timeout"))
+	viper.BindPFlag("tcp.write-timeout", cmd.Flags().Lookup("tcp-write-timeout"))
+	viper.BindPFlag("tcp.max-clients", cmd.Flags().Lookup("tcp-max-clients"))
+	viper.BindPFlag("hub.broadcast-rate", cmd.Flags().Lookup("hub-broadcast-rate"))
+	viper.BindPFlag("hub.duplicate-keepalive", cmd.Flags().Lookup("hub-duplicate-keepalive"))
+	viper.BindPFlag("hub.max-slew-rate", cmd.Flags().Lookup("hub-max-slew-rate"))
+	viper.BindPFlag("hub.command-rate", cmd.Flags().Lookup("hub-command-rate"))
+	viper.BindPFlag("hub.stop-on-last-disconnect", cmd.Flags().Lookup("hub-stop-on-last-disconnect"))
+	viper.BindPFlag("hub.dry-run", cmd.Flags().Lookup("hub-dry-run"))
+	viper.BindPFlag("hub.shortest-path", cmd.Flags().Lookup("hub-shortest-path"))
+	viper.BindPFlag("hub.azimuth-path", cmd.Flags().Lookup("hub-azimuth-path"))
+	viper.BindPFlag("hub.direct-path-within", cmd.Flags().Lookup("hub-direct-path-within"))
+	viper.BindPFlag("hub.ramp-step", cmd.Flags().Lookup("hub-ramp-step"))
+	viper.BindPFlag("hub.ramp-threshold", cmd.Flags().Lookup("hub-ramp-threshold"))
+	viper.BindPFlag("hub.ramp-dwell", cmd.Flags().Lookup("hub-ramp-dwell"))
+	viper.BindPFlag("hub.preset-tolerance", cmd.Flags().Lookup("hub-preset-tolerance"))
+	viper.BindPFlag("hub.keep-out", cmd.Flags().Lookup("hub-keep-out"))
+	viper.BindPFlag("hub.preset-file", cmd.Flags().Lookup("hub-preset-file"))
 	viper.BindPFlag("http.enabled", cmd.Flags().Lookup("http-enabled"))
 	viper.BindPFlag("http.host", cmd.Flags().Lookup("http-host"))
 	viper.BindPFlag("http.port", cmd.Flags().Lookup("http-port"))
+	viper.BindPFlag("http.tcp", cmd.Flags().Lookup("http-tcp"))
+	viper.BindPFlag("http.tls-cert", cmd.Flags().Lookup("http-tls-cert"))
+	viper.BindPFlag("http.tls-key", cmd.Flags().Lookup("http-tls-key"))
+	viper.BindPFlag("http.auth-token", cmd.Flags().Lookup("http-auth-token"))
+	viper.BindPFlag("http.keepalive", cmd.Flags().Lookup("http-keepalive"))
+	viper.BindPFlag("http.ws-binary", cmd.Flags().Lookup("http-ws-binary"))
+	viper.BindPFlag("http.ws-compression", cmd.Flags().Lookup("http-ws-compression"))
+	viper.BindPFlag("http.ws-path", cmd.Flags().Lookup("http-ws-path"))
+	viper.BindPFlag("http.max-clients", cmd.Flags().Lookup("http-max-clients"))
+	viper.BindPFlag("http.config-token", cmd.Flags().Lookup("http-config-token"))
+	viper.BindPFlag("http.metrics", cmd.Flags().Lookup("http-metrics"))
+	viper.BindPFlag("http.allowed-origins", cmd.Flags().Lookup("http-allowed-origins"))
 	viper.BindPFlag("discovery.enabled", cmd.Flags().Lookup("discovery-enabled"))
 	viper.BindPFlag("rotator.portname", cmd.Flags().Lookup("portname"))
 	viper.BindPFlag("rotator.baudrate", cmd.Flags().Lookup("baudrate"))
 	viper.BindPFlag("rotator.type", cmd.Flags().Lookup("type"))
 	viper.BindPFlag("rotator.name", cmd.Flags().Lookup("name"))
+	viper.BindPFlag("rotator.description", cmd.Flags().Lookup("description"))
+	viper.BindPFlag("rotator.location", cmd.Flags().Lookup("location"))
+	viper.BindPFlag("rotator.tags", cmd.Flags().Lookup("tags"))
 	viper.BindPFlag("rotator.has-azimuth", cmd.Flags().Lookup("has-azimuth"))
 	viper.BindPFlag("rotator.has-elevation", cmd.Flags().Lookup("has-elevation"))
 	viper.BindPFlag("rotator.pollingrate", cmd.Flags().Lookup("pollingrate"))
 	viper.BindPFlag("rotator.azimuth-min", cmd.Flags().Lookup("azimuth-min"))
 	viper.BindPFlag("rotator.azimuth-max", cmd.Flags().Lookup("azimuth-max"))
 	viper.BindPFlag("rotator.azimuth-stop", cmd.Flags().Lookup("azimuth-stop"))
+	viper.BindPFlag("rotator.azimuth-offset", cmd.Flags().Lookup("azimuth-offset"))
 	viper.BindPFlag("rotator.elevation-min", cmd.Flags().Lookup("elevation-min"))
 	viper.BindPFlag("rotator.elevation-max", cmd.Flags().Lookup("elevation-max"))
+	viper.BindPFlag("station.locator", cmd.Flags().Lookup("locator"))
+	viper.BindPFlag("station.latitude", cmd.Flags().Lookup("latitude"))
+	viper.BindPFlag("station.longitude", cmd.Flags().Lookup("longitude"))
+	viper.BindPFlag("park.at", cmd.Flags().Lookup("park-at"))
+	viper.BindPFlag("park.azimuth", cmd.Flags().Lookup("park-azimuth"))
+	viper.BindPFlag("park.elevation", cmd.Flags().Lookup("park-elevation"))
+	viper.BindPFlag("park.operating-from", cmd.Flags().Lookup("operating-from"))
+	viper.BindPFlag("park.operating-to", cmd.Flags().Lookup("operating-to"))
+	viper.BindPFlag("influx.enabled", cmd.Flags().Lookup("influx-enabled"))
+	viper.BindPFlag("influx.address", cmd.Flags().Lookup("influx-address"))
+	viper.BindPFlag("influx.database", cmd.Flags().Lookup("influx-database"))
+	viper.BindPFlag("hass.enabled", cmd.Flags().Lookup("hass-enabled"))
+	viper.BindPFlag("hass.broker", cmd.Flags().Lookup("hass-broker"))
+	viper.BindPFlag("hass.username", cmd.Flags().Lookup("hass-username"))
+	viper.BindPFlag("hass.password", cmd.Flags().Lookup("hass-password"))
+	viper.BindPFlag("hass.discovery-prefix", cmd.Flags().Lookup("hass-discovery-prefix"))
 
 	if err := sanityCheckRotatorInputs(); err != nil {
 		fmt.Println(err)
@@ -123,14 +241,145 @@ func lanServer(cmd *cobra.Command, args []string) {
 	// 	log.Println(http.ListenAndServe("0.0.0.0:6060", http.DefaultServeMux))
 	// }()
 
+	hubOpts := []func(*hub.Hub){
+		hub.TCPKeepAlive(viper.GetDuration("tcp.keepalive")),
+		hub.TCPNoDelay(viper.GetBool("tcp.nodelay")),
+		hub.TCPIdleTimeout(viper.GetDuration("tcp.idle-timeout")),
+		hub.TCPWriteTimeout(viper.GetDuration("tcp.write-timeout")),
+		hub.MaxTCPClients(viper.GetInt("tcp.max-clients")),
+		hub.WsKeepAlive(viper.GetDuration("http.keepalive")),
+		hub.WsBinary(viper.GetBool("http.ws-binary")),
+		hub.WsCompression(viper.GetBool("http.ws-compression")),
+		hub.WsPath(viper.GetString("http.ws-path")),
+		hub.MaxWsClients(viper.GetInt("http.max-clients")),
+		hub.BroadcastRate(viper.GetInt("hub.broadcast-rate")),
+		hub.MaxSlewRate(viper.GetFloat64("hub.max-slew-rate")),
+		hub.CommandRate(viper.GetFloat64("hub.command-rate")),
+		hub.StopOnLastDisconnect(viper.GetBool("hub.stop-on-last-disconnect")),
+		hub.DryRun(viper.GetBool("hub.dry-run")),
+		hub.Ramp(hub.RampProfile{
+			Threshold: viper.GetInt("hub.ramp-threshold"),
+			Step:      viper.GetInt("hub.ramp-step"),
+			Dwell:     viper.GetDuration("hub.ramp-dwell"),
+		}),
+		hub.PresetTolerance(viper.GetInt("hub.preset-tolerance")),
+		hub.AllowedOrigins(viper.GetStringSlice("http.allowed-origins")...),
+	}
+
+	azPath, err := hub.ParseAzimuthPath(viper.GetString("hub.azimuth-path"))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if viper.GetBool("hub.shortest-path") {
+		azPath = hub.PathShortest
+	}
+	hubOpts = append(hubOpts, hub.AzimuthRouting(azPath),
+		hub.DirectPathWithin(viper.GetInt("hub.direct-path-within")))
+
+	for _, s := range viper.GetStringSlice("hub.keep-out") {
+		z, err := hub.ParseKeepOutZone(s)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		hubOpts = append(hubOpts, hub.KeepOut(z))
+	}
+
+	if len(viper.GetString("station.locator")) > 0 {
+		lat, lon, err := astro.ParseLocator(viper.GetString("station.locator"))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		hubOpts = append(hubOpts, hub.Location(lat, lon))
+	} else if viper.IsSet("station.latitude") || viper.IsSet("station.longitude") {
+		hubOpts = append(hubOpts, hub.Location(viper.GetFloat64("station.latitude"),
+			viper.GetFloat64("station.longitude")))
+	}
+
+	if viper.GetDuration("hub.duplicate-keepalive") > 0 {
+		hubOpts = append(hubOpts, hub.SuppressDuplicates(viper.GetDuration("hub.duplicate-keepalive")))
+	}
+
+	if len(viper.GetString("http.auth-token")) > 0 {
+		hubOpts = append(hubOpts, hub.AuthToken(viper.GetString("http.auth-token")))
+	}
+
+	if len(viper.GetString("hub.preset-file")) > 0 {
+		hubOpts = append(hubOpts, hub.PresetStore(hub.NewFileStore(viper.GetString("hub.preset-file"))))
+	}
+
+	if len(viper.GetString("http.config-token")) > 0 {
+		hubOpts = append(hubOpts, hub.ConfigToken(viper.GetString("http.config-token")))
+	}
+
+	var collector *metrics.Collector
+
+	if viper.GetBool("http.metrics") {
+		collector = metrics.New()
+		hubOpts = append(hubOpts, hub.Metrics(collector))
+	}
+
+	h, err := hub.New(hubOpts...)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var exporter *influx.Exporter
+
+	if viper.GetBool("influx.enabled") {
+		e, err := influx.New(
+			influx.Address(viper.GetString("influx.address")),
+			influx.Database(viper.GetString("influx.database")),
+		)
+		if err != nil {
+			fmt.Println("unable to initialize influxdb exporter:", err)
+			os.Exit(1)
+		}
+		defer e.Close()
+		exporter = e
+	}
+
+	var bridge *hass.Bridge
+
+	if viper.GetBool("hass.enabled") {
+		b, err := hass.New(h,
+			hass.Broker(viper.GetString("hass.broker")),
+			hass.Credentials(viper.GetString("hass.username"), viper.GetString("hass.password")),
+			hass.DiscoveryPrefix(viper.GetString("hass.discovery-prefix")),
+		)
+		if err != nil {
+			fmt.Println("unable to initialize home assistant bridge:", err)
+			os.Exit(1)
+		}
+		defer b.Close()
+		bridge = b
+	}
+
 	bcast := make(chan rotator.Heading, 10)
 
 	var rEventHandler = func(r rotator.Rotator, heading rotator.Heading) {
+		if exporter != nil {
+			exporter.Write(r.Name(), heading)
+		}
+		if bridge != nil {
+			bridge.Update(r, heading)
+		}
+		if collector != nil {
+			collector.Update(r.Name(), heading)
+		}
 		bcast <- heading
 	}
 
 	rotatorError := make(chan struct{})
 
+	azOffset := viper.GetInt("rotator.azimuth-offset")
+	if azOffset != 0 {
+		rEventHandler = offset.EventHandler(azOffset, rEventHandler)
+	}
+
 	// initialize our Rotator
 	r, err := initRotator(viper.GetString("rotator.type"), rEventHandler, rotatorError)
 	if err != nil {
@@ -138,31 +387,103 @@ func lanServer(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	h, err := hub.NewHub(r)
+	if azOffset != 0 {
+		r = offset.New(r, azOffset)
+	}
+
+	if err := h.AddRotator(r); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	md := rotator.Metadata{
+		Description: viper.GetString("rotator.description"),
+		Location:    viper.GetString("rotator.location"),
+		Tags:        viper.GetStringSlice("rotator.tags"),
+	}
+	if len(md.Description) > 0 || len(md.Location) > 0 || len(md.Tags) > 0 {
+		if err := h.SetMetadata(r.Name(), md); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if bridge != nil {
+		if err := bridge.AddRotator(r); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if len(viper.GetString("park.at")) > 0 {
+		ps := hub.ParkSchedule{
+			At:            viper.GetString("park.at"),
+			Azimuth:       viper.GetInt("park.azimuth"),
+			Elevation:     viper.GetInt("park.elevation"),
+			OperatingFrom: viper.GetString("park.operating-from"),
+			OperatingTo:   viper.GetString("park.operating-to"),
+		}
+		if err := h.SetParkSchedule(r.Name(), ps); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	dialect, err := hub.ParseDialect(viper.GetString("tcp.dialect"))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	frame, err := hub.ParseFrame(viper.GetString("tcp.frame"))
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	if err := hub.CheckDigits(viper.GetInt("tcp.digits")); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	tcpOpts := []func(*hub.TCPClient){
+		hub.TCPDialect(dialect), hub.TCPFrame(frame),
+		hub.TCPDigits(viper.GetInt("tcp.digits")),
+		hub.TCPReadOnly(viper.GetBool("tcp.readonly")),
+	}
 
-	tcpError := make(chan bool)
+	tcpError := make(chan error)
 
 	// start TCP server
 	if viper.GetBool("tcp.enabled") {
-		go h.ListenTCP(viper.GetString("tcp.host"), viper.GetInt("tcp.port"), tcpError)
+		listenAll(listenHosts("tcp.host"), tcpError, func(host string, errCh chan<- error) {
+			h.ListenTCP(host, viper.GetInt("tcp.port"), errCh, tcpOpts...)
+		})
 	}
 
-	webServerError := make(chan struct{})
+	webServerError := make(chan error)
 
 	// start HTTP server
 	if viper.GetBool("http.enabled") {
-		go h.ListenHTTP(viper.GetString("http.host"), viper.GetInt("http.port"), webServerError)
+		certFile := viper.GetString("http.tls-cert")
+		keyFile := viper.GetString("http.tls-key")
+		useTLS := len(certFile) > 0 && len(keyFile) > 0
+		if useTLS && viper.GetBool("http.tcp") {
+			fmt.Println("the TCP protocol can not be served on the HTTPS port")
+			os.Exit(1)
+		}
+		listenAll(listenHosts("http.host"), webServerError, func(host string, errCh chan<- error) {
+			switch {
+			case useTLS:
+				h.ListenHTTPS(host, viper.GetInt("http.port"), certFile, keyFile, errCh)
+			case viper.GetBool("http.tcp"):
+				h.ListenCombined(host, viper.GetInt("http.port"), errCh, tcpOpts...)
+			default:
+				h.ListenHTTP(host, viper.GetInt("http.port"), errCh)
+			}
+		})
 	}
 
-	// start mDNS server
-	mDNSShutdown := make(chan struct{})
-
+	// advertise the rotator via mDNS
 	if viper.GetBool("discovery.enabled") {
-		if err := startMdnsServer(mDNSShutdown); err != nil {
+		if err := startMdnsServer(h); err != nil {
 			log.Println(err)
 		}
 	}
@@ -177,57 +498,88 @@ func lanServer(cmd *cobra.Command, args []string) {
 		select {
 		case sig := <-osSignals:
 			if sig == os.Interrupt {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+				if err := h.Shutdown(ctx); err != nil {
+					log.Println(err)
+				}
+				cancel()
 				r.Close()
-				close(mDNSShutdown)
 				return
 			}
 		case msg := <-bcast:
 			h.Broadcast(msg)
 		case <-rotatorError:
+			// let the clients know why the rotator is gone
+			h.ReportFault(r.Name(), fmt.Errorf("lost communication with the rotator controller"))
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			if err := h.Shutdown(ctx); err != nil {
+				log.Println(err)
+			}
+			cancel()
 			return
-		case <-tcpError:
+		case err := <-tcpError:
+			if err != nil {
+				fmt.Println("unable to start the tcp server:", err)
+			}
 			return
-		case <-webServerError:
+		case err := <-webServerError:
+			if err != nil {
+				fmt.Println("unable to start the http server:", err)
+			}
 			return
 		}
 	}
 
 }
 
-func startMdnsServer(shutdown <-chan struct{}) error {
+func startMdnsServer(h *hub.Hub) error {
 
 	if !viper.GetBool("http.enabled") {
 		return fmt.Errorf("discovery disabled; the HTTP server must be enabled and accessible over a network interface (e.g. 0.0.0.0)")
 	}
 
-	netif := net.ParseIP(viper.GetString("http.host"))
+	accessible := false
+	for _, host := range listenHosts("http.host") {
+		netif := net.ParseIP(strings.Trim(host, "[]"))
+		if host == "" ||
+			bytes.Compare(netif, net.IPv4zero) == 0 ||
+			bytes.Compare(netif, net.IPv6zero) == 0 ||
+			bytes.Compare(netif, getOutboundIP()) == 0 {
+			accessible = true
+		}
+	}
 
-	if bytes.Compare(netif, net.IPv4zero) != 0 &&
-		bytes.Compare(netif, net.IPv6zero) != 0 &&
-		bytes.Compare(netif, getOutboundIP()) != 0 {
+	if !accessible {
 		return fmt.Errorf("discovery disabled; the HTTP server must listen on an accessible network interface (e.g. 0.0.0.0)")
 	}
 
-	go func() {
-		mDNSService, err := mdns.NewMDNSService(viper.GetString("rotator.name"),
-			"_rotator._tcp", "", "", viper.GetInt("http.port"),
-			[]net.IP{getOutboundIP()}, nil)
+	if err := h.Advertise(viper.GetInt("http.port"), []net.IP{getOutboundIP()}); err != nil {
+		return fmt.Errorf("discovery disabled; %v", err)
+	}
 
-		if err != nil {
-			log.Printf("discovery disabled; unable to start mDNS service: %s\n", err)
-			return
-		}
+	return nil
+}
 
-		mDNSServer, err := mdns.NewServer(&mdns.Config{Zone: mDNSService})
-		if err != nil {
-			log.Printf("discovery disabled; unable to start mDNS service: %s\n", err)
-			return
-		}
-		defer mDNSServer.Shutdown()
-		<-shutdown
-	}()
+// listenHosts returns the hosts on which the server configured under key
+// shall listen. An empty list binds all network interfaces.
+func listenHosts(key string) []string {
+	hosts := viper.GetStringSlice(key)
+	if len(hosts) == 0 {
+		return []string{""}
+	}
+	return hosts
+}
 
-	return nil
+// listenAll starts listen in a go routine for each of the hosts. When a
+// listener returns, its error (or nil) is forwarded to errorCh.
+func listenAll(hosts []string, errorCh chan<- error, listen func(host string, errCh chan<- error)) {
+	for _, host := range hosts {
+		errCh := make(chan error)
+		go listen(host, errCh)
+		go func() {
+			errorCh <- <-errCh
+		}()
+	}
 }
 
 // Get preferred outbound ip of this machine
@@ -243,3 +595,13 @@ func getOutboundIP() net.IP {
 
 	return localAddr.IP
 }
+
+// dialectNames returns the comma separated list of the tcp dialects
+// supported by the hub.
+func dialectNames() string {
+	names := []string{}
+	for _, d := range hub.Dialects() {
+		names = append(names, string(d))
+	}
+	return strings.Join(names, ", ")
+}
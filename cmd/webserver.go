@@ -75,7 +75,7 @@ func webServer(cmd *cobra.Command, args []string) {
 	viper.BindPFlag("nats.password", cmd.Flags().Lookup("password"))
 	viper.BindPFlag("nats.username", cmd.Flags().Lookup("username"))
 
-	h, err := hub.NewHub()
+	h, err := hub.New()
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -152,8 +152,9 @@ func webServer(cmd *cobra.Command, args []string) {
 	}
 	w := webserver{h, cl, cache}
 
-	// will be closed when an error occures in the webserver goroutine
-	webserverErrorCh := make(chan struct{})
+	// receives the error (if any) and will be closed when the
+	// webserver goroutine returns
+	webserverErrorCh := make(chan error)
 
 	// launch webserver
 	go w.ListenHTTP(viper.GetString("web.host"), viper.GetInt("web.port"), webserverErrorCh)
@@ -187,8 +188,8 @@ func webServer(cmd *cobra.Command, args []string) {
 			for _, r := range rotators {
 				r.Close()
 			}
-		case <-webserverErrorCh:
-			fmt.Println("web server crashed")
+		case err := <-webserverErrorCh:
+			fmt.Println("web server crashed:", err)
 			return
 		case <-ticker.C:
 			switch sbTransport {
@@ -391,17 +392,17 @@ func (w *webserver) update() {
 		r, err := proxy.New(done, host, port, eh)
 		if err != nil {
 			log.Println("unable to create proxy object:", err)
-			r.Close()
-			r = nil
 			continue
 		}
 		if err := w.AddRotator(r); err != nil {
 			log.Println(err)
+			r.Close()
 			continue
 		}
 		go func() {
 			<-doneCh
 			w.RemoveRotator(r)
+			r.Close()
 		}()
 	}
 }
@@ -2,6 +2,7 @@ package discovery
 
 import (
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/micro/mdns"
@@ -15,6 +16,9 @@ type RotatorMdnsEntry struct {
 	AddrV4 net.IP
 	AddrV6 net.IP
 	Port   int
+	// supported axes as advertised in the TXT records
+	HasAzimuth   bool
+	HasElevation bool
 }
 
 // LookupRotators will perform an mDNS query are lookup all available
@@ -47,6 +51,7 @@ func LookupRotators() ([]RotatorMdnsEntry, error) {
 				AddrV6: entry.AddrV6,
 				Port:   entry.Port,
 			}
+			parseTXT(&r, entry.InfoFields)
 			rotators = append(rotators, r)
 		}
 	}()
@@ -56,3 +61,20 @@ func LookupRotators() ([]RotatorMdnsEntry, error) {
 	close(entriesCh)
 	return rotators, nil
 }
+
+// parseTXT sets the fields of r advertised in the TXT records
+// (key=value). Unknown keys are ignored.
+func parseTXT(r *RotatorMdnsEntry, fields []string) {
+	for _, f := range fields {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "has_azimuth":
+			r.HasAzimuth, _ = strconv.ParseBool(kv[1])
+		case "has_elevation":
+			r.HasElevation, _ = strconv.ParseBool(kv[1])
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// Package hass exposes rotators to Home Assistant through MQTT discovery.
+// Each rotator shows up in Home Assistant as a "cover" entity whose
+// position corresponds to the rotator's azimuth.
+package hass
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Commander executes the commands received from Home Assistant.
+// *hub.Hub satisfies this interface.
+type Commander interface {
+	SetAzimuth(name string, az int) error
+	Stop(name string) error
+}
+
+// Bridge publishes the Home Assistant discovery configuration and the
+// state of the rotators to an MQTT broker and forwards the commands
+// received from Home Assistant to the Commander.
+type Bridge struct {
+	sync.Mutex
+	broker          string
+	clientID        string
+	username        string
+	password        string
+	discoveryPrefix string
+	baseTopic       string
+	timeout         time.Duration
+	commander       Commander
+	client          mqtt.Client
+	rotators        map[string]rotator.Rotator
+}
+
+// payloadStop is sent by Home Assistant on the command topic
+const payloadStop = "STOP"
+
+// config is the Home Assistant MQTT discovery payload of a cover
+type config struct {
+	Name              string  `json:"name"`
+	UniqueID          string  `json:"unique_id"`
+	CommandTopic      string  `json:"command_topic"`
+	PayloadOpen       *string `json:"payload_open"`
+	PayloadClose      *string `json:"payload_close"`
+	PayloadStop       string  `json:"payload_stop"`
+	StateTopic        string  `json:"state_topic"`
+	PositionTopic     string  `json:"position_topic"`
+	SetPositionTopic  string  `json:"set_position_topic"`
+	PositionOpen      int     `json:"position_open"`
+	PositionClosed    int     `json:"position_closed"`
+	AvailabilityTopic string  `json:"availability_topic"`
+	Device            device  `json:"device"`
+}
+
+type device struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model"`
+}
+
+// New returns the pointer to an initialized and connected Home Assistant
+// Bridge. Options can be injected through functional options.
+// Default settings are:
+// broker: tcp://localhost:1883,
+// clientID: remoteRotator,
+// discoveryPrefix: homeassistant,
+// baseTopic: remoteRotator,
+// timeout: 5sec.
+func New(c Commander, opts ...func(*Bridge)) (*Bridge, error) {
+
+	b := &Bridge{
+		broker:          "tcp://localhost:1883",
+		clientID:        "remoteRotator",
+		discoveryPrefix: "homeassistant",
+		baseTopic:       "remoteRotator",
+		timeout:         time.Second * 5,
+		commander:       c,
+		rotators:        make(map[string]rotator.Rotator),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	mqttOpts := mqtt.NewClientOptions().
+		AddBroker(b.broker).
+		SetClientID(b.clientID).
+		SetUsername(b.username).
+		SetPassword(b.password).
+		SetAutoReconnect(true).
+		SetWill(b.availabilityTopic(), "offline", 1, true).
+		SetOnConnectHandler(b.onConnect)
+
+	b.client = mqtt.NewClient(mqttOpts)
+
+	token := b.client.Connect()
+	if !token.WaitTimeout(b.timeout) {
+		return nil, fmt.Errorf("timeout while connecting to mqtt broker %s", b.broker)
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("unable to connect to mqtt broker %s: %v", b.broker, err)
+	}
+
+	return b, nil
+}
+
+// AddRotator publishes the discovery configuration of a rotator and
+// subscribes to its command topics.
+func (b *Bridge) AddRotator(r rotator.Rotator) error {
+	if !r.HasAzimuth() {
+		return fmt.Errorf("rotator %s does not support azimuth", r.Name())
+	}
+
+	b.Lock()
+	b.rotators[r.Name()] = r
+	b.Unlock()
+
+	if err := b.announce(r); err != nil {
+		return err
+	}
+
+	b.Update(r, r.Serialize().Heading)
+
+	return nil
+}
+
+// RemoveRotator deletes the discovery configuration of a rotator, so
+// that Home Assistant removes the entity.
+func (b *Bridge) RemoveRotator(r rotator.Rotator) {
+	b.Lock()
+	delete(b.rotators, r.Name())
+	b.Unlock()
+
+	id := objectID(r.Name())
+	b.client.Unsubscribe(b.topic(id, "azimuth/set"), b.topic(id, "command"))
+	b.publish(b.discoveryTopic(id), true, "")
+}
+
+// Update publishes the heading of a rotator. It is typically called
+// from the rotator's event handler and therefore doesn't wait for the
+// broker to acknowledge the messages.
+func (b *Bridge) Update(r rotator.Rotator, h rotator.Heading) {
+	b.Lock()
+	_, ok := b.rotators[r.Name()]
+	b.Unlock()
+	if !ok {
+		return
+	}
+
+	id := objectID(r.Name())
+	b.client.Publish(b.topic(id, "azimuth"), 1, true, strconv.Itoa(h.Azimuth))
+	b.client.Publish(b.topic(id, "state"), 1, true, state(h))
+}
+
+// Close marks the rotators as unavailable and disconnects from the
+// MQTT broker.
+func (b *Bridge) Close() {
+	b.publish(b.availabilityTopic(), true, "offline")
+	b.client.Disconnect(250)
+}
+
+// onConnect is called on every (re-)connect to the broker
+func (b *Bridge) onConnect(c mqtt.Client) {
+	b.publish(b.availabilityTopic(), true, "online")
+
+	b.Lock()
+	rotators := make([]rotator.Rotator, 0, len(b.rotators))
+	for _, r := range b.rotators {
+		rotators = append(rotators, r)
+	}
+	b.Unlock()
+
+	for _, r := range rotators {
+		if err := b.announce(r); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// announce publishes the discovery configuration of a rotator and
+// subscribes to its command topics.
+func (b *Bridge) announce(r rotator.Rotator) error {
+	id := objectID(r.Name())
+
+	data, err := json.Marshal(b.config(r.Serialize()))
+	if err != nil {
+		return err
+	}
+
+	if err := b.publish(b.discoveryTopic(id), true, string(data)); err != nil {
+		return err
+	}
+
+	if err := b.subscribe(b.topic(id, "azimuth/set"), b.setPositionHandler(r.Name())); err != nil {
+		return err
+	}
+
+	return b.subscribe(b.topic(id, "command"), b.commandHandler(r.Name()))
+}
+
+func (b *Bridge) setPositionHandler(name string) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		payload := strings.TrimSpace(string(msg.Payload()))
+		az, err := strconv.ParseFloat(payload, 64)
+		if err != nil {
+			log.Printf("invalid azimuth %q received from home assistant for rotator %s\n", payload, name)
+			return
+		}
+		if err := b.commander.SetAzimuth(name, int(math.Round(az))); err != nil {
+			log.Printf("unable to set azimuth of rotator %s: %v\n", name, err)
+		}
+	}
+}
+
+func (b *Bridge) commandHandler(name string) mqtt.MessageHandler {
+	return func(c mqtt.Client, msg mqtt.Message) {
+		if strings.TrimSpace(string(msg.Payload())) != payloadStop {
+			return
+		}
+		if err := b.commander.Stop(name); err != nil {
+			log.Printf("unable to stop rotator %s: %v\n", name, err)
+		}
+	}
+}
+
+// config returns the discovery configuration of a rotator
+func (b *Bridge) config(obj rotator.Object) config {
+	id := objectID(obj.Name)
+	return config{
+		Name:              obj.Name,
+		UniqueID:          "remoterotator_" + id,
+		CommandTopic:      b.topic(id, "command"),
+		PayloadStop:       payloadStop,
+		StateTopic:        b.topic(id, "state"),
+		PositionTopic:     b.topic(id, "azimuth"),
+		SetPositionTopic:  b.topic(id, "azimuth/set"),
+		PositionOpen:      obj.Config.AzimuthMax,
+		PositionClosed:    obj.Config.AzimuthMin,
+		AvailabilityTopic: b.availabilityTopic(),
+		Device: device{
+			Identifiers:  []string{"remoterotator_" + id},
+			Name:         obj.Name,
+			Manufacturer: "remoteRotator",
+			Model:        "Antenna Rotator",
+		},
+	}
+}
+
+func (b *Bridge) publish(topic string, retained bool, payload string) error {
+	token := b.client.Publish(topic, 1, retained, payload)
+	if !token.WaitTimeout(b.timeout) {
+		return fmt.Errorf("timeout while publishing to %s", topic)
+	}
+	return token.Error()
+}
+
+func (b *Bridge) subscribe(topic string, handler mqtt.MessageHandler) error {
+	token := b.client.Subscribe(topic, 1, handler)
+	if !token.WaitTimeout(b.timeout) {
+		return fmt.Errorf("timeout while subscribing to %s", topic)
+	}
+	return token.Error()
+}
+
+func (b *Bridge) topic(id, suffix string) string {
+	return fmt.Sprintf("%s/%s/%s", b.baseTopic, id, suffix)
+}
+
+func (b *Bridge) availabilityTopic() string {
+	return b.baseTopic + "/status"
+}
+
+func (b *Bridge) discoveryTopic(id string) string {
+	return fmt.Sprintf("%s/cover/%s/config", b.discoveryPrefix, id)
+}
+
+// state maps the heading to the states of a Home Assistant cover
+func state(h rotator.Heading) string {
+	switch {
+	case h.AzPreset > h.Azimuth:
+		return "opening"
+	case h.AzPreset < h.Azimuth:
+		return "closing"
+	default:
+		return "stopped"
+	}
+}
+
+// objectID converts a rotator name into a string which can be used
+// in MQTT topics and as Home Assistant object ID
+func objectID(name string) string {
+	id := []rune(strings.ToLower(name))
+	for i, c := range id {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			id[i] = '_'
+		}
+	}
+	return string(id)
+}
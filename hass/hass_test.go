@@ -0,0 +1,96 @@
+package hass
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+func TestObjectID(t *testing.T) {
+
+	tt := []struct {
+		name  string
+		expID string
+	}{
+		{"myRotator", "myrotator"},
+		{"40m Yagi", "40m_yagi"},
+		{"EME/2m#1", "eme_2m_1"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if id := objectID(tc.name); id != tc.expID {
+				t.Fatalf("expected %s, got %s", tc.expID, id)
+			}
+		})
+	}
+}
+
+func TestState(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		h        rotator.Heading
+		expState string
+	}{
+		{"stopped", rotator.Heading{Azimuth: 120, AzPreset: 120}, "stopped"},
+		{"clockwise", rotator.Heading{Azimuth: 120, AzPreset: 200}, "opening"},
+		{"counter clockwise", rotator.Heading{Azimuth: 120, AzPreset: 20}, "closing"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if s := state(tc.h); s != tc.expState {
+				t.Fatalf("expected %s, got %s", tc.expState, s)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+
+	b := &Bridge{
+		discoveryPrefix: "homeassistant",
+		baseTopic:       "remoteRotator",
+	}
+
+	obj := rotator.Object{
+		Name: "40m Yagi",
+		Config: rotator.Config{
+			HasAzimuth: true,
+			AzimuthMin: 0,
+			AzimuthMax: 450,
+		},
+	}
+
+	data, err := json.Marshal(b.config(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := string(data)
+
+	exp := []string{
+		`"unique_id":"remoterotator_40m_yagi"`,
+		`"payload_open":null`,
+		`"payload_close":null`,
+		`"payload_stop":"STOP"`,
+		`"position_topic":"remoteRotator/40m_yagi/azimuth"`,
+		`"set_position_topic":"remoteRotator/40m_yagi/azimuth/set"`,
+		`"position_open":450`,
+		`"position_closed":0`,
+		`"availability_topic":"remoteRotator/status"`,
+	}
+
+	for _, e := range exp {
+		if !strings.Contains(res, e) {
+			t.Fatalf("expected %s in %s", e, res)
+		}
+	}
+
+	if topic := b.discoveryTopic("40m_yagi"); topic != "homeassistant/cover/40m_yagi/config" {
+		t.Fatalf("unexpected discovery topic %s", topic)
+	}
+}
@@ -0,0 +1,51 @@
+package hass
+
+import "time"
+
+// Broker is a functional option to set the URL of the MQTT broker
+// (e.g. tcp://localhost:1883).
+func Broker(url string) func(*Bridge) {
+	return func(b *Bridge) {
+		b.broker = url
+	}
+}
+
+// ClientID is a functional option to set the MQTT client ID.
+func ClientID(id string) func(*Bridge) {
+	return func(b *Bridge) {
+		b.clientID = id
+	}
+}
+
+// Credentials is a functional option to set the username and password
+// for the MQTT broker.
+func Credentials(username, password string) func(*Bridge) {
+	return func(b *Bridge) {
+		b.username = username
+		b.password = password
+	}
+}
+
+// DiscoveryPrefix is a functional option to set the topic prefix
+// Home Assistant is listening on for discovery messages.
+func DiscoveryPrefix(prefix string) func(*Bridge) {
+	return func(b *Bridge) {
+		b.discoveryPrefix = prefix
+	}
+}
+
+// BaseTopic is a functional option to set the topic prefix under which
+// the state and command topics of the rotators are published.
+func BaseTopic(topic string) func(*Bridge) {
+	return func(b *Bridge) {
+		b.baseTopic = topic
+	}
+}
+
+// Timeout is a functional option to set the timeout for connecting and
+// publishing to the MQTT broker.
+func Timeout(d time.Duration) func(*Bridge) {
+	return func(b *Bridge) {
+		b.timeout = d
+	}
+}
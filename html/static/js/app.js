@@ -36,6 +36,7 @@ var vm = new Vue({
         hideConnectionMsg: false,
         resizeTimeout: null,
         connected: false,
+        token: null, // auth token
     },
     components: {
         'azimuth-rotator': AzimuthRotator,
@@ -43,6 +44,12 @@ var vm = new Vue({
         'rotator-name': RotatorName,
     },
     created: function () {
+        // the server might require an auth token which can be provided
+        // as query parameter (e.g. http://myhost:7070/?token=secret)
+        this.token = new URLSearchParams(window.location.search).get("token");
+        if (this.token) {
+            Vue.http.headers.common['Authorization'] = 'Bearer ' + this.token;
+        }
         window.addEventListener('resize', this.getWindowSize);
         this.resizeWindow();
     },
@@ -147,14 +154,22 @@ var vm = new Vue({
             if (window.location.protocol.indexOf("https") !== -1) {
                 protocol = "wss://";
             }
-            this.ws = new ReconnectingWebSocket(protocol + window.location.host + '/ws');
+            var url = protocol + window.location.host + '/ws';
+            if (this.token) {
+                url += '?token=' + encodeURIComponent(this.token);
+            }
+            this.ws = new ReconnectingWebSocket(url);
             this.ws.addEventListener('message', function (e) {
                 var eventMsg = JSON.parse(e.data);
                 // console.log(eventMsg);
 
                 // add rotator
                 if (eventMsg['name'] == 'add') {
-                    this.getRotatorObj(eventMsg['rotator_name']);
+                    if (eventMsg['rotator']) {
+                        this.addRotator(eventMsg['rotator']);
+                    } else {
+                        this.getRotatorObj(eventMsg['rotator_name']);
+                    }
 
                 // remove rotator
                 } else if (eventMsg['name'] == 'remove') {
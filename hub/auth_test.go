@@ -0,0 +1,82 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// newTestRouter loads the hub's routes without the static files
+func newTestRouter(h *Hub) {
+	h.fileServer = http.NotFoundHandler()
+	h.router = mux.NewRouter().StrictSlash(true)
+	h.routes()
+}
+
+func TestAuthToken(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+	AuthToken("secret")(h)
+	newTestRouter(h)
+
+	srv := httptest.NewServer(h.router)
+	defer srv.Close()
+
+	tt := []struct {
+		name    string
+		path    string
+		header  string
+		expCode int
+	}{
+		{"no token", "/api/rotators", "", http.StatusUnauthorized},
+		{"wrong token", "/api/rotators", "Bearer wrong", http.StatusUnauthorized},
+		{"header token", "/api/rotators", "Bearer secret", http.StatusOK},
+		{"query token", "/api/rotators?token=secret", "", http.StatusOK},
+		{"wrong query token", "/api/rotators?token=wrong", "", http.StatusUnauthorized},
+		{"ws no token", "/ws", "", http.StatusUnauthorized},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", srv.URL+tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tc.header) > 0 {
+				req.Header.Set("Authorization", tc.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.expCode {
+				t.Fatalf("expected status %d, got %d", tc.expCode, resp.StatusCode)
+			}
+		})
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	if _, _, err := websocket.DefaultDialer.Dial(wsURL, nil); err == nil {
+		t.Fatal("expected unauthorized websocket connection to fail")
+	}
+
+	h.RLock()
+	n := len(h.wsClients)
+	h.RUnlock()
+	if n != 0 {
+		t.Fatalf("expected 0 websocket clients, got %d", n)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,68 @@
+package hub
+
+// ClientEventType describes whether a client has connected or disconnected.
+type ClientEventType string
+
+const (
+	// ClientConnected is emitted when a client connects to the hub.
+	ClientConnected ClientEventType = "connected"
+	// ClientDisconnected is emitted when a client disconnects from the hub.
+	ClientDisconnected ClientEventType = "disconnected"
+)
+
+// Protocols through which clients connect to the hub
+const (
+	ProtocolTCP       = "tcp"
+	ProtocolWebsocket = "websocket"
+	ProtocolHTTP      = "http"
+)
+
+// ClientEvent describes a client which has connected to or disconnected
+// from the hub.
+type ClientEvent struct {
+	Type       ClientEventType `json:"type"`
+	Protocol   string          `json:"protocol"`
+	RemoteAddr string          `json:"remote_addr"`
+	// number of clients (all protocols) connected after the event
+	Clients int `json:"clients"`
+}
+
+// size of the buffer for client events which haven't been handled yet
+const clientEventBufferSize = 100
+
+// emitClientEvent queues a client event for the client event handler and
+// updates the client metrics. If the handler falls behind, the event is
+// dropped. The caller must hold the lock.
+func (hub *Hub) emitClientEvent(t ClientEventType, protocol, remoteAddr string) {
+	hub.updateClientMetrics()
+
+	if hub.clientEventHandler == nil {
+		return
+	}
+
+	ev := ClientEvent{
+		Type:       t,
+		Protocol:   protocol,
+		RemoteAddr: remoteAddr,
+		Clients:    len(hub.tcpClients) + len(hub.wsClients),
+	}
+
+	select {
+	case hub.clientEvents <- ev:
+	default:
+		hub.logger.Warnf("client event handler too slow; dropped event %+v", ev)
+	}
+}
+
+// handleClientEvents executes the client event handler for every queued
+// event, preserving their order, until the hub is closed.
+func (hub *Hub) handleClientEvents() {
+	for {
+		select {
+		case ev := <-hub.clientEvents:
+			hub.clientEventHandler(ev)
+		case <-hub.closeCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package hub
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+	"github.com/gorilla/websocket"
+)
+
+func TestClientEvents(t *testing.T) {
+
+	evCh := make(chan ClientEvent, 10)
+	h, err := New(ClientEventHandler(func(ev ClientEvent) {
+		evCh <- ev
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// tcp clients are only served if a rotator is available
+	r, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(expType ClientEventType, expProtocol string, expClients int) {
+		select {
+		case ev := <-evCh:
+			if ev.Type != expType || ev.Protocol != expProtocol || ev.Clients != expClients {
+				t.Fatalf("expected %s %s event with %d clients, got %+v",
+					expProtocol, expType, expClients, ev)
+			}
+			if len(ev.RemoteAddr) == 0 {
+				t.Fatal("remote address missing")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %s %s event", expProtocol, expType)
+		}
+	}
+
+	client, server := net.Pipe()
+	h.addTCPClient(&TCPClient{Conn: server})
+	expect(ClientConnected, ProtocolTCP, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(ClientConnected, ProtocolWebsocket, 2)
+
+	client.Close()
+	expect(ClientDisconnected, ProtocolTCP, 1)
+
+	conn.Close()
+	expect(ClientDisconnected, ProtocolWebsocket, 0)
+}
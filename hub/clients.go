@@ -0,0 +1,59 @@
+package hub
+
+import (
+	"sort"
+	"time"
+)
+
+// ClientInfo describes a client connected to the hub.
+type ClientInfo struct {
+	Protocol   string    `json:"protocol"`
+	RemoteAddr string    `json:"remote_addr"`
+	Connected  time.Time `json:"connected"`
+	ReadOnly   bool      `json:"read_only"`
+	// protocol dialect of tcp clients
+	Dialect Dialect `json:"dialect,omitempty"`
+	// rotator the client has subscribed to; all rotators if empty
+	Rotator string `json:"rotator,omitempty"`
+	// number of messages queued for the client; a client which doesn't
+	// keep up is disconnected once its queue is full
+	Queued int `json:"queued"`
+}
+
+// Clients returns the clients which are currently connected to the hub,
+// sorted by their connect time.
+func (hub *Hub) Clients() []ClientInfo {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	clients := make([]ClientInfo, 0, len(hub.tcpClients)+len(hub.wsClients))
+
+	for c := range hub.tcpClients {
+		clients = append(clients, ClientInfo{
+			Protocol:   ProtocolTCP,
+			RemoteAddr: c.RemoteAddr().String(),
+			Connected:  c.connected,
+			ReadOnly:   c.readOnly,
+			Dialect:    c.dialect,
+			Rotator:    c.subscription,
+			Queued:     len(c.send),
+		})
+	}
+
+	for c := range hub.wsClients {
+		clients = append(clients, ClientInfo{
+			Protocol:   ProtocolWebsocket,
+			RemoteAddr: c.RemoteAddr().String(),
+			Connected:  c.connected,
+			ReadOnly:   c.readOnly,
+			Rotator:    c.subscription,
+			Queued:     len(c.send),
+		})
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].Connected.Before(clients[j].Connected)
+	})
+
+	return clients
+}
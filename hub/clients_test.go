@@ -0,0 +1,62 @@
+package hub
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClientsHandler(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+	newTestRouter(h)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server, dialect: DCU1, readOnly: true})
+
+	srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var clients []ClientInfo
+	deadline := time.Now().Add(time.Second)
+	for len(clients) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 clients, got %+v", clients)
+		}
+		time.Sleep(time.Millisecond * 10)
+
+		rec := httptest.NewRecorder()
+		h.router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/clients", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&clients); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tcp, ws := clients[0], clients[1]
+	if tcp.Protocol != ProtocolTCP || !tcp.ReadOnly || tcp.Dialect != DCU1 || tcp.RemoteAddr != "pipe" {
+		t.Fatalf("unexpected tcp client %+v", tcp)
+	}
+	if ws.Protocol != ProtocolWebsocket || ws.ReadOnly || len(ws.RemoteAddr) == 0 {
+		t.Fatalf("unexpected websocket client %+v", ws)
+	}
+	if ws.Connected.Before(tcp.Connected) || tcp.Connected.IsZero() {
+		t.Fatalf("unexpected connect times %v, %v", tcp.Connected, ws.Connected)
+	}
+}
@@ -0,0 +1,181 @@
+package hub
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sniffTimeout is the time within which a client on a combined listener
+// has to send the first bytes of an HTTP request. Clients which remain
+// silent (e.g. TCP clients which only listen to the heading updates) are
+// served with the TCP rotator protocol afterwards.
+const sniffTimeout = time.Millisecond * 500
+
+// httpMethods are the request lines by which HTTP clients are recognized.
+var httpMethods = []string{
+	"GET ", "HEAD ", "POST ", "PUT ", "DELETE ",
+	"OPTIONS ", "PATCH ", "CONNECT ", "TRACE ",
+}
+
+// ListenCombined serves the HTTP API, the websocket, the web interface and
+// the TCP rotator protocol on a single port, which is useful if only one
+// port can be forwarded to the hub. The first bytes of each connection
+// decide: HTTP requests (incl. websocket upgrades) are passed to the HTTP
+// server, everything else is served like a client of ListenTCP with the
+// given options. TCP clients which don't send anything are handed over
+// to the TCP protocol after a short delay. TLS is not supported.
+// Errors are reported on errorCh the same way as with ListenHTTP.
+func (hub *Hub) ListenCombined(host string, port int, errorCh chan<- error, opts ...func(*TCPClient)) {
+
+	defer close(errorCh)
+
+	// Listen for incoming connections.
+	hub.logger.Infof("listening on %s for HTTP and TCP connections", listenAddr(host, port))
+
+	err := hub.serveHTTP(host, port, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(newSniffListener(hub, l, opts))
+	})
+	hub.reportError(errorCh, err)
+}
+
+// sniffListener accepts the connections of a combined listener. TCP
+// clients are added to the hub, while HTTP connections are returned by
+// Accept.
+type sniffListener struct {
+	net.Listener
+	hub       *Hub
+	opts      []func(*TCPClient)
+	httpConns chan net.Conn
+	errors    chan error
+	done      chan struct{}
+	closer    sync.Once
+}
+
+func newSniffListener(hub *Hub, l net.Listener, opts []func(*TCPClient)) *sniffListener {
+	sl := &sniffListener{
+		Listener:  l,
+		hub:       hub,
+		opts:      opts,
+		httpConns: make(chan net.Conn),
+		errors:    make(chan error),
+		done:      make(chan struct{}),
+	}
+	go sl.acceptLoop()
+	return sl
+}
+
+// Accept returns the next HTTP connection.
+func (sl *sniffListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-sl.httpConns:
+		return conn, nil
+	case err := <-sl.errors:
+		return nil, err
+	case <-sl.done:
+		return nil, http.ErrServerClosed
+	}
+}
+
+// Close closes the underlying listener.
+func (sl *sniffListener) Close() error {
+	sl.closer.Do(func() { close(sl.done) })
+	return sl.Listener.Close()
+}
+
+// acceptLoop accepts the connections and sniffs them concurrently, so
+// that a silent client doesn't delay the others. Accept errors are
+// passed on to the HTTP server, which retries on temporary errors.
+func (sl *sniffListener) acceptLoop() {
+	for {
+		conn, err := sl.Listener.Accept()
+		if err != nil {
+			select {
+			case sl.errors <- err:
+			case <-sl.done:
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		go sl.route(conn)
+	}
+}
+
+// route passes conn either to the HTTP server or to the TCP protocol.
+func (sl *sniffListener) route(conn net.Conn) {
+	sc := &sniffedConn{Conn: conn, reader: bufio.NewReader(conn)}
+
+	conn.SetReadDeadline(time.Now().Add(sniffTimeout))
+	isHTTP := sniffHTTP(sc.reader)
+	conn.SetReadDeadline(time.Time{})
+
+	if isHTTP {
+		select {
+		case sl.httpConns <- sc:
+		case <-sl.done:
+			conn.Close()
+		}
+		return
+	}
+
+	c := &TCPClient{
+		Conn:    sc,
+		dialect: ARSVCOM,
+		frame:   FrameAuto,
+		digits:  DefaultDigits,
+	}
+	for _, opt := range sl.opts {
+		opt(c)
+	}
+	sl.hub.addTCPClient(c)
+}
+
+// sniffHTTP returns true if the data received from r starts with an
+// HTTP request line. The data remains buffered in r.
+func sniffHTTP(r *bufio.Reader) bool {
+	for n := 1; ; n++ {
+		b, err := r.Peek(n)
+		if err != nil {
+			return false
+		}
+		candidate := false
+		for _, m := range httpMethods {
+			if !strings.HasPrefix(m, string(b)) {
+				continue
+			}
+			if len(m) == n {
+				return true
+			}
+			candidate = true
+		}
+		if !candidate {
+			return false
+		}
+	}
+}
+
+// sniffedConn is a connection whose first bytes have already been read
+// into a buffer.
+type sniffedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *sniffedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// tcpConn returns the TCP connection underlying conn (if any).
+func tcpConn(conn net.Conn) (*net.TCPConn, bool) {
+	if sc, ok := conn.(*sniffedConn); ok {
+		conn = sc.Conn
+	}
+	c, ok := conn.(*net.TCPConn)
+	return c, ok
+}
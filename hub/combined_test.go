@@ -0,0 +1,128 @@
+package hub
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSniffHTTP(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		data    string
+		expHTTP bool
+	}{
+		{"get", "GET / HTTP/1.1\r\n", true},
+		{"put", "PUT /api/rotator/r1/azimuth HTTP/1.1\r\n", true},
+		{"options", "OPTIONS * HTTP/1.1\r\n", true},
+		{"gs232 query", "C\r\n", false},
+		{"gs232 query with elevation", "C2\r\n", false},
+		{"gs232 move", "M090\r\n", false},
+		{"dcu1 move", "AP1090;", false},
+		{"method without space", "GETX", false},
+		{"empty", "", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tc.data))
+			if isHTTP := sniffHTTP(r); isHTTP != tc.expHTTP {
+				t.Fatalf("expected %v, got %v", tc.expHTTP, isHTTP)
+			}
+			// the sniffed data must still be readable
+			if r.Buffered() != len(tc.data) {
+				t.Fatalf("expected %d buffered bytes, got %d", len(tc.data), r.Buffered())
+			}
+		})
+	}
+}
+
+func TestListenCombined(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	// find a free port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	go h.ListenCombined("127.0.0.1", port, make(chan error, 1), TCPDialect(GS232A))
+	waitForHTTPServers(t, h, 1)
+
+	t.Run("http", func(t *testing.T) {
+		resp, err := http.Get("http://" + addr + "/api/rotators")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/ws", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		readHello(t, conn)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		ev := Event{}
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Name != AddRotator {
+			t.Fatalf("expected %s event, got %s", AddRotator, ev.Name)
+		}
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if _, err := conn.Write([]byte("C\r\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(time.Second * 2))
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(line, "AZ=") {
+				break
+			}
+		}
+	})
+
+	t.Run("silent tcp client", func(t *testing.T) {
+		waitForTCPClients(t, h, 0)
+
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		// the client is handed over to the tcp protocol after sniffTimeout
+		waitForTCPClients(t, h, 1)
+	})
+}
@@ -0,0 +1,379 @@
+package hub
+
+import (
+	"fmt"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// The functions in this file are the single entry point for commands
+// coming from clients (HTTP, TCP, ...). They enforce the hub's policies
+// (follow mode, park schedule, soft limits) before the command is forwarded to the
+// rotator. Stop commands are never rejected and terminate the tracking
+// of celestial bodies.
+
+// ExecuteRequest executes a request on the rotator with the name
+// req.Name, applying the same policies as for commands received through
+// HTTP or TCP. The request handler sees these requests with the source
+// SourceLocal.
+func (hub *Hub) ExecuteRequest(req rotator.Request) error {
+	return hub.executeRequest(SourceLocal, req)
+}
+
+// executeRequest looks up the rotator with the name req.Name and
+// executes the request on it.
+func (hub *Hub) executeRequest(source string, req rotator.Request) error {
+	r, ok := hub.Rotator(req.Name)
+	if !ok {
+		return fmt.Errorf("unknown rotator %s", req.Name)
+	}
+	return hub.execute(source, r, req)
+}
+
+// execute executes a request received from source, unless it is
+// malformed or the request handler vetoes it, and records it in the
+// metrics (if enabled).
+func (hub *Hub) execute(source string, r rotator.Rotator, req rotator.Request) error {
+	if hub.metrics != nil {
+		hub.metrics.RequestReceived(r.Name())
+	}
+
+	hub.RLock()
+	handler := hub.requestHandler
+	hub.RUnlock()
+
+	err := validateRequest(req)
+	if err != nil {
+		hub.logger.Warnf("rejected invalid request from %s: %v", source, err)
+	} else if handler != nil && !handler(source, req) {
+		err = fmt.Errorf("request from %s vetoed by the request handler", source)
+	} else {
+		err = hub.dispatch(r, req)
+	}
+
+	if err != nil && hub.metrics != nil {
+		hub.metrics.RequestRejected(r.Name())
+	}
+
+	return err
+}
+
+// dispatch dispatches a request to the corresponding commands. A request
+// may combine several commands, which are applied in the following order:
+//
+//  1. Stop stops the rotator and overrides all other fields.
+//  2. StopAzimuth and StopElevation stop the axis and override an
+//     absolute or relative move of the same axis.
+//  3. The speed is set, so that the subsequent moves run at the new speed.
+//  4. The azimuth is set, followed by the elevation. Rotators which can
+//     move both axes with a single command (e.g. GS-232 controllers)
+//     receive a combined command instead.
+//
+// A speed which can not be set is rejected before any axis is commanded.
+func (hub *Hub) dispatch(r rotator.Rotator, req rotator.Request) error {
+	if req.Stop {
+		return hub.stop(r)
+	}
+
+	if req.HasSpeed && !r.HasSpeed() {
+		return fmt.Errorf("rotator %s does not support setting the speed", r.Name())
+	}
+
+	if req.StopAzimuth {
+		if err := hub.stopAzimuth(r); err != nil {
+			return err
+		}
+		req.HasAzimuth = false
+		req.AzimuthDelta = 0
+	}
+
+	if req.StopElevation {
+		if err := hub.stopElevation(r); err != nil {
+			return err
+		}
+		req.HasElevation = false
+		req.ElevationDelta = 0
+	}
+
+	// relative moves are executed like absolute headings
+	if !req.HasAzimuth && req.AzimuthDelta != 0 {
+		req.HasAzimuth = true
+		req.Azimuth = jogAzimuth(r, req.AzimuthDelta)
+	}
+
+	if !req.HasElevation && req.ElevationDelta != 0 {
+		req.HasElevation = true
+		req.Elevation = jogElevation(r, req.ElevationDelta)
+	}
+
+	if req.HasSpeed {
+		if err := hub.setSpeed(r, req.Speed); err != nil {
+			return err
+		}
+	}
+
+	if req.HasAzimuth && req.HasElevation && r.HasAzimuth() && r.HasElevation() {
+		if s, ok := azElSetterOf(r); ok {
+			return hub.setAzEl(r, s, req.Azimuth, req.Elevation)
+		}
+	}
+
+	if req.HasAzimuth {
+		if err := hub.setAzimuth(r, req.Azimuth); err != nil {
+			return err
+		}
+	}
+
+	if req.HasElevation {
+		if err := hub.setElevation(r, req.Elevation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetAzimuth sets the azimuth of the rotator with the given name,
+// applying the same policies as for commands received through HTTP or TCP.
+func (hub *Hub) SetAzimuth(name string, az int) error {
+	r, ok := hub.Rotator(name)
+	if !ok {
+		return fmt.Errorf("unknown rotator %s", name)
+	}
+	return hub.execute(SourceLocal, r, rotator.Request{Name: name, HasAzimuth: true, Azimuth: az})
+}
+
+// Stop stops the rotator with the given name and all rotators following it.
+func (hub *Hub) Stop(name string) error {
+	r, ok := hub.Rotator(name)
+	if !ok {
+		return fmt.Errorf("unknown rotator %s", name)
+	}
+	return hub.execute(SourceLocal, r, rotator.Request{Name: name, Stop: true})
+}
+
+// setAzimuth enforces the follow policy, the operating hours, the soft
+// limits and the keep-out zones and forwards the command to r and all
+// rotators following r.
+// Each rotator takes the configured path to the azimuth (see
+// AzimuthRouting) and large movements are ramped (see Ramp).
+func (hub *Hub) setAzimuth(r rotator.Rotator, az int) error {
+	return hub.commandAzimuth(r, az, false)
+}
+
+// commandAzimuth implements setAzimuth. A park (see ParkSchedule) is
+// executed outside of the operating hours as well, and a rotator which
+// follows another rotator is decoupled from it.
+func (hub *Hub) commandAzimuth(r rotator.Rotator, az int, park bool) error {
+	hub.Lock()
+	az, followers, err := hub.checkAzimuth(r, az, park)
+	hub.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := hub.moveAzimuth(r, az); err != nil {
+		return err
+	}
+
+	hub.moveFollowers(followers)
+
+	return nil
+}
+
+// checkAzimuth enforces the policies of setAzimuth for r and returns the
+// azimuth to which r and its followers shall be moved. The caller must
+// hold the lock.
+func (hub *Hub) checkAzimuth(r rotator.Rotator, az int, park bool) (int, map[rotator.Rotator]int, error) {
+	if !park {
+		if err := hub.checkOperatingHours(r.Name()); err != nil {
+			return 0, nil, err
+		}
+	}
+	if fs, ok := hub.followers[r.Name()]; ok {
+		if fs.Policy != BreakCoupling && !park {
+			return 0, nil, fmt.Errorf("rotator %s is following rotator %s", r.Name(), fs.Leader)
+		}
+		hub.unfollow(r.Name())
+	}
+	az, err := hub.limitAzimuth(r.Name(), az)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := hub.checkKeepOut(az); err != nil {
+		hub.logger.Warnf("rejected command for rotator (%s): %v", r.Name(), err)
+		return 0, nil, err
+	}
+	followers := make(map[rotator.Rotator]int)
+	for fr, offset := range hub.followersOf(r.Name()) {
+		faz, err := hub.limitAzimuth(fr.Name(), normalizeAzimuth(az+offset))
+		if err != nil {
+			continue
+		}
+		if err := hub.checkKeepOut(faz); err != nil {
+			hub.logger.Warnf("rejected command for following rotator (%s): %v", fr.Name(), err)
+			continue
+		}
+		followers[fr] = faz
+	}
+	return az, followers, nil
+}
+
+// moveFollowers moves the following rotators to their azimuths.
+func (hub *Hub) moveFollowers(followers map[rotator.Rotator]int) {
+	for fr, faz := range followers {
+		if err := hub.moveAzimuth(fr, faz); err != nil {
+			hub.logger.Errorf("unable to set azimuth of following rotator %s: %v", fr.Name(), err)
+		}
+	}
+}
+
+// setAzEl applies the policies of setAzimuth and setElevation and moves
+// both axes of r with a single command. Movements which are ramped (see
+// Ramp) are commanded axis by axis.
+func (hub *Hub) setAzEl(r rotator.Rotator, s azElSetter, az, el int) error {
+	hub.Lock()
+	az, followers, err := hub.checkAzimuth(r, az, false)
+	if err == nil {
+		el, err = hub.limitElevation(r.Name(), el)
+	}
+	hub.Unlock()
+	if err != nil {
+		return err
+	}
+
+	routed := hub.routeAzimuth(r, az)
+
+	hub.Lock()
+	if hub.rampProfile.Step > 0 && abs(routed-r.Azimuth()) > hub.rampProfile.Threshold {
+		hub.Unlock()
+		if err := hub.moveAzimuth(r, az); err != nil {
+			return err
+		}
+		hub.moveFollowers(followers)
+		return hub.setElevation(r, el)
+	}
+	hub.stopRamp(r.Name())
+	hub.expectAzimuth(r.Name(), routed)
+	hub.expectElevation(r.Name(), el)
+	hub.Unlock()
+
+	if err := s.SetAzEl(routed, el); err != nil {
+		hub.Lock()
+		hub.clearAzimuthTarget(r.Name())
+		hub.clearElevationTarget(r.Name())
+		hub.Unlock()
+		return err
+	}
+
+	hub.moveFollowers(followers)
+
+	return nil
+}
+
+// setElevation enforces the operating hours and the soft limits and
+// forwards the command to r.
+func (hub *Hub) setElevation(r rotator.Rotator, el int) error {
+	return hub.commandElevation(r, el, false)
+}
+
+// commandElevation implements setElevation. A park is executed outside
+// of the operating hours as well.
+func (hub *Hub) commandElevation(r rotator.Rotator, el int, park bool) error {
+	hub.Lock()
+	var err error
+	if !park {
+		err = hub.checkOperatingHours(r.Name())
+	}
+	if err == nil {
+		el, err = hub.limitElevation(r.Name(), el)
+	}
+	if err == nil {
+		hub.expectElevation(r.Name(), el)
+	}
+	hub.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := r.SetElevation(el); err != nil {
+		hub.Lock()
+		hub.clearElevationTarget(r.Name())
+		hub.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// setSpeed forwards the speed level to r. It returns an error if r
+// does not support setting the speed.
+func (hub *Hub) setSpeed(r rotator.Rotator, speed int) error {
+	if !r.HasSpeed() {
+		return fmt.Errorf("rotator %s does not support setting the speed", r.Name())
+	}
+
+	return r.SetSpeed(speed)
+}
+
+// stopAzimuth stops the azimuth of r and of all rotators following r.
+func (hub *Hub) stopAzimuth(r rotator.Rotator) error {
+	hub.StopTracking(r.Name())
+
+	hub.Lock()
+	hub.stopRamp(r.Name())
+	hub.clearAzimuthTarget(r.Name())
+	hub.expectStop(r.Name(), true, false)
+	followers := hub.followersOf(r.Name())
+	for fr := range followers {
+		hub.stopRamp(fr.Name())
+		hub.clearAzimuthTarget(fr.Name())
+		hub.expectStop(fr.Name(), true, false)
+	}
+	hub.Unlock()
+
+	for fr := range followers {
+		if err := fr.StopAzimuth(); err != nil {
+			hub.logger.Errorf("unable to stop following rotator %s: %v", fr.Name(), err)
+		}
+	}
+
+	return r.StopAzimuth()
+}
+
+// stopElevation stops the elevation of r.
+func (hub *Hub) stopElevation(r rotator.Rotator) error {
+	hub.StopTracking(r.Name())
+
+	hub.Lock()
+	hub.clearElevationTarget(r.Name())
+	hub.expectStop(r.Name(), false, true)
+	hub.Unlock()
+
+	return r.StopElevation()
+}
+
+// stop stops r and all rotators following r.
+func (hub *Hub) stop(r rotator.Rotator) error {
+	hub.StopTracking(r.Name())
+
+	hub.Lock()
+	hub.stopRamp(r.Name())
+	delete(hub.presetTargets, r.Name())
+	hub.expectStop(r.Name(), true, true)
+	followers := hub.followersOf(r.Name())
+	for fr := range followers {
+		hub.stopRamp(fr.Name())
+		delete(hub.presetTargets, fr.Name())
+		hub.expectStop(fr.Name(), true, true)
+	}
+	hub.Unlock()
+
+	for fr := range followers {
+		if err := fr.Stop(); err != nil {
+			hub.logger.Errorf("unable to stop following rotator %s: %v", fr.Name(), err)
+		}
+	}
+
+	return r.Stop()
+}
@@ -0,0 +1,208 @@
+package hub
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+// callRecorder records the commands forwarded to the rotator
+type callRecorder struct {
+	rotator.Rotator
+	sync.Mutex
+	calls []string
+}
+
+func (r *callRecorder) record(call string) {
+	r.Lock()
+	defer r.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *callRecorder) SetAzimuth(az int) error {
+	r.record("azimuth")
+	return r.Rotator.SetAzimuth(az)
+}
+
+func (r *callRecorder) SetElevation(el int) error {
+	r.record("elevation")
+	return r.Rotator.SetElevation(el)
+}
+
+func (r *callRecorder) SetSpeed(speed int) error {
+	r.record("speed")
+	return r.Rotator.SetSpeed(speed)
+}
+
+func (r *callRecorder) StopAzimuth() error {
+	r.record("stop_azimuth")
+	return r.Rotator.StopAzimuth()
+}
+
+func (r *callRecorder) StopElevation() error {
+	r.record("stop_elevation")
+	return r.Rotator.StopElevation()
+}
+
+func (r *callRecorder) Stop() error {
+	r.record("stop")
+	return r.Rotator.Stop()
+}
+
+func TestCombinedRequest(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		req      rotator.Request
+		expCalls []string
+		expAz    int
+		expEl    int
+		expSpeed int
+	}{
+		{"azimuth, elevation and speed",
+			rotator.Request{HasAzimuth: true, Azimuth: 120, HasElevation: true, Elevation: 30, HasSpeed: true, Speed: 2},
+			[]string{"speed", "azimuth", "elevation"}, 120, 30, 2},
+		{"stop overrides all",
+			rotator.Request{Stop: true, HasAzimuth: true, Azimuth: 120, HasSpeed: true, Speed: 2},
+			[]string{"stop"}, 0, 0, rotator.SpeedMax},
+		{"stop azimuth overrides the azimuth",
+			rotator.Request{StopAzimuth: true, HasAzimuth: true, Azimuth: 120, HasElevation: true, Elevation: 30},
+			[]string{"stop_azimuth", "elevation"}, 0, 30, rotator.SpeedMax},
+		{"stop elevation overrides the jog",
+			rotator.Request{StopElevation: true, ElevationDelta: 10, HasSpeed: true, Speed: 1},
+			[]string{"stop_elevation", "speed"}, 0, 0, 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			d, err := dummy.New(dummy.Name("r1"), dummy.HasElevation(true),
+				dummy.AzimuthSpeed(0), dummy.ElevationSpeed(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+
+			r := &callRecorder{Rotator: d}
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			tc.req.Name = "r1"
+			if err := h.ExecuteRequest(tc.req); err != nil {
+				t.Fatal(err)
+			}
+
+			r.Lock()
+			calls := strings.Join(r.calls, ",")
+			r.Unlock()
+			if calls != strings.Join(tc.expCalls, ",") {
+				t.Fatalf("expected calls %v, got %v", tc.expCalls, calls)
+			}
+			if d.AzPreset() != tc.expAz {
+				t.Fatalf("expected azimuth preset %d, got %d", tc.expAz, d.AzPreset())
+			}
+			if d.ElPreset() != tc.expEl {
+				t.Fatalf("expected elevation preset %d, got %d", tc.expEl, d.ElPreset())
+			}
+			if d.Speed() != tc.expSpeed {
+				t.Fatalf("expected speed %d, got %d", tc.expSpeed, d.Speed())
+			}
+		})
+	}
+}
+
+// azElRecorder can move both axes with a single command
+type azElRecorder struct {
+	callRecorder
+}
+
+func (r *azElRecorder) SetAzEl(az, el int) error {
+	r.record("azel")
+	if err := r.Rotator.SetAzimuth(az); err != nil {
+		return err
+	}
+	return r.Rotator.SetElevation(el)
+}
+
+func TestAzElRequest(t *testing.T) {
+
+	elMax := 20
+
+	tt := []struct {
+		name     string
+		opts     []func(*Hub)
+		limits   *SoftLimits
+		req      rotator.Request
+		expCalls []string
+		expAz    int
+		expEl    int
+	}{
+		{"both axes", nil, nil,
+			rotator.Request{HasAzimuth: true, Azimuth: 120, HasElevation: true, Elevation: 30},
+			[]string{"azel"}, 120, 30},
+		{"azimuth only", nil, nil,
+			rotator.Request{HasAzimuth: true, Azimuth: 120},
+			[]string{"azimuth"}, 120, 0},
+		{"elevation limited", nil, &SoftLimits{ElevationMax: &elMax, Policy: ClampToLimits},
+			rotator.Request{HasAzimuth: true, Azimuth: 120, HasElevation: true, Elevation: 30},
+			[]string{"azel"}, 120, 20},
+		{"ramped", []func(*Hub){Ramp(RampProfile{Threshold: 30, Step: 200, Dwell: time.Hour})}, nil,
+			rotator.Request{HasAzimuth: true, Azimuth: 120, HasElevation: true, Elevation: 30},
+			[]string{"azimuth", "elevation"}, 120, 30},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			d, err := dummy.New(dummy.Name("r1"), dummy.HasElevation(true),
+				dummy.AzimuthSpeed(0), dummy.ElevationSpeed(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+
+			r := &azElRecorder{callRecorder{Rotator: d}}
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+			if tc.limits != nil {
+				if err := h.SetSoftLimits("r1", *tc.limits); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tc.req.Name = "r1"
+			if err := h.ExecuteRequest(tc.req); err != nil {
+				t.Fatal(err)
+			}
+
+			r.Lock()
+			calls := strings.Join(r.calls, ",")
+			r.Unlock()
+			if calls != strings.Join(tc.expCalls, ",") {
+				t.Fatalf("expected calls %v, got %v", tc.expCalls, calls)
+			}
+			if d.AzPreset() != tc.expAz {
+				t.Fatalf("expected azimuth preset %d, got %d", tc.expAz, d.AzPreset())
+			}
+			if d.ElPreset() != tc.expEl {
+				t.Fatalf("expected elevation preset %d, got %d", tc.expEl, d.ElPreset())
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package hub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dh1tw/remoteRotator/astro"
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// Config is a snapshot of the runtime configuration of the hub. The
+// rotators themselves are created by their backends; their definitions
+// (incl. the azimuth offsets) can't be changed at runtime. All rotators
+// referenced in an imported Config must be registered in the hub and
+// their definitions must match the registered rotators.
+type Config struct {
+	Rotators      map[string]rotator.Config   `json:"rotators"`
+	Follow        map[string]FollowState      `json:"follow,omitempty"`
+	ParkSchedules map[string]ParkSchedule     `json:"park_schedules,omitempty"`
+	Tracking      map[string]astro.Body       `json:"tracking,omitempty"`
+	SoftLimits    map[string]SoftLimits       `json:"soft_limits,omitempty"`
+	KeepOut       []KeepOutZone               `json:"keep_out,omitempty"`
+	Metadata      map[string]rotator.Metadata `json:"metadata,omitempty"`
+	Presets       map[string]Presets          `json:"presets,omitempty"`
+}
+
+// Presets are the presets reported for a rotator. On import, they are
+// restored like the presets loaded from a Store; the rotator is not
+// moved.
+type Presets struct {
+	Azimuth   int `json:"azimuth"`
+	Elevation int `json:"elevation"`
+}
+
+// ExportConfig returns the runtime configuration of the hub as JSON.
+func (hub *Hub) ExportConfig() []byte {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	c := Config{
+		Rotators:      make(map[string]rotator.Config),
+		Follow:        make(map[string]FollowState),
+		ParkSchedules: make(map[string]ParkSchedule),
+		Tracking:      make(map[string]astro.Body),
+		SoftLimits:    make(map[string]SoftLimits),
+		KeepOut:       append([]KeepOutZone{}, hub.keepOut...),
+		Metadata:      make(map[string]rotator.Metadata),
+		Presets:       make(map[string]Presets),
+	}
+
+	for name, r := range hub.rotators {
+		obj := hub.serialize(r)
+		c.Rotators[name] = obj.Config
+		c.Presets[name] = Presets{obj.Heading.AzPreset, obj.Heading.ElPreset}
+	}
+	for name, fs := range hub.followers {
+		c.Follow[name] = fs
+	}
+	for name, s := range hub.parkSchedules {
+		c.ParkSchedules[name] = s.ParkSchedule
+	}
+	for name, t := range hub.trackers {
+		c.Tracking[name] = t.body
+	}
+	for name, sl := range hub.softLimits {
+		c.SoftLimits[name] = sl
+	}
+	for name, m := range hub.metadata {
+		c.Metadata[name] = m
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		// can't happen; Config only contains marshalable types
+		hub.logger.Errorf("%v", err)
+	}
+
+	return data
+}
+
+// ImportConfig replaces the runtime configuration of the hub with the
+// JSON encoded Config. The configuration is validated completely before
+// it is applied; if an error is returned, the hub remains unchanged.
+func (hub *Hub) ImportConfig(data []byte) error {
+
+	c := Config{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	schedules := make(map[string]*parkSchedule)
+	for name, ps := range c.ParkSchedules {
+		s, err := newParkSchedule(ps)
+		if err != nil {
+			return fmt.Errorf("invalid park schedule for rotator %s: %v", name, err)
+		}
+		schedules[name] = s
+	}
+
+	tracking := make(map[string]astro.Body)
+	for name, b := range c.Tracking {
+		body, err := astro.ParseBody(string(b))
+		if err != nil {
+			return err
+		}
+		tracking[name] = body
+	}
+
+	softLimits := make(map[string]SoftLimits)
+	for name, sl := range c.SoftLimits {
+		if sl.Policy == "" {
+			sl.Policy = ClampToLimits
+		}
+		if err := sl.validate(); err != nil {
+			return fmt.Errorf("invalid soft limits for rotator %s: %v", name, err)
+		}
+		softLimits[name] = sl
+	}
+
+	for _, z := range c.KeepOut {
+		if err := z.validate(); err != nil {
+			return err
+		}
+	}
+
+	metadata := make(map[string]rotator.Metadata)
+	for name, m := range c.Metadata {
+		tags := make([]string, len(m.Tags))
+		copy(tags, m.Tags)
+		m.Tags = tags
+		metadata[name] = m
+	}
+
+	followers := make(map[string]FollowState)
+	for name, fs := range c.Follow {
+		if fs.Policy == "" {
+			fs.Policy = RejectCommands
+		}
+		followers[name] = fs
+	}
+
+	hub.Lock()
+	defer hub.Unlock()
+
+	if hub.closed() {
+		return fmt.Errorf("hub closed")
+	}
+
+	names := []string{}
+	for name := range c.Rotators {
+		names = append(names, name)
+	}
+	for name := range schedules {
+		names = append(names, name)
+	}
+	for name := range tracking {
+		names = append(names, name)
+	}
+	for name := range softLimits {
+		names = append(names, name)
+	}
+	for name := range metadata {
+		names = append(names, name)
+	}
+	for name := range c.Presets {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		if _, ok := hub.rotators[name]; !ok {
+			return fmt.Errorf("unknown rotator %s", name)
+		}
+	}
+
+	// the rotators are defined by their backends
+	for name, rc := range c.Rotators {
+		if rc != hub.rotators[name].Serialize().Config {
+			return fmt.Errorf("definition of rotator %s differs from the registered rotator", name)
+		}
+	}
+
+	for name, fs := range followers {
+		if err := hub.checkFollow(followers, name, fs); err != nil {
+			return err
+		}
+	}
+
+	if len(tracking) > 0 && !hub.hasLocation {
+		return fmt.Errorf("station location not set")
+	}
+
+	// the config is valid; apply it
+
+	for name := range hub.followers {
+		if _, ok := followers[name]; !ok {
+			hub.unfollow(name)
+		}
+	}
+	for name, fs := range followers {
+		fs := fs
+		hub.followers[name] = fs
+		hub.broadcastFollowState(name, &fs)
+	}
+
+	for name := range hub.parkSchedules {
+		if _, ok := schedules[name]; !ok {
+			hub.clearParkSchedule(name)
+		}
+	}
+	for name, s := range schedules {
+		hub.parkSchedules[name] = s
+		hub.broadcastParkState(name, s)
+	}
+
+	hub.softLimits = softLimits
+	hub.keepOut = append([]KeepOutZone{}, c.KeepOut...)
+	hub.metadata = metadata
+
+	for name, p := range c.Presets {
+		hub.restorePresets(hub.rotators[name], rotator.Heading{AzPreset: p.Azimuth, ElPreset: p.Elevation})
+	}
+
+	for name := range hub.trackers {
+		if _, ok := tracking[name]; !ok {
+			hub.stopTracking(name)
+		}
+	}
+	for name, body := range tracking {
+		hub.startTracking(hub.rotators[name], body)
+	}
+
+	hub.logger.Infof("hub configuration imported")
+
+	return nil
+}
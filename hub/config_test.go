@@ -0,0 +1,107 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+func TestExportImportConfig(t *testing.T) {
+
+	h := newTestHub(t, "r1", "r2")
+
+	if err := h.Follow("r1", "r2", 90, BreakCoupling); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetParkSchedule("r1", ParkSchedule{At: "22:00", Azimuth: 180}); err != nil {
+		t.Fatal(err)
+	}
+	azMax := 270
+	if err := h.SetSoftLimits("r2", SoftLimits{AzimuthMax: &azMax}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetMetadata("r1", rotator.Metadata{Description: "HF beam"}); err != nil {
+		t.Fatal(err)
+	}
+	h.keepOut = []KeepOutZone{{100, 130}}
+	h.restoredPresets["r1"] = &restoredPreset{hasAzimuth: true, azimuth: 45}
+
+	data := h.ExportConfig()
+
+	h2 := newTestHub(t, "r1", "r2")
+	if err := h2.ImportConfig(data); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, ok := h2.Following("r2")
+	if !ok || fs.Leader != "r1" || fs.Offset != 90 || fs.Policy != BreakCoupling {
+		t.Fatalf("follow state not imported: %+v", fs)
+	}
+
+	ps, ok := h2.ParkState("r1")
+	if !ok || ps.At != "22:00" || ps.Azimuth != 180 {
+		t.Fatalf("park schedule not imported: %+v", ps)
+	}
+
+	sl, ok := h2.SoftLimits("r2")
+	if !ok || sl.AzimuthMax == nil || *sl.AzimuthMax != 270 || sl.Policy != ClampToLimits {
+		t.Fatalf("soft limits not imported: %+v", sl)
+	}
+
+	if m, ok := h2.Metadata("r1"); !ok || m.Description != "HF beam" {
+		t.Fatalf("metadata not imported: %+v", m)
+	}
+
+	if err := h2.SetAzimuth("r2", 110); err == nil {
+		t.Fatal("keep-out zone not imported")
+	}
+
+	r1, _ := h2.Rotator("r1")
+	if r1.AzPreset() != 0 {
+		t.Fatalf("rotator must not be moved, got azimuth preset %d", r1.AzPreset())
+	}
+	for _, obj := range h2.Info() {
+		if obj.Name == "r1" && obj.Heading.AzPreset != 45 {
+			t.Fatalf("presets not imported: %+v", obj.Heading)
+		}
+	}
+}
+
+func TestImportConfigInvalid(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		config string
+	}{
+		{"invalid json", `{"follow":`},
+		{"unknown field", `{"limits":{}}`},
+		{"unknown rotator", `{"rotators":{"r3":{}}}`},
+		{"different rotator definition", `{"rotators":{"r1":{"has_azimuth":true,"azimuth_max":90}}}`},
+		{"invalid keep-out zone", `{"keep_out":[{"from":400,"to":10}]}`},
+		{"metadata of unknown rotator", `{"metadata":{"r3":{"description":"HF beam"}}}`},
+		{"follow itself", `{"follow":{"r1":{"leader":"r1"}}}`},
+		{"follow chain", `{"follow":{"r1":{"leader":"r2"},"r2":{"leader":"r1"}}}`},
+		{"invalid park time", `{"park_schedules":{"r1":{"at":"25:00"}}}`},
+		{"unknown body", `{"tracking":{"r1":"mars"}}`},
+		{"no location", `{"tracking":{"r1":"moon"}}`},
+		{"invalid limit policy", `{"soft_limits":{"r1":{"policy":"ignore"}}}`},
+		{"invalid soft limits", `{"soft_limits":{"r1":{"azimuth_min":200,"azimuth_max":100}}}`},
+		{"presets of unknown rotator", `{"presets":{"r3":{"azimuth":90}}}`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHub(t, "r1", "r2")
+			if err := h.SetParkSchedule("r2", ParkSchedule{At: "22:00"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := h.ImportConfig([]byte(tc.config)); err == nil {
+				t.Fatal("expected error")
+			}
+			// the hub must remain unchanged
+			if _, ok := h.ParkState("r2"); !ok {
+				t.Fatal("park schedule has been modified")
+			}
+		})
+	}
+}
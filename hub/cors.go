@@ -0,0 +1,72 @@
+package hub
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// cors adds the CORS headers to the responses for requests from the
+// allowed origins and answers their preflight requests. If no origins
+// are allowed, next is returned unchanged.
+func (hub *Hub) cors(next http.Handler) http.Handler {
+	if len(hub.allowedOrigins) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if len(origin) == 0 || !hub.originAllowed(origin) {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+
+		// preflight requests don't carry the credentials; they must be
+		// answered before the authorization is checked
+		if req.Method == http.MethodOptions && len(req.Header.Get("Access-Control-Request-Method")) > 0 {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// originAllowed returns true if origin is in the list of allowed origins
+// or if all origins ("*") are allowed.
+func (hub *Hub) originAllowed(origin string) bool {
+	origin = strings.TrimSuffix(origin, "/")
+	for _, o := range hub.allowedOrigins {
+		if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin is the CheckOrigin function of the websocket upgrader.
+// Requests without an Origin header (non-browser clients) and requests
+// from the hub's own origin are accepted, as well as requests from the
+// allowed origins.
+func (hub *Hub) checkOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if len(origin) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, req.Host) {
+		return true
+	}
+
+	return hub.originAllowed(origin)
+}
@@ -0,0 +1,141 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCORS(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+	AuthToken("secret")(h)
+	AllowedOrigins("https://panel.example.com")(h)
+	newTestRouter(h)
+
+	srv := httptest.NewServer(h.cors(h.router))
+	defer srv.Close()
+
+	tt := []struct {
+		name      string
+		method    string
+		origin    string
+		preflight bool
+		expCode   int
+		expOrigin string
+	}{
+		{"allowed origin", "GET", "https://panel.example.com", false, http.StatusOK, "https://panel.example.com"},
+		{"trailing slash", "GET", "https://panel.example.com/", false, http.StatusOK, "https://panel.example.com/"},
+		{"unknown origin", "GET", "https://evil.example.com", false, http.StatusOK, ""},
+		{"no origin", "GET", "", false, http.StatusOK, ""},
+		{"preflight", "OPTIONS", "https://panel.example.com", true, http.StatusNoContent, "https://panel.example.com"},
+		{"preflight unknown origin", "OPTIONS", "https://evil.example.com", true, http.StatusUnauthorized, ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, srv.URL+"/api/rotator/r1/azimuth", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tc.origin) > 0 {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.preflight {
+				// preflight requests don't carry the credentials
+				req.Header.Set("Access-Control-Request-Method", "PUT")
+			} else {
+				req.Header.Set("Authorization", "Bearer secret")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tc.expCode {
+				t.Fatalf("expected status %d, got %d", tc.expCode, resp.StatusCode)
+			}
+			if o := resp.Header.Get("Access-Control-Allow-Origin"); o != tc.expOrigin {
+				t.Fatalf("expected allowed origin %q, got %q", tc.expOrigin, o)
+			}
+			if tc.preflight && tc.expCode == http.StatusNoContent &&
+				!strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), "Authorization") {
+				t.Fatal("expected Authorization in the allowed headers")
+			}
+		})
+	}
+}
+
+func TestCORSDisabled(t *testing.T) {
+
+	h := newTestHub(t)
+	defer h.Close()
+	newTestRouter(h)
+
+	if h.cors(h.router) != http.Handler(h.router) {
+		t.Fatal("expected unchanged handler without allowed origins")
+	}
+}
+
+func TestWsCheckOrigin(t *testing.T) {
+
+	allowPanel := func(req *http.Request) bool {
+		return req.Header.Get("Origin") == "https://panel.example.com"
+	}
+
+	tt := []struct {
+		name    string
+		origins []string
+		check   func(*http.Request) bool
+		origin  string
+		expOk   bool
+	}{
+		{"no origin", nil, nil, "", true},
+		{"same origin", nil, nil, "http://{host}", true},
+		{"cross origin", nil, nil, "https://panel.example.com", false},
+		{"allowed origin", []string{"https://panel.example.com"}, nil, "https://panel.example.com", true},
+		{"other origin", []string{"https://panel.example.com"}, nil, "https://evil.example.com", false},
+		{"all origins", []string{"*"}, nil, "https://evil.example.com", true},
+		{"custom check", nil, allowPanel, "https://panel.example.com", true},
+		{"custom check rejects", nil, allowPanel, "https://evil.example.com", false},
+		{"custom check replaces allowlist", []string{"*"}, allowPanel, "https://evil.example.com", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := []func(*Hub){AllowedOrigins(tc.origins...)}
+			if tc.check != nil {
+				opts = append(opts, CheckOrigin(tc.check))
+			}
+			h, err := New(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+			defer srv.Close()
+
+			header := http.Header{}
+			if len(tc.origin) > 0 {
+				host := strings.TrimPrefix(srv.URL, "http://")
+				header.Set("Origin", strings.Replace(tc.origin, "{host}", host, 1))
+			}
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), header)
+			if tc.expOk && err != nil {
+				t.Fatal(err)
+			}
+			if !tc.expOk && err == nil {
+				t.Fatal("expected the upgrade to be rejected")
+			}
+			if conn != nil {
+				conn.Close()
+			}
+		})
+	}
+}
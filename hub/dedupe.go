@@ -0,0 +1,46 @@
+package hub
+
+import (
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// lastBroadcast is the last heading which has been broadcast for a
+// rotator.
+type lastBroadcast struct {
+	heading rotator.Heading
+	sent    time.Time
+}
+
+// suppress returns true if h received at time t is identical to the last
+// broadcast heading and keepAlive (if > 0) hasn't elapsed since then.
+// Otherwise h is recorded as the last broadcast heading.
+func (b *lastBroadcast) suppress(h rotator.Heading, t time.Time, keepAlive time.Duration) bool {
+	if !b.sent.IsZero() && h == b.heading &&
+		(keepAlive == 0 || t.Sub(b.sent) < keepAlive) {
+		return true
+	}
+	b.heading = h
+	b.sent = t
+	return false
+}
+
+// duplicate returns true if the heading h of the rotator name has to be
+// skipped because it has already been broadcast (see SuppressDuplicates).
+func (hub *Hub) duplicate(name string, h rotator.Heading) bool {
+	hub.Lock()
+	defer hub.Unlock()
+
+	if hub.lastBroadcasts == nil {
+		return false
+	}
+
+	b, ok := hub.lastBroadcasts[name]
+	if !ok {
+		b = &lastBroadcast{}
+		hub.lastBroadcasts[name] = b
+	}
+
+	return b.suppress(h, time.Now(), hub.duplicateKeepAlive)
+}
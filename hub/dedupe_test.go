@@ -0,0 +1,131 @@
+package hub
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+func TestLastBroadcast(t *testing.T) {
+
+	type sample struct {
+		az      int
+		elapsed time.Duration // since the first sample
+	}
+
+	tt := []struct {
+		name        string
+		keepAlive   time.Duration
+		samples     []sample
+		expSuppress []bool
+	}{
+		{"changing headings",
+			time.Second * 10,
+			[]sample{{90, 0}, {91, time.Second}, {92, time.Second * 2}},
+			[]bool{false, false, false}},
+		{"identical headings",
+			time.Second * 10,
+			[]sample{{90, 0}, {90, time.Second}, {90, time.Second * 2}},
+			[]bool{false, true, true}},
+		{"keep-alive",
+			time.Second * 10,
+			[]sample{{90, 0}, {90, time.Second * 9}, {90, time.Second * 10}, {90, time.Second * 11}},
+			[]bool{false, true, false, true}},
+		{"keep-alive restarts with a new heading",
+			time.Second * 10,
+			[]sample{{90, 0}, {91, time.Second * 5}, {91, time.Second * 10}, {91, time.Second * 15}},
+			[]bool{false, false, true, false}},
+		{"without keep-alive",
+			0,
+			[]sample{{90, 0}, {90, time.Hour}},
+			[]bool{false, true}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &lastBroadcast{}
+			ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			for i, s := range tc.samples {
+				suppress := b.suppress(rotator.Heading{Azimuth: s.az}, ts.Add(s.elapsed), tc.keepAlive)
+				if suppress != tc.expSuppress[i] {
+					t.Fatalf("sample %d (%d° after %v): expected suppress=%v, got %v",
+						i, s.az, s.elapsed, tc.expSuppress[i], suppress)
+				}
+			}
+		})
+	}
+}
+
+func TestSuppressDuplicates(t *testing.T) {
+
+	if _, err := New(SuppressDuplicates(-time.Second)); err == nil {
+		t.Fatal("expected error for negative keep-alive")
+	}
+
+	keepAlive := time.Millisecond * 200
+
+	h, err := New(SuppressDuplicates(keepAlive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server})
+
+	msgs := make(chan string, 10)
+	go func() {
+		reader := bufio.NewReader(client)
+		for {
+			msg, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			msgs <- msg
+		}
+	}()
+
+	expect := func(exp string) {
+		t.Helper()
+		select {
+		case msg := <-msgs:
+			if msg != exp {
+				t.Fatalf("expected %q, got %q", exp, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout while waiting for %q", exp)
+		}
+	}
+
+	expectNone := func() {
+		t.Helper()
+		select {
+		case msg := <-msgs:
+			t.Fatalf("unexpected message %q", msg)
+		case <-time.After(time.Millisecond * 50):
+		}
+	}
+
+	// identical headings are suppressed
+	for i := 0; i < 3; i++ {
+		h.Broadcast(rotator.Heading{Azimuth: 90})
+	}
+	expect("+0090\r\n")
+	expectNone()
+
+	// a changed heading is sent immediately
+	h.Broadcast(rotator.Heading{Azimuth: 91})
+	expect("+0091\r\n")
+
+	// the keep-alive repeats the unchanged heading
+	time.Sleep(keepAlive)
+	h.Broadcast(rotator.Heading{Azimuth: 91})
+	expect("+0091\r\n")
+	h.Broadcast(rotator.Heading{Azimuth: 91})
+	expectNone()
+}
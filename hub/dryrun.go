@@ -0,0 +1,140 @@
+package hub
+
+import (
+	"sync"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// simulatedRotator takes the place of a rotator in dry run mode. The
+// commands are logged and applied to a simulated heading, which is
+// broadcast to the clients, but never forwarded to the rotator.
+type simulatedRotator struct {
+	rotator.Rotator
+	sync.RWMutex
+	hub     *Hub
+	heading rotator.Heading
+	// serializes the broadcasts of the simulated heading
+	broadcastMu sync.Mutex
+}
+
+func newSimulatedRotator(hub *Hub, r rotator.Rotator) *simulatedRotator {
+	return &simulatedRotator{
+		Rotator: r,
+		hub:     hub,
+		heading: r.Serialize().Heading,
+	}
+}
+
+func (r *simulatedRotator) Azimuth() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.heading.Azimuth
+}
+
+func (r *simulatedRotator) AzPreset() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.heading.AzPreset
+}
+
+func (r *simulatedRotator) Elevation() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.heading.Elevation
+}
+
+func (r *simulatedRotator) ElPreset() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.heading.ElPreset
+}
+
+func (r *simulatedRotator) Speed() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.heading.Speed
+}
+
+func (r *simulatedRotator) SetAzimuth(az int) error {
+	r.hub.logger.Infof("dry run: rotator (%s) moves to azimuth %d°", r.Name(), az)
+	r.update(func(h *rotator.Heading) {
+		h.Azimuth = az
+		h.AzPreset = az
+	})
+	return nil
+}
+
+func (r *simulatedRotator) SetElevation(el int) error {
+	r.hub.logger.Infof("dry run: rotator (%s) moves to elevation %d°", r.Name(), el)
+	r.update(func(h *rotator.Heading) {
+		h.Elevation = el
+		h.ElPreset = el
+	})
+	return nil
+}
+
+func (r *simulatedRotator) SetSpeed(speed int) error {
+	if speed < rotator.SpeedMin {
+		speed = rotator.SpeedMin
+	}
+	if speed > rotator.SpeedMax {
+		speed = rotator.SpeedMax
+	}
+	r.hub.logger.Infof("dry run: rotator (%s) changes speed to %d", r.Name(), speed)
+	r.update(func(h *rotator.Heading) {
+		h.Speed = speed
+	})
+	return nil
+}
+
+// the simulated rotator is always at its preset, so there is nothing
+// to stop
+func (r *simulatedRotator) StopAzimuth() error {
+	r.hub.logger.Infof("dry run: rotator (%s) stops azimuth", r.Name())
+	r.update(func(*rotator.Heading) {})
+	return nil
+}
+
+func (r *simulatedRotator) StopElevation() error {
+	r.hub.logger.Infof("dry run: rotator (%s) stops elevation", r.Name())
+	r.update(func(*rotator.Heading) {})
+	return nil
+}
+
+func (r *simulatedRotator) Stop() error {
+	r.hub.logger.Infof("dry run: rotator (%s) stops", r.Name())
+	r.update(func(*rotator.Heading) {})
+	return nil
+}
+
+// Serialize returns the rotator's configuration with the simulated
+// heading.
+func (r *simulatedRotator) Serialize() rotator.Object {
+	obj := r.Rotator.Serialize()
+	r.RLock()
+	obj.Heading = r.heading
+	r.RUnlock()
+	return obj
+}
+
+// update applies f to the simulated heading and broadcasts the result.
+// The commands are executed by the rotator's command queue, which must
+// not wait for the hub's lock; therefore the heading is broadcast in a
+// separate go routine. Each of them broadcasts the simulated heading at
+// the time it runs, so the last broadcast always carries the latest
+// heading.
+func (r *simulatedRotator) update(f func(*rotator.Heading)) {
+	r.Lock()
+	f(&r.heading)
+	r.Unlock()
+
+	r.hub.goRoutine(func() {
+		r.broadcastMu.Lock()
+		defer r.broadcastMu.Unlock()
+		r.RLock()
+		h := r.heading
+		r.RUnlock()
+		r.hub.broadcast(r.Name(), h)
+	})
+}
@@ -0,0 +1,119 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+	"github.com/gorilla/websocket"
+)
+
+func TestDryRun(t *testing.T) {
+
+	h, err := New(DryRun(true), KeepOut(KeepOutZone{From: 200, To: 220}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("r1"), dummy.HasElevation(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	readHello(t, conn)
+
+	// the headings of the hardware are not broadcast
+	h.BroadcastHeading("r1", rotator.Heading{Azimuth: 5})
+
+	req := rotator.Request{Name: "r1", HasAzimuth: true, Azimuth: 120,
+		HasElevation: true, Elevation: 30, HasSpeed: true, Speed: 2}
+	if err := h.ExecuteRequest(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// the commands are validated as usual
+	if err := h.ExecuteRequest(rotator.Request{Name: "r1", HasAzimuth: true, Azimuth: 210}); err == nil {
+		t.Fatal("expected keep-out zone to be enforced")
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		ev := Event{}
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Name != UpdateHeading {
+			continue
+		}
+		if ev.Heading.Azimuth == 5 {
+			t.Fatal("unexpected heading of the hardware")
+		}
+		if ev.Heading.Azimuth == 120 && ev.Heading.Elevation == 30 {
+			if ev.Heading.Speed != 2 {
+				t.Fatalf("expected speed 2, got %d", ev.Heading.Speed)
+			}
+			break
+		}
+	}
+
+	r, _ := h.Rotator("r1")
+	if hd := r.Serialize().Heading; hd.Azimuth != 120 || hd.Elevation != 30 {
+		t.Fatalf("expected simulated heading 120°/30°, got %d°/%d°", hd.Azimuth, hd.Elevation)
+	}
+
+	// the hardware has not been commanded
+	if d.AzPreset() != 0 || d.ElPreset() != 0 || d.Speed() != rotator.SpeedMax {
+		t.Fatalf("unexpected command to the rotator: %+v", d.Serialize().Heading)
+	}
+}
+
+func TestDryRunWithRamp(t *testing.T) {
+
+	h, err := New(DryRun(true), Ramp(RampProfile{Threshold: 10, Step: 5, Dwell: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// new targets interrupt the running ramps
+		for i := 0; i < 20; i++ {
+			h.SetAzimuth("r1", 100+i*100%300)
+			time.Sleep(time.Millisecond * 3)
+		}
+		h.RemoveRotator(d)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("deadlock")
+	}
+}
@@ -0,0 +1,43 @@
+package hub
+
+// ReportFault reports a fault of the controller of the rotator name
+// (e.g. the controller does not respond anymore) to the websocket
+// clients subscribed to the rotator. A nil err reports that the rotator
+// has recovered. Clients connecting while a fault is active are informed
+// about it as well.
+func (hub *Hub) ReportFault(name string, err error) {
+	hub.Lock()
+	defer hub.Unlock()
+
+	ev := Event{
+		Name:        RotatorFault,
+		RotatorName: name,
+	}
+
+	if err == nil {
+		if _, ok := hub.faults[name]; !ok {
+			return
+		}
+		delete(hub.faults, name)
+		hub.logger.Infof("rotator (%s) recovered", name)
+	} else {
+		if hub.faults[name] == err.Error() {
+			return
+		}
+		hub.faults[name] = err.Error()
+		ev.Error = err.Error()
+		hub.logger.Errorf("rotator (%s) fault: %v", name, err)
+	}
+
+	if err := hub.broadcastToWsClients(ev); err != nil {
+		hub.logger.Errorf("%v", err)
+	}
+}
+
+// Fault returns the fault of the rotator name which is currently
+// active or an empty string if the rotator works fine.
+func (hub *Hub) Fault(name string) string {
+	hub.RLock()
+	defer hub.RUnlock()
+	return hub.faults[name]
+}
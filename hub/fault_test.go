@@ -0,0 +1,79 @@
+package hub
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestReportFault(t *testing.T) {
+
+	h := newTestHub(t, "r1", "r2")
+	defer h.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+	defer srv.Close()
+
+	h.ReportFault("r1", fmt.Errorf("controller not responding"))
+	if f := h.Fault("r1"); f != "controller not responding" {
+		t.Fatalf("expected fault of r1, got %q", f)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	// readFault skips all events except faults
+	readFault := func() Event {
+		for {
+			ev := Event{}
+			if err := conn.ReadJSON(&ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Name == RotatorFault {
+				return ev
+			}
+		}
+	}
+
+	// the active fault is reported right after connecting
+	ev := readFault()
+	if ev.RotatorName != "r1" || ev.Error != "controller not responding" {
+		t.Fatalf("expected fault of r1, got %+v", ev)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(h.Clients()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("ws client not added")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// repeated faults are only reported once
+	h.ReportFault("r1", fmt.Errorf("controller not responding"))
+	h.ReportFault("r2", fmt.Errorf("stuck"))
+
+	ev = readFault()
+	if ev.RotatorName != "r2" || ev.Error != "stuck" {
+		t.Fatalf("expected fault of r2, got %+v", ev)
+	}
+
+	h.ReportFault("r1", nil)
+	ev = readFault()
+	if ev.RotatorName != "r1" || ev.Error != "" {
+		t.Fatalf("expected recovery of r1, got %+v", ev)
+	}
+	if f := h.Fault("r1"); f != "" {
+		t.Fatalf("expected no fault of r1, got %q", f)
+	}
+}
@@ -0,0 +1,159 @@
+package hub
+
+import (
+	"fmt"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// FollowPolicy defines how the hub treats commands which are sent directly
+// to a rotator while it is following another rotator.
+type FollowPolicy string
+
+const (
+	// RejectCommands rejects all movement commands sent directly to
+	// the following rotator.
+	RejectCommands FollowPolicy = "reject"
+	// BreakCoupling executes a movement command sent directly to the
+	// following rotator and terminates the coupling.
+	BreakCoupling FollowPolicy = "break"
+)
+
+// FollowState describes the coupling of a rotator to its leader.
+type FollowState struct {
+	Leader string       `json:"leader"`
+	Offset int          `json:"offset"`
+	Policy FollowPolicy `json:"policy"`
+}
+
+// Follow couples the rotator follower to the rotator leader. Whenever
+// the leader is commanded to a new azimuth, the follower will be commanded
+// to the same azimuth plus the given offset. Stop commands are always
+// forwarded and never rejected.
+func (hub *Hub) Follow(leader, follower string, offset int, policy FollowPolicy) error {
+	hub.Lock()
+	defer hub.Unlock()
+
+	if policy == "" {
+		policy = RejectCommands
+	}
+
+	fs := FollowState{
+		Leader: leader,
+		Offset: offset,
+		Policy: policy,
+	}
+
+	if err := hub.checkFollow(hub.followers, follower, fs); err != nil {
+		return err
+	}
+
+	hub.followers[follower] = fs
+
+	hub.broadcastFollowState(follower, &fs)
+	hub.logger.Infof("rotator (%s) follows rotator (%s) with offset %d°", follower, leader, offset)
+
+	return nil
+}
+
+// checkFollow validates the coupling of follower against the existing
+// couplings. The caller must hold the lock.
+func (hub *Hub) checkFollow(followers map[string]FollowState, follower string, fs FollowState) error {
+
+	switch fs.Policy {
+	case RejectCommands, BreakCoupling:
+	default:
+		return fmt.Errorf("unknown follow policy (%s)", fs.Policy)
+	}
+
+	if fs.Leader == follower {
+		return fmt.Errorf("rotator %s can not follow itself", fs.Leader)
+	}
+
+	l, ok := hub.rotators[fs.Leader]
+	if !ok {
+		return fmt.Errorf("unknown rotator %s", fs.Leader)
+	}
+	f, ok := hub.rotators[follower]
+	if !ok {
+		return fmt.Errorf("unknown rotator %s", follower)
+	}
+
+	if !l.HasAzimuth() || !f.HasAzimuth() {
+		return fmt.Errorf("both rotators must support azimuth")
+	}
+
+	if _, ok := followers[fs.Leader]; ok {
+		return fmt.Errorf("rotator %s is already following another rotator", fs.Leader)
+	}
+
+	for name, other := range followers {
+		if other.Leader == follower && name != follower {
+			return fmt.Errorf("rotator %s is already leading another rotator", follower)
+		}
+	}
+
+	return nil
+}
+
+// Unfollow terminates the coupling of the rotator follower to its leader.
+func (hub *Hub) Unfollow(follower string) {
+	hub.Lock()
+	defer hub.Unlock()
+
+	hub.unfollow(follower)
+}
+
+func (hub *Hub) unfollow(follower string) {
+	if _, ok := hub.followers[follower]; !ok {
+		return
+	}
+	delete(hub.followers, follower)
+
+	hub.broadcastFollowState(follower, nil)
+	hub.logger.Infof("rotator (%s) stopped following", follower)
+}
+
+// Following returns the coupling of a rotator. If the rotator is not
+// following another rotator, (FollowState{}, false) will be returned.
+func (hub *Hub) Following(follower string) (FollowState, bool) {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	fs, ok := hub.followers[follower]
+	return fs, ok
+}
+
+func (hub *Hub) broadcastFollowState(follower string, fs *FollowState) {
+	ev := Event{
+		Name:        FollowRotator,
+		RotatorName: follower,
+		Follow:      fs,
+	}
+	if fs == nil {
+		ev.Name = UnfollowRotator
+	}
+	if err := hub.broadcastToWsClients(ev); err != nil {
+		hub.logger.Errorf("%v", err)
+	}
+}
+
+// followersOf returns all rotators which follow the given leader,
+// together with their offset. The caller must hold the lock.
+func (hub *Hub) followersOf(leader string) map[rotator.Rotator]int {
+	fr := make(map[rotator.Rotator]int)
+	for name, fs := range hub.followers {
+		if fs.Leader != leader {
+			continue
+		}
+		if r, ok := hub.rotators[name]; ok {
+			fr[r] = fs.Offset
+		}
+	}
+	return fr
+}
+
+// normalizeAzimuth maps an azimuth into the range 0...359°
+func normalizeAzimuth(az int) int {
+	return ((az % 360) + 360) % 360
+}
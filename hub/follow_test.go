@@ -0,0 +1,201 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func newTestHub(t *testing.T, names ...string) *Hub {
+	t.Helper()
+	return newTestHubWith(t, nil, names...)
+}
+
+// newTestHubWith returns a hub with dummy rotators which are created
+// with the given options
+func newTestHubWith(t *testing.T, opts []func(*dummy.Dummy), names ...string) *Hub {
+	t.Helper()
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		r, err := dummy.New(append([]func(*dummy.Dummy){dummy.Name(name)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.AddRotator(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return h
+}
+
+// staticRotator creates dummy rotators which don't move, so that their
+// presets can be checked
+var staticRotator = []func(*dummy.Dummy){
+	dummy.AzimuthSpeed(0),
+	dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}),
+}
+
+func azPreset(t *testing.T, h *Hub, name string) int {
+	t.Helper()
+
+	r, ok := h.Rotator(name)
+	if !ok {
+		t.Fatalf("unknown rotator %s", name)
+	}
+	return r.AzPreset()
+}
+
+func TestFollowOffset(t *testing.T) {
+
+	tt := []struct {
+		name      string
+		offset    int
+		azimuth   int
+		expPreset int
+	}{
+		{"no offset", 0, 100, 100},
+		{"positive offset", 90, 100, 190},
+		{"negative offset", -90, 100, 10},
+		{"across 0°", 300, 100, 40},
+		{"negative across 0°", -120, 100, 340},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHubWith(t, staticRotator, "r1", "r2")
+			defer h.Close()
+
+			if err := h.Follow("r1", "r2", tc.offset, RejectCommands); err != nil {
+				t.Fatal(err)
+			}
+			if err := h.SetAzimuth("r1", tc.azimuth); err != nil {
+				t.Fatal(err)
+			}
+			if p := azPreset(t, h, "r1"); p != tc.azimuth {
+				t.Fatalf("expected leader preset %d, got %d", tc.azimuth, p)
+			}
+			if p := azPreset(t, h, "r2"); p != tc.expPreset {
+				t.Fatalf("expected follower preset %d, got %d", tc.expPreset, p)
+			}
+		})
+	}
+}
+
+func TestFollowPolicy(t *testing.T) {
+
+	tt := []struct {
+		name         string
+		policy       FollowPolicy
+		expErr       bool
+		expFollowing bool
+		expPreset    int
+	}{
+		{"reject", RejectCommands, true, true, 0},
+		{"default is reject", "", true, true, 0},
+		{"break coupling", BreakCoupling, false, false, 200},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHubWith(t, staticRotator, "r1", "r2")
+			defer h.Close()
+
+			if err := h.Follow("r1", "r2", 0, tc.policy); err != nil {
+				t.Fatal(err)
+			}
+
+			err := h.SetAzimuth("r2", 200)
+			if tc.expErr && err == nil {
+				t.Fatal("expected error")
+			}
+			if !tc.expErr && err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := h.Following("r2"); ok != tc.expFollowing {
+				t.Fatalf("expected following %v, got %v", tc.expFollowing, ok)
+			}
+			if p := azPreset(t, h, "r2"); p != tc.expPreset {
+				t.Fatalf("expected follower preset %d, got %d", tc.expPreset, p)
+			}
+
+			// the leader still moves a coupled follower
+			if err := h.SetAzimuth("r1", 50); err != nil {
+				t.Fatal(err)
+			}
+			expPreset := tc.expPreset
+			if tc.expFollowing {
+				expPreset = 50
+			}
+			if p := azPreset(t, h, "r2"); p != expPreset {
+				t.Fatalf("expected follower preset %d, got %d", expPreset, p)
+			}
+		})
+	}
+}
+
+func TestFollowInvalid(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		leader   string
+		follower string
+		policy   FollowPolicy
+	}{
+		{"follow itself", "r1", "r1", RejectCommands},
+		{"unknown leader", "r9", "r1", RejectCommands},
+		{"unknown follower", "r1", "r9", RejectCommands},
+		{"unknown policy", "r1", "r2", FollowPolicy("ignore")},
+		{"leader is following", "r2", "r3", RejectCommands},
+		{"follower is leading", "r3", "r1", RejectCommands},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHubWith(t, staticRotator, "r1", "r2", "r3")
+			defer h.Close()
+
+			// r2 follows r1
+			if err := h.Follow("r1", "r2", 0, RejectCommands); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := h.Follow(tc.leader, tc.follower, 0, tc.policy); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestUnfollowOnRemove(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		remove string
+	}{
+		{"leader removed", "r1"},
+		{"follower removed", "r2"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHubWith(t, staticRotator, "r1", "r2")
+			defer h.Close()
+
+			if err := h.Follow("r1", "r2", 0, RejectCommands); err != nil {
+				t.Fatal(err)
+			}
+
+			r, _ := h.Rotator(tc.remove)
+			h.RemoveRotator(r)
+
+			if _, ok := h.Following("r2"); ok {
+				t.Fatal("expected coupling to be terminated")
+			}
+		})
+	}
+}
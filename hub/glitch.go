@@ -0,0 +1,84 @@
+package hub
+
+import (
+	"math"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// glitchMargin is the change of the heading (in degrees) which is always
+// considered plausible, independent of the time since the last sample.
+const glitchMargin = 2
+
+// glitchFilter drops headings which would imply that the rotator moved
+// faster than maxRate (degrees per second) since the last accepted
+// heading. A heading which has been dropped is kept as suspect; if the
+// next heading confirms it, the rotator really moved and the heading is
+// accepted. This way single sample spikes are filtered while genuine
+// fast moves pass through with a delay of one sample.
+type glitchFilter struct {
+	maxRate  float64
+	last     *rotator.Heading
+	lastTime time.Time
+	suspect  *rotator.Heading
+	susTime  time.Time
+}
+
+// accept returns true if the heading h received at time t is plausible.
+func (f *glitchFilter) accept(h rotator.Heading, t time.Time) bool {
+	if f.last == nil || f.plausible(*f.last, f.lastTime, h, t) {
+		f.set(h, t)
+		return true
+	}
+
+	// the previous sample was off as well; if both agree with each other,
+	// the rotator has moved (or the last accepted heading was wrong)
+	if f.suspect != nil && f.plausible(*f.suspect, f.susTime, h, t) {
+		f.set(h, t)
+		return true
+	}
+
+	f.suspect = &h
+	f.susTime = t
+	return false
+}
+
+func (f *glitchFilter) set(h rotator.Heading, t time.Time) {
+	f.last = &h
+	f.lastTime = t
+	f.suspect = nil
+}
+
+// plausible returns true if the rotator could have moved from heading a
+// (at ta) to heading b (at tb).
+func (f *glitchFilter) plausible(a rotator.Heading, ta time.Time, b rotator.Heading, tb time.Time) bool {
+	max := int(math.Ceil(f.maxRate*tb.Sub(ta).Seconds())) + glitchMargin
+	return angleDistance(a.Azimuth, b.Azimuth) <= max &&
+		abs(a.Elevation-b.Elevation) <= max
+}
+
+// filterGlitch returns false if the heading h of the rotator name has to
+// be dropped by the glitch filter. All headings pass if the filter is
+// disabled.
+func (hub *Hub) filterGlitch(name string, h rotator.Heading) bool {
+	hub.Lock()
+	defer hub.Unlock()
+
+	if hub.glitchFilters == nil {
+		return true
+	}
+
+	f, ok := hub.glitchFilters[name]
+	if !ok {
+		f = &glitchFilter{maxRate: hub.maxSlewRate}
+		hub.glitchFilters[name] = f
+	}
+
+	if !f.accept(h, time.Now()) {
+		hub.logger.Debugf("dropped implausible heading %d°/%d°", h.Azimuth, h.Elevation)
+		return false
+	}
+
+	return true
+}
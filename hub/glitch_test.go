@@ -0,0 +1,113 @@
+package hub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+func TestGlitchFilter(t *testing.T) {
+
+	type sample struct {
+		az, el int
+	}
+
+	tt := []struct {
+		name    string
+		samples []sample
+		expPass []bool
+	}{
+		{"steady movement",
+			[]sample{{90, 0}, {92, 0}, {94, 1}, {96, 1}},
+			[]bool{true, true, true, true}},
+		{"single spike",
+			[]sample{{90, 0}, {91, 0}, {300, 0}, {92, 0}, {93, 0}},
+			[]bool{true, true, false, true, true}},
+		{"elevation spike",
+			[]sample{{90, 10}, {90, 11}, {90, 80}, {90, 12}},
+			[]bool{true, true, false, true}},
+		{"consecutive spikes",
+			[]sample{{90, 0}, {300, 0}, {10, 0}, {91, 0}},
+			[]bool{true, false, false, true}},
+		{"genuine jump confirmed by the next sample",
+			[]sample{{90, 0}, {200, 0}, {201, 0}, {202, 0}},
+			[]bool{true, false, true, true}},
+		{"crossing north",
+			[]sample{{358, 0}, {359, 0}, {0, 0}, {1, 0}},
+			[]bool{true, true, true, true}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// 2°/s; samples are 1s apart, so up to 4° (incl. margin) pass
+			f := &glitchFilter{maxRate: 2}
+			ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			for i, s := range tc.samples {
+				pass := f.accept(rotator.Heading{Azimuth: s.az, Elevation: s.el}, ts)
+				if pass != tc.expPass[i] {
+					t.Fatalf("sample %d (%d°/%d°): expected pass=%v, got %v",
+						i, s.az, s.el, tc.expPass[i], pass)
+				}
+				ts = ts.Add(time.Second)
+			}
+		})
+	}
+}
+
+func TestGlitchFilterElapsedTime(t *testing.T) {
+
+	f := &glitchFilter{maxRate: 6}
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if !f.accept(rotator.Heading{Azimuth: 0}, ts) {
+		t.Fatal("expected first heading to pass")
+	}
+
+	// 60° within 10s is plausible at 6°/s
+	if !f.accept(rotator.Heading{Azimuth: 60}, ts.Add(time.Second*10)) {
+		t.Fatal("expected heading to pass")
+	}
+
+	// but not within the next second
+	if f.accept(rotator.Heading{Azimuth: 120}, ts.Add(time.Second*11)) {
+		t.Fatal("expected heading to be dropped")
+	}
+}
+
+func TestMaxSlewRate(t *testing.T) {
+
+	if _, err := New(MaxSlewRate(-1)); err == nil {
+		t.Fatal("expected error for negative slew rate")
+	}
+
+	h, err := New(MaxSlewRate(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	if !h.filterGlitch("r1", rotator.Heading{Azimuth: 90}) {
+		t.Fatal("expected first heading to pass")
+	}
+	if h.filterGlitch("r1", rotator.Heading{Azimuth: 300}) {
+		t.Fatal("expected spike to be dropped")
+	}
+	if !h.filterGlitch("r1", rotator.Heading{Azimuth: 91}) {
+		t.Fatal("expected heading to pass")
+	}
+
+	// all headings pass if the filter is disabled
+	h2, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h2.Close()
+
+	for _, az := range []int{90, 300, 91} {
+		if !h2.filterGlitch("r1", rotator.Heading{Azimuth: az}) {
+			t.Fatalf("unexpected drop of heading %d°", az)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package hub
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// connector is implemented by rotators which depend on a connection to
+// a remote rotator (e.g. the rotator proxy).
+type connector interface {
+	Connected() bool
+}
+
+// Health returns an error if the hub is not healthy. The check can be
+// replaced with the HealthCheck option.
+func (hub *Hub) Health() error {
+	if hub.healthCheck != nil {
+		return hub.healthCheck()
+	}
+	return hub.checkRotators()
+}
+
+// checkRotators returns an error if a rotator reports a fault or has
+// lost the connection to its remote rotator.
+func (hub *Hub) checkRotators() error {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	names := make([]string, 0, len(hub.rotators))
+	for name := range hub.rotators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if fault, ok := hub.faults[name]; ok {
+			return fmt.Errorf("rotator (%s): %s", name, fault)
+		}
+		r := hub.rotators[name]
+		if qr, ok := r.(*queuedRotator); ok {
+			r = qr.Rotator
+		}
+		if c, ok := r.(connector); ok && !c.Connected() {
+			return fmt.Errorf("rotator (%s) is disconnected", name)
+		}
+	}
+
+	return nil
+}
+
+func (hub *Hub) healthHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+
+	if err := hub.Health(); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Write([]byte("ok"))
+}
@@ -0,0 +1,73 @@
+package hub
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+// remoteRotator is a rotator which depends on a connection
+type remoteRotator struct {
+	rotator.Rotator
+	connected bool
+}
+
+func (r *remoteRotator) Connected() bool {
+	return r.connected
+}
+
+func TestHealthHandler(t *testing.T) {
+
+	tt := []struct {
+		name      string
+		setup     func(*testing.T, *Hub)
+		expStatus int
+		expBody   string
+	}{
+		{"healthy", func(*testing.T, *Hub) {}, http.StatusOK, "ok"},
+		{"fault", func(t *testing.T, h *Hub) {
+			h.ReportFault("r1", errors.New("stuck"))
+		}, http.StatusServiceUnavailable, "rotator (r1): stuck"},
+		{"recovered", func(t *testing.T, h *Hub) {
+			h.ReportFault("r1", errors.New("stuck"))
+			h.ReportFault("r1", nil)
+		}, http.StatusOK, "ok"},
+		{"disconnected", func(t *testing.T, h *Hub) {
+			d, err := dummy.New(dummy.Name("r2"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := h.AddRotator(&remoteRotator{Rotator: d}); err != nil {
+				t.Fatal(err)
+			}
+		}, http.StatusServiceUnavailable, "rotator (r2) is disconnected"},
+		{"custom check", func(t *testing.T, h *Hub) {
+			h.ReportFault("r1", errors.New("stuck"))
+			HealthCheck(func() error { return nil })(h)
+		}, http.StatusOK, "ok"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHub(t, "r1")
+			defer h.Close()
+			tc.setup(t, h)
+			newTestRouter(h)
+
+			req := httptest.NewRequest("GET", "/healthz", nil)
+			rr := httptest.NewRecorder()
+			h.router.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expStatus {
+				t.Fatalf("expected status %d, got %d", tc.expStatus, rr.Code)
+			}
+			if rr.Body.String() != tc.expBody {
+				t.Fatalf("expected body %q, got %q", tc.expBody, rr.Body.String())
+			}
+		})
+	}
+}
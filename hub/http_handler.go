@@ -1,43 +1,106 @@
 package hub
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/dh1tw/remoteRotator/rotator"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
 
+// authorize wraps a handler and rejects all requests which don't carry
+// the auth token (if set), either as "Authorization: Bearer <token>"
+// header or as "token" query parameter. Browsers can't set headers
+// on websocket connections, therefore the query parameter is needed.
+func (hub *Hub) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if len(hub.authToken) == 0 {
+			next(w, req)
+			return
+		}
+
+		token := req.URL.Query().Get("token")
+		if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(hub.authToken)) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("unauthorized"))
+			return
+		}
+
+		next(w, req)
+	}
+}
+
 func (hub *Hub) wsHandler(w http.ResponseWriter, r *http.Request) {
+	hub.serveWs(w, r, false)
+}
+
+// wsReadOnlyHandler accepts websocket clients which receive all events
+// but can not command the rotators.
+func (hub *Hub) wsReadOnlyHandler(w http.ResponseWriter, r *http.Request) {
+	hub.serveWs(w, r, true)
+}
+
+func (hub *Hub) serveWs(w http.ResponseWriter, r *http.Request, readOnly bool) {
+
+	hub.RLock()
+	limitReached := hub.wsClientLimitReached()
+	hub.RUnlock()
+	if limitReached {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("too many websocket clients"))
+		return
+	}
+
+	checkOrigin := hub.checkOrigin
+	if hub.originChecker != nil {
+		checkOrigin = hub.originChecker
+	}
+
+	if err := checkSubprotocols(r); err != nil {
+		hub.logger.Warnf("rejected websocket client (%v): %v", r.RemoteAddr, err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
 
 	upgrader := websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		CheckOrigin:       checkOrigin,
+		EnableCompression: hub.wsCompression,
+		Subprotocols:      wsSubprotocols,
 	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println(err)
+		hub.logger.Errorf("%v", err)
 		return
 	}
+	conn.SetReadLimit(wsMaxMessageSize)
 
 	c := &WsClient{
-		Conn: conn,
+		Conn:        conn,
+		readOnly:    readOnly,
+		messageType: websocket.TextMessage,
+		limiter:     newTokenBucket(hub.commandRate),
 	}
-
-	hub.RLock()
-	for _, r := range hub.rotators {
-		ev := Event{
-			Name:        AddRotator,
-			RotatorName: r.Name(),
-		}
-		if err := c.write(ev); err != nil {
-			fmt.Println(err)
-		}
+	// compact clients receive the heading updates as binary and all
+	// other events as text messages
+	if conn.Subprotocol() == WsCompactSubprotocol {
+		c.compact = true
+	} else if hub.wsBinary {
+		c.messageType = websocket.BinaryMessage
 	}
-	hub.RUnlock()
+	// clients can subscribe to the events of a single rotator
+	c.subscription = r.URL.Query().Get("rotator")
 
 	hub.addWsClient(c)
 }
@@ -49,12 +112,76 @@ func (hub *Hub) rotatorsHandler(w http.ResponseWriter, req *http.Request) {
 	rotators := hub.serializeRotators()
 
 	if err := json.NewEncoder(w).Encode(rotators); err != nil {
-		log.Println(err)
+		hub.logger.Errorf("%v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("unable to encode rotator msg"))
 	}
 }
 
+// statusHandler returns the live state of all rotators as an array,
+// sorted by name (see Info).
+func (hub *Hub) statusHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if err := json.NewEncoder(w).Encode(hub.Info()); err != nil {
+		hub.logger.Errorf("%v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode rotator msg"))
+	}
+}
+
+func (hub *Hub) clientsHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if err := json.NewEncoder(w).Encode(hub.Clients()); err != nil {
+		hub.logger.Errorf("%v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode clients to json"))
+	}
+}
+
+// commandHandler executes a rotator.Request and responds with the
+// rotator's state. It allows to command the rotators from scripts
+// without a websocket connection.
+func (hub *Hub) commandHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	rReq := rotator.Request{}
+	dec := json.NewDecoder(req.Body)
+
+	if err := dec.Decode(&rReq); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid json"))
+		return
+	}
+
+	r, ok := hub.Rotator(rReq.Name)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to find rotator"))
+		return
+	}
+
+	if err := hub.execute(requestSource(ProtocolHTTP, req.RemoteAddr), r, rReq); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("unable to execute request: %s", err)))
+		return
+	}
+
+	hub.RLock()
+	obj := hub.serialize(r)
+	hub.RUnlock()
+
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		hub.logger.Errorf("%v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to encode rotatorData to json"))
+	}
+}
+
 func (hub *Hub) rotatorHandler(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -69,8 +196,12 @@ func (hub *Hub) rotatorHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(r.Serialize()); err != nil {
-		log.Println(err)
+	hub.RLock()
+	obj := hub.serialize(r)
+	hub.RUnlock()
+
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		hub.logger.Errorf("%v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("unable to encode rotatorData to json"))
 	}
@@ -100,7 +231,7 @@ func (hub *Hub) azimuthHandler(w http.ResponseWriter, req *http.Request) {
 		}
 
 		if err := json.NewEncoder(w).Encode(rs); err != nil {
-			log.Println(err)
+			hub.logger.Errorf("%v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("unable to encode rotatorData to json"))
 		}
@@ -127,7 +258,7 @@ func (hub *Hub) azimuthHandler(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		err := r.SetAzimuth(*azPUT.Azimuth)
+		err := hub.execute(requestSource(ProtocolHTTP, req.RemoteAddr), r, rotator.Request{Name: r.Name(), HasAzimuth: true, Azimuth: *azPUT.Azimuth})
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(fmt.Sprintf("unable to set azimuth to %v: %s", *azPUT.Azimuth, err)))
@@ -163,7 +294,7 @@ func (hub *Hub) elevationHandler(w http.ResponseWriter, req *http.Request) {
 		}
 
 		if err := json.NewEncoder(w).Encode(rs); err != nil {
-			log.Println(err)
+			hub.logger.Errorf("%v", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("unable to encode rotatorData to json"))
 		}
@@ -190,7 +321,7 @@ func (hub *Hub) elevationHandler(w http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		err := r.SetElevation(*elPUT.Elevation)
+		err := hub.execute(requestSource(ProtocolHTTP, req.RemoteAddr), r, rotator.Request{Name: r.Name(), HasElevation: true, Elevation: *elPUT.Elevation})
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(fmt.Sprintf("unable to set elevation to %v: %s", *elPUT.Elevation, err)))
@@ -201,6 +332,173 @@ func (hub *Hub) elevationHandler(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// azElHandler sets the azimuth and the elevation of a rotator through
+// a single request, so that the controller can move both axes together.
+func (hub *Hub) azElHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(req)
+	rName := vars["rotator"]
+
+	r, ok := hub.Rotator(rName)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to find rotator"))
+		return
+	}
+
+	azElPUT := rotator.AzElPut{}
+	dec := json.NewDecoder(req.Body)
+
+	if err := dec.Decode(&azElPUT); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid json"))
+		return
+	}
+
+	if azElPUT.Azimuth == nil || azElPUT.Elevation == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid request"))
+		return
+	}
+
+	if !r.HasAzimuth() || !r.HasElevation() {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(("rotator does not support azimuth and elevation")))
+		return
+	}
+
+	rReq := rotator.Request{
+		Name:         r.Name(),
+		HasAzimuth:   true,
+		Azimuth:      *azElPUT.Azimuth,
+		HasElevation: true,
+		Elevation:    *azElPUT.Elevation,
+	}
+
+	err := hub.execute(requestSource(ProtocolHTTP, req.RemoteAddr), r, rReq)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("unable to set azimuth / elevation to %v / %v: %s",
+			*azElPUT.Azimuth, *azElPUT.Elevation, err)))
+	}
+}
+
+// jogHandler moves a rotator relative to its current heading, e.g. for
+// peaking on a signal.
+func (hub *Hub) jogHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(req)
+	rName := vars["rotator"]
+
+	r, ok := hub.Rotator(rName)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to find rotator"))
+		return
+	}
+
+	jogPUT := rotator.JogPut{}
+	dec := json.NewDecoder(req.Body)
+
+	if err := dec.Decode(&jogPUT); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid json"))
+		return
+	}
+
+	if jogPUT.AzimuthDelta == nil && jogPUT.ElevationDelta == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid request"))
+		return
+	}
+
+	rReq := rotator.Request{Name: r.Name()}
+
+	if jogPUT.AzimuthDelta != nil {
+		if !r.HasAzimuth() {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(("rotator does not support azimuth")))
+			return
+		}
+		rReq.AzimuthDelta = *jogPUT.AzimuthDelta
+	}
+
+	if jogPUT.ElevationDelta != nil {
+		if !r.HasElevation() {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(("rotator does not support elevation")))
+			return
+		}
+		rReq.ElevationDelta = *jogPUT.ElevationDelta
+	}
+
+	err := hub.execute(requestSource(ProtocolHTTP, req.RemoteAddr), r, rReq)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("unable to jog rotator: %s", err)))
+	}
+}
+
+func (hub *Hub) speedHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(req)
+	rName := vars["rotator"]
+
+	r, ok := hub.Rotator(rName)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to find rotator"))
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+
+		rs := rotator.SpeedGet{
+			HasSpeed: r.HasSpeed(),
+			Speed:    r.Speed(),
+		}
+
+		if err := json.NewEncoder(w).Encode(rs); err != nil {
+			hub.logger.Errorf("%v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("unable to encode rotatorData to json"))
+		}
+
+	case "PUT":
+		speedPUT := rotator.SpeedPut{}
+		dec := json.NewDecoder(req.Body)
+
+		if err := dec.Decode(&speedPUT); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid json"))
+			return
+		}
+
+		if speedPUT.Speed == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid request"))
+			return
+		}
+
+		err := hub.execute(requestSource(ProtocolHTTP, req.RemoteAddr), r, rotator.Request{Name: r.Name(), HasSpeed: true, Speed: *speedPUT.Speed})
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(fmt.Sprintf("unable to set speed to %v: %s", *speedPUT.Speed, err)))
+		}
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+
+}
+
 func (hub *Hub) stopAzimuthHandler(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -221,7 +519,7 @@ func (hub *Hub) stopAzimuthHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err := r.StopAzimuth()
+	err := hub.execute(requestSource(ProtocolHTTP, req.RemoteAddr), r, rotator.Request{Name: r.Name(), StopAzimuth: true})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(fmt.Sprintf("unable to stop rotator: %v", err.Error())))
@@ -249,7 +547,7 @@ func (hub *Hub) stopElevationHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err := r.StopElevation()
+	err := hub.execute(requestSource(ProtocolHTTP, req.RemoteAddr), r, rotator.Request{Name: r.Name(), StopElevation: true})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(fmt.Sprintf("unable to stop rotator: %v", err.Error())))
@@ -271,13 +569,285 @@ func (hub *Hub) stopHandler(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err := r.Stop()
+	err := hub.execute(requestSource(ProtocolHTTP, req.RemoteAddr), r, rotator.Request{Name: r.Name(), Stop: true})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(fmt.Sprintf("unable to stop rotator: %v", err.Error())))
-		log.Println(err)
+		hub.logger.Errorf("%v", err)
+		return
+	}
+}
+
+func (hub *Hub) followHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(req)
+	rName := vars["rotator"]
+
+	if _, ok := hub.Rotator(rName); !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to find rotator"))
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		fs, ok := hub.Following(rName)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("rotator is not following"))
+			return
+		}
+		if err := json.NewEncoder(w).Encode(fs); err != nil {
+			hub.logger.Errorf("%v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("unable to encode follow state to json"))
+		}
+
+	case "PUT":
+		fs := FollowState{}
+		dec := json.NewDecoder(req.Body)
+
+		if err := dec.Decode(&fs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid json"))
+			return
+		}
+
+		if err := hub.Follow(fs.Leader, rName, fs.Offset, fs.Policy); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(fmt.Sprintf("unable to follow rotator %s: %s", fs.Leader, err)))
+		}
+
+	case "DELETE":
+		hub.Unfollow(rName)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (hub *Hub) limitsHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(req)
+	rName := vars["rotator"]
+
+	if _, ok := hub.Rotator(rName); !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to find rotator"))
 		return
 	}
+
+	switch req.Method {
+	case "GET":
+		sl, ok := hub.SoftLimits(rName)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("no soft limits set"))
+			return
+		}
+		if err := json.NewEncoder(w).Encode(sl); err != nil {
+			hub.logger.Errorf("%v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("unable to encode soft limits to json"))
+		}
+
+	case "PUT":
+		sl := SoftLimits{}
+		dec := json.NewDecoder(req.Body)
+
+		if err := dec.Decode(&sl); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid json"))
+			return
+		}
+
+		if err := hub.SetSoftLimits(rName, sl); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("unable to set soft limits: %s", err)))
+		}
+
+	case "DELETE":
+		hub.ClearSoftLimits(rName)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (hub *Hub) parkHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(req)
+	rName := vars["rotator"]
+
+	if _, ok := hub.Rotator(rName); !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to find rotator"))
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		ps, ok := hub.ParkState(rName)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("no park schedule set"))
+			return
+		}
+		if err := json.NewEncoder(w).Encode(ps); err != nil {
+			hub.logger.Errorf("%v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("unable to encode park schedule to json"))
+		}
+
+	case "PUT":
+		ps := ParkSchedule{}
+		dec := json.NewDecoder(req.Body)
+
+		if err := dec.Decode(&ps); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid json"))
+			return
+		}
+
+		if err := hub.SetParkSchedule(rName, ps); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("unable to set park schedule: %s", err)))
+		}
+
+	case "DELETE":
+		hub.ClearParkSchedule(rName)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (hub *Hub) parkOverrideHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(req)
+	rName := vars["rotator"]
+
+	override := struct {
+		Override *bool `json:"override"`
+	}{}
+
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&override); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid json"))
+		return
+	}
+
+	if override.Override == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid request"))
+		return
+	}
+
+	if err := hub.OverridePark(rName, *override.Override); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+	}
+}
+
+func (hub *Hub) trackHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	vars := mux.Vars(req)
+	rName := vars["rotator"]
+
+	if _, ok := hub.Rotator(rName); !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("unable to find rotator"))
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		body, ok := hub.Tracking(rName)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("rotator is not tracking"))
+			return
+		}
+		if err := json.NewEncoder(w).Encode(TrackState{body}); err != nil {
+			hub.logger.Errorf("%v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("unable to encode tracking state to json"))
+		}
+
+	case "PUT":
+		ts := TrackState{}
+		dec := json.NewDecoder(req.Body)
+
+		if err := dec.Decode(&ts); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid json"))
+			return
+		}
+
+		if err := hub.Track(rName, ts.Body); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(fmt.Sprintf("unable to track %s: %s", ts.Body, err)))
+		}
+
+	case "DELETE":
+		hub.StopTracking(rName)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// configHandler exports / imports the hub's runtime configuration. The
+// endpoint is only available if a config token has been set and the
+// request carries the token as "Authorization: Bearer <token>".
+func (hub *Hub) configHandler(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if len(hub.configToken) == 0 {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("config api disabled"))
+		return
+	}
+
+	token := []byte("Bearer " + hub.configToken)
+	if subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), token) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("invalid token"))
+		return
+	}
+
+	switch req.Method {
+	case "GET":
+		w.Write(hub.ExportConfig())
+
+	case "PUT":
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("unable to read request"))
+			return
+		}
+
+		if err := hub.ImportConfig(data); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("unable to import config: %s", err)))
+		}
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
 }
 
 func (hub *Hub) serializeRotators() rotator.Objects {
@@ -288,7 +858,7 @@ func (hub *Hub) serializeRotators() rotator.Objects {
 	rs := rotator.Objects{}
 
 	for _, r := range hub.rotators {
-		sr := r.Serialize()
+		sr := hub.serialize(r)
 		rs[sr.Name] = sr
 	}
 
@@ -0,0 +1,253 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestRotatorsHandlerLive(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+	newTestRouter(h)
+
+	get := func() rotator.Objects {
+		rec := httptest.NewRecorder()
+		h.router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/rotators", nil))
+		objs := rotator.Objects{}
+		if err := json.NewDecoder(rec.Body).Decode(&objs); err != nil {
+			t.Fatal(err)
+		}
+		return objs
+	}
+
+	objs := get()
+	if objs["r1"].Heading.Speed != rotator.SpeedMax {
+		t.Fatalf("expected speed %d, got %d", rotator.SpeedMax, objs["r1"].Heading.Speed)
+	}
+
+	r, _ := h.Rotator("r1")
+	defer r.Close()
+	if err := r.SetSpeed(2); err != nil {
+		t.Fatal(err)
+	}
+
+	objs = get()
+	if objs["r1"].Heading.Speed != 2 {
+		t.Fatalf("expected speed 2, got %d", objs["r1"].Heading.Speed)
+	}
+}
+
+func TestInfo(t *testing.T) {
+
+	h := newTestHub(t, "r2", "r1", "r3")
+	defer h.Close()
+
+	info := h.Info()
+	if len(info) != 3 {
+		t.Fatalf("expected 3 rotators, got %d", len(info))
+	}
+	for i, name := range []string{"r1", "r2", "r3"} {
+		if info[i].Name != name {
+			t.Fatalf("expected rotator %s at index %d, got %s", name, i, info[i].Name)
+		}
+	}
+
+	r, _ := h.Rotator("r1")
+	h.RemoveRotator(r)
+	if info := h.Info(); len(info) != 2 || info[0].Name != "r2" {
+		t.Fatalf("unexpected info after removing r1: %+v", info)
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+
+	h := newTestHub(t)
+	defer h.Close()
+	newTestRouter(h)
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		h.router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/status", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		return strings.TrimSpace(rec.Body.String())
+	}
+
+	// valid json without rotators
+	if body := get(); body != "[]" {
+		t.Fatalf("expected empty array, got %s", body)
+	}
+
+	for _, name := range []string{"r2", "r1"} {
+		r, err := dummy.New(dummy.Name(name), dummy.AzimuthSpeed(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		if err := h.AddRotator(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, _ := h.Rotator("r2")
+	if err := r.SetAzimuth(90); err != nil {
+		t.Fatal(err)
+	}
+
+	objs := []rotator.Object{}
+	if err := json.Unmarshal([]byte(get()), &objs); err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 || objs[0].Name != "r1" || objs[1].Name != "r2" {
+		t.Fatalf("unexpected rotators %+v", objs)
+	}
+	if objs[1].Heading.AzPreset != 90 {
+		t.Fatalf("expected azimuth preset 90, got %d", objs[1].Heading.AzPreset)
+	}
+}
+
+func TestAzElHandler(t *testing.T) {
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	newTestRouter(h)
+
+	r, err := dummy.New(dummy.Name("r1"), dummy.HasElevation(true),
+		dummy.AzimuthSpeed(0), dummy.ElevationSpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	var reqs []rotator.Request
+	h.SetRequestHandler(func(source string, req rotator.Request) bool {
+		reqs = append(reqs, req)
+		return true
+	})
+
+	tt := []struct {
+		name       string
+		body       string
+		expCode    int
+		expReqs    int
+		expAzimuth int
+		expElev    int
+	}{
+		{"azimuth and elevation", `{"azimuth":120,"elevation":30}`, http.StatusOK, 1, 120, 30},
+		{"missing elevation", `{"azimuth":200}`, http.StatusBadRequest, 0, 120, 30},
+		{"missing azimuth", `{"elevation":60}`, http.StatusBadRequest, 0, 120, 30},
+		{"invalid json", `{"azimuth":`, http.StatusBadRequest, 0, 120, 30},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			reqs = nil
+
+			rec := httptest.NewRecorder()
+			h.router.ServeHTTP(rec, httptest.NewRequest("PUT", "/api/rotator/r1/azel",
+				strings.NewReader(tc.body)))
+
+			if rec.Code != tc.expCode {
+				t.Fatalf("expected status %d, got %d (%s)", tc.expCode, rec.Code, rec.Body.String())
+			}
+			if len(reqs) != tc.expReqs {
+				t.Fatalf("expected %d requests, got %d", tc.expReqs, len(reqs))
+			}
+			if tc.expReqs > 0 && (!reqs[0].HasAzimuth || !reqs[0].HasElevation) {
+				t.Fatalf("expected request with azimuth and elevation, got %+v", reqs[0])
+			}
+			if r.AzPreset() != tc.expAzimuth {
+				t.Fatalf("expected azimuth preset %d, got %d", tc.expAzimuth, r.AzPreset())
+			}
+			if r.ElPreset() != tc.expElev {
+				t.Fatalf("expected elevation preset %d, got %d", tc.expElev, r.ElPreset())
+			}
+		})
+	}
+}
+
+func TestCommandHandler(t *testing.T) {
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	AuthToken("secret")(h)
+	newTestRouter(h)
+
+	r, err := dummy.New(dummy.Name("r1"), dummy.HasElevation(true),
+		dummy.AzimuthSpeed(0), dummy.ElevationSpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	azMax := 300
+	if err := h.SetSoftLimits("r1", SoftLimits{AzimuthMax: &azMax, Policy: RejectBeyondLimits}); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name       string
+		body       string
+		token      string
+		expCode    int
+		expAzimuth int
+		expElev    int
+	}{
+		{"azimuth", `{"name":"r1","has_azimuth":true,"azimuth":120}`, "secret", http.StatusOK, 120, 0},
+		{"azimuth and elevation", `{"name":"r1","has_azimuth":true,"azimuth":200,"has_elevation":true,"elevation":45}`, "secret", http.StatusOK, 200, 45},
+		{"beyond soft limit", `{"name":"r1","has_azimuth":true,"azimuth":310}`, "secret", http.StatusInternalServerError, 200, 45},
+		{"unknown rotator", `{"name":"r2","has_azimuth":true,"azimuth":10}`, "secret", http.StatusInternalServerError, 200, 45},
+		{"malformed json", `{"name":"r1",`, "secret", http.StatusBadRequest, 200, 45},
+		{"unauthorized", `{"name":"r1","has_azimuth":true,"azimuth":10}`, "", http.StatusUnauthorized, 200, 45},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/command", strings.NewReader(tc.body))
+			if len(tc.token) > 0 {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			h.router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expCode {
+				t.Fatalf("expected status %d, got %d (%s)", tc.expCode, rec.Code, rec.Body.String())
+			}
+
+			if tc.expCode == http.StatusOK {
+				obj := rotator.Object{}
+				if err := json.NewDecoder(rec.Body).Decode(&obj); err != nil {
+					t.Fatal(err)
+				}
+				if obj.Name != "r1" || obj.Heading.AzPreset != tc.expAzimuth || obj.Heading.ElPreset != tc.expElev {
+					t.Fatalf("unexpected response %+v", obj)
+				}
+			}
+
+			if r.AzPreset() != tc.expAzimuth || r.ElPreset() != tc.expElev {
+				t.Fatalf("expected presets %d/%d, got %d/%d",
+					tc.expAzimuth, tc.expElev, r.AzPreset(), r.ElPreset())
+			}
+		})
+	}
+}
@@ -1,18 +1,27 @@
 package hub
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/GeertJohan/go.rice"
 
 	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/compact"
 	"github.com/gorilla/mux"
 )
 
+// number of messages which can be queued for a client; clients which
+// fall further behind are disconnected
+const clientSendBufferSize = 64
+
 // Hub is a struct which makes a rotator available through network
 // interfaces, supporting several protocols.
 type Hub struct {
@@ -22,31 +31,334 @@ type Hub struct {
 	wsClients      map[*WsClient]bool
 	closeWsClient  chan *WsClient
 	rotators       map[string]rotator.Rotator //key: Rotator name
+	followers      map[string]FollowState     //key: name of the following Rotator
+	parkSchedules  map[string]*parkSchedule   //key: Rotator name
+	trackers       map[string]*tracker        //key: Rotator name
+	softLimits     map[string]SoftLimits      //key: Rotator name
+	ramps          map[string]*ramp           //key: Rotator name
+	faults         map[string]string          //key: Rotator name
+	httpServers    map[*http.Server]bool
 	router         *mux.Router
+	handler        http.Handler
+	routerOnce     sync.Once
 	fileServer     http.Handler
+	latitude       float64
+	longitude      float64
+	hasLocation    bool
+	// interval in which tracked rotators are updated
+	trackingInterval time.Duration
+	// metadata set with SetMetadata; key: Rotator name
+	metadata map[string]rotator.Metadata
+	// ids of the rotators in the compact encoding; key: Rotator name
+	rotatorIDs map[string]int
+	// token required for /api/config; endpoint disabled if empty
+	configToken string
+	// token required for the HTTP API and the websocket; open if empty
+	authToken string
+	// origins from which browsers may access the HTTP API and the
+	// websocket (besides the hub's own origin)
+	allowedOrigins []string
+	// replaces the origin check of the websocket upgrader if set
+	originChecker func(*http.Request) bool
+	// TCP keep-alive period of tcp clients; disabled if 0
+	tcpKeepAlive time.Duration
+	// disable Nagle's algorithm on the connections of tcp clients
+	tcpNoDelay bool
+	// maximum time for writing to a tcp client; unlimited if 0
+	tcpWriteTimeout time.Duration
+	// tcp clients which don't send anything within this time are
+	// disconnected; disabled if 0
+	tcpIdleTimeout time.Duration
+	// ping period of websocket clients; disabled if 0
+	wsKeepAlive time.Duration
+	// send the events to websocket clients as binary instead of text messages
+	wsBinary bool
+	// negotiate permessage-deflate compression with websocket clients
+	wsCompression bool
+	// path of the websocket endpoint; the read-only endpoint is
+	// located at wsPath + "-readonly"
+	wsPath string
+	// maximum number of connected clients; unlimited if 0
+	maxTCPClients int
+	maxWsClients  int
+	// maximum number of heading broadcasts per second; unlimited if 0
+	broadcastRate   int
+	pendingHeadings map[string]rotator.Heading //key: Rotator name
+	// stop all rotators when the last client which can send commands
+	// disconnects
+	stopOnLastDisconnect bool
+	// commands are applied to simulated headings instead of the rotators
+	dryRun bool
+	// maximum number of commands per second and client; unlimited if 0
+	commandRate float64
+	// maximum plausible angular velocity (deg/s); filter disabled if 0
+	maxSlewRate   float64
+	glitchFilters map[string]*glitchFilter //key: Rotator name
+	// identical headings are only resent after duplicateKeepAlive;
+	// all headings are sent if lastBroadcasts is nil
+	duplicateKeepAlive time.Duration
+	lastBroadcasts     map[string]*lastBroadcast //key: Rotator name
+	// persists the last known headings; disabled if nil
+	store          Store
+	storedHeadings map[string]rotator.Heading
+	saveCh         chan struct{}
+	// presets loaded from the store or imported with ImportConfig,
+	// reported until the next command
+	restoredPresets map[string]*restoredPreset //key: Rotator name
+	// path which rotators with overlap take to the azimuth
+	azimuthPath AzimuthPath
+	// azimuth movements up to this many degrees ignore azimuthPath
+	directPathWithin int
+	// azimuth ranges to which the rotators must not be commanded
+	keepOut []KeepOutZone
+	// large azimuth movements are broken into steps; disabled if Step is 0
+	rampProfile RampProfile
+	// commanded headings which haven't been reached yet
+	presetTargets   map[string]*presetTarget //key: Rotator name
+	stopTargets     map[string]*stopTarget   //key: Rotator name
+	presetTolerance int
+	settleTime      time.Duration
+	// collects the hub's metrics; disabled if nil
+	metrics MetricsCollector
+	logger  Logger
+	// called before a request is forwarded to the rotator
+	requestHandler func(source string, req rotator.Request) bool
+	// reports whether the rotators are healthy (/healthz)
+	healthCheck func() error
+	// called whenever a client connects or disconnects
+	clientEventHandler func(ClientEvent)
+	clientEvents       chan ClientEvent
+	// wg tracks all go routines spawned by the hub
+	wg      sync.WaitGroup
+	closeCh chan struct{}
 }
 
-// NewHub returns the pointer to an initialized Hub object.
-func NewHub(rotators ...rotator.Rotator) (*Hub, error) {
+// New returns the pointer to an initialized Hub object. Options can
+// be injected through functional options. Rotators can be added with
+// AddRotator.
+// Default settings are:
+// trackingInterval: 30sec,
+// tcpKeepAlive: 30sec,
+// tcpNoDelay: true,
+// tcpWriteTimeout: 5sec,
+// wsKeepAlive: 30sec,
+// wsPath: /ws,
+// azimuthPath: PathDirect,
+// presetTolerance: 1°,
+// settleTime: 1sec,
+// logger: StdLogger.
+func New(opts ...func(*Hub)) (*Hub, error) {
 	hub := &Hub{
-		tcpClients:     make(map[*TCPClient]bool),
-		closeTCPClient: make(chan *TCPClient),
-		wsClients:      make(map[*WsClient]bool),
-		closeWsClient:  make(chan *WsClient),
-		rotators:       make(map[string]rotator.Rotator),
+		tcpClients:       make(map[*TCPClient]bool),
+		closeTCPClient:   make(chan *TCPClient),
+		wsClients:        make(map[*WsClient]bool),
+		closeWsClient:    make(chan *WsClient),
+		httpServers:      make(map[*http.Server]bool),
+		rotators:         make(map[string]rotator.Rotator),
+		followers:        make(map[string]FollowState),
+		parkSchedules:    make(map[string]*parkSchedule),
+		trackers:         make(map[string]*tracker),
+		softLimits:       make(map[string]SoftLimits),
+		ramps:            make(map[string]*ramp),
+		faults:           make(map[string]string),
+		metadata:         make(map[string]rotator.Metadata),
+		rotatorIDs:       make(map[string]int),
+		presetTargets:    make(map[string]*presetTarget),
+		stopTargets:      make(map[string]*stopTarget),
+		restoredPresets:  make(map[string]*restoredPreset),
+		presetTolerance:  1,
+		settleTime:       time.Second,
+		trackingInterval: time.Second * 30,
+		tcpKeepAlive:     time.Second * 30,
+		tcpNoDelay:       true,
+		tcpWriteTimeout:  time.Second * 5,
+		wsKeepAlive:      time.Second * 30,
+		wsPath:           "/ws",
+		logger:           StdLogger{},
+		azimuthPath:      PathDirect,
+		closeCh:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(hub)
+	}
+
+	if err := hub.rampProfile.validate(); err != nil {
+		return nil, err
+	}
+
+	for _, z := range hub.keepOut {
+		if err := z.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if hub.presetTolerance < 0 {
+		return nil, fmt.Errorf("invalid preset tolerance %d", hub.presetTolerance)
+	}
+	if hub.tcpIdleTimeout < 0 {
+		return nil, fmt.Errorf("invalid tcp idle timeout %v", hub.tcpIdleTimeout)
+	}
+	if hub.settleTime <= 0 {
+		return nil, fmt.Errorf("invalid settle time %v", hub.settleTime)
+	}
+	if hub.trackingInterval <= 0 {
+		return nil, fmt.Errorf("invalid tracking interval %v", hub.trackingInterval)
+	}
+	if !strings.HasPrefix(hub.wsPath, "/") {
+		return nil, fmt.Errorf("invalid websocket path %q", hub.wsPath)
+	}
+	if hub.maxSlewRate < 0 {
+		return nil, fmt.Errorf("invalid maximum slew rate %v", hub.maxSlewRate)
+	}
+	if _, err := ParseAzimuthPath(string(hub.azimuthPath)); err != nil {
+		return nil, err
+	}
+	if hub.directPathWithin < 0 || hub.directPathWithin > 180 {
+		return nil, fmt.Errorf("invalid direct path threshold %d", hub.directPathWithin)
+	}
+	if hub.commandRate < 0 {
+		return nil, fmt.Errorf("invalid command rate %v", hub.commandRate)
+	}
+	if hub.maxSlewRate > 0 {
+		hub.glitchFilters = make(map[string]*glitchFilter)
+	}
+	if hub.duplicateKeepAlive < 0 {
+		return nil, fmt.Errorf("invalid duplicate keep-alive %v", hub.duplicateKeepAlive)
+	}
+
+	hub.goRoutine(hub.handleClose)
+	hub.goRoutine(hub.parkScheduler)
+	hub.goRoutine(hub.watchPresets)
+
+	if hub.broadcastRate > 0 {
+		hub.pendingHeadings = make(map[string]rotator.Heading)
+		hub.goRoutine(hub.throttleBroadcasts)
+	}
+
+	if hub.store != nil {
+		hub.loadHeadings()
+		hub.saveCh = make(chan struct{}, 1)
+		hub.goRoutine(hub.saveHeadings)
+	}
+
+	if hub.clientEventHandler != nil {
+		hub.clientEvents = make(chan ClientEvent, clientEventBufferSize)
+		hub.goRoutine(hub.handleClientEvents)
+	}
+
+	return hub, nil
+}
+
+// NewHub returns the pointer to an initialized Hub object with the default
+// settings (see New) and the given rotators.
+//
+// Deprecated: Use New and AddRotator, which allow to configure the hub
+// through functional options.
+func NewHub(rotators ...rotator.Rotator) (*Hub, error) {
+	hub, err := New()
+	if err != nil {
+		return nil, err
 	}
 
 	for _, r := range rotators {
 		if err := hub.AddRotator(r); err != nil {
+			hub.Close()
 			return nil, err
 		}
 	}
 
-	go hub.handleClose()
-
 	return hub, nil
 }
 
+// Close shuts down the hub. All clients will be disconnected, the
+// listeners and HTTP servers closed and Close waits until all go routines spawned by
+// the hub have returned. The rotators will not be closed.
+func (hub *Hub) Close() {
+	hub.Lock()
+	if hub.closed() {
+		hub.Unlock()
+		return
+	}
+	close(hub.closeCh)
+
+	for name := range hub.trackers {
+		hub.stopTracking(name)
+	}
+	for c := range hub.tcpClients {
+		c.Close()
+		delete(hub.tcpClients, c)
+	}
+	for c := range hub.wsClients {
+		c.Close()
+		delete(hub.wsClients, c)
+	}
+	for srv := range hub.httpServers {
+		srv.Close()
+	}
+	queues := []*commandQueue{}
+	for _, r := range hub.rotators {
+		if qr, ok := r.(*queuedRotator); ok {
+			qr.queue.close()
+			queues = append(queues, qr.queue)
+		}
+	}
+	hub.Unlock()
+
+	// the commands in flight may need the lock
+	for _, q := range queues {
+		q.wait()
+	}
+
+	hub.wg.Wait()
+}
+
+// Shutdown gracefully shuts down the HTTP servers of the hub, waiting
+// for active requests to complete until ctx expires, and then closes
+// the hub. The error of the first HTTP server which could not be shut
+// down gracefully is returned.
+func (hub *Hub) Shutdown(ctx context.Context) error {
+	hub.RLock()
+	servers := make([]*http.Server, 0, len(hub.httpServers))
+	for srv := range hub.httpServers {
+		servers = append(servers, srv)
+	}
+	hub.RUnlock()
+
+	var err error
+	for _, srv := range servers {
+		if e := srv.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	hub.Close()
+
+	return err
+}
+
+// closed returns true if the hub has been closed. The caller must
+// hold the lock.
+func (hub *Hub) closed() bool {
+	select {
+	case <-hub.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// goRoutine executes f in a go routine which is tracked by the hub's
+// wait group. Except during initialization, the caller must hold the lock
+// and make sure that the hub has not been closed.
+func (hub *Hub) goRoutine(f func()) {
+	hub.wg.Add(1)
+	go func() {
+		defer hub.wg.Done()
+		f()
+	}()
+}
+
 func (hub *Hub) handleClose() {
 	for {
 		select {
@@ -54,11 +366,15 @@ func (hub *Hub) handleClose() {
 			hub.removeTCPClient(c)
 		case c := <-hub.closeWsClient:
 			hub.removeWsClient(c)
+		case <-hub.closeCh:
+			return
 		}
 	}
 }
 
 // AddRotator adds / registers a rotator. The rotator's name must be unique.
+// The hub executes the commands for the rotator one at a time; a pending
+// command is discarded if a newer command for the same axis arrives.
 func (hub *Hub) AddRotator(r rotator.Rotator) error {
 	hub.Lock()
 	defer hub.Unlock()
@@ -71,23 +387,50 @@ func (hub *Hub) addRotator(r rotator.Rotator) error {
 	if ok {
 		return fmt.Errorf("rotator names must be unique; %s provided twice", r.Name())
 	}
-	hub.rotators[r.Name()] = r
+	// the commands for the rotator are serialized
+	if hub.dryRun {
+		hub.rotators[r.Name()] = newQueuedRotator(newSimulatedRotator(hub, r))
+	} else {
+		hub.rotators[r.Name()] = newQueuedRotator(r)
+	}
+	hub.assignRotatorID(r.Name())
+	if h, ok := hub.storedHeadings[r.Name()]; ok {
+		hub.restorePresets(r, h)
+	}
+	obj := hub.serialize(r)
 	ev := Event{
 		Name:        AddRotator,
 		RotatorName: r.Name(),
+		Rotator:     &obj,
 	}
 	if err := hub.broadcastToWsClients(ev); err != nil {
-		fmt.Println(err)
+		hub.logger.Errorf("%v", err)
 	}
-	log.Printf("added rotator (%s)\n", r.Name())
+	hub.logger.Infof("added rotator (%s)", r.Name())
 
 	return nil
 }
 
+// assignRotatorID assigns the lowest free id of the compact encoding to
+// the rotator with the given name. If all ids are taken, the rotator gets
+// none and its heading updates are always JSON encoded. The caller must
+// hold the lock.
+func (hub *Hub) assignRotatorID(name string) {
+	taken := make(map[int]bool, len(hub.rotatorIDs))
+	for _, id := range hub.rotatorIDs {
+		taken[id] = true
+	}
+	for id := 1; id <= compact.MaxID; id++ {
+		if !taken[id] {
+			hub.rotatorIDs[name] = id
+			return
+		}
+	}
+}
+
 // RemoveRotator deletes / de-registers a rotator.
 func (hub *Hub) RemoveRotator(r rotator.Rotator) {
 	hub.Lock()
-	defer hub.Unlock()
 
 	ev := Event{
 		Name:        RemoveRotator,
@@ -95,12 +438,43 @@ func (hub *Hub) RemoveRotator(r rotator.Rotator) {
 	}
 
 	if err := hub.broadcastToWsClients(ev); err != nil {
-		fmt.Println(err)
+		hub.logger.Errorf("%v", err)
 	}
 
-	r.Close()
+	hub.clearParkSchedule(r.Name())
+	hub.stopTracking(r.Name())
+	hub.stopRamp(r.Name())
+	delete(hub.presetTargets, r.Name())
+	delete(hub.stopTargets, r.Name())
+	hub.unfollow(r.Name())
+	delete(hub.softLimits, r.Name())
+	delete(hub.restoredPresets, r.Name())
+	delete(hub.faults, r.Name())
+	delete(hub.metadata, r.Name())
+	delete(hub.rotatorIDs, r.Name())
+	if hub.lastBroadcasts != nil {
+		delete(hub.lastBroadcasts, r.Name())
+	}
+	for follower, fs := range hub.followers {
+		if fs.Leader == r.Name() {
+			hub.unfollow(follower)
+		}
+	}
+
+	qr, queued := hub.rotators[r.Name()].(*queuedRotator)
+	if queued {
+		qr.queue.close()
+	}
 	delete(hub.rotators, r.Name())
-	log.Printf("removed rotator (%s)\n", r.Name())
+	hub.Unlock()
+
+	// the command in flight may need the lock; the rotator is closed
+	// once it has been executed
+	if queued {
+		qr.queue.wait()
+	}
+	r.Close()
+	hub.logger.Infof("removed rotator (%s)", r.Name())
 }
 
 // Rotator returns a particular rotator stored from the hub. If no
@@ -126,24 +500,107 @@ func (hub *Hub) Rotators() []rotator.Rotator {
 	return rotators
 }
 
+// Info returns the serialized state (name, heading and configuration) of
+// all registered rotators, sorted by name. Contrary to the /api/rotators
+// endpoint, which returns a map, the rotators are returned as a slice.
+func (hub *Hub) Info() []rotator.Object {
+	objs := hub.serializeRotators()
+
+	info := make([]rotator.Object, 0, len(objs))
+	for _, obj := range objs {
+		info = append(info, obj)
+	}
+
+	sort.Slice(info, func(i, j int) bool {
+		return info[i].Name < info[j].Name
+	})
+
+	return info
+}
+
 // addTCPClient registers a new tcp client
 func (hub *Hub) addTCPClient(client *TCPClient) {
 	hub.Lock()
 	defer hub.Unlock()
 
+	if hub.closed() {
+		client.Close()
+		return
+	}
+
+	if hub.tcpClientLimitReached() {
+		hub.logger.Warnf("tcp client limit (%d) reached; refusing client (%v)", hub.maxTCPClients, client.RemoteAddr())
+		client.Close()
+		return
+	}
+
+	// the TCP dialects can only talk to a single rotator; unless the
+	// client subscribed to a rotator, we pick the first one
+	var r rotator.Rotator
+	if client.subscription != "" {
+		var ok bool
+		r, ok = hub.rotators[client.subscription]
+		if !ok {
+			hub.logger.Warnf("unknown rotator %s; refusing tcp client (%v)", client.subscription, client.RemoteAddr())
+			client.Close()
+			return
+		}
+	} else {
+		for _, rr := range hub.rotators {
+			r = rr
+			break
+		}
+	}
+
 	if _, alreadyInMap := hub.tcpClients[client]; alreadyInMap {
 		delete(hub.tcpClients, client)
 	}
 	hub.tcpClients[client] = true
+	client.connected = time.Now()
+	client.writeTimeout = hub.tcpWriteTimeout
+	client.idleTimeout = hub.tcpIdleTimeout
+	client.limiter = newTokenBucket(hub.commandRate)
+	client.send = make(chan string, clientSendBufferSize)
+	hub.goRoutine(func() { client.writePump(hub) })
 	// start listening on TCP socket
-	log.Printf("tcp client connected (%v)\n", client.RemoteAddr())
+	hub.logger.Infof("tcp client connected (%v)", client.RemoteAddr())
+	hub.emitClientEvent(ClientConnected, ProtocolTCP, client.RemoteAddr().String())
 
-	// we always pick the first rotator since the TCP client implements
-	// the Yaesu GS232 protocol which can only talk to a single rotator.
-	for _, r := range hub.rotators {
-		go client.listen(r, hub.closeTCPClient)
-		break
+	// keep-alive probes prevent NAT routers and firewalls from silently
+	// dropping the connections of idle clients
+	if conn, ok := tcpConn(client.Conn); ok {
+		if err := conn.SetKeepAlive(hub.tcpKeepAlive > 0); err != nil {
+			hub.logger.Errorf("unable to set tcp keep-alive (%v): %v", client.RemoteAddr(), err)
+		}
+		if hub.tcpKeepAlive > 0 {
+			if err := conn.SetKeepAlivePeriod(hub.tcpKeepAlive); err != nil {
+				hub.logger.Errorf("unable to set tcp keep-alive period (%v): %v", client.RemoteAddr(), err)
+			}
+		}
+		// the replies and heading updates are tiny; they must not be
+		// delayed until more data is available
+		if err := conn.SetNoDelay(hub.tcpNoDelay); err != nil {
+			hub.logger.Errorf("unable to set tcp no-delay (%v): %v", client.RemoteAddr(), err)
+		}
 	}
+
+	if r != nil {
+		client.hasAzimuth = r.HasAzimuth()
+		client.hasElevation = r.HasElevation()
+		hub.goRoutine(func() { client.listen(hub, r) })
+	}
+}
+
+// tcpClientLimitReached returns true if no further tcp clients are
+// accepted. The caller must hold the lock.
+func (hub *Hub) tcpClientLimitReached() bool {
+	return hub.maxTCPClients > 0 && len(hub.tcpClients) >= hub.maxTCPClients
+}
+
+// wsClientLimitReached returns true if no further websocket clients are
+// accepted. The caller must hold the lock.
+func (hub *Hub) wsClientLimitReached() bool {
+	return hub.maxWsClients > 0 && len(hub.wsClients) >= hub.maxWsClients
 }
 
 // RemoveTCPClient removes a tcp client
@@ -153,10 +610,15 @@ func (hub *Hub) removeTCPClient(c *TCPClient) {
 
 	if _, ok := hub.tcpClients[c]; ok {
 		delete(hub.tcpClients, c)
+		close(c.send)
+		hub.emitClientEvent(ClientDisconnected, ProtocolTCP, c.RemoteAddr().String())
+		if !c.readOnly {
+			hub.stopIfUnattended()
+		}
 	}
 
 	c.Close()
-	log.Printf("tcp client disconnected (%v)\n", c.RemoteAddr())
+	hub.logger.Infof("tcp client disconnected (%v)", c.RemoteAddr())
 }
 
 // AddWsClient registers a new websocket client
@@ -164,16 +626,107 @@ func (hub *Hub) addWsClient(client *WsClient) {
 	hub.Lock()
 	defer hub.Unlock()
 
+	if hub.closed() {
+		client.Close()
+		return
+	}
+
+	if hub.wsClientLimitReached() {
+		hub.logger.Warnf("websocket client limit (%d) reached; refusing client (%v)", hub.maxWsClients, client.RemoteAddr())
+		client.Close()
+		return
+	}
+
 	if _, alreadyInMap := hub.wsClients[client]; alreadyInMap {
 		delete(hub.wsClients, client)
 	}
 	hub.wsClients[client] = true
+	client.connected = time.Now()
+	hub.emitClientEvent(ClientConnected, ProtocolWebsocket, client.RemoteAddr().String())
+
+	// the current state is queued ahead of all events broadcasted
+	// from now on, so the client doesn't miss any changes
+	initial := hub.initialEvents(client)
+	client.send = make(chan Event, clientSendBufferSize+len(initial))
+	for _, ev := range initial {
+		client.queue(ev)
+	}
 
 	// we need to listen on the websocket so that the incoming ping
 	// messages can be (automatically) answered (with a pong message)
-	go client.listen(hub.closeWsClient)
+	client.done = make(chan struct{})
+	hub.goRoutine(func() { client.listen(hub) })
+	hub.goRoutine(func() { client.writePump(hub) })
+	if hub.wsKeepAlive > 0 {
+		hub.goRoutine(func() { client.ping(hub, hub.wsKeepAlive) })
+	}
 
-	log.Printf("websocket client connected (%v)\n", client.RemoteAddr())
+	hub.logger.Infof("websocket client connected (%v)", client.RemoteAddr())
+}
+
+// initialEvents returns the events which bring a newly connected client
+// up to date: the Hello followed by the rotators, couplings, trackers,
+// park schedules and faults the client has subscribed to. The caller
+// must hold the lock.
+func (hub *Hub) initialEvents(c *WsClient) []Event {
+	events := []Event{{Name: Hello, Protocol: ProtocolVersion}}
+
+	for _, r := range hub.rotators {
+		if !c.subscribed(r.Name()) {
+			continue
+		}
+		obj := hub.serialize(r)
+		events = append(events, Event{
+			Name:        AddRotator,
+			RotatorName: r.Name(),
+			RotatorID:   hub.rotatorIDs[r.Name()],
+			Rotator:     &obj,
+		})
+	}
+	for follower, fs := range hub.followers {
+		if !c.subscribed(follower) {
+			continue
+		}
+		fs := fs
+		events = append(events, Event{
+			Name:        FollowRotator,
+			RotatorName: follower,
+			Follow:      &fs,
+		})
+	}
+	for name, t := range hub.trackers {
+		if !c.subscribed(name) {
+			continue
+		}
+		events = append(events, Event{
+			Name:        UpdateTracking,
+			RotatorName: name,
+			Tracking:    &TrackState{t.body},
+		})
+	}
+	for name, ps := range hub.parkSchedules {
+		if !c.subscribed(name) {
+			continue
+		}
+		state := ps.state()
+		events = append(events, Event{
+			Name:        UpdateParkSchedule,
+			RotatorName: name,
+			Park:        &state,
+		})
+	}
+	for name, fault := range hub.faults {
+		if !c.subscribed(name) {
+			continue
+		}
+		events = append(events, Event{
+			Name:        RotatorFault,
+			RotatorName: name,
+			Error:       fault,
+		})
+	}
+
+	return events
 }
 
 // removeWsClient removes a websocket client
@@ -183,102 +736,310 @@ func (hub *Hub) removeWsClient(c *WsClient) {
 
 	if _, ok := hub.wsClients[c]; ok {
 		delete(hub.wsClients, c)
+		close(c.send)
+		hub.emitClientEvent(ClientDisconnected, ProtocolWebsocket, c.RemoteAddr().String())
+		if !c.readOnly {
+			hub.stopIfUnattended()
+		}
 	}
 
 	c.Close()
-	log.Printf("websocket client disconnected (%v)\n", c.RemoteAddr())
+	hub.logger.Infof("websocket client disconnected (%v)", c.RemoteAddr())
 }
 
 // ListenTCP starts a TCP listener on a given network adapter / port.
+// host may be an IPv4 or IPv6 address (with or without brackets) or a
+// hostname; an empty host or "::" binds all interfaces.
+// The protocol spoken by the clients can be set through functional
+// options (e.g. TCPDialect), which allows to run several listeners with
+// different dialects simultaneously.
 // Since this function contains an endless loop, it should be executed
-// in a go routine. If the listener can not be initialized, it will
-// close the tcpError channel.
-func (hub *Hub) ListenTCP(host string, port int, tcpError chan<- bool) {
+// in a go routine. If the listener can not be initialized (e.g. because
+// the port is already in use), the error is sent on tcpError before the
+// channel is closed; the caller may then try another port. tcpError is
+// also closed when the hub is closed.
+func (hub *Hub) ListenTCP(host string, port int, tcpError chan<- error, opts ...func(*TCPClient)) {
 	defer close(tcpError)
 
 	// Listen for incoming connections.
-	l, err := net.Listen("tcp", fmt.Sprintf("%s:%d", host, port))
+	l, err := net.Listen("tcp", listenAddr(host, port))
 	if err != nil {
-		log.Printf("tcp listener error (%v)", err.Error())
+		hub.logger.Errorf("tcp listener error (%v)", err.Error())
+		select {
+		case tcpError <- err:
+		case <-hub.closeCh:
+		}
 		return
 	}
 
 	// Close the listener when the application closes.
 	defer l.Close()
 
-	log.Printf("listening on %s:%d for TCP connections\n", host, port)
+	if err := hub.closeOnShutdown(l); err != nil {
+		return
+	}
+
+	hub.logger.Infof("listening on %s for TCP connections", l.Addr())
+
+	// delay after a failed Accept (e.g. too many open files)
+	var retryDelay time.Duration
 
 	for {
 		// Listen for an incoming connection.
 		conn, err := l.Accept()
 		if err != nil {
-			log.Println("error accepting: ", err.Error())
+			select {
+			case <-hub.closeCh:
+				return
+			default:
+			}
+			if retryDelay == 0 {
+				retryDelay = 5 * time.Millisecond
+			} else if retryDelay *= 2; retryDelay > time.Second {
+				retryDelay = time.Second
+			}
+			hub.logger.Errorf("error accepting: %v; retrying in %v", err, retryDelay)
+			select {
+			case <-hub.closeCh:
+				return
+			case <-time.After(retryDelay):
+			}
+			continue
 		}
+		retryDelay = 0
 
 		c := &TCPClient{
-			Conn: conn,
+			Conn:    conn,
+			dialect: ARSVCOM,
+			frame:   FrameAuto,
+			digits:  DefaultDigits,
+		}
+		for _, opt := range opts {
+			opt(c)
 		}
 		hub.addTCPClient(c)
 	}
 }
 
 // ListenHTTP starts a HTTP Server on a given network adapter / port and
-// sets a HTTP and Websocket handler.
+// sets a HTTP and Websocket handler. The host is interpreted the same
+// way as with ListenTCP.
 // Since this function contains an endless loop, it should be executed
-// in a go routine. If the listener can not be initialized, it will
-// close the errorCh channel.
-func (hub *Hub) ListenHTTP(host string, port int, errorCh chan<- struct{}) {
+// in a go routine. If the listener can not be initialized (e.g. because
+// the port is already in use) or the server fails, the error is sent on
+// errorCh before the channel is closed. After a regular Shutdown or
+// Close of the hub, errorCh is closed without an error.
+func (hub *Hub) ListenHTTP(host string, port int, errorCh chan<- error) {
 
 	defer close(errorCh)
 
-	box := rice.MustFindBox("../html")
-	hub.fileServer = http.FileServer(box.HTTPBox())
-	hub.router = mux.NewRouter().StrictSlash(true)
+	// Listen for incoming connections.
+	hub.logger.Infof("listening on %s for HTTP connections", listenAddr(host, port))
 
-	// load the HTTP routes with their respective endpoints
-	hub.routes()
+	err := hub.serveHTTP(host, port, func(srv *http.Server, l net.Listener) error {
+		return srv.Serve(l)
+	})
+	hub.reportError(errorCh, err)
+}
+
+// ListenHTTPS starts a HTTPS Server on a given network adapter / port
+// with the given certificate and key (PEM encoded) and sets a HTTP and
+// Websocket (wss://) handler.
+// Since this function contains an endless loop, it should be executed
+// in a go routine. Errors are reported on errorCh the same way as with
+// ListenHTTP.
+func (hub *Hub) ListenHTTPS(host string, port int, certFile, keyFile string, errorCh chan<- error) {
+
+	defer close(errorCh)
 
 	// Listen for incoming connections.
-	log.Printf("listening on %s:%d for HTTP connections\n", host, port)
+	hub.logger.Infof("listening on %s for HTTPS connections", listenAddr(host, port))
 
-	err := http.ListenAndServe(fmt.Sprintf("%s:%d", host, port), hub.router)
-	if err != nil {
-		log.Println(err)
+	err := hub.serveHTTP(host, port, func(srv *http.Server, l net.Listener) error {
+		return srv.ServeTLS(l, certFile, keyFile)
+	})
+	hub.reportError(errorCh, err)
+}
+
+// listenAddr returns the address for net.Listen. IPv6 literals may be
+// given with or without brackets.
+func listenAddr(host string, port int) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// reportError sends a non-nil err on errorCh unless the hub is closed
+// in the meantime.
+func (hub *Hub) reportError(errorCh chan<- error, err error) {
+	if err == nil {
 		return
 	}
+	select {
+	case errorCh <- err:
+	case <-hub.closeCh:
+	}
+}
+
+// Handler returns the http.Handler which serves the hub's HTTP API
+// (including /api/rotators), the websocket and the web interface.
+// It can be used to embed the hub into an existing HTTP server.
+func (hub *Hub) Handler() http.Handler {
+	hub.routerOnce.Do(func() {
+		box := rice.MustFindBox("../html")
+		hub.fileServer = http.FileServer(box.HTTPBox())
+		hub.router = mux.NewRouter().StrictSlash(true)
+
+		// load the HTTP routes with their respective endpoints
+		hub.routes()
+		hub.handler = versioned(hub.cors(hub.router))
+	})
+
+	return hub.handler
+}
+
+// serveHTTP serves the hub's routes through a dedicated http.Server
+// with the serve function until an error occurs or the hub is closed.
+// A regular shutdown of the server is not reported as an error.
+func (hub *Hub) serveHTTP(host string, port int, serve func(*http.Server, net.Listener) error) error {
+
+	handler := hub.Handler()
+
+	l, err := net.Listen("tcp", listenAddr(host, port))
+	if err != nil {
+		hub.logger.Errorf("%v", err)
+		return err
+	}
+	defer l.Close()
+
+	srv := &http.Server{Handler: handler}
+
+	hub.Lock()
+	if hub.closed() {
+		hub.Unlock()
+		return nil
+	}
+	hub.httpServers[srv] = true
+	hub.Unlock()
+
+	err = serve(srv, l)
+
+	hub.Lock()
+	delete(hub.httpServers, srv)
+	hub.Unlock()
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	hub.logger.Errorf("%v", err)
+	return err
+}
+
+// closeOnShutdown closes the listener when the hub is closed. If the hub
+// has already been closed, an error will be returned.
+func (hub *Hub) closeOnShutdown(l net.Listener) error {
+	hub.Lock()
+	defer hub.Unlock()
+
+	if hub.closed() {
+		return fmt.Errorf("hub closed")
+	}
+
+	hub.goRoutine(func() {
+		<-hub.closeCh
+		l.Close()
+	})
+
+	return nil
 }
 
-// Broadcast sends a rotator Status struct to all connected clients
+// Broadcast sends a rotator Status struct to all connected clients. If
+// a broadcast rate has been set, the headings are coalesced and only the
+// latest heading is sent. If a maximum slew rate has been set, implausible
+// headings are dropped. Since the heading doesn't contain the name of the
+// rotator, it is sent to all clients; use BroadcastHeading on hubs with
+// several rotators.
 func (hub *Hub) Broadcast(h rotator.Heading) {
+	hub.BroadcastHeading("", h)
+}
+
+// BroadcastHeading is like Broadcast, but the heading is only sent to the
+// clients which have subscribed to the rotator with the given name (or to
+// all rotators).
+func (hub *Hub) BroadcastHeading(name string, h rotator.Heading) {
+	// the clients only see the simulated headings
+	if hub.dryRun {
+		return
+	}
+	if !hub.filterGlitch(name, h) {
+		return
+	}
+	if hub.duplicate(name, h) {
+		return
+	}
+	if hub.store != nil {
+		hub.requestSave()
+	}
+	if hub.broadcastRate > 0 {
+		hub.queueHeading(name, h)
+		return
+	}
+	hub.broadcast(name, h)
+}
 
-	hub.BroadcastToTCPClients(h)
+// broadcast sends h immediately to all clients subscribed to the
+// rotator name
+func (hub *Hub) broadcast(name string, h rotator.Heading) {
+
+	hub.Lock()
+	hub.applyRestoredPresets(name, &h)
+	hub.broadcastToTCPClients(name, h)
+	hub.Unlock()
 
 	ev := Event{
-		Name:    UpdateHeading,
-		Heading: h,
+		Name:        UpdateHeading,
+		RotatorName: name,
+		Heading:     h,
 	}
 	if err := hub.BroadcastToWsClients(ev); err != nil {
-		log.Println(err)
+		hub.logger.Errorf("%v", err)
 	}
 }
 
 // BroadcastToTCPClients will send a rotator.Status struct to all connected
-// TCP Clients
+// TCP Clients (except GS-232A/B and DCU-1 clients). The messages are queued for each
+// client so that slow clients don't delay the others.
 func (hub *Hub) BroadcastToTCPClients(s rotator.Heading) {
-	// Lock needed for writing to the tcp socket
 	hub.Lock()
 	defer hub.Unlock()
 
+	hub.broadcastToTCPClients("", s)
+}
+
+// broadcastToTCPClients sends the heading s of the rotator name to the
+// tcp clients subscribed to it. The caller must hold the lock.
+func (hub *Hub) broadcastToTCPClients(name string, s rotator.Heading) {
+
 	// update the tcp Clients
 	for c := range hub.tcpClients {
-		// EA4TX's ARSVCOM doesn't understand single Azimuth
-		// messages (+0nnn). It always expects +0nnn+0nnn
-		data := fmt.Sprintf("+0%.3d+0%.3d\r\n", s.Azimuth, s.Elevation)
-		if err := c.write(data); err != nil {
-			log.Printf("error writing to client %v: %v\n", c.RemoteAddr(), err)
-			log.Printf("disconnecting client %v\n", c.RemoteAddr())
+		// GS-232 and DCU-1 clients poll the heading; unsolicited
+		// messages would be mistaken for replies
+		if c.dialect == GS232A || c.dialect == GS232B || c.dialect == DCU1 {
+			continue
+		}
+		if !c.subscribed(name) {
+			continue
+		}
+		data := headingMessage(c.dialect, c.frame, c.digits, c.hasAzimuth, c.hasElevation, s)
+		if !c.queue(data) {
+			hub.logger.Warnf("client %v too slow; disconnecting", c.RemoteAddr())
 			c.Close()
 			delete(hub.tcpClients, c)
+			close(c.send)
+			hub.broadcastError(ProtocolTCP)
+			hub.emitClientEvent(ClientDisconnected, ProtocolTCP, c.RemoteAddr().String())
+			if !c.readOnly {
+				hub.stopIfUnattended()
+			}
 		}
 	}
 }
@@ -286,19 +1047,58 @@ func (hub *Hub) BroadcastToTCPClients(s rotator.Heading) {
 type Event struct {
 	Name        RotatorEvent    `json:"name,omitempty"`
 	RotatorName string          `json:"rotator_name,omitempty"`
+	Rotator     *rotator.Object `json:"rotator,omitempty"`
 	Heading     rotator.Heading `json:"heading,omitempty"`
+	Follow      *FollowState    `json:"follow,omitempty"`
+	Park        *ParkState      `json:"park,omitempty"`
+	Tracking    *TrackState     `json:"tracking,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	// stopped axis (AxisAzimuth or AxisElevation) of a RotatorStopped
+	// event; empty if all stopped axes have come to rest
+	Axis string `json:"axis,omitempty"`
+	// ProtocolVersion of the hub; only set in Hello events
+	Protocol int `json:"protocol,omitempty"`
+	// id of the rotator in the compact encoding of the heading
+	// updates (see WsCompactSubprotocol); 0 if it has none
+	RotatorID int `json:"rotator_id,omitempty"`
 }
 
 type RotatorEvent string
 
 const (
+	// Hello is the first event sent to each websocket client; it
+	// announces the hub's ProtocolVersion
+	Hello         RotatorEvent = "hello"
 	AddRotator    RotatorEvent = "add"
 	RemoveRotator RotatorEvent = "remove"
 	UpdateHeading RotatorEvent = "heading"
+	// FollowRotator is sent when a rotator starts following another rotator
+	FollowRotator RotatorEvent = "follow"
+	// UnfollowRotator is sent when a rotator stops following another rotator
+	UnfollowRotator RotatorEvent = "unfollow"
+	// UpdateParkSchedule is sent when the park schedule of a rotator
+	// has been set, changed or cleared (no Park data)
+	UpdateParkSchedule RotatorEvent = "park_schedule"
+	// UpdateTracking is sent when a rotator starts tracking a celestial
+	// body or stops tracking (no Tracking data)
+	UpdateTracking RotatorEvent = "tracking"
+	// RequestError is sent to a websocket client if its request
+	// could not be executed (or was rejected)
+	RequestError RotatorEvent = "error"
+	// PresetReached is sent when a rotator has come to rest within the
+	// preset tolerance of the heading to which the hub commanded it
+	PresetReached RotatorEvent = "preset_reached"
+	// RotatorStopped is sent when a stopped axis of a rotator has come
+	// to rest (see SettleTime); it is preceded by a heading update
+	RotatorStopped RotatorEvent = "stopped"
+	// RotatorFault is sent when the controller of a rotator reports a
+	// fault (Error set) or has recovered from it (no Error)
+	RotatorFault RotatorEvent = "fault"
 )
 
 // BroadcastToWsClients will send a rotator.Status struct to all clients
-// connected through a Websocket
+// connected through a Websocket. Events of a rotator (RotatorName set) are
+// only sent to the clients subscribed to it.
 func (hub *Hub) BroadcastToWsClients(event Event) error {
 	hub.Lock()
 	defer hub.Unlock()
@@ -308,12 +1108,24 @@ func (hub *Hub) BroadcastToWsClients(event Event) error {
 
 func (hub *Hub) broadcastToWsClients(event Event) error {
 
+	if event.RotatorName != "" {
+		event.RotatorID = hub.rotatorIDs[event.RotatorName]
+	}
+
 	for c := range hub.wsClients {
-		if err := c.write(event); err != nil {
-			log.Printf("error writing to client %v: %v\n", c.RemoteAddr(), err)
-			log.Printf("disconnecting client %v\n", c.RemoteAddr())
+		if !c.subscribed(event.RotatorName) {
+			continue
+		}
+		if !c.queue(event) {
+			hub.logger.Warnf("client %v too slow; disconnecting", c.RemoteAddr())
 			c.Close()
 			delete(hub.wsClients, c)
+			close(c.send)
+			hub.broadcastError(ProtocolWebsocket)
+			hub.emitClientEvent(ClientDisconnected, ProtocolWebsocket, c.RemoteAddr().String())
+			if !c.readOnly {
+				hub.stopIfUnattended()
+			}
 		}
 	}
 
@@ -0,0 +1,41 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestNewHub(t *testing.T) {
+
+	r1, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := dummy.New(dummy.Name("r2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHub(r1, r2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	for _, name := range []string{"r1", "r2"} {
+		if _, ok := h.Rotator(name); !ok {
+			t.Fatalf("rotator %s not added", name)
+		}
+	}
+
+	// the names of the rotators must be unique
+	r3, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r3.Close()
+	if _, err := NewHub(r3, r3); err == nil {
+		t.Fatal("expected error for duplicate rotator")
+	}
+}
@@ -0,0 +1,78 @@
+package hub
+
+import (
+	"fmt"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// jogAzimuth returns the azimuth which is delta degrees away from the
+// current azimuth of r.
+func jogAzimuth(r rotator.Rotator, delta int) int {
+	cfg := r.Serialize().Config
+
+	// the limits of rotators with an azimuth offset are mechanical
+	// positions; the hub can't relate them to the azimuth
+	if cfg.AzimuthOffset != 0 {
+		return normalizeAzimuth(r.Azimuth() + delta)
+	}
+
+	return jog(r.Azimuth(), delta, cfg.AzimuthMin, cfg.AzimuthMax)
+}
+
+// rotationLimit returns the azimuth limit of r in the direction of a
+// continuous rotation; the clockwise limit if dir > 0, otherwise the
+// counter clockwise limit. Since the rotators can only be commanded to
+// a heading, a continuous rotation is a move to this limit.
+func rotationLimit(r rotator.Rotator, dir int) (int, error) {
+	cfg := r.Serialize().Config
+
+	if cfg.AzimuthMin == cfg.AzimuthMax || cfg.AzimuthOffset != 0 {
+		return 0, fmt.Errorf("azimuth limits of rotator %s unknown", r.Name())
+	}
+
+	if dir > 0 {
+		return cfg.AzimuthMax, nil
+	}
+	return cfg.AzimuthMin, nil
+}
+
+// jogElevation returns the elevation which is delta degrees away from
+// the current elevation of r, clamped to the limits of r.
+func jogElevation(r rotator.Rotator, delta int) int {
+	cfg := r.Serialize().Config
+	el := r.Elevation() + delta
+
+	if cfg.ElevationMin >= cfg.ElevationMax {
+		return el
+	}
+
+	return clamp(el, cfg.ElevationMin, cfg.ElevationMax)
+}
+
+// jog returns the azimuth which is delta degrees away from az. If the
+// rotator can turn a full circle (or the limits min / max are unknown),
+// the azimuth wraps around 0°. Otherwise it is clamped to the limits.
+// Limits with min > max overlap 0°.
+func jog(az, delta, min, max int) int {
+	if min == max || max-min >= 360 {
+		return normalizeAzimuth(az + delta)
+	}
+
+	// relative to min, the range doesn't overlap 0°
+	span := normalizeAzimuth(max - min)
+	pos := normalizeAzimuth(az - min)
+
+	return normalizeAzimuth(min + clamp(pos+delta, 0, span))
+}
+
+// clamp limits v to [min, max].
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,121 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestJog(t *testing.T) {
+
+	tt := []struct {
+		name  string
+		az    int
+		delta int
+		min   int
+		max   int
+		exp   int
+	}{
+		{"clockwise", 90, 5, 0, 360, 95},
+		{"counter clockwise", 90, -5, 0, 360, 85},
+		{"crossing 360°", 358, 5, 0, 360, 3},
+		{"crossing 0°", 2, -5, 0, 360, 357},
+		{"overlap crossing 360°", 358, 5, 0, 450, 3},
+		{"unknown limits", 2, -5, 0, 0, 357},
+		{"clamped at max", 175, 10, 0, 180, 180},
+		{"clamped at min", 5, -10, 0, 180, 0},
+		{"limits overlapping 0° crossing 0°", 2, -5, 270, 90, 357},
+		{"limits overlapping 0° clamped at max", 85, 10, 270, 90, 90},
+		{"limits overlapping 0° clamped at min", 275, -10, 270, 90, 270},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := jog(tc.az, tc.delta, tc.min, tc.max); res != tc.exp {
+				t.Fatalf("expected %d°, got %d°", tc.exp, res)
+			}
+		})
+	}
+}
+
+func TestJogRequest(t *testing.T) {
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// the dummy doesn't move; jogs are relative to 0° / 0°
+	d, err := dummy.New(dummy.Name("r1"), dummy.HasElevation(true),
+		dummy.ElevationMax(90), dummy.AzimuthSpeed(0), dummy.ElevationSpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name  string
+		req   rotator.Request
+		expAz int
+		expEl int
+	}{
+		{"azimuth crossing 0°", rotator.Request{AzimuthDelta: -10}, 350, 0},
+		{"elevation", rotator.Request{ElevationDelta: 10}, 350, 10},
+		{"elevation clamped", rotator.Request{ElevationDelta: -10}, 350, 0},
+		{"absolute heading takes precedence", rotator.Request{HasAzimuth: true, Azimuth: 45, AzimuthDelta: 10}, 45, 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.req.Name = "r1"
+			if err := h.ExecuteRequest(tc.req); err != nil {
+				t.Fatal(err)
+			}
+			if d.AzPreset() != tc.expAz || d.ElPreset() != tc.expEl {
+				t.Fatalf("expected preset %d°/%d°, got %d°/%d°",
+					tc.expAz, tc.expEl, d.AzPreset(), d.ElPreset())
+			}
+		})
+	}
+}
+
+func TestRotationLimit(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		min    int
+		max    int
+		dir    int
+		expAz  int
+		expErr bool
+	}{
+		{"clockwise", 0, 360, 1, 360, false},
+		{"counter clockwise", 0, 360, -1, 0, false},
+		{"overlap clockwise", 0, 450, 1, 450, false},
+		{"limits overlapping 0° counter clockwise", 270, 90, -1, 270, false},
+		{"unknown limits", 0, 0, 1, 0, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := dummy.New(dummy.AzimuthMin(tc.min), dummy.AzimuthMax(tc.max))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+
+			az, err := rotationLimit(d, tc.dir)
+			if (err != nil) != tc.expErr {
+				t.Fatalf("expected error %v, got %v", tc.expErr, err)
+			}
+			if err == nil && az != tc.expAz {
+				t.Fatalf("expected %d°, got %d°", tc.expAz, az)
+			}
+		})
+	}
+}
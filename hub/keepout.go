@@ -0,0 +1,90 @@
+package hub
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeepOutZone is a range of azimuths to which the hub doesn't command
+// the rotators, e.g. the direction of a neighbor's house or a guy wire.
+// The zone extends clockwise from From to To (both included); zones with
+// From > To include 0°.
+type KeepOutZone struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// ParseKeepOutZone converts a string in the format "from-to" (e.g.
+// "100-130" or "350-10") into a KeepOutZone.
+func ParseKeepOutZone(s string) (KeepOutZone, error) {
+	values := strings.Split(strings.TrimSpace(s), "-")
+	if len(values) != 2 {
+		return KeepOutZone{}, fmt.Errorf("invalid keep-out zone (%s)", s)
+	}
+
+	from, err := strconv.Atoi(strings.TrimSpace(values[0]))
+	if err != nil {
+		return KeepOutZone{}, fmt.Errorf("invalid keep-out zone (%s)", s)
+	}
+	to, err := strconv.Atoi(strings.TrimSpace(values[1]))
+	if err != nil {
+		return KeepOutZone{}, fmt.Errorf("invalid keep-out zone (%s)", s)
+	}
+
+	z := KeepOutZone{From: from, To: to}
+	if err := z.validate(); err != nil {
+		return KeepOutZone{}, err
+	}
+
+	return z, nil
+}
+
+func (z KeepOutZone) String() string {
+	return fmt.Sprintf("%d°-%d°", z.From, z.To)
+}
+
+// validate checks that the boundaries of the zone are within [0°, 360°).
+func (z KeepOutZone) validate() error {
+	if z.From < 0 || z.From >= 360 || z.To < 0 || z.To >= 360 {
+		return fmt.Errorf("invalid keep-out zone %s", z)
+	}
+	return nil
+}
+
+// contains returns true if the azimuth az lies within the zone. Azimuths
+// outside [0°, 360°) (e.g. within the overlap) are normalized.
+func (z KeepOutZone) contains(az int) bool {
+	az = normalizeAzimuth(az)
+	if z.From <= z.To {
+		return az >= z.From && az <= z.To
+	}
+	return az >= z.From || az <= z.To
+}
+
+// checkKeepOut returns an error if az lies within a keep-out zone. The
+// caller must hold the lock.
+func (hub *Hub) checkKeepOut(az int) error {
+	for _, z := range hub.keepOut {
+		if z.contains(az) {
+			return fmt.Errorf("azimuth %d lies within the keep-out zone %s", az, z)
+		}
+	}
+	return nil
+}
+
+// crossesKeepOut returns true if a rotator moving from the mechanical
+// position from to the mechanical position to passes one of the zones.
+func crossesKeepOut(zones []KeepOutZone, from, to int) bool {
+	if from > to {
+		from, to = to, from
+	}
+	for az := from; az <= to; az++ {
+		for _, z := range zones {
+			if z.contains(az) {
+				return true
+			}
+		}
+	}
+	return false
+}
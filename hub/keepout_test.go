@@ -0,0 +1,170 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestParseKeepOutZone(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		input   string
+		expZone KeepOutZone
+		expErr  bool
+	}{
+		{"valid", "100-130", KeepOutZone{100, 130}, false},
+		{"across 0°", "350-10", KeepOutZone{350, 10}, false},
+		{"whitespace", " 100 - 130 ", KeepOutZone{100, 130}, false},
+		{"single value", "100", KeepOutZone{}, true},
+		{"no number", "a-130", KeepOutZone{}, true},
+		{"out of range", "100-360", KeepOutZone{}, true},
+		{"negative", "-10-20", KeepOutZone{}, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			z, err := ParseKeepOutZone(tc.input)
+			if tc.expErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if z != tc.expZone {
+				t.Fatalf("expected %v, got %v", tc.expZone, z)
+			}
+		})
+	}
+}
+
+func TestKeepOutZoneContains(t *testing.T) {
+
+	tt := []struct {
+		name string
+		zone KeepOutZone
+		az   int
+		exp  bool
+	}{
+		{"inside", KeepOutZone{100, 130}, 110, true},
+		{"lower boundary", KeepOutZone{100, 130}, 100, true},
+		{"upper boundary", KeepOutZone{100, 130}, 130, true},
+		{"outside", KeepOutZone{100, 130}, 131, false},
+		{"across 0°, inside", KeepOutZone{350, 10}, 5, true},
+		{"across 0°, outside", KeepOutZone{350, 10}, 180, false},
+		{"overlap", KeepOutZone{100, 130}, 470, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := tc.zone.contains(tc.az); res != tc.exp {
+				t.Fatalf("expected %v, got %v", tc.exp, res)
+			}
+		})
+	}
+}
+
+func TestInvalidKeepOut(t *testing.T) {
+	if _, err := New(KeepOut(KeepOutZone{From: 0, To: 400})); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestKeepOutRejected(t *testing.T) {
+
+	tt := []struct {
+		name      string
+		azimuth   int
+		expPreset int
+		expErr    bool
+	}{
+		{"allowed", 90, 90, false},
+		{"keep-out", 110, 0, true},
+		{"keep-out across 0°", 355, 0, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(KeepOut(KeepOutZone{100, 130}, KeepOutZone{350, 10}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			// the rotator doesn't move
+			r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+				dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			err = h.SetAzimuth("r1", tc.azimuth)
+			if tc.expErr && err == nil {
+				t.Fatal("expected error")
+			}
+			if !tc.expErr && err != nil {
+				t.Fatal(err)
+			}
+			if r.AzPreset() != tc.expPreset {
+				t.Fatalf("expected azimuth preset %d, got %d", tc.expPreset, r.AzPreset())
+			}
+		})
+	}
+}
+
+// positionRotator reports a fixed heading and configuration
+type positionRotator struct {
+	rotator.Rotator
+	obj rotator.Object
+}
+
+func (r *positionRotator) Serialize() rotator.Object {
+	return r.obj
+}
+
+func TestRouteAroundKeepOut(t *testing.T) {
+
+	tt := []struct {
+		name         string
+		shortestPath bool
+		zone         KeepOutZone
+		current      int
+		target       int
+		expAz        int
+	}{
+		{"no crossing", false, KeepOutZone{100, 130}, 50, 80, 80},
+		{"through the overlap", false, KeepOutZone{100, 130}, 350, 30, 390},
+		{"leave the overlap across the zone", true, KeepOutZone{0, 10}, 400, 300, 300},
+		{"both paths cross", true, KeepOutZone{20, 30}, 10, 40, 40},
+		{"avoid the shortest path", true, KeepOutZone{0, 5}, 350, 30, 30},
+		{"no alternative", false, KeepOutZone{100, 130}, 50, 200, 200},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(ShortestPath(tc.shortestPath), KeepOut(tc.zone))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			r := &positionRotator{obj: rotator.Object{
+				Heading: rotator.Heading{Azimuth: tc.current},
+				Config:  rotator.Config{AzimuthMin: 0, AzimuthMax: 450},
+			}}
+
+			if az := h.routeAzimuth(r, tc.target); az != tc.expAz {
+				t.Fatalf("expected %d, got %d", tc.expAz, az)
+			}
+		})
+	}
+}
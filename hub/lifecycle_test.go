@@ -0,0 +1,389 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// waitForGoroutines waits until the number of go routines drops to max
+func waitForGoroutines(t *testing.T, max int) {
+	deadline := time.Now().Add(time.Second * 2)
+	for runtime.NumGoroutine() > max {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			n := runtime.Stack(buf, true)
+			t.Fatalf("goroutine leak: expected <= %d, got %d\n%s",
+				max, runtime.NumGoroutine(), buf[:n])
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+// waitForHTTPServers waits until n http servers of the hub are running
+func waitForHTTPServers(t *testing.T, h *Hub, n int) {
+	deadline := time.Now().Add(time.Second * 2)
+	for {
+		h.RLock()
+		running := len(h.httpServers)
+		h.RUnlock()
+		if running == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d http servers, got %d", n, running)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestTCPClientsNoLeak(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	base := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		client, server := net.Pipe()
+		h.addTCPClient(&TCPClient{Conn: server})
+		client.Close()
+	}
+
+	waitForGoroutines(t, base)
+
+	h.RLock()
+	n := len(h.tcpClients)
+	h.RUnlock()
+	if n != 0 {
+		t.Fatalf("expected 0 tcp clients, got %d", n)
+	}
+
+	h.Close()
+	for _, r := range h.Rotators() {
+		r.Close()
+	}
+}
+
+func TestWsClientsNoLeak(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	base := runtime.NumGoroutine()
+
+	for i := 0; i < 50; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+
+	waitForGoroutines(t, base)
+
+	h.Close()
+	for _, r := range h.Rotators() {
+		r.Close()
+	}
+}
+
+func TestCloseNoLeak(t *testing.T) {
+
+	base := runtime.NumGoroutine()
+
+	h := newTestHub(t, "r1")
+
+	clients := []net.Conn{}
+	for i := 0; i < 10; i++ {
+		client, server := net.Pipe()
+		h.addTCPClient(&TCPClient{Conn: server})
+		clients = append(clients, client)
+	}
+
+	h.Close()
+	// Close is idempotent
+	h.Close()
+
+	for _, c := range clients {
+		c.Close()
+	}
+	for _, r := range h.Rotators() {
+		r.Close()
+	}
+
+	waitForGoroutines(t, base)
+
+	// clients connecting after Close must be rejected
+	client, server := net.Pipe()
+	h.addTCPClient(&TCPClient{Conn: server})
+	if _, err := client.Write([]byte("C\n")); err == nil {
+		t.Fatal("expected connection to be closed")
+	}
+}
+
+func TestListenTCP(t *testing.T) {
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// occupy a port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	t.Run("port in use", func(t *testing.T) {
+		tcpError := make(chan error)
+		go h.ListenTCP("127.0.0.1", port, tcpError)
+
+		select {
+		case err := <-tcpError:
+			if err == nil {
+				t.Fatal("expected error")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+
+		if _, ok := <-tcpError; ok {
+			t.Fatal("expected closed channel")
+		}
+	})
+
+	t.Run("hub closed", func(t *testing.T) {
+		tcpError := make(chan error)
+		go h.ListenTCP("127.0.0.1", 0, tcpError)
+
+		// wait until the listener is running
+		time.Sleep(time.Millisecond * 50)
+		h.Close()
+
+		select {
+		case err, ok := <-tcpError:
+			if ok || err != nil {
+				t.Fatalf("expected closed channel, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	})
+}
+
+func TestListenAddr(t *testing.T) {
+
+	tt := []struct {
+		host    string
+		expAddr string
+	}{
+		{"", ":7373"},
+		{"127.0.0.1", "127.0.0.1:7373"},
+		{"localhost", "localhost:7373"},
+		{"::", "[::]:7373"},
+		{"::1", "[::1]:7373"},
+		{"[::1]", "[::1]:7373"},
+		{"fe80::1%eth0", "[fe80::1%eth0]:7373"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.host, func(t *testing.T) {
+			if addr := listenAddr(tc.host, 7373); addr != tc.expAddr {
+				t.Fatalf("expected %q, got %q", tc.expAddr, addr)
+			}
+		})
+	}
+}
+
+func TestListenTCPIPv6(t *testing.T) {
+
+	// find a free port on the IPv6 loopback
+	l, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 not available:", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	go h.ListenTCP("::1", port, make(chan error, 1))
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn, err := net.Dial("tcp", net.JoinHostPort("::1", strconv.Itoa(port)))
+		if err == nil {
+			conn.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestListenHTTPPortInUse(t *testing.T) {
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// occupy a port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	errorCh := make(chan error)
+	go h.ListenHTTP("127.0.0.1", port, errorCh)
+
+	select {
+	case err := <-errorCh:
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	if _, ok := <-errorCh; ok {
+		t.Fatal("expected closed channel")
+	}
+}
+
+func TestCloseHTTPServer(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer func() {
+		for _, r := range h.Rotators() {
+			r.Close()
+		}
+	}()
+
+	errorCh := make(chan error)
+	go h.ListenHTTP("127.0.0.1", 0, errorCh)
+
+	waitForHTTPServers(t, h, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err, ok := <-errorCh:
+		if ok || err != nil {
+			t.Fatalf("expected closed channel, got %v", err)
+		}
+	case <-time.After(time.Second * 2):
+		t.Fatal("ListenHTTP did not return after Shutdown")
+	}
+
+	h.RLock()
+	n := len(h.httpServers)
+	h.RUnlock()
+	if n != 0 {
+		t.Fatalf("expected 0 http servers, got %d", n)
+	}
+}
+
+func TestMultipleHubs(t *testing.T) {
+
+	hubs := []*Hub{newTestHub(t, "r1"), newTestHub(t, "r2")}
+
+	for _, h := range hubs {
+		go h.ListenHTTP("127.0.0.1", 0, make(chan error))
+	}
+
+	for i, h := range hubs {
+		waitForHTTPServers(t, h, 1)
+
+		// each hub must serve its own rotators
+		rec := httptest.NewRecorder()
+		h.router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/rotators", nil))
+		if !strings.Contains(rec.Body.String(), fmt.Sprintf("r%d", i+1)) {
+			t.Fatalf("hub %d: unexpected response %s", i, rec.Body.String())
+		}
+	}
+
+	for _, h := range hubs {
+		h.Close()
+		for _, r := range h.Rotators() {
+			r.Close()
+		}
+	}
+}
+
+func TestMaxClients(t *testing.T) {
+
+	limit := 3
+
+	h := newTestHub(t, "r1")
+	MaxTCPClients(limit)(h)
+	MaxWsClients(limit)(h)
+	defer func() {
+		h.Close()
+		for _, r := range h.Rotators() {
+			r.Close()
+		}
+	}()
+
+	for i := 0; i <= limit; i++ {
+		client, server := net.Pipe()
+		defer client.Close()
+		h.addTCPClient(&TCPClient{Conn: server})
+		_, err := client.Write([]byte("C\n"))
+		if i < limit && err != nil {
+			t.Fatalf("tcp client %d refused: %v", i, err)
+		}
+		if i == limit && err == nil {
+			t.Fatal("expected tcp client to be refused")
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	for i := 0; i <= limit; i++ {
+		conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+		if i < limit {
+			if err != nil {
+				t.Fatalf("websocket client %d refused: %v", i, err)
+			}
+			defer conn.Close()
+			continue
+		}
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected websocket client to be refused")
+		}
+		if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("expected status %d, got %v", http.StatusServiceUnavailable, resp)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package hub
+
+import (
+	"fmt"
+)
+
+// LimitPolicy defines how the hub treats commands which exceed the
+// soft limits of a rotator.
+type LimitPolicy string
+
+const (
+	// ClampToLimits moves the rotator to the nearest soft limit.
+	ClampToLimits LimitPolicy = "clamp"
+	// RejectBeyondLimits rejects the command and returns an error
+	// to the client.
+	RejectBeyondLimits LimitPolicy = "reject"
+)
+
+// SoftLimits restrict the headings to which the hub commands a rotator,
+// independent of the mechanical limits reported by the rotator itself.
+// Limits which are not set (nil) don't restrict the heading.
+type SoftLimits struct {
+	AzimuthMin   *int        `json:"azimuth_min,omitempty"`
+	AzimuthMax   *int        `json:"azimuth_max,omitempty"`
+	ElevationMin *int        `json:"elevation_min,omitempty"`
+	ElevationMax *int        `json:"elevation_max,omitempty"`
+	Policy       LimitPolicy `json:"policy"`
+}
+
+// validate checks the policy and the ranges of the soft limits.
+func (sl SoftLimits) validate() error {
+	switch sl.Policy {
+	case ClampToLimits, RejectBeyondLimits:
+	default:
+		return fmt.Errorf("unknown limit policy (%s)", sl.Policy)
+	}
+
+	if sl.AzimuthMin != nil && sl.AzimuthMax != nil && *sl.AzimuthMin > *sl.AzimuthMax {
+		return fmt.Errorf("azimuth min (%d) greater than azimuth max (%d)", *sl.AzimuthMin, *sl.AzimuthMax)
+	}
+	if sl.ElevationMin != nil && sl.ElevationMax != nil && *sl.ElevationMin > *sl.ElevationMax {
+		return fmt.Errorf("elevation min (%d) greater than elevation max (%d)", *sl.ElevationMin, *sl.ElevationMax)
+	}
+
+	return nil
+}
+
+// apply returns value restricted to [min, max] according to the policy.
+// If the value is rejected, an error is returned.
+func (sl SoftLimits) apply(axis string, value int, min, max *int) (int, error) {
+	limit := value
+	if min != nil && value < *min {
+		limit = *min
+	}
+	if max != nil && value > *max {
+		limit = *max
+	}
+
+	if limit == value {
+		return value, nil
+	}
+
+	if sl.Policy == RejectBeyondLimits {
+		return 0, fmt.Errorf("%s %d exceeds the soft limit %d", axis, value, limit)
+	}
+
+	return limit, nil
+}
+
+// SetSoftLimits sets the soft limits of the rotator with the given name.
+// The hub clamps or rejects (depending on the policy) all commands
+// exceeding these limits before they are forwarded to the rotator.
+func (hub *Hub) SetSoftLimits(name string, sl SoftLimits) error {
+	hub.Lock()
+	defer hub.Unlock()
+
+	if sl.Policy == "" {
+		sl.Policy = ClampToLimits
+	}
+
+	if _, ok := hub.rotators[name]; !ok {
+		return fmt.Errorf("unknown rotator %s", name)
+	}
+
+	if err := sl.validate(); err != nil {
+		return err
+	}
+
+	hub.softLimits[name] = sl
+	hub.logger.Infof("soft limits of rotator (%s) set (policy: %s)", name, sl.Policy)
+
+	return nil
+}
+
+// ClearSoftLimits removes the soft limits of the rotator with the given
+// name.
+func (hub *Hub) ClearSoftLimits(name string) {
+	hub.Lock()
+	defer hub.Unlock()
+
+	delete(hub.softLimits, name)
+}
+
+// SoftLimits returns the soft limits of the rotator with the given name.
+// If no soft limits are set, false is returned.
+func (hub *Hub) SoftLimits(name string) (SoftLimits, bool) {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	sl, ok := hub.softLimits[name]
+	return sl, ok
+}
+
+// limitAzimuth applies the soft limits of the rotator name to az.
+// The caller must hold the lock.
+func (hub *Hub) limitAzimuth(name string, az int) (int, error) {
+	sl, ok := hub.softLimits[name]
+	if !ok {
+		return az, nil
+	}
+
+	limited, err := sl.apply("azimuth", az, sl.AzimuthMin, sl.AzimuthMax)
+	if err != nil {
+		hub.logger.Warnf("rejected command for rotator (%s): %v", name, err)
+		return 0, err
+	}
+
+	return limited, nil
+}
+
+// limitElevation applies the soft limits of the rotator name to el.
+// The caller must hold the lock.
+func (hub *Hub) limitElevation(name string, el int) (int, error) {
+	sl, ok := hub.softLimits[name]
+	if !ok {
+		return el, nil
+	}
+
+	limited, err := sl.apply("elevation", el, sl.ElevationMin, sl.ElevationMax)
+	if err != nil {
+		hub.logger.Warnf("rejected command for rotator (%s): %v", name, err)
+		return 0, err
+	}
+
+	return limited, nil
+}
@@ -0,0 +1,131 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestSoftLimitsApply(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		policy   LimitPolicy
+		value    int
+		min      *int
+		max      *int
+		expValue int
+		expErr   bool
+	}{
+		{"within limits", ClampToLimits, 45, intPtr(0), intPtr(90), 45, false},
+		{"clamp max", ClampToLimits, 180, intPtr(0), intPtr(90), 90, false},
+		{"clamp min", ClampToLimits, -5, intPtr(0), intPtr(90), 0, false},
+		{"no max", ClampToLimits, 180, intPtr(0), nil, 180, false},
+		{"no limits", RejectBeyondLimits, 180, nil, nil, 180, false},
+		{"reject max", RejectBeyondLimits, 180, intPtr(0), intPtr(90), 0, true},
+		{"reject min", RejectBeyondLimits, 5, intPtr(10), intPtr(90), 0, true},
+		{"reject on limit", RejectBeyondLimits, 90, intPtr(0), intPtr(90), 90, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sl := SoftLimits{Policy: tc.policy}
+			res, err := sl.apply("elevation", tc.value, tc.min, tc.max)
+			if tc.expErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tc.expValue {
+				t.Fatalf("expected %d, got %d", tc.expValue, res)
+			}
+		})
+	}
+}
+
+func TestSetSoftLimitsInvalid(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		rName  string
+		limits SoftLimits
+	}{
+		{"unknown rotator", "r3", SoftLimits{}},
+		{"unknown policy", "r1", SoftLimits{Policy: "ignore"}},
+		{"azimuth min > max", "r1", SoftLimits{AzimuthMin: intPtr(200), AzimuthMax: intPtr(100)}},
+		{"elevation min > max", "r1", SoftLimits{ElevationMin: intPtr(90), ElevationMax: intPtr(0)}},
+	}
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := h.SetSoftLimits(tc.rName, tc.limits); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestSoftLimitsEnforced(t *testing.T) {
+
+	tt := []struct {
+		name      string
+		policy    LimitPolicy
+		elevation int
+		expPreset int
+		expErr    bool
+	}{
+		{"clamp", ClampToLimits, 180, 90, false},
+		{"reject", RejectBeyondLimits, 180, 0, true},
+		{"within limits", RejectBeyondLimits, 45, 45, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			// the rotator doesn't move
+			r, err := dummy.New(dummy.Name("r1"), dummy.HasElevation(true),
+				dummy.ElevationSpeed(0),
+				dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			sl := SoftLimits{ElevationMax: intPtr(90), Policy: tc.policy}
+			if err := h.SetSoftLimits("r1", sl); err != nil {
+				t.Fatal(err)
+			}
+
+			err = h.ExecuteRequest(rotator.Request{Name: "r1", HasElevation: true, Elevation: tc.elevation})
+			if tc.expErr && err == nil {
+				t.Fatal("expected error")
+			}
+			if !tc.expErr && err != nil {
+				t.Fatal(err)
+			}
+			if r.ElPreset() != tc.expPreset {
+				t.Fatalf("expected elevation preset %d, got %d", tc.expPreset, r.ElPreset())
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package hub
+
+import "log"
+
+// Logger is the interface through which the hub (and the proxy) report
+// connection events, disconnects and errors. It is satisfied by common
+// leveled loggers like zap's SugaredLogger or logrus.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// StdLogger is a Logger which writes all levels to the standard logger
+// of the log package.
+type StdLogger struct{}
+
+// Debugf logs a debug message.
+func (StdLogger) Debugf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+// Infof logs an informational message.
+func (StdLogger) Infof(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+// Warnf logs a warning.
+func (StdLogger) Warnf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+// Errorf logs an error.
+func (StdLogger) Errorf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
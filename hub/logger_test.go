@@ -0,0 +1,66 @@
+package hub
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+type recordLogger struct {
+	sync.Mutex
+	msgs []string
+}
+
+func (l *recordLogger) record(level, format string, args ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.msgs = append(l.msgs, level+" "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordLogger) Debugf(format string, args ...interface{}) { l.record("debug", format, args...) }
+func (l *recordLogger) Infof(format string, args ...interface{})  { l.record("info", format, args...) }
+func (l *recordLogger) Warnf(format string, args ...interface{})  { l.record("warn", format, args...) }
+func (l *recordLogger) Errorf(format string, args ...interface{}) { l.record("error", format, args...) }
+
+func TestWithLogger(t *testing.T) {
+
+	l := &recordLogger{}
+
+	h, err := New(WithLogger(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+	h.RemoveRotator(r)
+
+	tt := []string{
+		"info added rotator (r1)",
+		"info removed rotator (r1)",
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if len(l.msgs) != len(tt) {
+		t.Fatalf("expected %d messages, got %v", len(tt), l.msgs)
+	}
+	for i, exp := range tt {
+		if l.msgs[i] != exp {
+			t.Fatalf("expected %q, got %q", exp, l.msgs[i])
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package hub
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/micro/mdns"
+)
+
+// MdnsService is the service type under which the rotators of a hub are
+// advertised via mDNS.
+const MdnsService = "_rotator._tcp"
+
+// Advertise announces all rotators of the hub via mDNS (MdnsService) as
+// reachable through the HTTP server at port on the given ips. The TXT
+// records of each service contain the rotator's name, the port and the
+// supported axes. Rotators added afterwards are not advertised. The
+// announcements end when the hub is closed.
+func (hub *Hub) Advertise(port int, ips []net.IP) error {
+	hub.Lock()
+	defer hub.Unlock()
+
+	if hub.closed() {
+		return fmt.Errorf("hub closed")
+	}
+
+	servers := []*mdns.Server{}
+	shutdown := func() {
+		for _, s := range servers {
+			if err := s.Shutdown(); err != nil {
+				hub.logger.Errorf("%v", err)
+			}
+		}
+	}
+
+	for _, r := range hub.rotators {
+		service, err := mdns.NewMDNSService(r.Name(), MdnsService, "", "",
+			port, ips, mdnsTXT(r, port))
+		if err != nil {
+			shutdown()
+			return fmt.Errorf("unable to create mDNS service for rotator %s: %v", r.Name(), err)
+		}
+
+		server, err := mdns.NewServer(&mdns.Config{Zone: service})
+		if err != nil {
+			shutdown()
+			return fmt.Errorf("unable to start mDNS server for rotator %s: %v", r.Name(), err)
+		}
+		servers = append(servers, server)
+		hub.logger.Infof("advertising rotator (%s) via mDNS", r.Name())
+	}
+
+	hub.goRoutine(func() {
+		<-hub.closeCh
+		shutdown()
+	})
+
+	return nil
+}
+
+// mdnsTXT returns the TXT records advertised for r
+func mdnsTXT(r rotator.Rotator, port int) []string {
+	return []string{
+		fmt.Sprintf("name=%s", r.Name()),
+		fmt.Sprintf("port=%d", port),
+		fmt.Sprintf("has_azimuth=%t", r.HasAzimuth()),
+		fmt.Sprintf("has_elevation=%t", r.HasElevation()),
+	}
+}
@@ -0,0 +1,21 @@
+package hub
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMdnsTXT(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	r, _ := h.Rotator("r1")
+	defer r.Close()
+
+	exp := []string{"name=r1", "port=7070", "has_azimuth=true", "has_elevation=false"}
+
+	if txt := mdnsTXT(r, 7070); !reflect.DeepEqual(txt, exp) {
+		t.Fatalf("expected %v, got %v", exp, txt)
+	}
+}
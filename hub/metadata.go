@@ -0,0 +1,50 @@
+package hub
+
+import (
+	"fmt"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// SetMetadata sets the description, location and tags of the rotator with
+// the given name. The hub adds them to the rotator's serialized state
+// (e.g. in /api/rotators and the add events sent to websocket clients).
+// Metadata reported by the rotator itself is overridden.
+func (hub *Hub) SetMetadata(name string, m rotator.Metadata) error {
+	hub.Lock()
+	defer hub.Unlock()
+
+	if _, ok := hub.rotators[name]; !ok {
+		return fmt.Errorf("unknown rotator %s", name)
+	}
+
+	tags := make([]string, len(m.Tags))
+	copy(tags, m.Tags)
+	m.Tags = tags
+
+	hub.metadata[name] = m
+
+	return nil
+}
+
+// Metadata returns the metadata of the rotator with the given name which
+// has been set with SetMetadata. If no metadata is set, false is returned.
+func (hub *Hub) Metadata(name string) (rotator.Metadata, bool) {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	m, ok := hub.metadata[name]
+	return m, ok
+}
+
+// serialize returns the serialized state of r, including the metadata
+// set with SetMetadata and the presets restored from the store. The
+// caller must hold the lock.
+func (hub *Hub) serialize(r rotator.Rotator) rotator.Object {
+	obj := r.Serialize()
+	if m, ok := hub.metadata[r.Name()]; ok {
+		obj.Metadata = m
+	}
+	hub.applyRestoredPresets(r.Name(), &obj.Heading)
+	return obj
+}
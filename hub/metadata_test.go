@@ -0,0 +1,110 @@
+package hub
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+func TestSetMetadata(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	md := rotator.Metadata{
+		Description: "Tribander",
+		Location:    "Tower 1",
+		Tags:        []string{"hf", "20m"},
+	}
+
+	if err := h.SetMetadata("unknown", md); err == nil {
+		t.Fatal("expected error for unknown rotator")
+	}
+
+	if err := h.SetMetadata("r1", md); err != nil {
+		t.Fatal(err)
+	}
+
+	// the hub keeps its own copy of the tags
+	md.Tags[0] = "vhf"
+
+	exp := rotator.Metadata{Description: "Tribander", Location: "Tower 1", Tags: []string{"hf", "20m"}}
+	if m, ok := h.Metadata("r1"); !ok || !reflect.DeepEqual(m, exp) {
+		t.Fatalf("expected metadata %+v, got %+v", exp, m)
+	}
+
+	info := h.Info()
+	if len(info) != 1 || !reflect.DeepEqual(info[0].Metadata, exp) {
+		t.Fatalf("expected metadata %+v in info, got %+v", exp, info)
+	}
+
+	r, _ := h.Rotator("r1")
+	h.RemoveRotator(r)
+	if _, ok := h.Metadata("r1"); ok {
+		t.Fatal("expected metadata to be removed with the rotator")
+	}
+}
+
+func TestMetadataJSON(t *testing.T) {
+
+	tt := []struct {
+		name        string
+		md          *rotator.Metadata
+		expContains []string
+		expMissing  []string
+	}{
+		{"without metadata",
+			nil,
+			[]string{`"name":"r1"`},
+			[]string{"description", "location", "tags"}},
+		{"with metadata",
+			&rotator.Metadata{Description: "Tribander", Location: "Tower 1", Tags: []string{"hf"}},
+			[]string{`"description":"Tribander"`, `"location":"Tower 1"`, `"tags":["hf"]`},
+			nil},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHub(t, "r1")
+			defer h.Close()
+			newTestRouter(h)
+
+			if tc.md != nil {
+				if err := h.SetMetadata("r1", *tc.md); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			srv := httptest.NewServer(h.router)
+			defer srv.Close()
+
+			for _, path := range []string{"/api/rotators", "/api/rotator/r1"} {
+				resp, err := http.Get(srv.URL + path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, err := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, s := range tc.expContains {
+					if !strings.Contains(string(body), s) {
+						t.Fatalf("%s: expected %s in %s", path, s, body)
+					}
+				}
+				for _, s := range tc.expMissing {
+					if strings.Contains(string(body), s) {
+						t.Fatalf("%s: unexpected %s in %s", path, s, body)
+					}
+				}
+			}
+		})
+	}
+}
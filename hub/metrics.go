@@ -0,0 +1,39 @@
+package hub
+
+import "net/http"
+
+// MetricsCollector collects measurements of the hub for monitoring systems (see
+// the package metrics for a Prometheus implementation). The hub serves
+// the metrics on /metrics. Implementations must be safe for concurrent use.
+type MetricsCollector interface {
+	http.Handler
+	// SetClients is called whenever the number of clients connected
+	// through protocol (ProtocolTCP, ProtocolWebsocket) changes.
+	SetClients(protocol string, n int)
+	// RequestReceived is called for every command received from a client.
+	RequestReceived(rotator string)
+	// RequestRejected is called for every command which could not be
+	// executed (e.g. because it exceeds the soft limits).
+	RequestRejected(rotator string)
+	// BroadcastError is called whenever a client is disconnected because
+	// an update could not be delivered.
+	BroadcastError(protocol string)
+}
+
+// updateClientMetrics reports the number of connected clients. The caller
+// must hold the lock.
+func (hub *Hub) updateClientMetrics() {
+	if hub.metrics == nil {
+		return
+	}
+	hub.metrics.SetClients(ProtocolTCP, len(hub.tcpClients))
+	hub.metrics.SetClients(ProtocolWebsocket, len(hub.wsClients))
+}
+
+// broadcastError reports a client which has been disconnected because
+// an update could not be delivered.
+func (hub *Hub) broadcastError(protocol string) {
+	if hub.metrics != nil {
+		hub.metrics.BroadcastError(protocol)
+	}
+}
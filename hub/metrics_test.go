@@ -0,0 +1,101 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+type fakeMetrics struct {
+	sync.Mutex
+	clients  map[string]int
+	requests int
+	rejected int
+}
+
+func (m *fakeMetrics) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Write([]byte("metrics"))
+}
+
+func (m *fakeMetrics) SetClients(protocol string, n int) {
+	m.Lock()
+	defer m.Unlock()
+	m.clients[protocol] = n
+}
+
+func (m *fakeMetrics) RequestReceived(rotator string) {
+	m.Lock()
+	defer m.Unlock()
+	m.requests++
+}
+
+func (m *fakeMetrics) RequestRejected(rotator string) {
+	m.Lock()
+	defer m.Unlock()
+	m.rejected++
+}
+
+func (m *fakeMetrics) BroadcastError(protocol string) {}
+
+func TestMetrics(t *testing.T) {
+
+	m := &fakeMetrics{clients: make(map[string]int)}
+
+	h, err := New(Metrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	azMax := 180
+	if err := h.SetSoftLimits("r1", SoftLimits{AzimuthMax: &azMax, Policy: RejectBeyondLimits}); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		azimuth int
+		err     bool
+	}{
+		{90, false},
+		{270, true},
+	}
+
+	for _, tc := range tt {
+		err := h.ExecuteRequest(rotator.Request{Name: "r1", HasAzimuth: true, Azimuth: tc.azimuth})
+		if (err != nil) != tc.err {
+			t.Fatalf("azimuth %d: unexpected error: %v", tc.azimuth, err)
+		}
+	}
+
+	m.Lock()
+	if m.requests != 2 || m.rejected != 1 {
+		t.Fatalf("expected 2 requests / 1 rejected, got %d / %d", m.requests, m.rejected)
+	}
+	m.Unlock()
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+}
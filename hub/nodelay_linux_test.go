@@ -0,0 +1,71 @@
+package hub
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+// noDelay returns the TCP_NODELAY socket option of conn.
+func noDelay(t *testing.T, conn *net.TCPConn) bool {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		v, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_NODELAY)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+	return v != 0
+}
+
+func TestTCPNoDelay(t *testing.T) {
+
+	tt := []struct {
+		name       string
+		opts       []func(*Hub)
+		expNoDelay bool
+	}{
+		{"enabled by default", nil, true},
+		{"disabled", []func(*Hub){TCPNoDelay(false)}, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+
+			client, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			conn, err := ln.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			h.addTCPClient(&TCPClient{Conn: conn})
+
+			if nd := noDelay(t, conn.(*net.TCPConn)); nd != tc.expNoDelay {
+				t.Fatalf("expected TCP_NODELAY=%v, got %v", tc.expNoDelay, nd)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+package hub
+
+import (
+	"net/http"
+	"time"
+)
+
+// Location is a functional option to set the position of the station
+// (latitude / longitude in degrees, North and East positive). The location
+// is needed to track celestial bodies.
+func Location(lat, lon float64) func(*Hub) {
+	return func(hub *Hub) {
+		hub.latitude = lat
+		hub.longitude = lon
+		hub.hasLocation = true
+	}
+}
+
+// TrackingInterval is a functional option to set the interval in which
+// a tracked rotator will be updated with a new heading. The interval
+// must be positive.
+func TrackingInterval(d time.Duration) func(*Hub) {
+	return func(hub *Hub) {
+		hub.trackingInterval = d
+	}
+}
+
+// ConfigToken is a functional option to enable the /api/config endpoint
+// for exporting and importing the hub's configuration. Requests must
+// provide the token in the header "Authorization: Bearer <token>".
+func ConfigToken(token string) func(*Hub) {
+	return func(hub *Hub) {
+		hub.configToken = token
+	}
+}
+
+// TCPKeepAlive is a functional option to set the period of the TCP
+// keep-alive probes sent to idle tcp clients. A period of 0 disables
+// the keep-alive probes.
+func TCPKeepAlive(d time.Duration) func(*Hub) {
+	return func(hub *Hub) {
+		hub.tcpKeepAlive = d
+	}
+}
+
+// TCPIdleTimeout is a functional option to disconnect tcp clients which
+// haven't sent a message (command or query) within the given time. Such
+// clients are often leftovers of crashed applications; clients which
+// stopped reading are already disconnected through the write timeout.
+// Note that clients which only listen to the heading updates will be
+// disconnected as well. A timeout of 0 (default) disables the check.
+func TCPIdleTimeout(d time.Duration) func(*Hub) {
+	return func(hub *Hub) {
+		hub.tcpIdleTimeout = d
+	}
+}
+
+// TCPNoDelay is a functional option to disable Nagle's algorithm
+// (TCP_NODELAY) on the connections of tcp clients, so that the small
+// replies and heading updates are sent without delay. It is enabled by
+// default; disabling it reduces the number of packets at the cost of
+// latency.
+func TCPNoDelay(enabled bool) func(*Hub) {
+	return func(hub *Hub) {
+		hub.tcpNoDelay = enabled
+	}
+}
+
+// TCPWriteTimeout is a functional option to set the maximum time for
+// writing to a tcp client. Clients which don't accept the data in time
+// (e.g. half-open connections) are disconnected, so that they can't
+// stall the broadcasts to the other clients. A timeout of 0 disables
+// the limit.
+func TCPWriteTimeout(d time.Duration) func(*Hub) {
+	return func(hub *Hub) {
+		hub.tcpWriteTimeout = d
+	}
+}
+
+// WsKeepAlive is a functional option to set the period in which pings are
+// sent to the websocket clients. Clients which don't answer within two
+// periods are disconnected. A period of 0 disables the pings.
+func WsKeepAlive(d time.Duration) func(*Hub) {
+	return func(hub *Hub) {
+		hub.wsKeepAlive = d
+	}
+}
+
+// MaxSlewRate is a functional option to drop headings which imply that
+// the rotator moved faster than rate (degrees per second) since the last
+// heading, e.g. garbage values read from a serial line. A genuine move is
+// accepted once it has been confirmed by the next heading. The headings of
+// each rotator are filtered separately (see BroadcastHeading). A rate of 0
+// disables the filter.
+func MaxSlewRate(rate float64) func(*Hub) {
+	return func(hub *Hub) {
+		hub.maxSlewRate = rate
+	}
+}
+
+// SuppressDuplicates is a functional option to skip heading broadcasts
+// which are identical to the last heading sent for the same rotator,
+// e.g. while a rotator is parked. An identical heading is sent again once
+// keepAlive has elapsed, so that the clients still receive an occasional
+// update confirming that the rotator is alive. With a keepAlive of 0, all
+// duplicates are suppressed.
+func SuppressDuplicates(keepAlive time.Duration) func(*Hub) {
+	return func(hub *Hub) {
+		hub.lastBroadcasts = make(map[string]*lastBroadcast)
+		hub.duplicateKeepAlive = keepAlive
+	}
+}
+
+// StopOnLastDisconnect is a functional option to stop all rotators when
+// the last TCP or websocket client which is able to send commands
+// disconnects, e.g. because the operator's software crashed. Read-only
+// clients are not taken into account. Movements started by the hub itself
+// (tracking, following, park schedules) are not cancelled and will
+// continue with their next update.
+func StopOnLastDisconnect(enabled bool) func(*Hub) {
+	return func(hub *Hub) {
+		hub.stopOnLastDisconnect = enabled
+	}
+}
+
+// DryRun is a functional option to run the hub without moving the
+// rotators. The commands are validated (limits, keep-out zones, ...) and
+// logged as usual, but applied to a simulated heading of the rotator
+// instead of being forwarded to the hardware. The simulated rotator
+// reaches each preset immediately and its headings are broadcast to the
+// clients, while the headings of the hardware (BroadcastHeading) are
+// ignored.
+func DryRun(enabled bool) func(*Hub) {
+	return func(hub *Hub) {
+		hub.dryRun = enabled
+	}
+}
+
+// CommandRate is a functional option to limit the number of commands
+// which each TCP and websocket client may send per second. Short bursts
+// of up to one second worth of commands are accepted. Commands exceeding
+// the rate are dropped and the client receives an error (if its protocol
+// supports it). Queries are not limited. A rate of 0 disables the limit.
+func CommandRate(rate float64) func(*Hub) {
+	return func(hub *Hub) {
+		hub.commandRate = rate
+	}
+}
+
+// WsBinary is a functional option to send the events to the websocket
+// clients as binary messages. By default, the JSON encoded events are
+// sent as text messages, which are easier to inspect with browser dev
+// tools or wscat.
+func WsBinary(enabled bool) func(*Hub) {
+	return func(hub *Hub) {
+		hub.wsBinary = enabled
+	}
+}
+
+// WsCompression is a functional option to compress the messages sent to
+// the websocket clients (permessage-deflate, RFC 7692). Compression is
+// only used with clients which support it as well. The JSON encoded
+// events compress very well, which reduces the traffic on metered links
+// at the cost of some CPU time.
+func WsCompression(enabled bool) func(*Hub) {
+	return func(hub *Hub) {
+		hub.wsCompression = enabled
+	}
+}
+
+// WsPath is a functional option to set the path of the websocket
+// endpoint, e.g. if the hub is mounted under a sub path by a reverse
+// proxy which doesn't strip the prefix. The read-only endpoint is
+// located at the same path with the suffix "-readonly". Note that the
+// bundled web interface connects to the default path (/ws).
+func WsPath(path string) func(*Hub) {
+	return func(hub *Hub) {
+		hub.wsPath = path
+	}
+}
+
+// MaxTCPClients is a functional option to limit the number of
+// simultaneously connected tcp clients. Further clients will be
+// disconnected immediately. A limit of 0 disables the limit.
+func MaxTCPClients(n int) func(*Hub) {
+	return func(hub *Hub) {
+		hub.maxTCPClients = n
+	}
+}
+
+// MaxWsClients is a functional option to limit the number of
+// simultaneously connected websocket clients. Further websocket upgrades
+// will be rejected with 503. A limit of 0 disables the limit.
+func MaxWsClients(n int) func(*Hub) {
+	return func(hub *Hub) {
+		hub.maxWsClients = n
+	}
+}
+
+// AuthToken is a functional option to protect the HTTP API and the
+// websocket with a shared secret. Clients have to provide the token either
+// in the header "Authorization: Bearer <token>" or as query parameter
+// (e.g. /ws?token=<token>). Unauthorized requests are rejected with 401.
+func AuthToken(token string) func(*Hub) {
+	return func(hub *Hub) {
+		hub.authToken = token
+	}
+}
+
+// BroadcastRate is a functional option to limit the number of heading
+// updates sent to the clients to n per second. Headings received in
+// between are coalesced; the latest heading is always delivered.
+// A rate of 0 disables the limit.
+func BroadcastRate(n int) func(*Hub) {
+	return func(hub *Hub) {
+		hub.broadcastRate = n
+	}
+}
+
+// ShortestPath is a functional option to let rotators which can turn more
+// than 360° (overlap) reach the commanded azimuth with the least travel.
+// If the azimuth lies within the overlap, the hub commands the rotator
+// to the position in the overlap if it is closer to the current azimuth.
+// It is a shorthand for AzimuthRouting(PathShortest) or, if disabled,
+// AzimuthRouting(PathDirect).
+func ShortestPath(enabled bool) func(*Hub) {
+	return func(hub *Hub) {
+		hub.azimuthPath = PathDirect
+		if enabled {
+			hub.azimuthPath = PathShortest
+		}
+	}
+}
+
+// AzimuthRouting is a functional option to set the path which rotators
+// which can turn more than 360° (overlap) take to an azimuth within the
+// overlap (see AzimuthPath).
+func AzimuthRouting(p AzimuthPath) func(*Hub) {
+	return func(hub *Hub) {
+		hub.azimuthPath = p
+	}
+}
+
+// DirectPathWithin is a functional option to move rotators which can
+// turn more than 360° (overlap) straight to the commanded azimuth if it
+// is at most deg degrees away, regardless of the AzimuthPath. This keeps
+// slow rotators from taking the long way around for small corrections
+// (e.g. with PathClockwise). Larger movements follow the AzimuthPath.
+// Keep-out zones are still respected. The default is 0 (disabled).
+func DirectPathWithin(deg int) func(*Hub) {
+	return func(hub *Hub) {
+		hub.directPathWithin = deg
+	}
+}
+
+// KeepOut is a functional option to set the azimuth ranges to which the
+// hub doesn't command the rotators. Azimuth commands into a keep-out zone
+// are rejected. If a rotator can reach an azimuth in two positions
+// (overlap), the hub prefers the position whose path doesn't cross a
+// keep-out zone. New returns an error if a zone is invalid.
+func KeepOut(zones ...KeepOutZone) func(*Hub) {
+	return func(hub *Hub) {
+		hub.keepOut = append(hub.keepOut, zones...)
+	}
+}
+
+// AllowedOrigins is a functional option to set the origins (e.g.
+// "https://panel.example.com") from which browsers may access the HTTP
+// API and the websocket. The hub adds the corresponding CORS headers and
+// answers preflight requests. "*" allows all origins. By default only
+// the hub's own origin is allowed.
+func AllowedOrigins(origins ...string) func(*Hub) {
+	return func(hub *Hub) {
+		hub.allowedOrigins = append(hub.allowedOrigins, origins...)
+	}
+}
+
+// CheckOrigin is a functional option to replace the origin check of the
+// websocket upgrader with f. f returns true if the websocket request may
+// be upgraded. By default, requests without an Origin header and requests
+// from the hub's own origin or an allowed origin (see AllowedOrigins) are
+// accepted.
+func CheckOrigin(f func(req *http.Request) bool) func(*Hub) {
+	return func(hub *Hub) {
+		hub.originChecker = f
+	}
+}
+
+// PresetTolerance is a functional option to set the deviation in degrees
+// from the commanded heading within which a rotator is considered to have
+// reached its preset (see PresetReached).
+func PresetTolerance(deg int) func(*Hub) {
+	return func(hub *Hub) {
+		hub.presetTolerance = deg
+	}
+}
+
+// SettleTime is a functional option to set the interval at which the hub
+// checks whether the commanded rotators have reached their presets and
+// whether the stopped rotators have come to rest. A rotator is considered
+// at rest if its heading hasn't changed during this interval.
+func SettleTime(d time.Duration) func(*Hub) {
+	return func(hub *Hub) {
+		hub.settleTime = d
+	}
+}
+
+// Ramp is a functional option to break azimuth movements of more than
+// p.Threshold degrees into steps of p.Step degrees, each followed by a
+// dwell time of p.Dwell. A stop command or a new azimuth aborts the
+// ramp. New returns an error if the profile is invalid.
+func Ramp(p RampProfile) func(*Hub) {
+	return func(hub *Hub) {
+		hub.rampProfile = p
+	}
+}
+
+// PresetStore is a functional option to persist the last known headings
+// of the rotators in s. When a rotator is added to the hub, the presets
+// it had before the hub was restarted are reported again until the
+// rotator is commanded. The rotator itself is not moved.
+func PresetStore(s Store) func(*Hub) {
+	return func(hub *Hub) {
+		hub.store = s
+	}
+}
+
+// Metrics is a functional option to collect the hub's metrics in m
+// and serve them on /metrics.
+func Metrics(m MetricsCollector) func(*Hub) {
+	return func(hub *Hub) {
+		hub.metrics = m
+	}
+}
+
+// WithLogger is a functional option to route the hub's log messages
+// (connection events, disconnects and errors) through l.
+func WithLogger(l Logger) func(*Hub) {
+	return func(hub *Hub) {
+		hub.logger = l
+	}
+}
+
+// ClientEventHandler is a functional option to set a callback which is
+// executed whenever a TCP or websocket client connects to or disconnects
+// from the hub. The events are delivered in order from a separate
+// go routine; if the callback falls behind, events will be dropped.
+func ClientEventHandler(h func(ClientEvent)) func(*Hub) {
+	return func(hub *Hub) {
+		hub.clientEventHandler = h
+	}
+}
+
+// HealthCheck is a functional option to replace the check behind the
+// /healthz endpoint. The endpoint responds with 503 (Service Unavailable)
+// if f returns an error and with 200 (OK) otherwise. By default, the hub
+// is unhealthy while a rotator reports a fault (see ReportFault) or a
+// rotator proxy has lost the connection to its remote hub. f is called
+// for every request and must not block.
+func HealthCheck(f func() error) func(*Hub) {
+	return func(hub *Hub) {
+		hub.healthCheck = f
+	}
+}
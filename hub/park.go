@@ -0,0 +1,274 @@
+package hub
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// ParkSchedule defines when and where a rotator will be parked
+// automatically (e.g. for storm / wind safety of unattended stations).
+// All times are daily local times in the format "15:04".
+type ParkSchedule struct {
+	At        string `json:"at"`
+	Azimuth   int    `json:"azimuth"`
+	Elevation int    `json:"elevation"`
+	// OperatingFrom and OperatingTo are optional. If both are set,
+	// movement commands outside of these operating hours will be rejected.
+	OperatingFrom string `json:"operating_from,omitempty"`
+	OperatingTo   string `json:"operating_to,omitempty"`
+}
+
+// ParkState is broadcasted to the clients whenever the park schedule
+// of a rotator changes.
+type ParkState struct {
+	ParkSchedule
+	NextPark time.Time `json:"next_park"`
+	// Override indicates that an operator has countermanded the
+	// park schedule. While set, the rotator will not be parked and the
+	// operating hours are not enforced.
+	Override bool `json:"override"`
+}
+
+type parkSchedule struct {
+	ParkSchedule
+	at       time.Duration // offset since midnight
+	from     time.Duration
+	to       time.Duration
+	nextPark time.Time
+	override bool
+}
+
+// parkCheckInterval is the resolution of the park scheduler
+const parkCheckInterval = time.Second * 10
+
+// SetParkSchedule enables the automatic parking of a rotator.
+func (hub *Hub) SetParkSchedule(name string, ps ParkSchedule) error {
+	s, err := newParkSchedule(ps)
+	if err != nil {
+		return err
+	}
+
+	hub.Lock()
+	defer hub.Unlock()
+
+	if _, ok := hub.rotators[name]; !ok {
+		return fmt.Errorf("unknown rotator %s", name)
+	}
+
+	hub.parkSchedules[name] = s
+	hub.broadcastParkState(name, s)
+	hub.logger.Infof("rotator (%s) will be parked daily at %s", name, ps.At)
+
+	return nil
+}
+
+// newParkSchedule validates ps and returns the internal representation
+// of the schedule.
+func newParkSchedule(ps ParkSchedule) (*parkSchedule, error) {
+	at, err := parseTimeOfDay(ps.At)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &parkSchedule{
+		ParkSchedule: ps,
+		at:           at,
+	}
+
+	if ps.OperatingFrom != "" || ps.OperatingTo != "" {
+		if s.from, err = parseTimeOfDay(ps.OperatingFrom); err != nil {
+			return nil, err
+		}
+		if s.to, err = parseTimeOfDay(ps.OperatingTo); err != nil {
+			return nil, err
+		}
+		// the window would be empty and reject all commands
+		if s.from == s.to {
+			return nil, fmt.Errorf("operating hours %s - %s are empty",
+				ps.OperatingFrom, ps.OperatingTo)
+		}
+	}
+
+	s.nextPark = nextOccurrence(time.Now(), at)
+
+	return s, nil
+}
+
+// ClearParkSchedule disables the automatic parking of a rotator.
+func (hub *Hub) ClearParkSchedule(name string) {
+	hub.Lock()
+	defer hub.Unlock()
+
+	hub.clearParkSchedule(name)
+}
+
+func (hub *Hub) clearParkSchedule(name string) {
+	if _, ok := hub.parkSchedules[name]; !ok {
+		return
+	}
+	delete(hub.parkSchedules, name)
+	hub.broadcastParkState(name, nil)
+}
+
+// ParkState returns the park schedule of a rotator. If no schedule
+// has been set, (ParkState{}, false) will be returned.
+func (hub *Hub) ParkState(name string) (ParkState, bool) {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	s, ok := hub.parkSchedules[name]
+	if !ok {
+		return ParkState{}, false
+	}
+	return s.state(), true
+}
+
+// OverridePark allows an operator to countermand the park schedule
+// of a rotator. While the override is active, the rotator will not be
+// parked and movement commands are accepted outside of the operating hours.
+func (hub *Hub) OverridePark(name string, override bool) error {
+	hub.Lock()
+	defer hub.Unlock()
+
+	s, ok := hub.parkSchedules[name]
+	if !ok {
+		return fmt.Errorf("no park schedule set for rotator %s", name)
+	}
+
+	s.override = override
+	hub.broadcastParkState(name, s)
+
+	return nil
+}
+
+// checkOperatingHours returns an error if the operating hours of the
+// rotator are enforced and the current time is outside of them.
+// The caller must hold the lock.
+func (hub *Hub) checkOperatingHours(name string) error {
+	s, ok := hub.parkSchedules[name]
+	if !ok || s.override || s.OperatingFrom == "" {
+		return nil
+	}
+
+	if !withinTimeOfDay(time.Now(), s.from, s.to) {
+		return fmt.Errorf("rotator %s is parked outside of the operating hours (%s - %s)",
+			name, s.OperatingFrom, s.OperatingTo)
+	}
+
+	return nil
+}
+
+// parkScheduler parks the rotators at their scheduled time.
+// Since this function contains an endless loop, it should be executed
+// in a go routine.
+func (hub *Hub) parkScheduler() {
+	ticker := time.NewTicker(parkCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			hub.parkDue(time.Now())
+		case <-hub.closeCh:
+			return
+		}
+	}
+}
+
+// parkDue parks all rotators whose scheduled park time has passed. Their
+// tracking is stopped and the park position is commanded like any other
+// heading (incl. followers, soft limits and keep-out zones), but
+// regardless of the operating hours.
+func (hub *Hub) parkDue(now time.Time) {
+	type parking struct {
+		r  rotator.Rotator
+		ps ParkSchedule
+	}
+	due := []parking{}
+
+	hub.Lock()
+	for name, s := range hub.parkSchedules {
+		if now.Before(s.nextPark) {
+			continue
+		}
+		s.nextPark = nextOccurrence(now, s.at)
+		r, ok := hub.rotators[name]
+		if ok && !s.override {
+			due = append(due, parking{r, s.ParkSchedule})
+		}
+		hub.broadcastParkState(name, s)
+	}
+	hub.Unlock()
+
+	for _, p := range due {
+		hub.logger.Infof("parking rotator (%s)", p.r.Name())
+		hub.StopTracking(p.r.Name())
+		if p.r.HasAzimuth() {
+			if err := hub.commandAzimuth(p.r, p.ps.Azimuth, true); err != nil {
+				hub.logger.Errorf("unable to park rotator %s: %v", p.r.Name(), err)
+			}
+		}
+		if p.r.HasElevation() {
+			if err := hub.commandElevation(p.r, p.ps.Elevation, true); err != nil {
+				hub.logger.Errorf("unable to park rotator %s: %v", p.r.Name(), err)
+			}
+		}
+	}
+}
+
+func (hub *Hub) broadcastParkState(name string, s *parkSchedule) {
+	ev := Event{
+		Name:        UpdateParkSchedule,
+		RotatorName: name,
+	}
+	if s != nil {
+		ps := s.state()
+		ev.Park = &ps
+	}
+	if err := hub.broadcastToWsClients(ev); err != nil {
+		hub.logger.Errorf("%v", err)
+	}
+}
+
+func (s *parkSchedule) state() ParkState {
+	return ParkState{
+		ParkSchedule: s.ParkSchedule,
+		NextPark:     s.nextPark,
+		Override:     s.override,
+	}
+}
+
+// parseTimeOfDay parses a time in the format "15:04" and returns the
+// offset since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q (expected hh:mm)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// nextOccurrence returns the next point in time after now at the given
+// offset since midnight (local time).
+func nextOccurrence(now time.Time, at time.Duration) time.Time {
+	y, m, d := now.Date()
+	next := time.Date(y, m, d, 0, 0, 0, 0, now.Location()).Add(at)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// withinTimeOfDay checks if now is within the daily window [from, to).
+// The window may span midnight (e.g. 22:00 - 06:00).
+func withinTimeOfDay(now time.Time, from, to time.Duration) bool {
+	y, m, d := now.Date()
+	t := now.Sub(time.Date(y, m, d, 0, 0, 0, 0, now.Location()))
+
+	if from <= to {
+		return t >= from && t < to
+	}
+	return t >= from || t < to
+}
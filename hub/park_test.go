@@ -0,0 +1,160 @@
+package hub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/astro"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestNextOccurrence(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		now    time.Time
+		at     string
+		expRes time.Time
+	}{
+		{"later today", time.Date(2018, 1, 1, 20, 0, 0, 0, time.Local), "23:00",
+			time.Date(2018, 1, 1, 23, 0, 0, 0, time.Local)},
+		{"tomorrow", time.Date(2018, 1, 1, 23, 30, 0, 0, time.Local), "23:00",
+			time.Date(2018, 1, 2, 23, 0, 0, 0, time.Local)},
+		{"exactly now", time.Date(2018, 1, 1, 23, 0, 0, 0, time.Local), "23:00",
+			time.Date(2018, 1, 2, 23, 0, 0, 0, time.Local)},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			at, err := parseTimeOfDay(tc.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := nextOccurrence(tc.now, at)
+			if !res.Equal(tc.expRes) {
+				t.Fatalf("expected %v, got %v", tc.expRes, res)
+			}
+		})
+	}
+}
+
+func TestWithinTimeOfDay(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		from   string
+		to     string
+		now    time.Time
+		expRes bool
+	}{
+		{"within day window", "07:00", "22:00", time.Date(2018, 1, 1, 12, 0, 0, 0, time.Local), true},
+		{"before day window", "07:00", "22:00", time.Date(2018, 1, 1, 6, 59, 0, 0, time.Local), false},
+		{"after day window", "07:00", "22:00", time.Date(2018, 1, 1, 22, 0, 0, 0, time.Local), false},
+		{"within night window", "22:00", "06:00", time.Date(2018, 1, 1, 23, 0, 0, 0, time.Local), true},
+		{"within night window after midnight", "22:00", "06:00", time.Date(2018, 1, 1, 2, 0, 0, 0, time.Local), true},
+		{"outside night window", "22:00", "06:00", time.Date(2018, 1, 1, 12, 0, 0, 0, time.Local), false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			from, _ := parseTimeOfDay(tc.from)
+			to, _ := parseTimeOfDay(tc.to)
+			if res := withinTimeOfDay(tc.now, from, to); res != tc.expRes {
+				t.Fatalf("expected %v, got %v", tc.expRes, res)
+			}
+		})
+	}
+}
+
+func TestParseTimeOfDayInvalid(t *testing.T) {
+	for _, s := range []string{"", "25:00", "23", "noon"} {
+		if _, err := parseTimeOfDay(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
+
+func TestParkDue(t *testing.T) {
+
+	// operating hours which don't include now
+	now := time.Now()
+	closed := ParkSchedule{
+		At:            "23:00",
+		Azimuth:       180,
+		OperatingFrom: now.Add(time.Hour * 2).Format("15:04"),
+		OperatingTo:   now.Add(time.Hour * 3).Format("15:04"),
+	}
+
+	tt := []struct {
+		name      string
+		opts      []func(*Hub)
+		ps        ParkSchedule
+		setup     func(h *Hub) error
+		expPreset int
+	}{
+		{"park", nil, ParkSchedule{At: "23:00", Azimuth: 180}, nil, 180},
+		{"outside operating hours", nil, closed, nil, 180},
+		{"keep-out", []func(*Hub){KeepOut(KeepOutZone{170, 190})},
+			ParkSchedule{At: "23:00", Azimuth: 180}, nil, 0},
+		{"following", nil, ParkSchedule{At: "23:00", Azimuth: 180},
+			func(h *Hub) error { return h.Follow("r2", "r1", 0, RejectCommands) }, 180},
+		{"tracking", []func(*Hub){Location(40.4, -3.7)}, ParkSchedule{At: "23:00"},
+			func(h *Hub) error { return h.Track("r1", astro.Sun) }, -1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			// the rotators don't move
+			for _, name := range []string{"r1", "r2"} {
+				r, err := dummy.New(append([]func(*dummy.Dummy){dummy.Name(name)}, staticRotator...)...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := h.AddRotator(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tc.setup != nil {
+				if err := tc.setup(h); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := h.SetParkSchedule("r1", tc.ps); err != nil {
+				t.Fatal(err)
+			}
+
+			h.parkDue(now.Add(time.Hour * 25))
+
+			if _, ok := h.Tracking("r1"); ok {
+				t.Fatal("expected tracking to be stopped")
+			}
+			if _, ok := h.Following("r1"); ok {
+				t.Fatal("expected coupling to be terminated")
+			}
+			if tc.expPreset < 0 {
+				return
+			}
+			r, _ := h.Rotator("r1")
+			if r.AzPreset() != tc.expPreset {
+				t.Fatalf("expected azimuth preset %d, got %d", tc.expPreset, r.AzPreset())
+			}
+		})
+	}
+}
+
+func TestEmptyOperatingHours(t *testing.T) {
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	ps := ParkSchedule{At: "23:00", OperatingFrom: "08:00", OperatingTo: "08:00"}
+	if err := h.SetParkSchedule("r1", ps); err == nil {
+		t.Fatal("expected error")
+	}
+}
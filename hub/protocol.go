@@ -0,0 +1,79 @@
+package hub
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// ProtocolVersion is the version of the hub's wire protocol (HTTP API and
+// websocket events). It is incremented whenever a change breaks the
+// compatibility with existing clients; new optional fields don't change it.
+const ProtocolVersion = 1
+
+// ProtocolHeader is the HTTP header through which the hub announces its
+// ProtocolVersion in all responses of the HTTP API.
+const ProtocolHeader = "X-Rotator-Protocol"
+
+// WsSubprotocol is the websocket subprotocol (Sec-WebSocket-Protocol)
+// through which the hub exchanges JSON encoded events and requests.
+// Further encodings can be added as additional subprotocols.
+const WsSubprotocol = "rotator-json-v1"
+
+// WsCompactSubprotocol is like WsSubprotocol, but the heading updates
+// are sent as binary messages in the compact encoding of the package
+// rotator/compact, which is considerably smaller than JSON. All other
+// events remain JSON encoded text messages.
+const WsCompactSubprotocol = "rotator-compact-v1"
+
+// wsSubprotocols are the subprotocols supported by the hub, in order of
+// preference. The compact encoding is only used if the client requests
+// it.
+var wsSubprotocols = []string{WsCompactSubprotocol, WsSubprotocol}
+
+// checkSubprotocols returns an error if the client requested websocket
+// subprotocols, none of which is supported by the hub. Clients which
+// don't request a subprotocol (e.g. the web interface and older proxies)
+// are served with WsSubprotocol.
+func checkSubprotocols(req *http.Request) error {
+	requested := websocket.Subprotocols(req)
+	if len(requested) == 0 {
+		return nil
+	}
+	for _, p := range requested {
+		for _, s := range wsSubprotocols {
+			if p == s {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unsupported websocket subprotocols (%s); supported: %s",
+		strings.Join(requested, ", "), strings.Join(wsSubprotocols, ", "))
+}
+
+// ParseProtocolVersion returns the protocol version announced in the HTTP
+// header h. Hubs which predate the versioning don't announce a version;
+// in this case 0 is returned.
+func ParseProtocolVersion(h http.Header) (int, error) {
+	s := h.Get(ProtocolHeader)
+	if len(s) == 0 {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 1 {
+		return 0, fmt.Errorf("invalid protocol version %q", s)
+	}
+	return v, nil
+}
+
+// versioned adds the ProtocolHeader to all responses of next.
+func versioned(next http.Handler) http.Handler {
+	version := strconv.Itoa(ProtocolVersion)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set(ProtocolHeader, version)
+		next.ServeHTTP(w, req)
+	})
+}
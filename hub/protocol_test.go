@@ -0,0 +1,204 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/compact"
+	"github.com/gorilla/websocket"
+)
+
+// readHello reads the Hello event with which the hub greets websocket
+// clients.
+func readHello(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	ev := Event{}
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Name != Hello || ev.Protocol != ProtocolVersion {
+		t.Fatalf("expected %s event with protocol %d, got %+v", Hello, ProtocolVersion, ev)
+	}
+}
+
+func TestProtocolHeader(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	for _, path := range []string{"/api/rotators", "/api/rotator/r1", "/api/rotator/unknown"} {
+		t.Run(path, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			v, err := ParseProtocolVersion(resp.Header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != ProtocolVersion {
+				t.Fatalf("expected protocol version %d, got %d", ProtocolVersion, v)
+			}
+		})
+	}
+}
+
+func TestWsSubprotocol(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	tt := []struct {
+		name         string
+		subprotocols []string
+		expProtocol  string
+		expStatus    int
+	}{
+		{"json", []string{WsSubprotocol}, WsSubprotocol, 0},
+		{"json among others", []string{"rotator-cbor-v1", WsSubprotocol}, WsSubprotocol, 0},
+		{"compact", []string{WsCompactSubprotocol}, WsCompactSubprotocol, 0},
+		{"none requested", nil, "", 0},
+		{"unsupported", []string{"rotator-cbor-v1"}, "", http.StatusBadRequest},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dialer := websocket.Dialer{Subprotocols: tc.subprotocols}
+			conn, resp, err := dialer.Dial(wsURL, nil)
+			if tc.expStatus != 0 {
+				if err == nil {
+					conn.Close()
+					t.Fatal("expected handshake to fail")
+				}
+				if resp == nil || resp.StatusCode != tc.expStatus {
+					t.Fatalf("expected status %d, got %v", tc.expStatus, resp)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			if p := conn.Subprotocol(); p != tc.expProtocol {
+				t.Fatalf("expected subprotocol %q, got %q", tc.expProtocol, p)
+			}
+			readHello(t, conn)
+		})
+	}
+}
+
+func TestWsCompactHeadings(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	dialer := websocket.Dialer{Subprotocols: []string{WsCompactSubprotocol}}
+	conn, _, err := dialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	readHello(t, conn)
+
+	// all events except the heading updates remain JSON encoded
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	msgType, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := Event{}
+	if err := json.Unmarshal(msg, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if msgType != websocket.TextMessage || ev.Name != AddRotator {
+		t.Fatalf("expected %s text message, got %+v", AddRotator, ev)
+	}
+	if ev.RotatorID == 0 {
+		t.Fatal("expected rotator id in add event")
+	}
+
+	exp := rotator.Heading{Azimuth: 270, AzPreset: 90, Elevation: 10, ElPreset: 20, Speed: 2}
+	h.BroadcastHeading("r1", exp)
+
+	for {
+		msgType, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msgType != websocket.BinaryMessage {
+			continue
+		}
+		id, heading, err := compact.DecodeHeading(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != ev.RotatorID {
+			t.Fatalf("expected rotator id %d, got %d", ev.RotatorID, id)
+		}
+		if heading != exp {
+			t.Fatalf("expected heading %+v, got %+v", exp, heading)
+		}
+		return
+	}
+}
+
+func TestParseProtocolVersion(t *testing.T) {
+
+	tt := []struct {
+		name       string
+		value      string
+		expVersion int
+		expErr     bool
+	}{
+		{"current", strconv.Itoa(ProtocolVersion), ProtocolVersion, false},
+		{"future", "7", 7, false},
+		{"not announced", "", 0, false},
+		{"zero", "0", 0, true},
+		{"garbage", "v1", 0, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := http.Header{}
+			if len(tc.value) > 0 {
+				h.Set(ProtocolHeader, tc.value)
+			}
+			v, err := ParseProtocolVersion(h)
+			if tc.expErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != tc.expVersion {
+				t.Fatalf("expected version %d, got %d", tc.expVersion, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package hub
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// commandKind identifies the axis (or the stop) which a command affects.
+type commandKind int
+
+const (
+	moveAzimuth commandKind = iota
+	rampAzimuth
+	moveElevation
+	moveAzEl
+	changeSpeed
+	haltAzimuth
+	haltElevation
+	haltAll
+)
+
+// supersedes returns true if a command of kind k makes a pending command
+// of kind pending obsolete. Newer targets replace older targets of the
+// same axis; stop commands discard the pending moves of their axes.
+func (k commandKind) supersedes(pending commandKind) bool {
+	switch k {
+	case moveAzimuth:
+		return pending == moveAzimuth || pending == rampAzimuth
+	case rampAzimuth, moveElevation, changeSpeed:
+		return k == pending
+	case moveAzEl:
+		// a move of a single axis doesn't supersede a combined move,
+		// since the other axis would be lost
+		return pending == moveAzimuth || pending == rampAzimuth ||
+			pending == moveElevation || pending == moveAzEl
+	case haltAzimuth:
+		return pending == moveAzimuth || pending == rampAzimuth
+	case haltElevation:
+		return pending == moveElevation
+	case haltAll:
+		return pending == moveAzimuth || pending == rampAzimuth ||
+			pending == moveElevation || pending == moveAzEl
+	}
+	return false
+}
+
+// command is a call into a rotator which is executed by a command queue.
+type command struct {
+	kind commandKind
+	exec func() error
+	done chan error
+}
+
+// commandQueue executes the commands for a rotator one at a time in the
+// order in which they were enqueued. Many rotator controllers can't
+// accept a new command while they are still processing one.
+type commandQueue struct {
+	sync.Mutex
+	pending []*command
+	wakeCh  chan struct{}
+	closeCh chan struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func newCommandQueue() *commandQueue {
+	q := &commandQueue{
+		wakeCh:  make(chan struct{}, 1),
+		closeCh: make(chan struct{}),
+	}
+	q.wg.Add(1)
+	go q.run()
+	return q
+}
+
+// do enqueues the command exec of the given kind and waits until it has
+// been executed. Pending commands which are superseded by the new command
+// are discarded; their callers return nil.
+func (q *commandQueue) do(kind commandKind, exec func() error) error {
+	cmd := &command{
+		kind: kind,
+		exec: exec,
+		done: make(chan error, 1),
+	}
+
+	q.Lock()
+	if q.closed {
+		q.Unlock()
+		return fmt.Errorf("command queue closed")
+	}
+	pending := q.pending[:0]
+	for _, c := range q.pending {
+		if kind.supersedes(c.kind) {
+			c.done <- nil
+			continue
+		}
+		pending = append(pending, c)
+	}
+	q.pending = append(pending, cmd)
+	q.Unlock()
+
+	select {
+	case q.wakeCh <- struct{}{}:
+	default:
+	}
+
+	return <-cmd.done
+}
+
+// run executes the pending commands until the queue is closed.
+func (q *commandQueue) run() {
+	defer q.wg.Done()
+
+	for {
+		q.Lock()
+		if len(q.pending) == 0 {
+			q.Unlock()
+			select {
+			case <-q.wakeCh:
+				continue
+			case <-q.closeCh:
+				return
+			}
+		}
+		cmd := q.pending[0]
+		q.pending = q.pending[1:]
+		q.Unlock()
+
+		cmd.done <- cmd.exec()
+	}
+}
+
+// close stops the queue. Pending commands are discarded with an error.
+// close doesn't wait for a command which is being executed (see wait),
+// so it may be called while holding the hub's lock.
+func (q *commandQueue) close() {
+	q.Lock()
+	if q.closed {
+		q.Unlock()
+		return
+	}
+	q.closed = true
+	for _, c := range q.pending {
+		c.done <- fmt.Errorf("command queue closed")
+	}
+	q.pending = nil
+	close(q.closeCh)
+	q.Unlock()
+}
+
+// wait waits until the queue has been closed and the command in flight
+// (if any) has been executed. The hub's lock must not be held, since
+// the command may need it (e.g. to broadcast a heading).
+func (q *commandQueue) wait() {
+	q.wg.Wait()
+}
+
+// queuedRotator passes all commands for the embedded rotator through a
+// command queue. The hub wraps each rotator added through AddRotator.
+type queuedRotator struct {
+	rotator.Rotator
+	queue *commandQueue
+}
+
+// azElSetter is implemented by rotators which can move both axes with a
+// single command (e.g. the "W" command of GS-232 controllers).
+type azElSetter interface {
+	SetAzEl(az, el int) error
+}
+
+// azElSetterOf returns the azElSetter through which both axes of r can be
+// moved with a single command. A queued rotator supports it only if the
+// rotator it wraps does.
+func azElSetterOf(r rotator.Rotator) (azElSetter, bool) {
+	if qr, ok := r.(*queuedRotator); ok {
+		if _, ok := qr.Rotator.(azElSetter); !ok {
+			return nil, false
+		}
+		return qr, true
+	}
+	s, ok := r.(azElSetter)
+	return s, ok
+}
+
+func newQueuedRotator(r rotator.Rotator) *queuedRotator {
+	return &queuedRotator{
+		Rotator: r,
+		queue:   newCommandQueue(),
+	}
+}
+
+func (r *queuedRotator) SetAzimuth(az int) error {
+	return r.queue.do(moveAzimuth, func() error { return r.Rotator.SetAzimuth(az) })
+}
+
+func (r *queuedRotator) SetElevation(el int) error {
+	return r.queue.do(moveElevation, func() error { return r.Rotator.SetElevation(el) })
+}
+
+// SetAzEl moves both axes with a single command. It returns an error if
+// the embedded rotator doesn't support it (see azElSetterOf).
+func (r *queuedRotator) SetAzEl(az, el int) error {
+	s, ok := r.Rotator.(azElSetter)
+	if !ok {
+		return fmt.Errorf("rotator %s can not set azimuth and elevation together", r.Name())
+	}
+	return r.queue.do(moveAzEl, func() error { return s.SetAzEl(az, el) })
+}
+
+func (r *queuedRotator) SetSpeed(speed int) error {
+	return r.queue.do(changeSpeed, func() error { return r.Rotator.SetSpeed(speed) })
+}
+
+func (r *queuedRotator) StopAzimuth() error {
+	return r.queue.do(haltAzimuth, r.Rotator.StopAzimuth)
+}
+
+func (r *queuedRotator) StopElevation() error {
+	return r.queue.do(haltElevation, r.Rotator.StopElevation)
+}
+
+func (r *queuedRotator) Stop() error {
+	return r.queue.do(haltAll, r.Rotator.Stop)
+}
+
+// Close stops the command queue and closes the embedded rotator.
+func (r *queuedRotator) Close() {
+	r.queue.close()
+	r.queue.wait()
+	r.Rotator.Close()
+}
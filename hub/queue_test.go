@@ -0,0 +1,305 @@
+package hub
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+// gateRotator records the executed commands. The first command blocks
+// until the gate is opened. Overlapping commands are counted.
+type gateRotator struct {
+	rotator.Rotator
+	gate     chan struct{}
+	once     sync.Once
+	inFlight int32
+	overlaps int32
+	mu       sync.Mutex
+	executed []string
+}
+
+func (r *gateRotator) record(cmd string) error {
+	if atomic.AddInt32(&r.inFlight, 1) > 1 {
+		atomic.AddInt32(&r.overlaps, 1)
+	}
+	defer atomic.AddInt32(&r.inFlight, -1)
+
+	first := false
+	r.once.Do(func() { first = true })
+	if first {
+		<-r.gate
+	} else {
+		time.Sleep(time.Millisecond)
+	}
+
+	r.mu.Lock()
+	r.executed = append(r.executed, cmd)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *gateRotator) SetAzimuth(az int) error   { return r.record(fmt.Sprintf("az%d", az)) }
+func (r *gateRotator) SetElevation(el int) error { return r.record(fmt.Sprintf("el%d", el)) }
+func (r *gateRotator) StopAzimuth() error        { return r.record("stop_az") }
+func (r *gateRotator) Stop() error               { return r.record("stop") }
+
+// SetAzEl moves both axes with a single command
+func (r *gateRotator) SetAzEl(az, el int) error {
+	return r.record(fmt.Sprintf("azel%d/%d", az, el))
+}
+
+func (r *gateRotator) commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.executed...)
+}
+
+// waitEnqueued waits until a command other than last has been appended
+// to q and returns it.
+func waitEnqueued(t *testing.T, q *commandQueue, last *command) *command {
+	timeout := time.After(time.Second * 2)
+	for {
+		q.Lock()
+		if l := len(q.pending); l > 0 && q.pending[l-1] != last {
+			c := q.pending[l-1]
+			q.Unlock()
+			return c
+		}
+		q.Unlock()
+		select {
+		case <-timeout:
+			t.Fatal("command not enqueued")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestCommandQueue(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		commands []func(r rotator.Rotator) error
+		expCmds  []string
+	}{
+		{"in order",
+			[]func(r rotator.Rotator) error{
+				func(r rotator.Rotator) error { return r.SetElevation(5) },
+				func(r rotator.Rotator) error { return r.SetAzimuth(20) },
+			},
+			[]string{"az10", "el5", "az20"}},
+		{"replace pending azimuth",
+			[]func(r rotator.Rotator) error{
+				func(r rotator.Rotator) error { return r.SetAzimuth(20) },
+				func(r rotator.Rotator) error { return r.SetElevation(5) },
+				func(r rotator.Rotator) error { return r.SetAzimuth(30) },
+			},
+			[]string{"az10", "el5", "az30"}},
+		{"stop discards moves",
+			[]func(r rotator.Rotator) error{
+				func(r rotator.Rotator) error { return r.SetAzimuth(20) },
+				func(r rotator.Rotator) error { return r.SetElevation(5) },
+				func(r rotator.Rotator) error { return r.Stop() },
+			},
+			[]string{"az10", "stop"}},
+		{"stop azimuth keeps elevation",
+			[]func(r rotator.Rotator) error{
+				func(r rotator.Rotator) error { return r.SetAzimuth(20) },
+				func(r rotator.Rotator) error { return r.SetElevation(5) },
+				func(r rotator.Rotator) error { return r.StopAzimuth() },
+			},
+			[]string{"az10", "el5", "stop_az"}},
+		{"az/el replaces pending moves",
+			[]func(r rotator.Rotator) error{
+				func(r rotator.Rotator) error { return r.SetAzimuth(20) },
+				func(r rotator.Rotator) error { return r.SetElevation(5) },
+				func(r rotator.Rotator) error { return r.(*queuedRotator).SetAzEl(30, 6) },
+			},
+			[]string{"az10", "azel30/6"}},
+		{"azimuth keeps pending az/el",
+			[]func(r rotator.Rotator) error{
+				func(r rotator.Rotator) error { return r.(*queuedRotator).SetAzEl(20, 5) },
+				func(r rotator.Rotator) error { return r.SetAzimuth(30) },
+			},
+			[]string{"az10", "azel20/5", "az30"}},
+		{"stop discards az/el",
+			[]func(r rotator.Rotator) error{
+				func(r rotator.Rotator) error { return r.(*queuedRotator).SetAzEl(20, 5) },
+				func(r rotator.Rotator) error { return r.Stop() },
+			},
+			[]string{"az10", "stop"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gr := &gateRotator{gate: make(chan struct{})}
+			qr := newQueuedRotator(gr)
+			defer qr.queue.close()
+
+			var wg sync.WaitGroup
+			errs := make(chan error, len(tc.commands)+1)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- qr.SetAzimuth(10)
+			}()
+			// the first command is executing
+			for atomic.LoadInt32(&gr.inFlight) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			var last *command
+			for _, cmd := range tc.commands {
+				cmd := cmd
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- cmd(qr)
+				}()
+				last = waitEnqueued(t, qr.queue, last)
+			}
+
+			close(gr.gate)
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cmds := gr.commands()
+			if fmt.Sprint(cmds) != fmt.Sprint(tc.expCmds) {
+				t.Fatalf("expected %v, got %v", tc.expCmds, cmds)
+			}
+			if gr.overlaps > 0 {
+				t.Fatalf("%d overlapping commands", gr.overlaps)
+			}
+		})
+	}
+}
+
+func TestConcurrentSetAzimuth(t *testing.T) {
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	gr := &gateRotator{Rotator: d, gate: make(chan struct{})}
+	close(gr.gate)
+	if err := h.AddRotator(gr); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(az int) {
+			defer wg.Done()
+			if err := h.SetAzimuth("r1", az); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if gr.overlaps > 0 {
+		t.Fatalf("%d overlapping commands", gr.overlaps)
+	}
+	if n := len(gr.commands()); n == 0 || n > 50 {
+		t.Fatalf("unexpected number of executed commands %d", n)
+	}
+}
+
+func TestCommandQueueClosed(t *testing.T) {
+
+	gr := &gateRotator{gate: make(chan struct{})}
+	close(gr.gate)
+	qr := newQueuedRotator(gr)
+	qr.queue.close()
+
+	if err := qr.SetAzimuth(10); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// broadcastRotator broadcasts a heading through the hub while executing
+// SetAzimuth, which requires the hub's lock. The command blocks until
+// the gate is closed.
+type broadcastRotator struct {
+	rotator.Rotator
+	hub     *Hub
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func (r *broadcastRotator) SetAzimuth(az int) error {
+	close(r.entered)
+	<-r.gate
+	r.hub.BroadcastHeading(r.Name(), rotator.Heading{Azimuth: az})
+	return nil
+}
+
+func TestCommandInFlightOnRemove(t *testing.T) {
+
+	for _, name := range []string{"remove", "close"} {
+		t.Run(name, func(t *testing.T) {
+			h, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			d, err := dummy.New(dummy.Name("r1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &broadcastRotator{Rotator: d, hub: h,
+				entered: make(chan struct{}), gate: make(chan struct{})}
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			go h.SetAzimuth("r1", 10)
+			select {
+			case <-r.entered:
+			case <-time.After(time.Second):
+				t.Fatal("command not executed")
+			}
+
+			done := make(chan struct{})
+			go func() {
+				if name == "remove" {
+					h.RemoveRotator(r)
+				} else {
+					h.Close()
+				}
+				close(done)
+			}()
+
+			// let the hub wait for the command in flight
+			time.Sleep(time.Millisecond * 20)
+			close(r.gate)
+
+			select {
+			case <-done:
+			case <-time.After(time.Second * 2):
+				t.Fatal("deadlock while waiting for the command in flight")
+			}
+		})
+	}
+}
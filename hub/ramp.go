@@ -0,0 +1,198 @@
+package hub
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// RampProfile breaks large azimuth movements into smaller intermediate
+// targets in order to reduce the stress on the mast. Movements of more
+// than Threshold degrees are commanded in steps of Step degrees. The hub
+// waits Dwell after each intermediate target before commanding the next
+// one. The profile is disabled if Step is 0.
+type RampProfile struct {
+	Threshold int
+	Step      int
+	Dwell     time.Duration
+}
+
+// validate checks the values of the ramp profile.
+func (rp RampProfile) validate() error {
+	if rp.Step < 0 {
+		return fmt.Errorf("invalid ramp step size %d", rp.Step)
+	}
+	if rp.Threshold < 0 {
+		return fmt.Errorf("invalid ramp threshold %d", rp.Threshold)
+	}
+	if rp.Step > 0 && rp.Dwell <= 0 {
+		return fmt.Errorf("invalid ramp dwell time %v", rp.Dwell)
+	}
+	return nil
+}
+
+// ramp drives a rotator through the intermediate targets of a movement.
+// The mutex only guards stopped; it is never held while the rotator is
+// commanded.
+type ramp struct {
+	sync.Mutex
+	stopCh  chan struct{}
+	readyCh chan struct{} // closed once the first target has been commanded
+	stopped bool
+}
+
+// stop marks the ramp as stopped without waiting for a pending step.
+func (rp *ramp) stop() {
+	rp.Lock()
+	defer rp.Unlock()
+	if rp.stopped {
+		return
+	}
+	rp.stopped = true
+	close(rp.stopCh)
+}
+
+func (rp *ramp) isStopped() bool {
+	rp.Lock()
+	defer rp.Unlock()
+	return rp.stopped
+}
+
+// step commands r to az unless the ramp has been stopped. For queued
+// rotators the check is done when the command is executed, so that a
+// step can't overtake the command which stopped the ramp.
+func (rp *ramp) step(r rotator.Rotator, az int) error {
+	if qr, ok := r.(*queuedRotator); ok {
+		return qr.queue.do(rampAzimuth, func() error {
+			if rp.isStopped() {
+				return nil
+			}
+			return qr.Rotator.SetAzimuth(az)
+		})
+	}
+	if rp.isStopped() {
+		return nil
+	}
+	return r.SetAzimuth(az)
+}
+
+// rampSteps returns the targets through which a rotator is commanded
+// from the azimuth current to target. The last element is always target.
+func rampSteps(current, target, step int) []int {
+	delta := target - current
+	if step <= 0 || abs(delta) <= step {
+		return []int{target}
+	}
+
+	dir := 1
+	if delta < 0 {
+		dir = -1
+	}
+
+	steps := []int{}
+	for az := current + dir*step; dir*(target-az) > 0; az += dir * step {
+		steps = append(steps, az)
+	}
+
+	return append(steps, target)
+}
+
+// moveAzimuth commands r to az, taking the shortest path (if enabled)
+// and applying the ramp profile (if enabled). A pending ramp of r is
+// aborted.
+func (hub *Hub) moveAzimuth(r rotator.Rotator, az int) error {
+	az = hub.routeAzimuth(r, az)
+
+	hub.Lock()
+	hub.stopRamp(r.Name())
+	hub.expectAzimuth(r.Name(), az)
+
+	steps := []int{az}
+	if hub.rampProfile.Step > 0 && abs(az-r.Azimuth()) > hub.rampProfile.Threshold {
+		steps = rampSteps(r.Azimuth(), az, hub.rampProfile.Step)
+	}
+
+	if len(steps) == 1 || hub.closed() {
+		hub.Unlock()
+		err := r.SetAzimuth(az)
+		if err != nil {
+			hub.Lock()
+			hub.clearAzimuthTarget(r.Name())
+			hub.Unlock()
+		}
+		return err
+	}
+
+	rp := &ramp{stopCh: make(chan struct{}), readyCh: make(chan struct{})}
+	hub.ramps[r.Name()] = rp
+	hub.goRoutine(func() { hub.runRamp(r, rp, steps[1:]) })
+	hub.Unlock()
+
+	err := rp.step(r, steps[0])
+	close(rp.readyCh)
+
+	if err != nil {
+		hub.Lock()
+		if hub.ramps[r.Name()] == rp {
+			hub.stopRamp(r.Name())
+		}
+		hub.clearAzimuthTarget(r.Name())
+		hub.Unlock()
+	}
+
+	return err
+}
+
+// stopRamp aborts the ramp of the rotator with the given name. A step
+// which is in flight is not waited for; once stopRamp returns, the ramp
+// won't command the rotator anymore and the rotator's command queue
+// discards a step which hasn't been executed yet. The caller must hold
+// the lock.
+func (hub *Hub) stopRamp(name string) {
+	rp, ok := hub.ramps[name]
+	if !ok {
+		return
+	}
+	delete(hub.ramps, name)
+	rp.stop()
+}
+
+// runRamp commands r to the remaining targets of a ramp, waiting the
+// dwell time before each of them, until the ramp is stopped or the hub
+// is closed. It should be executed in a go routine.
+func (hub *Hub) runRamp(r rotator.Rotator, rp *ramp, steps []int) {
+	defer func() {
+		hub.Lock()
+		if hub.ramps[r.Name()] == rp {
+			delete(hub.ramps, r.Name())
+		}
+		hub.Unlock()
+	}()
+
+	select {
+	case <-rp.readyCh:
+	case <-hub.closeCh:
+		return
+	}
+
+	for _, az := range steps {
+		select {
+		case <-time.After(hub.rampProfile.Dwell):
+		case <-rp.stopCh:
+			return
+		case <-hub.closeCh:
+			return
+		}
+
+		err := rp.step(r, az)
+		if rp.isStopped() {
+			return
+		}
+		if err != nil {
+			hub.logger.Errorf("unable to set azimuth of rotator %s: %v", r.Name(), err)
+			return
+		}
+	}
+}
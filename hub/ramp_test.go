@@ -0,0 +1,213 @@
+package hub
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestRampSteps(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		current  int
+		target   int
+		step     int
+		expSteps []int
+	}{
+		{"170° clockwise", 0, 170, 50, []int{50, 100, 150, 170}},
+		{"170° counter clockwise", 180, 10, 50, []int{130, 80, 30, 10}},
+		{"multiple of step", 0, 150, 50, []int{50, 100, 150}},
+		{"smaller than step", 100, 140, 50, []int{140}},
+		{"no movement", 100, 100, 50, []int{100}},
+		{"into overlap", 300, 420, 50, []int{350, 400, 420}},
+		{"disabled", 0, 170, 0, []int{170}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			steps := rampSteps(tc.current, tc.target, tc.step)
+			if !reflect.DeepEqual(steps, tc.expSteps) {
+				t.Fatalf("expected %v, got %v", tc.expSteps, steps)
+			}
+		})
+	}
+}
+
+// recordRotator records the azimuths commanded by the hub. If gate is
+// set, the command to blockAt signals entered and blocks until the gate
+// is closed.
+type recordRotator struct {
+	rotator.Rotator
+	sync.Mutex
+	azimuths []int
+	blockAt  int
+	entered  chan struct{}
+	gate     chan struct{}
+}
+
+func (r *recordRotator) SetAzimuth(az int) error {
+	if r.gate != nil && az == r.blockAt {
+		close(r.entered)
+		<-r.gate
+	}
+	r.Lock()
+	r.azimuths = append(r.azimuths, az)
+	r.Unlock()
+	return r.Rotator.SetAzimuth(az)
+}
+
+func (r *recordRotator) commanded() []int {
+	r.Lock()
+	defer r.Unlock()
+	return append([]int{}, r.azimuths...)
+}
+
+func TestRamp(t *testing.T) {
+
+	dwell := time.Millisecond * 20
+
+	tt := []struct {
+		name      string
+		interrupt func(h *Hub) error
+		expAz     []int
+	}{
+		{"complete", nil, []int{50, 100, 150, 170}},
+		{"stop", func(h *Hub) error { return h.Stop("r1") }, []int{50}},
+		{"new target", func(h *Hub) error { return h.SetAzimuth("r1", 20) }, []int{50, 20}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(Ramp(RampProfile{Threshold: 30, Step: 50, Dwell: dwell}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			// the rotator doesn't move
+			d, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+				dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &recordRotator{Rotator: d}
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := h.SetAzimuth("r1", 170); err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.interrupt != nil {
+				if err := tc.interrupt(h); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			time.Sleep(dwell * 10)
+
+			if az := r.commanded(); !reflect.DeepEqual(az, tc.expAz) {
+				t.Fatalf("expected azimuths %v, got %v", tc.expAz, az)
+			}
+		})
+	}
+}
+
+func TestRampStepInFlight(t *testing.T) {
+
+	dwell := time.Millisecond * 20
+
+	h, err := New(Ramp(RampProfile{Threshold: 30, Step: 50, Dwell: dwell}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &recordRotator{Rotator: d, blockAt: 100,
+		entered: make(chan struct{}), gate: make(chan struct{})}
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.SetAzimuth("r1", 170); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-r.entered:
+	case <-time.After(time.Second):
+		t.Fatal("second step not commanded")
+	}
+
+	// a new target while the step is in flight doesn't block the hub
+	done := make(chan error, 1)
+	go func() { done <- h.SetAzimuth("r1", 20) }()
+
+	locked := make(chan struct{})
+	go func() {
+		time.Sleep(dwell)
+		h.Lock()
+		h.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("hub locked while the ramp step is in flight")
+	}
+
+	close(r.gate)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("new target not commanded")
+	}
+
+	time.Sleep(dwell * 5)
+
+	exp := []int{50, 100, 20}
+	if az := r.commanded(); !reflect.DeepEqual(az, exp) {
+		t.Fatalf("expected azimuths %v, got %v", exp, az)
+	}
+}
+
+func TestInvalidRamp(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		profile RampProfile
+		expErr  bool
+	}{
+		{"disabled", RampProfile{}, false},
+		{"valid", RampProfile{Threshold: 30, Step: 20, Dwell: time.Second}, false},
+		{"negative step", RampProfile{Step: -10, Dwell: time.Second}, true},
+		{"negative threshold", RampProfile{Threshold: -1, Step: 10, Dwell: time.Second}, true},
+		{"no dwell", RampProfile{Step: 10}, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(Ramp(tc.profile))
+			if (err != nil) != tc.expErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if h != nil {
+				h.Close()
+			}
+		})
+	}
+}
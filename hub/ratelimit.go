@@ -0,0 +1,59 @@
+package hub
+
+import (
+	"fmt"
+	"time"
+)
+
+// errRateLimit is returned to clients whose commands are dropped
+// because they exceeded the command rate.
+var errRateLimit = fmt.Errorf("command rate exceeded")
+
+// tokenBucket limits the rate of a client's commands. The bucket holds
+// up to one second worth of commands, so that short bursts pass while
+// the sustained rate is limited to rate commands per second. A nil
+// tokenBucket allows all commands.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full tokenBucket which allows rate commands
+// per second or nil if rate is 0 (unlimited).
+func newTokenBucket(rate float64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	burst := rate
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+	}
+}
+
+// allow returns true if a command received at time t may be executed.
+func (b *tokenBucket) allow(t time.Time) bool {
+	if b == nil {
+		return true
+	}
+
+	if !b.last.IsZero() {
+		b.tokens += t.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = t
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,191 @@
+package hub
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+	"github.com/gorilla/websocket"
+)
+
+func TestTokenBucket(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		rate     float64
+		offsets  []time.Duration
+		expAllow []bool
+	}{
+		{"burst",
+			2,
+			[]time.Duration{0, 0, 0},
+			[]bool{true, true, false}},
+		{"refill",
+			2,
+			[]time.Duration{0, 0, 0, time.Millisecond * 500, time.Millisecond * 500},
+			[]bool{true, true, false, true, false}},
+		{"burst limited to one second",
+			2,
+			[]time.Duration{0, time.Second * 10, time.Second * 10, time.Second * 10},
+			[]bool{true, true, true, false}},
+		{"slow rate",
+			0.5,
+			[]time.Duration{0, time.Second, time.Second * 2},
+			[]bool{true, false, true}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			b := newTokenBucket(tc.rate)
+			ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, offset := range tc.offsets {
+				if allow := b.allow(ts.Add(offset)); allow != tc.expAllow[i] {
+					t.Fatalf("command %d: expected allow=%v, got %v", i, tc.expAllow[i], allow)
+				}
+			}
+		})
+	}
+
+	var unlimited *tokenBucket
+	for i := 0; i < 100; i++ {
+		if !unlimited.allow(time.Now()) {
+			t.Fatal("expected nil bucket to allow all commands")
+		}
+	}
+}
+
+// newRateLimitedHub returns a hub which limits the commands of each
+// client to rate and counts the requests forwarded to its rotator.
+func newRateLimitedHub(t *testing.T, rate float64) (*Hub, func() int) {
+	h, err := New(CommandRate(rate))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	count := 0
+	h.SetRequestHandler(func(string, rotator.Request) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		count++
+		return true
+	})
+
+	return h, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+}
+
+func TestCommandRate(t *testing.T) {
+
+	if _, err := New(CommandRate(-1)); err == nil {
+		t.Fatal("expected error for negative command rate")
+	}
+
+	const rate = 5
+	const burst = 20
+
+	t.Run("tcp", func(t *testing.T) {
+		h, forwarded := newRateLimitedHub(t, rate)
+		defer h.Close()
+
+		client, server := net.Pipe()
+		defer client.Close()
+		h.addTCPClient(&TCPClient{Conn: server, dialect: GS232A})
+
+		// the dropped commands are answered with a prompt; the reply to
+		// the query arrives once all commands have been processed
+		replied := make(chan struct{})
+		go func() {
+			reader := bufio.NewReader(client)
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if strings.Contains(line, "AZ=") {
+					close(replied)
+					io.Copy(ioutil.Discard, reader)
+					return
+				}
+			}
+		}()
+
+		for i := 0; i < burst; i++ {
+			if _, err := client.Write([]byte("M090\r\n")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := client.Write([]byte("C\r\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case <-replied:
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+
+		if n := forwarded(); n < rate || n > rate+1 {
+			t.Fatalf("expected %d forwarded commands, got %d", rate, n)
+		}
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		h, forwarded := newRateLimitedHub(t, rate)
+		defer h.Close()
+
+		srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+		defer srv.Close()
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		for i := 0; i < burst; i++ {
+			req := rotator.Request{Name: "r1", HasAzimuth: true, Azimuth: 90}
+			if err := conn.WriteJSON(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// the dropped requests are answered with an error
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		errors := 0
+		for errors < burst-rate-1 {
+			ev := Event{}
+			if err := conn.ReadJSON(&ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Name == RequestError && ev.Error == errRateLimit.Error() {
+				errors++
+			}
+		}
+
+		if n := forwarded(); n < rate || n > rate+1 {
+			t.Fatalf("expected %d forwarded commands, got %d", rate, n)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package hub
+
+import (
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// presetTarget is the heading to which the hub has commanded a rotator
+// and which the rotator hasn't reached yet.
+type presetTarget struct {
+	hasAzimuth   bool
+	azimuth      int
+	hasElevation bool
+	elevation    int
+	// heading at the previous check
+	last    rotator.Heading
+	sampled bool
+}
+
+// reached returns true if h lies within tolerance degrees of the target.
+func (t *presetTarget) reached(h rotator.Heading, tolerance int) bool {
+	if t.hasAzimuth && angleDistance(h.Azimuth, t.azimuth) > tolerance {
+		return false
+	}
+	if t.hasElevation && abs(h.Elevation-t.elevation) > tolerance {
+		return false
+	}
+	return true
+}
+
+// angleDistance returns the smallest angle between the azimuths a and b.
+func angleDistance(a, b int) int {
+	d := normalizeAzimuth(a - b)
+	if d > 180 {
+		return 360 - d
+	}
+	return d
+}
+
+// expectAzimuth registers az as the azimuth target of the rotator name.
+// The caller must hold the lock.
+func (hub *Hub) expectAzimuth(name string, az int) {
+	hub.cancelStop(name, true, false)
+	hub.clearRestoredPreset(name, true, false)
+	t := hub.presetTarget(name)
+	t.hasAzimuth = true
+	t.azimuth = az
+}
+
+// expectElevation registers el as the elevation target of the rotator
+// name. The caller must hold the lock.
+func (hub *Hub) expectElevation(name string, el int) {
+	hub.cancelStop(name, false, true)
+	hub.clearRestoredPreset(name, false, true)
+	t := hub.presetTarget(name)
+	t.hasElevation = true
+	t.elevation = el
+}
+
+// presetTarget returns the target of the rotator name, creating it if
+// necessary. The heading has to be sampled again. The caller must hold
+// the lock.
+func (hub *Hub) presetTarget(name string) *presetTarget {
+	t, ok := hub.presetTargets[name]
+	if !ok {
+		t = &presetTarget{}
+		hub.presetTargets[name] = t
+	}
+	t.sampled = false
+	return t
+}
+
+// clearAzimuthTarget removes the azimuth target of the rotator name,
+// e.g. after the azimuth has been stopped. The caller must hold the lock.
+func (hub *Hub) clearAzimuthTarget(name string) {
+	t, ok := hub.presetTargets[name]
+	if !ok {
+		return
+	}
+	t.hasAzimuth = false
+	if !t.hasElevation {
+		delete(hub.presetTargets, name)
+	}
+}
+
+// clearElevationTarget removes the elevation target of the rotator name.
+// The caller must hold the lock.
+func (hub *Hub) clearElevationTarget(name string) {
+	t, ok := hub.presetTargets[name]
+	if !ok {
+		return
+	}
+	t.hasElevation = false
+	if !t.hasAzimuth {
+		delete(hub.presetTargets, name)
+	}
+}
+
+// watchPresets checks the commanded and the stopped rotators every settle
+// time until the hub is closed. It should be executed in a go routine.
+func (hub *Hub) watchPresets() {
+	ticker := time.NewTicker(hub.settleTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			hub.checkPresets()
+			hub.checkStops()
+		case <-hub.closeCh:
+			return
+		}
+	}
+}
+
+// checkPresets emits a PresetReached event for every rotator which has
+// come to rest within the preset tolerance of its target. A rotator is
+// considered at rest if its heading hasn't changed since the previous
+// check.
+func (hub *Hub) checkPresets() {
+	hub.Lock()
+	defer hub.Unlock()
+
+	for name, t := range hub.presetTargets {
+		r, ok := hub.rotators[name]
+		if !ok {
+			delete(hub.presetTargets, name)
+			continue
+		}
+
+		h := r.Serialize().Heading
+		settled := t.sampled && h.Azimuth == t.last.Azimuth && h.Elevation == t.last.Elevation
+		t.last = h
+		t.sampled = true
+
+		if !settled || !t.reached(h, hub.presetTolerance) {
+			continue
+		}
+
+		delete(hub.presetTargets, name)
+		hub.logger.Debugf("rotator (%s) reached its preset", name)
+
+		ev := Event{
+			Name:        PresetReached,
+			RotatorName: name,
+			Heading:     h,
+		}
+		if err := hub.broadcastToWsClients(ev); err != nil {
+			hub.logger.Errorf("%v", err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+	"github.com/gorilla/websocket"
+)
+
+func TestAngleDistance(t *testing.T) {
+
+	tt := []struct {
+		a, b int
+		exp  int
+	}{
+		{10, 20, 10},
+		{20, 10, 10},
+		{359, 1, 2},
+		{1, 359, 2},
+		{390, 30, 0},
+		{0, 180, 180},
+	}
+
+	for _, tc := range tt {
+		if d := angleDistance(tc.a, tc.b); d != tc.exp {
+			t.Errorf("angleDistance(%d, %d): expected %d, got %d", tc.a, tc.b, tc.exp, d)
+		}
+	}
+}
+
+func TestPresetReached(t *testing.T) {
+
+	tt := []struct {
+		name       string
+		tolerance  int
+		speed      int
+		azimuth    int
+		stop       bool
+		expReached bool
+	}{
+		{"reached", 1, 100, 40, false, true},
+		{"stopped", 1, 1, 300, true, false},
+		{"within tolerance", 5, 0, 3, false, true},
+		{"beyond tolerance", 1, 0, 3, false, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(PresetTolerance(tc.tolerance), SettleTime(time.Millisecond*50))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(tc.speed),
+				dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+			defer srv.Close()
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			reached := make(chan Event, 10)
+			go func() {
+				for {
+					_, msg, err := conn.ReadMessage()
+					if err != nil {
+						return
+					}
+					ev := Event{}
+					if err := json.Unmarshal(msg, &ev); err != nil {
+						continue
+					}
+					if ev.Name == PresetReached {
+						reached <- ev
+					}
+				}
+			}()
+
+			if err := h.SetAzimuth("r1", tc.azimuth); err != nil {
+				t.Fatal(err)
+			}
+			if tc.stop {
+				if err := h.Stop("r1"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			select {
+			case ev := <-reached:
+				if !tc.expReached {
+					t.Fatalf("unexpected event %+v", ev)
+				}
+				if ev.RotatorName != "r1" {
+					t.Fatalf("expected rotator r1, got %s", ev.RotatorName)
+				}
+				if angleDistance(ev.Heading.Azimuth, tc.azimuth) > tc.tolerance {
+					t.Fatalf("expected azimuth %d, got %d", tc.azimuth, ev.Heading.Azimuth)
+				}
+			case <-time.After(time.Second):
+				if tc.expReached {
+					t.Fatal("timeout")
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidPresetSettings(t *testing.T) {
+	if _, err := New(PresetTolerance(-1)); err == nil {
+		t.Fatal("expected error for negative tolerance")
+	}
+	if _, err := New(SettleTime(0)); err == nil {
+		t.Fatal("expected error for settle time 0")
+	}
+}
@@ -0,0 +1,93 @@
+package hub
+
+import (
+	"bufio"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestTCPClientReadOnly(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server, dialect: ARSVCOM, readOnly: true})
+
+	reader := bufio.NewReader(client)
+
+	if _, err := client.Write([]byte("M100\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	prompt := make([]byte, 2)
+	if _, err := reader.Read(prompt); err != nil {
+		t.Fatal(err)
+	}
+	if string(prompt) != "?>" {
+		t.Fatalf("expected prompt, got %q", prompt)
+	}
+
+	r, _ := h.Rotator("r1")
+	if r.AzPreset() != 0 {
+		t.Fatalf("read-only client moved the rotator to %d", r.AzPreset())
+	}
+
+	// queries are still allowed
+	if _, err := client.Write([]byte("C\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	res, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "+0000\r\n" {
+		t.Fatalf("expected %q, got %q", "+0000\r\n", res)
+	}
+}
+
+func TestWsClientReadOnly(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+	newTestRouter(h)
+
+	srv := httptest.NewServer(h.router)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws-readonly"
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteMessage(websocket.TextMessage,
+		[]byte(`{"name":"r1","has_azimuth":true,"azimuth":100}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		ev := Event{}
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Name != RequestError {
+			continue
+		}
+		if ev.RotatorName != "r1" || ev.Error != "read-only client" {
+			t.Fatalf("unexpected error event %+v", ev)
+		}
+		break
+	}
+
+	r, _ := h.Rotator("r1")
+	if r.AzPreset() != 0 {
+		t.Fatalf("read-only client moved the rotator to %d", r.AzPreset())
+	}
+}
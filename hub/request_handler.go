@@ -0,0 +1,31 @@
+package hub
+
+import "github.com/dh1tw/remoteRotator/rotator"
+
+// SourceLocal is the source of requests issued through the hub's methods
+// (e.g. ExecuteRequest, SetAzimuth) instead of a client connection.
+const SourceLocal = "local"
+
+// SetRequestHandler sets a callback which is executed for every heading,
+// speed or stop request (rotator.Request) before the hub forwards it to
+// the rotator. The source identifies the client as
+// protocol://remote-address (e.g. tcp://192.168.1.10:52044) or is
+// SourceLocal. If the callback returns false, the request is rejected.
+// The callback is executed synchronously and must not call back into
+// the hub. A nil handler removes the callback.
+//
+// The callback is not executed for the hub's automations and their
+// configuration: Track, Follow, OverridePark and the park schedules
+// (including the corresponding HTTP endpoints), nor for the moves they
+// issue (tracker updates, followers, parking).
+func (hub *Hub) SetRequestHandler(h func(source string, req rotator.Request) (allow bool)) {
+	hub.Lock()
+	defer hub.Unlock()
+	hub.requestHandler = h
+}
+
+// requestSource returns the source of a request received through
+// protocol from the client with the address addr.
+func requestSource(protocol, addr string) string {
+	return protocol + "://" + addr
+}
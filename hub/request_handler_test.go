@@ -0,0 +1,100 @@
+package hub
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestRequestHandler(t *testing.T) {
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	viaHTTP := func(az int) error {
+		body := bytes.NewBufferString(`{"azimuth":` + strconv.Itoa(az) + `}`)
+		req, err := http.NewRequest("PUT", srv.URL+"/api/rotator/r1/azimuth", body)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	}
+
+	viaAPI := func(az int) error {
+		return h.ExecuteRequest(rotator.Request{Name: "r1", HasAzimuth: true, Azimuth: az})
+	}
+
+	tt := []struct {
+		name     string
+		execute  func(int) error
+		allow    bool
+		azimuth  int
+		source   string
+		azPreset int
+	}{
+		{"http allowed", viaHTTP, true, 90, "http://127.0.0.1:", 90},
+		{"http vetoed", viaHTTP, false, 190, "http://127.0.0.1:", 90},
+		{"api allowed", viaAPI, true, 120, SourceLocal, 120},
+		{"api vetoed", viaAPI, false, 220, SourceLocal, 120},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var source string
+			var request rotator.Request
+			h.SetRequestHandler(func(s string, req rotator.Request) bool {
+				source = s
+				request = req
+				return tc.allow
+			})
+
+			err := tc.execute(tc.azimuth)
+			if tc.allow && err != nil {
+				t.Fatal(err)
+			}
+			if !tc.allow && err == nil {
+				t.Fatal("expected request to be vetoed")
+			}
+			if !strings.HasPrefix(source, tc.source) {
+				t.Fatalf("expected source %s, got %s", tc.source, source)
+			}
+			if request.Name != "r1" || !request.HasAzimuth || request.Azimuth != tc.azimuth {
+				t.Fatalf("unexpected request %+v", request)
+			}
+			if r.AzPreset() != tc.azPreset {
+				t.Fatalf("expected azimuth preset %d, got %d", tc.azPreset, r.AzPreset())
+			}
+		})
+	}
+}
@@ -1,13 +1,30 @@
 package hub
 
 func (hub *Hub) routes() {
-	hub.router.HandleFunc("/api/rotators", hub.rotatorsHandler).Methods("GET")
-	hub.router.HandleFunc("/api/rotator/{rotator}", hub.rotatorHandler).Methods("GET")
-	hub.router.HandleFunc("/api/rotator/{rotator}/azimuth", hub.azimuthHandler)
-	hub.router.HandleFunc("/api/rotator/{rotator}/elevation", hub.elevationHandler)
-	hub.router.HandleFunc("/api/rotator/{rotator}/stop", hub.stopHandler)
-	hub.router.HandleFunc("/api/rotator/{rotator}/stop_azimuth", hub.stopAzimuthHandler)
-	hub.router.HandleFunc("/api/rotator/{rotator}/stop_elevation", hub.stopElevationHandler)
-	hub.router.HandleFunc("/ws", hub.wsHandler)
+	hub.router.HandleFunc("/api/rotators", hub.authorize(hub.rotatorsHandler)).Methods("GET")
+	hub.router.HandleFunc("/api/rotator/{rotator}", hub.authorize(hub.rotatorHandler)).Methods("GET")
+	hub.router.HandleFunc("/api/status", hub.authorize(hub.statusHandler)).Methods("GET")
+	hub.router.HandleFunc("/api/clients", hub.authorize(hub.clientsHandler)).Methods("GET")
+	hub.router.HandleFunc("/api/command", hub.authorize(hub.commandHandler)).Methods("POST")
+	hub.router.HandleFunc("/api/rotator/{rotator}/azimuth", hub.authorize(hub.azimuthHandler))
+	hub.router.HandleFunc("/api/rotator/{rotator}/elevation", hub.authorize(hub.elevationHandler))
+	hub.router.HandleFunc("/api/rotator/{rotator}/azel", hub.authorize(hub.azElHandler)).Methods("PUT")
+	hub.router.HandleFunc("/api/rotator/{rotator}/jog", hub.authorize(hub.jogHandler)).Methods("PUT")
+	hub.router.HandleFunc("/api/rotator/{rotator}/speed", hub.authorize(hub.speedHandler))
+	hub.router.HandleFunc("/api/rotator/{rotator}/stop", hub.authorize(hub.stopHandler))
+	hub.router.HandleFunc("/api/rotator/{rotator}/stop_azimuth", hub.authorize(hub.stopAzimuthHandler))
+	hub.router.HandleFunc("/api/rotator/{rotator}/stop_elevation", hub.authorize(hub.stopElevationHandler))
+	hub.router.HandleFunc("/api/rotator/{rotator}/follow", hub.authorize(hub.followHandler))
+	hub.router.HandleFunc("/api/rotator/{rotator}/park", hub.authorize(hub.parkHandler))
+	hub.router.HandleFunc("/api/rotator/{rotator}/track", hub.authorize(hub.trackHandler))
+	hub.router.HandleFunc("/api/rotator/{rotator}/limits", hub.authorize(hub.limitsHandler))
+	hub.router.HandleFunc("/api/rotator/{rotator}/park_override", hub.authorize(hub.parkOverrideHandler)).Methods("PUT")
+	hub.router.HandleFunc("/api/config", hub.configHandler)
+	hub.router.HandleFunc("/healthz", hub.healthHandler).Methods("GET")
+	hub.router.HandleFunc(hub.wsPath, hub.authorize(hub.wsHandler))
+	hub.router.HandleFunc(hub.wsPath+"-readonly", hub.authorize(hub.wsReadOnlyHandler))
+	if hub.metrics != nil {
+		hub.router.HandleFunc("/metrics", hub.authorize(hub.metrics.ServeHTTP)).Methods("GET")
+	}
 	hub.router.PathPrefix("/").Handler(hub.fileServer)
 }
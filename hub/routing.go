@@ -0,0 +1,181 @@
+package hub
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// AzimuthPath determines which of the two positions a rotator with
+// overlap (AzimuthMax - AzimuthMin > 360) is commanded to if the
+// commanded azimuth lies within the overlap.
+type AzimuthPath string
+
+const (
+	// PathDirect commands the azimuth unchanged; the rotator never
+	// enters the overlap unless an azimuth >= 360° is commanded.
+	PathDirect AzimuthPath = "direct"
+	// PathShortest commands the position which is the closest to the
+	// current azimuth.
+	PathShortest AzimuthPath = "shortest"
+	// PathClockwise commands the position which can be reached by
+	// turning clockwise. The rotator only turns counter clockwise if
+	// both positions lie counter clockwise of the current azimuth.
+	PathClockwise AzimuthPath = "clockwise"
+)
+
+// ParseAzimuthPath converts a string (case insensitive) into an
+// AzimuthPath.
+func ParseAzimuthPath(s string) (AzimuthPath, error) {
+	switch p := AzimuthPath(strings.ToLower(s)); p {
+	case PathDirect, PathShortest, PathClockwise:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown azimuth path (%s); supported: %s, %s, %s",
+		s, PathDirect, PathShortest, PathClockwise)
+}
+
+// routeAzimuth returns the azimuth to which r should be commanded in order
+// to reach az on the configured path (see AzimuthRouting) and without
+// crossing a keep-out zone. Only rotators which can turn more than 360°
+// (AzimuthMax - AzimuthMin > 360) have a choice; for all other rotators
+// az is returned unchanged.
+func (hub *Hub) routeAzimuth(r rotator.Rotator, az int) int {
+	// the keep-out zones can be replaced at runtime (see ImportConfig)
+	hub.RLock()
+	path, keepOut, within := hub.azimuthPath, hub.keepOut, hub.directPathWithin
+	hub.RUnlock()
+
+	if path == PathDirect && len(keepOut) == 0 && within == 0 {
+		return az
+	}
+
+	obj := r.Serialize()
+	overlap := obj.Config.AzimuthMax - obj.Config.AzimuthMin - 360
+	if overlap <= 0 {
+		return az
+	}
+
+	current := obj.Heading.Azimuth
+	target := az
+	if pos, ok := nearbyAzimuth(current, az, obj.Config.AzimuthStop, overlap, within); ok {
+		target = pos
+	} else {
+		switch path {
+		case PathShortest:
+			target = shortestAzimuth(current, az, obj.Config.AzimuthStop, overlap)
+		case PathClockwise:
+			target = clockwiseAzimuth(current, az, obj.Config.AzimuthStop, overlap)
+		}
+	}
+
+	if !crossesKeepOut(keepOut, current, target) {
+		return target
+	}
+
+	for _, pos := range azimuthPositions(az, obj.Config.AzimuthStop, overlap) {
+		if !crossesKeepOut(keepOut, current, pos) {
+			return pos
+		}
+	}
+
+	return target
+}
+
+// azimuthPositions returns the mechanical positions in which a rotator
+// with the given stop and overlap points at target. Targets outside
+// [0°, 360°) are considered explicit mechanical positions; for them no
+// positions are returned.
+func azimuthPositions(target, stop, overlap int) []int {
+	if target < 0 || target >= 360 {
+		return nil
+	}
+
+	az := stop + normalizeAzimuth(target-stop)
+	if alt := az + 360; alt <= stop+360+overlap {
+		return []int{az, alt}
+	}
+
+	return []int{az}
+}
+
+// shortestAzimuth returns the mechanical azimuth which points at target and
+// is the closest to the current azimuth. The rotator can turn clockwise
+// from its mechanical stop by 360° plus overlap degrees, reporting the
+// azimuths in the range [stop, stop+360+overlap]. Targets within the
+// overlap can therefore be reached in two positions. Targets outside
+// [0°, 360°) are considered explicit mechanical positions and are
+// returned unchanged.
+func shortestAzimuth(current, target, stop, overlap int) int {
+	if target < 0 || target >= 360 {
+		return target
+	}
+
+	// position of the target in the first turn after the stop
+	az := stop + normalizeAzimuth(target-stop)
+
+	// the same direction within the overlap
+	if alt := az + 360; alt <= stop+360+overlap && abs(alt-current) < abs(az-current) {
+		return alt
+	}
+
+	return az
+}
+
+// nearbyAzimuth returns the mechanical position pointing at target which
+// is the closest to the current azimuth, provided it is at most within
+// degrees away (see DirectPathWithin). If both positions are equally
+// close, the one without overlap is returned. ok is false if within is 0,
+// if no position is close enough or if target is an explicit mechanical
+// position outside [0°, 360°).
+func nearbyAzimuth(current, target, stop, overlap, within int) (az int, ok bool) {
+	if within <= 0 {
+		return 0, false
+	}
+
+	positions := azimuthPositions(target, stop, overlap)
+	if len(positions) == 0 {
+		return 0, false
+	}
+
+	az = positions[0]
+	for _, pos := range positions[1:] {
+		if abs(pos-current) < abs(az-current) {
+			az = pos
+		}
+	}
+
+	if abs(az-current) > within {
+		return 0, false
+	}
+
+	return az, true
+}
+
+// clockwiseAzimuth returns the mechanical azimuth which points at target
+// and can be reached from the current azimuth by turning clockwise. If
+// both positions lie counter clockwise, the closer one is returned.
+// Targets outside [0°, 360°) are returned unchanged.
+func clockwiseAzimuth(current, target, stop, overlap int) int {
+	positions := azimuthPositions(target, stop, overlap)
+	if len(positions) == 0 {
+		return target
+	}
+
+	// the positions are in ascending order
+	for _, pos := range positions {
+		if pos >= current {
+			return pos
+		}
+	}
+
+	return positions[len(positions)-1]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
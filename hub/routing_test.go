@@ -0,0 +1,231 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestShortestAzimuth(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		current int
+		target  int
+		stop    int
+		overlap int
+		expAz   int
+	}{
+		{"no overlap", 10, 350, 0, 0, 350},
+		{"target outside overlap", 400, 180, 0, 90, 180},
+		{"target in overlap, closer without overlap", 50, 30, 0, 90, 30},
+		{"target in overlap, closer with overlap", 350, 30, 0, 90, 390},
+		{"target at stop, rotator near stop", 10, 0, 0, 90, 0},
+		{"target at stop, rotator in overlap", 420, 0, 0, 90, 360},
+		{"target at end of overlap", 359, 90, 0, 90, 450},
+		{"target beyond overlap", 359, 91, 0, 90, 91},
+		{"already in overlap", 440, 20, 0, 90, 380},
+		{"stop at south", 500, 200, 180, 90, 560},
+		{"stop at south, target before stop", 300, 170, 180, 90, 530},
+		{"stop at south, target after stop", 250, 190, 180, 90, 190},
+		{"stop at south, target in overlap", 500, 190, 180, 90, 550},
+		{"mechanical position", 0, 400, 0, 90, 400},
+		{"negative target", 0, -10, 0, 90, -10},
+		{"10 to 350", 10, 350, 0, 90, 350},
+		{"10 to 350, no overlap", 10, 350, 0, 0, 350},
+		{"10 (in overlap) to 350", 370, 350, 0, 90, 350},
+		{"350 to 10", 350, 10, 0, 90, 370},
+		{"350 to 10, no overlap", 350, 10, 0, 0, 10},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			az := shortestAzimuth(tc.current, tc.target, tc.stop, tc.overlap)
+			if az != tc.expAz {
+				t.Fatalf("expected %d, got %d", tc.expAz, az)
+			}
+		})
+	}
+}
+
+func TestClockwiseAzimuth(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		current int
+		target  int
+		stop    int
+		overlap int
+		expAz   int
+	}{
+		{"no overlap", 350, 10, 0, 0, 10},
+		{"target outside overlap", 100, 180, 0, 90, 180},
+		{"10 to 350", 10, 350, 0, 90, 350},
+		{"10 (in overlap) to 350", 370, 350, 0, 90, 350},
+		{"350 to 10", 350, 10, 0, 90, 370},
+		{"50 to 30", 50, 30, 0, 90, 390},
+		{"both positions counter clockwise", 440, 30, 0, 90, 390},
+		{"stop at south", 300, 190, 180, 90, 550},
+		{"mechanical position", 0, 400, 0, 90, 400},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			az := clockwiseAzimuth(tc.current, tc.target, tc.stop, tc.overlap)
+			if az != tc.expAz {
+				t.Fatalf("expected %d, got %d", tc.expAz, az)
+			}
+		})
+	}
+}
+
+func TestNearbyAzimuth(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		current int
+		target  int
+		stop    int
+		overlap int
+		within  int
+		expAz   int
+		expOk   bool
+	}{
+		{"disabled", 20, 15, 0, 90, 0, 0, false},
+		{"below threshold", 20, 15, 0, 90, 10, 15, true},
+		{"at threshold", 20, 10, 0, 90, 10, 10, true},
+		{"above threshold", 20, 9, 0, 90, 10, 0, false},
+		{"into overlap at threshold", 355, 5, 0, 90, 10, 365, true},
+		{"into overlap above threshold", 355, 6, 0, 90, 10, 0, false},
+		{"within overlap", 370, 15, 0, 90, 10, 375, true},
+		{"out of overlap", 365, 355, 0, 90, 10, 355, true},
+		{"no overlap", 5, 355, 0, 0, 10, 0, false},
+		{"tie", 180, 0, 0, 180, 180, 0, true},
+		{"stop at south", 545, 175, 180, 90, 10, 535, true},
+		{"mechanical position", 395, 400, 0, 90, 10, 0, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			az, ok := nearbyAzimuth(tc.current, tc.target, tc.stop, tc.overlap, tc.within)
+			if ok != tc.expOk {
+				t.Fatalf("expected ok=%v, got %v", tc.expOk, ok)
+			}
+			if az != tc.expAz {
+				t.Fatalf("expected %d, got %d", tc.expAz, az)
+			}
+		})
+	}
+}
+
+func TestDirectPathWithin(t *testing.T) {
+
+	for _, deg := range []int{-1, 181} {
+		if _, err := New(DirectPathWithin(deg)); err == nil {
+			t.Fatalf("expected error for threshold %d", deg)
+		}
+	}
+
+	tt := []struct {
+		name    string
+		within  int
+		keepOut []KeepOutZone
+		target  int
+		expAz   int
+	}{
+		{"disabled", 0, nil, 15, 375},
+		{"below threshold", 10, nil, 15, 15},
+		{"at threshold", 10, nil, 10, 10},
+		{"above threshold", 10, nil, 9, 369},
+		{"keep-out zone", 10, []KeepOutZone{{From: 12, To: 17}}, 10, 370},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(AzimuthRouting(PathClockwise), DirectPathWithin(tc.within),
+				KeepOut(tc.keepOut...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			r := &positionRotator{obj: rotator.Object{
+				Heading: rotator.Heading{Azimuth: 20},
+				Config:  rotator.Config{AzimuthMin: 0, AzimuthMax: 450},
+			}}
+
+			if az := h.routeAzimuth(r, tc.target); az != tc.expAz {
+				t.Fatalf("expected %d, got %d", tc.expAz, az)
+			}
+		})
+	}
+}
+
+func TestParseAzimuthPath(t *testing.T) {
+
+	tt := []struct {
+		input   string
+		expPath AzimuthPath
+		expErr  bool
+	}{
+		{"direct", PathDirect, false},
+		{"Shortest", PathShortest, false},
+		{"CLOCKWISE", PathClockwise, false},
+		{"ccw", "", true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			p, err := ParseAzimuthPath(tc.input)
+			if (err != nil) != tc.expErr {
+				t.Fatalf("expected error %v, got %v", tc.expErr, err)
+			}
+			if p != tc.expPath {
+				t.Fatalf("expected %s, got %s", tc.expPath, p)
+			}
+		})
+	}
+
+	if _, err := New(AzimuthRouting("ccw")); err == nil {
+		t.Fatal("expected error for unknown azimuth path")
+	}
+}
+
+func TestRouteAzimuth(t *testing.T) {
+
+	tt := []struct {
+		name         string
+		shortestPath bool
+		azimuthMax   int
+		target       int
+		expAz        int
+	}{
+		{"disabled", false, 450, 0, 0},
+		{"no overlap", true, 360, 0, 0},
+		{"overlap", true, 450, 0, 0},
+		{"overlap, target beyond overlap", true, 450, 180, 180},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(ShortestPath(tc.shortestPath))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			// the rotator stays at 0°
+			r, err := dummy.New(dummy.AzimuthMax(tc.azimuthMax), dummy.AzimuthSpeed(0),
+				dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			if az := h.routeAzimuth(r, tc.target); az != tc.expAz {
+				t.Fatalf("expected %d, got %d", tc.expAz, az)
+			}
+		})
+	}
+}
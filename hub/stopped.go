@@ -0,0 +1,102 @@
+package hub
+
+import (
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// Axes of a RotatorStopped event.
+const (
+	AxisAzimuth   = "azimuth"
+	AxisElevation = "elevation"
+)
+
+// stopTarget contains the axes of a rotator which have been stopped but
+// haven't come to rest yet.
+type stopTarget struct {
+	azimuth   bool
+	elevation bool
+	// heading at the previous check
+	last    rotator.Heading
+	sampled bool
+}
+
+// expectStop registers the stop of the azimuth and / or elevation of the
+// rotator name. The caller must hold the lock.
+func (hub *Hub) expectStop(name string, az, el bool) {
+	t, ok := hub.stopTargets[name]
+	if !ok {
+		t = &stopTarget{}
+		hub.stopTargets[name] = t
+	}
+	t.azimuth = t.azimuth || az
+	t.elevation = t.elevation || el
+	t.sampled = false
+}
+
+// cancelStop removes the pending stop of the azimuth and / or elevation
+// of the rotator name, e.g. because the axis has been commanded again.
+// The caller must hold the lock.
+func (hub *Hub) cancelStop(name string, az, el bool) {
+	t, ok := hub.stopTargets[name]
+	if !ok {
+		return
+	}
+	t.azimuth = t.azimuth && !az
+	t.elevation = t.elevation && !el
+	if !t.azimuth && !t.elevation {
+		delete(hub.stopTargets, name)
+	}
+}
+
+// checkStops broadcasts the heading and a RotatorStopped event for every
+// stopped axis which has come to rest, i.e. which hasn't moved since the
+// previous check.
+func (hub *Hub) checkStops() {
+	hub.Lock()
+	defer hub.Unlock()
+
+	for name, t := range hub.stopTargets {
+		r, ok := hub.rotators[name]
+		if !ok {
+			delete(hub.stopTargets, name)
+			continue
+		}
+
+		h := r.Serialize().Heading
+		sampled := t.sampled
+		last := t.last
+		t.last = h
+		t.sampled = true
+		if !sampled {
+			continue
+		}
+
+		azStopped := t.azimuth && h.Azimuth == last.Azimuth
+		elStopped := t.elevation && h.Elevation == last.Elevation
+		if !azStopped && !elStopped {
+			continue
+		}
+
+		// no axis if both axes have come to rest
+		var axis string
+		switch {
+		case !elStopped:
+			axis = AxisAzimuth
+		case !azStopped:
+			axis = AxisElevation
+		}
+
+		hub.cancelStop(name, azStopped, elStopped)
+		hub.logger.Debugf("rotator (%s) stopped", name)
+
+		hub.broadcastToTCPClients(name, h)
+		for _, ev := range []Event{
+			{Name: UpdateHeading, RotatorName: name, Heading: h},
+			{Name: RotatorStopped, RotatorName: name, Heading: h, Axis: axis},
+		} {
+			if err := hub.broadcastToWsClients(ev); err != nil {
+				hub.logger.Errorf("%v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package hub
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+	"github.com/gorilla/websocket"
+)
+
+func TestRotatorStopped(t *testing.T) {
+
+	tt := []struct {
+		name       string
+		requests   []rotator.Request
+		expStopped bool
+		expAxis    string
+	}{
+		{"stop", []rotator.Request{{Stop: true}}, true, ""},
+		{"stop azimuth", []rotator.Request{{StopAzimuth: true}}, true, AxisAzimuth},
+		{"stop elevation", []rotator.Request{{StopElevation: true}}, true, AxisElevation},
+		{"azimuth commanded after stop",
+			[]rotator.Request{{StopAzimuth: true}, {HasAzimuth: true, Azimuth: 90}}, false, ""},
+		{"no stop", []rotator.Request{{HasAzimuth: true, Azimuth: 90}}, false, ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(SettleTime(time.Millisecond * 20))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			// the rotator doesn't move
+			r, err := dummy.New(dummy.Name("r1"), dummy.HasElevation(true),
+				dummy.AzimuthSpeed(0), dummy.ElevationSpeed(0),
+				dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+			defer srv.Close()
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			events := make(chan Event, 10)
+			go func() {
+				for {
+					_, msg, err := conn.ReadMessage()
+					if err != nil {
+						return
+					}
+					ev := Event{}
+					if err := json.Unmarshal(msg, &ev); err != nil {
+						continue
+					}
+					if ev.Name == UpdateHeading || ev.Name == RotatorStopped {
+						events <- ev
+					}
+				}
+			}()
+
+			for _, req := range tc.requests {
+				req.Name = "r1"
+				if err := h.ExecuteRequest(req); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			timeout := time.After(time.Millisecond * 300)
+			headingUpdated := false
+			for {
+				select {
+				case ev := <-events:
+					if ev.Name == UpdateHeading {
+						headingUpdated = true
+						continue
+					}
+					if !tc.expStopped {
+						t.Fatalf("unexpected event %+v", ev)
+					}
+					if !headingUpdated {
+						t.Fatal("expected heading update before stopped event")
+					}
+					if ev.RotatorName != "r1" || ev.Axis != tc.expAxis {
+						t.Fatalf("expected stopped event of r1 (axis %q), got %+v", tc.expAxis, ev)
+					}
+					return
+				case <-timeout:
+					if tc.expStopped {
+						t.Fatal("timeout")
+					}
+					return
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// Store persists the last known headings of the rotators (key: rotator
+// name) so that their presets survive a restart of the hub.
+type Store interface {
+	Save(headings map[string]rotator.Heading) error
+	Load() (map[string]rotator.Heading, error)
+}
+
+// FileStore is a Store which keeps the headings as JSON in a file.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a Store which keeps the headings in the file
+// at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Save writes the headings to the file. The file is replaced atomically
+// so that it doesn't get corrupted if the process is terminated.
+func (fs *FileStore) Save(headings map[string]rotator.Heading) error {
+	data, err := json.Marshal(headings)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(fs.path), filepath.Base(fs.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fs.path)
+}
+
+// Load reads the headings from the file. If the file doesn't exist yet,
+// no headings and no error are returned.
+func (fs *FileStore) Load() (map[string]rotator.Heading, error) {
+	headings := make(map[string]rotator.Heading)
+
+	data, err := ioutil.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return headings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &headings); err != nil {
+		return nil, fmt.Errorf("corrupt preset file %s: %v", fs.path, err)
+	}
+
+	return headings, nil
+}
+
+// minimum interval between two writes to the store
+const storeInterval = time.Second
+
+// loadHeadings loads the persisted headings from the store. Errors are
+// logged and result in no headings being restored.
+func (hub *Hub) loadHeadings() {
+	headings, err := hub.store.Load()
+	if err != nil {
+		hub.logger.Errorf("unable to load the last known headings: %v", err)
+		return
+	}
+	hub.storedHeadings = headings
+}
+
+// restoredPreset is a preset loaded from the store. It is reported in
+// the heading of the rotator until the rotator is commanded again.
+type restoredPreset struct {
+	hasAzimuth   bool
+	azimuth      int
+	hasElevation bool
+	elevation    int
+}
+
+// restorePresets restores the presets of r reported in h (e.g. before
+// the hub was restarted). Only the reported presets are restored; the
+// rotator is not moved. The caller must hold the lock.
+func (hub *Hub) restorePresets(r rotator.Rotator, h rotator.Heading) {
+	hub.restoredPresets[r.Name()] = &restoredPreset{
+		hasAzimuth:   r.HasAzimuth(),
+		azimuth:      h.AzPreset,
+		hasElevation: r.HasElevation(),
+		elevation:    h.ElPreset,
+	}
+	hub.logger.Infof("restored presets of rotator (%s)", r.Name())
+}
+
+// applyRestoredPresets replaces the presets in h with the restored
+// presets of the rotator name (if any). The caller must hold the lock.
+func (hub *Hub) applyRestoredPresets(name string, h *rotator.Heading) {
+	p, ok := hub.restoredPresets[name]
+	if !ok {
+		return
+	}
+	if p.hasAzimuth {
+		h.AzPreset = p.azimuth
+	}
+	if p.hasElevation {
+		h.ElPreset = p.elevation
+	}
+}
+
+// clearRestoredPreset discards the restored presets of the given axes of
+// the rotator name once it has been commanded. The caller must hold the
+// lock.
+func (hub *Hub) clearRestoredPreset(name string, az, el bool) {
+	p, ok := hub.restoredPresets[name]
+	if !ok {
+		return
+	}
+	if az {
+		p.hasAzimuth = false
+	}
+	if el {
+		p.hasElevation = false
+	}
+	if !p.hasAzimuth && !p.hasElevation {
+		delete(hub.restoredPresets, name)
+	}
+}
+
+// requestSave signals the store go routine that the headings have
+// changed. It never blocks.
+func (hub *Hub) requestSave() {
+	select {
+	case hub.saveCh <- struct{}{}:
+	default:
+		// a save is already pending
+	}
+}
+
+// saveHeadings persists the headings of all rotators whenever they
+// change, at most once per storeInterval, until the hub is closed.
+// A pending save is executed when the hub is closed.
+func (hub *Hub) saveHeadings() {
+	for {
+		select {
+		case <-hub.saveCh:
+		case <-hub.closeCh:
+			hub.savePending()
+			return
+		}
+
+		hub.save()
+
+		select {
+		case <-time.After(storeInterval):
+		case <-hub.closeCh:
+			hub.savePending()
+			return
+		}
+	}
+}
+
+// savePending saves the headings if a save has been requested.
+func (hub *Hub) savePending() {
+	select {
+	case <-hub.saveCh:
+		hub.save()
+	default:
+	}
+}
+
+// save writes the current headings of all rotators to the store.
+func (hub *Hub) save() {
+	hub.RLock()
+	rotators := make([]rotator.Rotator, 0, len(hub.rotators))
+	for _, r := range hub.rotators {
+		rotators = append(rotators, r)
+	}
+	hub.RUnlock()
+
+	headings := make(map[string]rotator.Heading)
+	for _, r := range rotators {
+		h := r.Serialize().Heading
+		hub.RLock()
+		hub.applyRestoredPresets(r.Name(), &h)
+		hub.RUnlock()
+		headings[r.Name()] = h
+	}
+
+	if err := hub.store.Save(headings); err != nil {
+		hub.logger.Errorf("unable to save the last known headings: %v", err)
+	}
+}
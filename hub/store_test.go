@@ -0,0 +1,132 @@
+package hub
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+// memStore is a Store which keeps the headings in memory
+type memStore struct {
+	sync.Mutex
+	headings map[string]rotator.Heading
+}
+
+func (s *memStore) Save(headings map[string]rotator.Heading) error {
+	s.Lock()
+	defer s.Unlock()
+	s.headings = headings
+	return nil
+}
+
+func (s *memStore) Load() (map[string]rotator.Heading, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.headings, nil
+}
+
+func TestFileStore(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "remoteRotator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "presets.json")
+	fs := NewFileStore(path)
+
+	// missing file on first run
+	headings, err := fs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(headings) != 0 {
+		t.Fatalf("expected no headings, got %v", headings)
+	}
+
+	exp := map[string]rotator.Heading{
+		"r1": {Azimuth: 10, AzPreset: 90, Elevation: 5, ElPreset: 45},
+	}
+	if err := fs.Save(exp); err != nil {
+		t.Fatal(err)
+	}
+	headings, err = fs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(headings, exp) {
+		t.Fatalf("expected %v, got %v", exp, headings)
+	}
+
+	// corrupt file
+	if err := ioutil.WriteFile(path, []byte("{\"r1\":"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Load(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestPresetStore(t *testing.T) {
+
+	store := &memStore{
+		headings: map[string]rotator.Heading{"r1": {AzPreset: 90}},
+	}
+
+	h, err := New(PresetStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rotator doesn't move
+	r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+	if p := h.Info()[0].Heading.AzPreset; p != 90 {
+		t.Fatalf("expected restored azimuth preset 90, got %d", p)
+	}
+
+	// the rotator is not commanded on startup
+	time.Sleep(time.Millisecond * 50)
+	if r.AzPreset() != 0 {
+		t.Fatalf("unexpected command to azimuth %d", r.AzPreset())
+	}
+
+	// the restored preset is reported until the rotator is commanded
+	if err := h.SetAzimuth("r1", 180); err != nil {
+		t.Fatal(err)
+	}
+	if p := h.Info()[0].Heading.AzPreset; p != 180 {
+		t.Fatalf("expected azimuth preset 180, got %d", p)
+	}
+	h.Broadcast(r.Serialize().Heading)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		headings, _ := store.Load()
+		if headings["r1"].AzPreset == 180 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("heading not saved: %v", headings)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	h.Close()
+}
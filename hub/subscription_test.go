@@ -0,0 +1,137 @@
+package hub
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/gorilla/websocket"
+)
+
+func TestTCPSubscription(t *testing.T) {
+
+	h := newTestHub(t, "r1", "r2")
+	defer h.Close()
+
+	sub, subServer := net.Pipe()
+	defer sub.Close()
+	h.addTCPClient(&TCPClient{Conn: subServer, subscription: "r2"})
+
+	all, allServer := net.Pipe()
+	defer all.Close()
+	h.addTCPClient(&TCPClient{Conn: allServer})
+
+	tt := []struct {
+		name    string
+		conn    net.Conn
+		expMsgs []string
+	}{
+		{"subscribed to r2", sub, []string{"+0180\r\n", "+0045\r\n"}},
+		{"subscribed to all", all, []string{"+0090\r\n", "+0180\r\n", "+0045\r\n"}},
+	}
+
+	results := make([]chan []string, len(tt))
+	for i, tc := range tt {
+		results[i] = make(chan []string, 1)
+		go func(conn net.Conn, n int, res chan<- []string) {
+			reader := bufio.NewReader(conn)
+			msgs := []string{}
+			for i := 0; i < n; i++ {
+				msg, err := reader.ReadString('\n')
+				if err != nil {
+					break
+				}
+				msgs = append(msgs, msg)
+			}
+			res <- msgs
+		}(tc.conn, len(tc.expMsgs), results[i])
+	}
+
+	h.BroadcastHeading("r1", rotator.Heading{Azimuth: 90})
+	h.BroadcastHeading("r2", rotator.Heading{Azimuth: 180})
+	// headings without a rotator name are sent to all clients
+	h.Broadcast(rotator.Heading{Azimuth: 45})
+
+	for i, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			select {
+			case msgs := <-results[i]:
+				if strings.Join(msgs, "") != strings.Join(tc.expMsgs, "") {
+					t.Fatalf("expected %q, got %q", tc.expMsgs, msgs)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("timeout")
+			}
+		})
+	}
+}
+
+func TestTCPSubscriptionUnknownRotator(t *testing.T) {
+
+	h := newTestHub(t, "r1", "r2")
+	defer h.Close()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server, subscription: "r3"})
+
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := client.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected client to be refused")
+	}
+	waitForTCPClients(t, h, 0)
+}
+
+func TestWsSubscription(t *testing.T) {
+
+	h := newTestHub(t, "r1", "r2")
+	defer h.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?rotator=r2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	readHello(t, conn)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	// only the subscribed rotator is announced
+	ev := Event{}
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Name != AddRotator || ev.RotatorName != "r2" {
+		t.Fatalf("expected %s event of r2, got %s of %s", AddRotator, ev.Name, ev.RotatorName)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(h.Clients()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("ws client not added")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if c := h.Clients()[0]; c.Rotator != "r2" {
+		t.Fatalf("expected subscription to r2, got %q", c.Rotator)
+	}
+
+	h.BroadcastHeading("r1", rotator.Heading{Azimuth: 90})
+	h.BroadcastHeading("r2", rotator.Heading{Azimuth: 180})
+
+	ev = Event{}
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Name != UpdateHeading || ev.RotatorName != "r2" || ev.Heading.Azimuth != 180 {
+		t.Fatalf("expected heading 180° of r2, got %+v", ev)
+	}
+}
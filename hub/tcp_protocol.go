@@ -0,0 +1,388 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// Dialect is the protocol spoken by the clients of a TCP listener.
+type Dialect string
+
+const (
+	// ARSVCOM is the GS232 subset used by EA4TX's ARSVCOM
+	// (M, C, C2, A, E, S).
+	ARSVCOM Dialect = "arsvcom"
+	// GS232A is the Yaesu GS-232A protocol (W, M, C, C2, B, X1-X4,
+	// R, L, A, E, S) which reports the position as AZ=aaa / EL=eee.
+	GS232A Dialect = "gs232a"
+	// GS232B is the Yaesu GS-232B protocol (W, M, C, C2, B, X1-X4,
+	// R, L, A, E, S).
+	GS232B Dialect = "gs232b"
+	// JSON exchanges newline-delimited JSON objects. Clients send
+	// rotator.Request objects and receive the heading updates as
+	// rotator.Heading objects. An empty request ({}) queries the heading.
+	JSON Dialect = "json"
+	// DCU1 is the Hy-Gain DCU-1 protocol (AP1aaa, AM1, AI1, SA) with
+	// commands terminated by ";". Queries (AI1; or just ;) are answered
+	// with ;aaa.
+	DCU1 Dialect = "dcu1"
+)
+
+// dialects contains all supported dialects
+var dialects = []Dialect{ARSVCOM, GS232A, GS232B, JSON, DCU1}
+
+// Dialects returns all dialects supported by the tcp listeners, e.g.
+// for validating user input.
+func Dialects() []Dialect {
+	ds := make([]Dialect, len(dialects))
+	copy(ds, dialects)
+	return ds
+}
+
+// ParseDialect converts a string (case insensitive) into a Dialect.
+func ParseDialect(s string) (Dialect, error) {
+	for _, d := range dialects {
+		if Dialect(strings.ToLower(s)) == d {
+			return d, nil
+		}
+	}
+
+	names := make([]string, 0, len(dialects))
+	for _, d := range dialects {
+		names = append(names, string(d))
+	}
+	return "", fmt.Errorf("unknown tcp dialect (%s); supported: %s", s, strings.Join(names, ", "))
+}
+
+// delimiter returns the byte which terminates the messages of the dialect.
+func (d Dialect) delimiter() byte {
+	if d == DCU1 {
+		return ';'
+	}
+	return '\n'
+}
+
+// Frame is the format of the heading updates which are broadcasted
+// to the clients of a TCP listener.
+type Frame string
+
+const (
+	// FrameAuto only contains the axes supported by the rotator
+	// (+0aaa, +0eee or +0aaa+0eee).
+	FrameAuto Frame = "auto"
+	// FrameAzEl always contains azimuth and elevation (+0aaa+0eee).
+	// EA4TX's ARSVCOM doesn't understand single azimuth messages.
+	FrameAzEl Frame = "azel"
+)
+
+// ParseFrame converts a string into a Frame.
+func ParseFrame(s string) (Frame, error) {
+	switch Frame(strings.ToLower(s)) {
+	case FrameAuto:
+		return FrameAuto, nil
+	case FrameAzEl:
+		return FrameAzEl, nil
+	}
+	return "", fmt.Errorf("unknown tcp frame format (%s)", s)
+}
+
+// DefaultDigits is the number of digits of each value in the heading
+// updates and position reports (+0aaa) as expected by ARSVCOM.
+const DefaultDigits = 4
+
+// MinDigits and MaxDigits limit the number of digits which can be
+// selected with TCPDigits.
+const (
+	MinDigits = 3
+	MaxDigits = 6
+)
+
+// CheckDigits returns an error if n digits are not supported.
+func CheckDigits(n int) error {
+	if n < MinDigits || n > MaxDigits {
+		return fmt.Errorf("unsupported number of digits (%d); must be between %d and %d", n, MinDigits, MaxDigits)
+	}
+	return nil
+}
+
+// formatValue returns v with a leading plus sign and zero padded to the
+// given number of digits (e.g. +0450). Since the format has no room for
+// a minus sign or additional digits, v is clamped to the range which can
+// be represented. The DefaultDigits are used if digits is not set.
+func formatValue(v, digits int) string {
+	if digits <= 0 {
+		digits = DefaultDigits
+	}
+	max := 1
+	for i := 0; i < digits; i++ {
+		max *= 10
+	}
+	switch {
+	case v < 0:
+		v = 0
+	case v >= max:
+		v = max - 1
+	}
+	return fmt.Sprintf("+%0*d", digits, v)
+}
+
+// headingMessage returns the heading update for a client speaking the
+// dialect d.
+func headingMessage(d Dialect, f Frame, digits int, hasAzimuth, hasElevation bool, h rotator.Heading) string {
+	if d == JSON {
+		return jsonMessage(h)
+	}
+	return formatFrame(f, digits, hasAzimuth, hasElevation, h)
+}
+
+// formatFrame returns the heading update for a rotator with the given
+// axes in the format f, with each value having the given number of digits.
+func formatFrame(f Frame, digits int, hasAzimuth, hasElevation bool, h rotator.Heading) string {
+	if f == FrameAzEl || (hasAzimuth && hasElevation) {
+		return formatValue(h.Azimuth, digits) + formatValue(h.Elevation, digits) + "\r\n"
+	}
+	if hasElevation {
+		return formatValue(h.Elevation, digits) + "\r\n"
+	}
+	return formatValue(h.Azimuth, digits) + "\r\n"
+}
+
+// query is a request for the current position of the rotator
+type query int
+
+const (
+	noQuery query = iota
+	queryAzimuth
+	queryElevation
+	queryAzEl
+)
+
+// tcpCommand is a parsed message received from a tcp client. Either
+// a query, a prompt, a rotation or a request is set.
+type tcpCommand struct {
+	query  query
+	prompt bool
+	// continuous rotation towards the clockwise (1) or counter
+	// clockwise (-1) limit of the rotator
+	rotate  int
+	request *rotator.Request
+}
+
+// parseCommand parses a message (without line termination) according
+// to the dialect.
+func parseCommand(d Dialect, msg string) (tcpCommand, error) {
+	msg = strings.TrimSpace(msg)
+
+	if d == DCU1 {
+		return parseDCU1Command(msg)
+	}
+
+	if len(msg) == 0 {
+		return tcpCommand{}, fmt.Errorf("empty message")
+	}
+
+	if d == JSON {
+		return parseJSONCommand(msg)
+	}
+
+	cmd := strings.ToUpper(msg[0:1])
+	args := strings.TrimSpace(msg[1:])
+
+	switch cmd {
+	// set azimuth
+	case "M":
+		if len(args) == 0 {
+			return tcpCommand{prompt: true}, nil
+		}
+		az, err := strconv.Atoi(args)
+		if err != nil {
+			return tcpCommand{}, fmt.Errorf("invalid azimuth (%s)", args)
+		}
+		return tcpCommand{request: &rotator.Request{HasAzimuth: true, Azimuth: az}}, nil
+
+	// query
+	case "C":
+		if args == "2" {
+			return tcpCommand{query: queryAzEl}, nil
+		}
+		return tcpCommand{query: queryAzimuth}, nil
+
+	// stop azimuth
+	case "A":
+		return tcpCommand{request: &rotator.Request{StopAzimuth: true}}, nil
+
+	// stop elevation
+	case "E":
+		return tcpCommand{request: &rotator.Request{StopElevation: true}}, nil
+
+	// stop all
+	case "S":
+		return tcpCommand{request: &rotator.Request{Stop: true}}, nil
+	}
+
+	if d != GS232A && d != GS232B {
+		return tcpCommand{}, fmt.Errorf("unknown command (%s)", msg)
+	}
+
+	switch cmd {
+	// set azimuth and elevation (e.g. "W123 045")
+	case "W":
+		f := strings.Fields(args)
+		if len(f) != 2 {
+			return tcpCommand{}, fmt.Errorf("invalid W command (%s)", msg)
+		}
+		az, err := strconv.Atoi(f[0])
+		if err != nil {
+			return tcpCommand{}, fmt.Errorf("invalid azimuth (%s)", f[0])
+		}
+		el, err := strconv.Atoi(f[1])
+		if err != nil {
+			return tcpCommand{}, fmt.Errorf("invalid elevation (%s)", f[1])
+		}
+		req := &rotator.Request{
+			HasAzimuth:   true,
+			Azimuth:      az,
+			HasElevation: true,
+			Elevation:    el,
+		}
+		return tcpCommand{request: req}, nil
+
+	// query elevation
+	case "B":
+		return tcpCommand{query: queryElevation}, nil
+
+	// rotate clockwise (right) until stopped or the limit is reached
+	case "R":
+		return tcpCommand{rotate: 1}, nil
+
+	// rotate counter clockwise (left) until stopped or the limit is reached
+	case "L":
+		return tcpCommand{rotate: -1}, nil
+
+	// set speed (X1 ... X4)
+	case "X":
+		speed, err := strconv.Atoi(args)
+		if err != nil || speed < rotator.SpeedMin || speed > rotator.SpeedMax {
+			return tcpCommand{}, fmt.Errorf("invalid speed (%s)", args)
+		}
+		return tcpCommand{request: &rotator.Request{HasSpeed: true, Speed: speed}}, nil
+	}
+
+	return tcpCommand{}, fmt.Errorf("unknown command (%s)", msg)
+}
+
+// rotationRequest returns the request for a continuous rotation of r in
+// the direction dir (see tcpCommand).
+func rotationRequest(r rotator.Rotator, dir int) (*rotator.Request, error) {
+	az, err := rotationLimit(r, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &rotator.Request{HasAzimuth: true, Azimuth: az}, nil
+}
+
+// parseJSONCommand parses a JSON encoded rotator.Request. A request
+// without any command is a query for the heading.
+func parseJSONCommand(msg string) (tcpCommand, error) {
+	req := rotator.Request{}
+	if err := json.Unmarshal([]byte(msg), &req); err != nil {
+		return tcpCommand{}, fmt.Errorf("invalid request (%s): %v", msg, err)
+	}
+
+	if !req.HasAzimuth && !req.HasElevation && !req.HasSpeed &&
+		req.AzimuthDelta == 0 && req.ElevationDelta == 0 &&
+		!req.StopAzimuth && !req.StopElevation && !req.Stop {
+		return tcpCommand{query: queryAzEl}, nil
+	}
+
+	return tcpCommand{request: &req}, nil
+}
+
+// parseDCU1Command parses a DCU-1 command. The rotator is commanded as
+// soon as the preset (AP1aaa) is received; the subsequent AM1 is
+// therefore ignored.
+func parseDCU1Command(msg string) (tcpCommand, error) {
+	msg = strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(msg, ";")))
+
+	switch {
+	// query
+	case len(msg) == 0, msg == "AI1":
+		return tcpCommand{query: queryAzimuth}, nil
+
+	// execute preset
+	case msg == "AM1":
+		return tcpCommand{}, nil
+
+	// stop azimuth
+	case msg == "SA":
+		return tcpCommand{request: &rotator.Request{StopAzimuth: true}}, nil
+
+	// set preset (e.g. "AP1123")
+	case strings.HasPrefix(msg, "AP1"):
+		args := strings.TrimSpace(msg[3:])
+		az, err := strconv.Atoi(args)
+		if err != nil {
+			return tcpCommand{}, fmt.Errorf("invalid azimuth (%s)", args)
+		}
+		return tcpCommand{request: &rotator.Request{HasAzimuth: true, Azimuth: az}}, nil
+	}
+
+	return tcpCommand{}, fmt.Errorf("unknown command (%s)", msg)
+}
+
+// jsonError is sent to clients speaking the JSON dialect if their
+// request could not be executed (or was rejected).
+type jsonError struct {
+	Error string `json:"error"`
+}
+
+// jsonMessage returns v as a newline-delimited JSON message.
+func jsonMessage(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("{\"error\":%q}\n", err.Error())
+	}
+	return string(data) + "\n"
+}
+
+// queryResponse returns the response to a query according to the dialect.
+// The GS-232B and ARSVCOM responses use the given number of digits.
+func queryResponse(d Dialect, q query, digits int, r rotator.Rotator) string {
+	if d == JSON {
+		return jsonMessage(r.Serialize().Heading)
+	}
+
+	if d == DCU1 {
+		return fmt.Sprintf(";%.3d", r.Azimuth())
+	}
+
+	// like the heading updates (FrameAuto), the position query of a
+	// rotator without azimuth reports the elevation
+	if q == queryAzimuth && !r.HasAzimuth() && r.HasElevation() {
+		q = queryElevation
+	}
+
+	if d == GS232A {
+		switch q {
+		case queryElevation:
+			return fmt.Sprintf("EL=%.3d\r\n", r.Elevation())
+		case queryAzEl:
+			return fmt.Sprintf("AZ=%.3d  EL=%.3d\r\n", r.Azimuth(), r.Elevation())
+		default:
+			return fmt.Sprintf("AZ=%.3d\r\n", r.Azimuth())
+		}
+	}
+
+	switch q {
+	case queryElevation:
+		return formatValue(r.Elevation(), digits) + "\r\n"
+	case queryAzEl:
+		return formatValue(r.Azimuth(), digits) + formatValue(r.Elevation(), digits) + "\r\n"
+	default:
+		return formatValue(r.Azimuth(), digits) + "\r\n"
+	}
+}
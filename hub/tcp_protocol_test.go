@@ -0,0 +1,612 @@
+package hub
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestParseDialect(t *testing.T) {
+
+	tt := []struct {
+		input  string
+		exp    Dialect
+		expErr bool
+	}{
+		{"arsvcom", ARSVCOM, false},
+		{"GS232B", GS232B, false},
+		{"dcu1", DCU1, false},
+		{"gs232", "", true},
+		{"", "", true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			d, err := ParseDialect(tc.input)
+			if tc.expErr {
+				if err == nil {
+					t.Fatalf("expected error, got %s", d)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d != tc.exp {
+				t.Fatalf("expected %s, got %s", tc.exp, d)
+			}
+		})
+	}
+
+	// all advertised dialects can be parsed
+	for _, d := range Dialects() {
+		if _, err := ParseDialect(string(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		dialect Dialect
+		msg     string
+		expCmd  tcpCommand
+		expErr  bool
+	}{
+		{"arsvcom set azimuth", ARSVCOM, "M123\r\n", tcpCommand{request: &rotator.Request{HasAzimuth: true, Azimuth: 123}}, false},
+		{"arsvcom prompt", ARSVCOM, "M\r\n", tcpCommand{prompt: true}, false},
+		{"arsvcom invalid azimuth", ARSVCOM, "Mabc\r\n", tcpCommand{}, true},
+		{"arsvcom query azimuth", ARSVCOM, "C\r\n", tcpCommand{query: queryAzimuth}, false},
+		{"arsvcom query az/el", ARSVCOM, "C2\r\n", tcpCommand{query: queryAzEl}, false},
+		{"arsvcom stop azimuth", ARSVCOM, "A\r\n", tcpCommand{request: &rotator.Request{StopAzimuth: true}}, false},
+		{"arsvcom stop elevation", ARSVCOM, "E\r\n", tcpCommand{request: &rotator.Request{StopElevation: true}}, false},
+		{"arsvcom stop", ARSVCOM, "s\r\n", tcpCommand{request: &rotator.Request{Stop: true}}, false},
+		{"arsvcom W not supported", ARSVCOM, "W123 045\r\n", tcpCommand{}, true},
+		{"arsvcom B not supported", ARSVCOM, "B\r\n", tcpCommand{}, true},
+		{"arsvcom empty", ARSVCOM, "\r\n", tcpCommand{}, true},
+		{"gs232b set az/el", GS232B, "W123 045\r\n", tcpCommand{request: &rotator.Request{HasAzimuth: true, Azimuth: 123, HasElevation: true, Elevation: 45}}, false},
+		{"gs232b set az/el invalid", GS232B, "W123\r\n", tcpCommand{}, true},
+		{"gs232b set az/el invalid elevation", GS232B, "W123 abc\r\n", tcpCommand{}, true},
+		{"gs232b set azimuth", GS232B, "M270\r\n", tcpCommand{request: &rotator.Request{HasAzimuth: true, Azimuth: 270}}, false},
+		{"gs232b query elevation", GS232B, "B\r\n", tcpCommand{query: queryElevation}, false},
+		{"gs232b query az/el", GS232B, "C2\r\n", tcpCommand{query: queryAzEl}, false},
+		{"gs232b stop", GS232B, "S\r\n", tcpCommand{request: &rotator.Request{Stop: true}}, false},
+		{"gs232b set speed", GS232B, "X1\r\n", tcpCommand{request: &rotator.Request{HasSpeed: true, Speed: 1}}, false},
+		{"gs232b set max speed", GS232B, "X4\r\n", tcpCommand{request: &rotator.Request{HasSpeed: true, Speed: 4}}, false},
+		{"gs232b invalid speed", GS232B, "X5\r\n", tcpCommand{}, true},
+		{"gs232b missing speed", GS232B, "X\r\n", tcpCommand{}, true},
+		{"arsvcom X not supported", ARSVCOM, "X1\r\n", tcpCommand{}, true},
+		{"gs232a set az/el", GS232A, "W123 045\r\n", tcpCommand{request: &rotator.Request{HasAzimuth: true, Azimuth: 123, HasElevation: true, Elevation: 45}}, false},
+		{"gs232a query elevation", GS232A, "B\r\n", tcpCommand{query: queryElevation}, false},
+		{"gs232a set speed", GS232A, "X2\r\n", tcpCommand{request: &rotator.Request{HasSpeed: true, Speed: 2}}, false},
+		{"gs232b rotate clockwise", GS232B, "R\r\n", tcpCommand{rotate: 1}, false},
+		{"gs232b rotate counter clockwise", GS232B, "l\r\n", tcpCommand{rotate: -1}, false},
+		{"gs232a rotate clockwise", GS232A, "R\r\n", tcpCommand{rotate: 1}, false},
+		{"arsvcom R not supported", ARSVCOM, "R\r\n", tcpCommand{}, true},
+		{"gs232b unknown", GS232B, "P36\r\n", tcpCommand{}, true},
+		{"json set azimuth", JSON, "{\"has_azimuth\":true,\"azimuth\":123}\n", tcpCommand{request: &rotator.Request{HasAzimuth: true, Azimuth: 123}}, false},
+		{"json jog", JSON, "{\"azimuth_delta\":-5}\n", tcpCommand{request: &rotator.Request{AzimuthDelta: -5}}, false},
+		{"json stop", JSON, "{\"stop\":true}\n", tcpCommand{request: &rotator.Request{Stop: true}}, false},
+		{"json query", JSON, "{}\n", tcpCommand{query: queryAzEl}, false},
+		{"json invalid", JSON, "M123\r\n", tcpCommand{}, true},
+		{"dcu1 set preset", DCU1, "AP1123;", tcpCommand{request: &rotator.Request{HasAzimuth: true, Azimuth: 123}}, false},
+		{"dcu1 set preset with cr", DCU1, "AP1123\r;", tcpCommand{request: &rotator.Request{HasAzimuth: true, Azimuth: 123}}, false},
+		{"dcu1 invalid preset", DCU1, "AP1abc;", tcpCommand{}, true},
+		{"dcu1 execute", DCU1, "AM1;", tcpCommand{}, false},
+		{"dcu1 query", DCU1, ";", tcpCommand{query: queryAzimuth}, false},
+		{"dcu1 query AI1", DCU1, "ai1;", tcpCommand{query: queryAzimuth}, false},
+		{"dcu1 stop", DCU1, "SA;", tcpCommand{request: &rotator.Request{StopAzimuth: true}}, false},
+		{"dcu1 unknown", DCU1, "M123;", tcpCommand{}, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, err := parseCommand(tc.dialect, tc.msg)
+			if tc.expErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cmd, tc.expCmd) {
+				t.Fatalf("expected %+v, got %+v", tc.expCmd, cmd)
+			}
+		})
+	}
+}
+
+func TestTCPClientQuery(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	tt := []struct {
+		name   string
+		msg    string
+		expMsg string
+	}{
+		{"azimuth", "C\r\n", "+0000\r\n"},
+		{"az/el", "C2\r\n", "+0000+0000\r\n"},
+		{"elevation", "B\r\n", "+0000\r\n"},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server, dialect: GS232B})
+
+	reader := bufio.NewReader(client)
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := client.Write([]byte(tc.msg)); err != nil {
+				t.Fatal(err)
+			}
+			res, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tc.expMsg {
+				t.Fatalf("expected %q, got %q", tc.expMsg, res)
+			}
+		})
+	}
+}
+
+func TestTCPClientQueryElevationOnly(t *testing.T) {
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// 45° per tick
+	r, err := dummy.New(dummy.Name("el"), dummy.HasAzimuth(false),
+		dummy.HasElevation(true), dummy.ElevationSpeed(450))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+	r.SetElevation(45)
+
+	deadline := time.Now().Add(time.Second)
+	for r.Elevation() != 45 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout while waiting for the elevation")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	tt := []struct {
+		dialect Dialect
+		expMsg  string
+	}{
+		{ARSVCOM, "+0045\r\n"},
+		{GS232A, "EL=045\r\n"},
+		{GS232B, "+0045\r\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(string(tc.dialect), func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			h.addTCPClient(&TCPClient{Conn: server, dialect: tc.dialect})
+
+			if _, err := client.Write([]byte("C\n")); err != nil {
+				t.Fatal(err)
+			}
+			res, err := bufio.NewReader(client).ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tc.expMsg {
+				t.Fatalf("expected %q, got %q", tc.expMsg, res)
+			}
+		})
+	}
+}
+
+func TestFormatFrame(t *testing.T) {
+
+	h := rotator.Heading{Azimuth: 123, Elevation: 45}
+
+	tt := []struct {
+		name         string
+		frame        Frame
+		hasAzimuth   bool
+		hasElevation bool
+		expMsg       string
+	}{
+		{"az only", FrameAuto, true, false, "+0123\r\n"},
+		{"el only", FrameAuto, false, true, "+0045\r\n"},
+		{"az/el", FrameAuto, true, true, "+0123+0045\r\n"},
+		{"az only azel frame", FrameAzEl, true, false, "+0123+0045\r\n"},
+		{"el only azel frame", FrameAzEl, false, true, "+0123+0045\r\n"},
+		{"az/el azel frame", FrameAzEl, true, true, "+0123+0045\r\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res := formatFrame(tc.frame, DefaultDigits, tc.hasAzimuth, tc.hasElevation, h)
+			if res != tc.expMsg {
+				t.Fatalf("expected %q, got %q", tc.expMsg, res)
+			}
+		})
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		value  int
+		digits int
+		expMsg string
+	}{
+		{"zero", 0, DefaultDigits, "+0000"},
+		{"360", 360, DefaultDigits, "+0360"},
+		{"overlap 450", 450, DefaultDigits, "+0450"},
+		{"999", 999, DefaultDigits, "+0999"},
+		{"four digits", 1000, DefaultDigits, "+1000"},
+		{"clamped to max", 12345, DefaultDigits, "+9999"},
+		{"negative clamped to zero", -5, DefaultDigits, "+0000"},
+		{"default digits", 450, 0, "+0450"},
+		{"three digits", 45, 3, "+045"},
+		{"three digits 999", 999, 3, "+999"},
+		{"three digits clamped", 1000, 3, "+999"},
+		{"five digits", 450, 5, "+00450"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res := formatValue(tc.value, tc.digits)
+			if res != tc.expMsg {
+				t.Fatalf("expected %q, got %q", tc.expMsg, res)
+			}
+		})
+	}
+}
+
+func TestCheckDigits(t *testing.T) {
+
+	tt := []struct {
+		digits int
+		expErr bool
+	}{
+		{MinDigits - 1, true},
+		{MinDigits, false},
+		{DefaultDigits, false},
+		{MaxDigits, false},
+		{MaxDigits + 1, true},
+	}
+
+	for _, tc := range tt {
+		err := CheckDigits(tc.digits)
+		if (err != nil) != tc.expErr {
+			t.Fatalf("%d digits: expected error %v, got %v", tc.digits, tc.expErr, err)
+		}
+	}
+}
+
+func TestBroadcastToTCPClients(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		frame   Frame
+		digits  int
+		azimuth int
+		expMsg  string
+	}{
+		{"auto", FrameAuto, DefaultDigits, 90, "+0090\r\n"},
+		{"azel", FrameAzEl, DefaultDigits, 90, "+0090+0000\r\n"},
+		{"overlap", FrameAuto, DefaultDigits, 450, "+0450\r\n"},
+		{"three digits", FrameAuto, 3, 90, "+090\r\n"},
+		{"three digits azel", FrameAzEl, 3, 90, "+090+000\r\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// dummy rotators only support azimuth by default
+			h := newTestHub(t, "r1")
+			defer h.Close()
+
+			client, server := net.Pipe()
+			defer client.Close()
+			h.addTCPClient(&TCPClient{Conn: server, dialect: ARSVCOM, frame: tc.frame, digits: tc.digits})
+
+			go h.BroadcastToTCPClients(rotator.Heading{Azimuth: tc.azimuth})
+
+			res, err := bufio.NewReader(client).ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tc.expMsg {
+				t.Fatalf("expected %q, got %q", tc.expMsg, res)
+			}
+		})
+	}
+}
+
+func TestTCPClientRoundTrip(t *testing.T) {
+
+	tt := []struct {
+		name         string
+		dialect      Dialect
+		expAzimuth   string
+		expAzEl      string
+		expElevation string
+	}{
+		{"arsvcom", ARSVCOM, "+0000\r\n", "+0000+0000\r\n", "?>"},
+		{"gs232a", GS232A, "AZ=000\r\n", "AZ=000  EL=000\r\n", "EL=000\r\n"},
+		{"gs232b", GS232B, "+0000\r\n", "+0000+0000\r\n", "+0000\r\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			// the rotator doesn't move so that the reported
+			// position is deterministic
+			r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+				dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			client, server := net.Pipe()
+			defer client.Close()
+			h.addTCPClient(&TCPClient{Conn: server, dialect: tc.dialect})
+			reader := bufio.NewReader(client)
+
+			if _, err := client.Write([]byte("M123\r\n")); err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(time.Second)
+			for r.AzPreset() != 123 {
+				if time.Now().After(deadline) {
+					t.Fatalf("expected azimuth preset 123, got %d", r.AzPreset())
+				}
+				time.Sleep(time.Millisecond * 10)
+			}
+
+			queries := []struct {
+				msg    string
+				expMsg string
+			}{
+				{"C\r\n", tc.expAzimuth},
+				{"C2\r\n", tc.expAzEl},
+				{"B\r\n", tc.expElevation},
+			}
+
+			for _, q := range queries {
+				if _, err := client.Write([]byte(q.msg)); err != nil {
+					t.Fatal(err)
+				}
+				buf := make([]byte, len(q.expMsg))
+				if _, err := io.ReadFull(reader, buf); err != nil {
+					t.Fatal(err)
+				}
+				if string(buf) != q.expMsg {
+					t.Fatalf("%q: expected %q, got %q", q.msg, q.expMsg, string(buf))
+				}
+			}
+		})
+	}
+}
+
+func TestGS232Rotate(t *testing.T) {
+
+	tt := []struct {
+		name       string
+		azimuthMax int
+		msgs       []string
+		expPreset  int
+		expMsg     string
+	}{
+		{"clockwise", 450, []string{"R\r\n"}, 450, ""},
+		{"counter clockwise", 450, []string{"R\r\n", "L\r\n"}, 0, ""},
+		{"unknown limits", 0, []string{"R\r\n"}, 0, "?>"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthMax(tc.azimuthMax),
+				dummy.AzimuthSpeed(0), dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			client, server := net.Pipe()
+			defer client.Close()
+			h.addTCPClient(&TCPClient{Conn: server, dialect: GS232B})
+
+			for _, msg := range tc.msgs {
+				if _, err := client.Write([]byte(msg)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(tc.expMsg) > 0 {
+				buf := make([]byte, len(tc.expMsg))
+				if _, err := io.ReadFull(client, buf); err != nil {
+					t.Fatal(err)
+				}
+				if string(buf) != tc.expMsg {
+					t.Fatalf("expected %q, got %q", tc.expMsg, string(buf))
+				}
+			}
+
+			// the position query is answered after the rotation
+			// commands have been executed
+			if _, err := client.Write([]byte("C\r\n")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := bufio.NewReader(client).ReadString('\n'); err != nil {
+				t.Fatal(err)
+			}
+			if r.AzPreset() != tc.expPreset {
+				t.Fatalf("expected azimuth preset %d, got %d", tc.expPreset, r.AzPreset())
+			}
+		})
+	}
+}
+
+func TestJSONDialect(t *testing.T) {
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// the rotator doesn't move so that the reported
+	// position is deterministic
+	r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server, dialect: JSON})
+	reader := bufio.NewReader(client)
+
+	if _, err := client.Write([]byte("{\"has_azimuth\":true,\"azimuth\":123}\n")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for r.AzPreset() != 123 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected azimuth preset 123, got %d", r.AzPreset())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	tt := []struct {
+		name   string
+		msg    string
+		expMsg string
+	}{
+		{"query", "{}\n", "{\"azimuth\":0,\"az_preset\":123,\"elevation\":0,\"el_preset\":0,\"speed\":4}\n"},
+		{"invalid", "M123\n", "{\"error\":\"invalid request (M123): invalid character 'M' looking for beginning of value\"}\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := client.Write([]byte(tc.msg)); err != nil {
+				t.Fatal(err)
+			}
+			res, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tc.expMsg {
+				t.Fatalf("expected %q, got %q", tc.expMsg, res)
+			}
+		})
+	}
+
+	t.Run("broadcast", func(t *testing.T) {
+		go h.BroadcastToTCPClients(rotator.Heading{Azimuth: 90, AzPreset: 123})
+
+		res, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := "{\"azimuth\":90,\"az_preset\":123,\"elevation\":0,\"el_preset\":0,\"speed\":0}\n"
+		if res != exp {
+			t.Fatalf("expected %q, got %q", exp, res)
+		}
+	})
+}
+
+func TestDCU1Dialect(t *testing.T) {
+
+	h, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// the rotator doesn't move so that the reported
+	// position is deterministic
+	r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server, dialect: DCU1})
+
+	// several commands in a single write
+	if _, err := client.Write([]byte("AP1123;AM1;")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for r.AzPreset() != 123 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected azimuth preset 123, got %d", r.AzPreset())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// heading updates are not broadcasted to DCU-1 clients; the
+	// reply to the query is the next message
+	go h.BroadcastToTCPClients(rotator.Heading{Azimuth: 90})
+
+	for _, query := range []string{";", "AI1;"} {
+		if _, err := client.Write([]byte(query)); err != nil {
+			t.Fatal(err)
+		}
+		res := make([]byte, 4)
+		if _, err := io.ReadFull(client, res); err != nil {
+			t.Fatal(err)
+		}
+		if string(res) != ";000" {
+			t.Fatalf("expected %q, got %q", ";000", res)
+		}
+	}
+}
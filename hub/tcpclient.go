@@ -4,10 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"log"
 	"net"
-	"strconv"
-	"strings"
+	"time"
 
 	"github.com/dh1tw/remoteRotator/rotator"
 )
@@ -15,103 +13,231 @@ import (
 //TCPClient is a wrapper for clients connected through plain a TCP socket.
 type TCPClient struct {
 	net.Conn
+	dialect Dialect
+	frame   Frame
+	digits  int
+	// axes of the rotator, needed to format the heading updates
+	hasAzimuth   bool
+	hasElevation bool
+	// read-only clients can query the heading but their
+	// commands are rejected
+	readOnly bool
+	// maximum time for writing to the client; unlimited if 0
+	writeTimeout time.Duration
+	// maximum time between two messages from the client; unlimited if 0
+	idleTimeout time.Duration
+	// limits the rate of the client's commands; unlimited if nil
+	limiter *tokenBucket
+	// name of the rotator the client talks to; the first rotator
+	// of the hub if empty
+	subscription string
+	// time at which the client has been added to the hub
+	connected time.Time
+	// messages queued for the client; closed by the hub when the
+	// client is removed
+	send chan string
+}
+
+// TCPDialect is a functional option to set the protocol spoken by the
+// tcp clients of a listener. The default dialect is ARSVCOM.
+func TCPDialect(d Dialect) func(*TCPClient) {
+	return func(c *TCPClient) {
+		c.dialect = d
+	}
+}
+
+// TCPFrame is a functional option to set the format of the heading
+// updates sent to the tcp clients of a listener. The default format
+// is FrameAuto.
+func TCPFrame(f Frame) func(*TCPClient) {
+	return func(c *TCPClient) {
+		c.frame = f
+	}
+}
+
+// TCPDigits is a functional option to set the number of digits of each
+// value in the heading updates and position reports (e.g. +0aaa with 4
+// digits) for controllers expecting a different width. The default is
+// DefaultDigits; see CheckDigits for the supported range.
+func TCPDigits(n int) func(*TCPClient) {
+	return func(c *TCPClient) {
+		c.digits = n
+	}
+}
+
+// TCPReadOnly is a functional option to reject all commands (except
+// queries) from the tcp clients of a listener. Read-only clients still
+// receive the heading updates.
+func TCPReadOnly(readOnly bool) func(*TCPClient) {
+	return func(c *TCPClient) {
+		c.readOnly = readOnly
+	}
+}
+
+// TCPRotator is a functional option to connect the tcp clients of a
+// listener to the rotator with the given name. Clients only receive the
+// heading updates of this rotator. By default, the clients are connected
+// to the first rotator of the hub.
+func TCPRotator(name string) func(*TCPClient) {
+	return func(c *TCPClient) {
+		c.subscription = name
+	}
+}
+
+// subscribed returns true if the client has subscribed to the rotator
+// with the given name. Headings which don't belong to a particular
+// rotator (empty name) are sent to all clients.
+func (c *TCPClient) subscribed(name string) bool {
+	return c.subscription == "" || name == "" || name == c.subscription
 }
 
 // listen starts listening for incoming messages from tcp connections. When
 // a error occurs, the routine returns and deletes the tcp connection.
 // Since this method contains an endless loop it should be executed
 // in a go routine.
-func (c *TCPClient) listen(rotator rotator.Rotator, closer chan<- *TCPClient) {
+func (c *TCPClient) listen(hub *Hub, rotator rotator.Rotator) {
 	defer func() {
-		closer <- c
+		select {
+		case hub.closeTCPClient <- c:
+		case <-hub.closeCh:
+		}
 	}()
 
+	reader := bufio.NewReader(c.Conn)
+
 	for {
-		msg, err := bufio.NewReader(c.Conn).ReadString('\n')
+		if c.idleTimeout > 0 {
+			c.Conn.SetReadDeadline(time.Now().Add(c.idleTimeout))
+		}
+		msg, err := reader.ReadString(c.dialect.delimiter())
 		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				hub.logger.Infof("tcp client (%v) idle for %v; disconnecting", c.Conn.RemoteAddr(), c.idleTimeout)
+				return
+			}
 			if err != io.EOF {
-				log.Printf("socket read error (%v): %v\n", c.Conn.RemoteAddr(), err)
+				hub.logger.Warnf("socket read error (%v): %v", c.Conn.RemoteAddr(), err)
 			}
 			return //disconnect and remove client
 		}
 
-		switch strings.ToUpper(msg[0:1]) {
-		// set azimuth / elevation heading
-		case "M":
-			msg = strings.TrimRight(msg[1:], "\r\n")
+		cmd, err := parseCommand(c.dialect, msg)
+		if err != nil {
+			hub.logger.Warnf("parse error (%v): %v", c.Conn.RemoteAddr(), err)
+			if err := c.reject(err); err != nil {
+				hub.logger.Errorf("%v", err)
+				return
+			}
+			continue
+		}
 
-			if len(msg) == 0 {
-				if err := c.prompt(); err != nil {
-					log.Println(err)
+		if cmd.rotate != 0 {
+			req, err := rotationRequest(rotator, cmd.rotate)
+			if err != nil {
+				hub.logger.Warnf("unable to rotate (%v): %v", c.Conn.RemoteAddr(), err)
+				if err := c.reject(err); err != nil {
+					hub.logger.Errorf("%v", err)
 					return
 				}
 				continue
 			}
-			// TBD need to handle elevation
-			az, err := strconv.Atoi(msg)
-			if err != nil {
-				log.Printf("parse error (%v): %v; msg: %s\n", c.Conn.RemoteAddr(), err, msg)
-				continue
+			cmd.request = req
+		}
+
+		switch {
+		case cmd.prompt:
+			if err := c.prompt(); err != nil {
+				hub.logger.Errorf("%v", err)
+				return
 			}
-			rotator.SetAzimuth(az)
-		// query
-		case "C":
-			// azimuth + elevation
-			if msg[1] == '2' {
-				az := rotator.Azimuth()
-				el := rotator.Elevation()
-				if err := c.write(fmt.Sprintf("+0%.3d+0%.3d\r\n", az, el)); err != nil {
-					log.Println(err)
-					return
-				}
-				// only azimuth
-			} else {
-				az := rotator.Azimuth()
-				if err := c.write(fmt.Sprintf("+0%.3d\r\n", az)); err != nil {
-					log.Println(err)
-					return
-				}
+		case cmd.query != noQuery:
+			if err := c.write(queryResponse(c.dialect, cmd.query, c.digits, rotator)); err != nil {
+				hub.logger.Errorf("%v", err)
+				return
 			}
-		// stop azimuth
-		case "A":
-			if err := rotator.StopAzimuth(); err != nil {
-				log.Println(err)
+		case cmd.request != nil && c.readOnly:
+			hub.logger.Warnf("rejected command from read-only tcp client (%v)", c.Conn.RemoteAddr())
+			if err := c.reject(fmt.Errorf("read-only client")); err != nil {
+				hub.logger.Errorf("%v", err)
 				return
 			}
-		// stop elevation
-		case "E":
-			if err := rotator.StopElevation(); err != nil {
-				log.Println(err)
+		case cmd.request != nil && !c.limiter.allow(time.Now()):
+			hub.logger.Warnf("dropped command from tcp client (%v): %v", c.Conn.RemoteAddr(), errRateLimit)
+			if err := c.reject(errRateLimit); err != nil {
+				hub.logger.Errorf("%v", err)
 				return
 			}
-		// stop all
-		case "S":
-			if err := rotator.Stop(); err != nil {
-				log.Println(err)
+		case cmd.request != nil:
+			cmd.request.Name = rotator.Name()
+			if err := hub.execute(requestSource(ProtocolTCP, c.Conn.RemoteAddr().String()), rotator, *cmd.request); err != nil {
+				hub.logger.Errorf("unable to execute command (%v): %v", c.Conn.RemoteAddr(), err)
+				if c.dialect == JSON {
+					if err := c.reject(err); err != nil {
+						hub.logger.Errorf("%v", err)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// queue queues msg for the client without blocking. It returns false
+// if the client's queue is full. The caller must hold the hub's lock.
+func (c *TCPClient) queue(msg string) bool {
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// writePump writes the queued messages to the tcp socket until the hub
+// removes the client or shuts down. If a write fails, the connection is
+// closed which in turn removes the client from the hub.
+func (c *TCPClient) writePump(hub *Hub) {
+	for {
+		select {
+		case msg, ok := <-c.send:
+			if !ok {
 				return
 			}
-			// unknown commando
-		default:
-			if err := c.write("?>"); err != nil {
-				log.Println(err)
+			if err := c.write(msg); err != nil {
+				hub.logger.Errorf("%v", err)
+				hub.broadcastError(ProtocolTCP)
+				c.Close()
 				return
 			}
+		case <-hub.closeCh:
+			return
 		}
 	}
 }
 
+// reject informs the client that its message has been rejected. Clients
+// speaking the JSON dialect receive the error, DCU-1 clients nothing (as
+// from a DCU-1 controller) and all others a prompt.
+func (c *TCPClient) reject(reason error) error {
+	switch c.dialect {
+	case JSON:
+		return c.write(jsonMessage(jsonError{reason.Error()}))
+	case DCU1:
+		return nil
+	}
+	return c.prompt()
+}
+
 // writes a prompt to the tcp socket
 func (c *TCPClient) prompt() error {
-	if _, err := c.Conn.Write([]byte("?>")); err != nil {
-		return err
-	}
-	return nil
+	return c.write("?>")
 }
 
 // write takes an empty interface and writes it's value to the client's
-// tcp socket. If the value in the interface is not supported a log
-// message will be printed. If it is not possible to write successfully
-// to the socket, an error will be returned with the details.
+// tcp socket. If the value in the interface is not supported, an error
+// will be returned. If it is not possible to write successfully
+// to the socket within the write timeout (e.g. because the connection
+// is half-open), an error will be returned with the details.
 func (c *TCPClient) write(v interface{}) error {
 
 	data := []byte{}
@@ -121,8 +247,11 @@ func (c *TCPClient) write(v interface{}) error {
 	case string:
 		data = []byte(v.(string))
 	default:
-		log.Printf("no handler for type %v (msg: %v)\n", t, v)
-		return nil
+		return fmt.Errorf("no handler for type %T (msg: %v)", t, v)
+	}
+
+	if c.writeTimeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
 	}
 
 	if _, err := c.Conn.Write(data); err != nil {
@@ -0,0 +1,227 @@
+package hub
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestStuckTCPClient(t *testing.T) {
+
+	h, err := New(TCPWriteTimeout(time.Millisecond * 50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	r, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stuck client never reads from its connection
+	stuck, stuckServer := net.Pipe()
+	defer stuck.Close()
+	h.addTCPClient(&TCPClient{Conn: stuckServer})
+
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server})
+
+	done := make(chan struct{})
+	go func() {
+		h.BroadcastToTCPClients(rotator.Heading{Azimuth: 90})
+		close(done)
+	}()
+
+	res, err := bufio.NewReader(client).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "+0090\r\n" {
+		t.Fatalf("expected %q, got %q", "+0090\r\n", res)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast stalled by stuck client")
+	}
+
+	waitForTCPClients(t, h, 1)
+}
+
+func TestSlowTCPClient(t *testing.T) {
+
+	// without a write timeout, only the queue protects the
+	// other clients from the slow client
+	h, err := New(TCPWriteTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	r, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	slow, slowServer := net.Pipe()
+	defer slow.Close()
+	h.addTCPClient(&TCPClient{Conn: slowServer})
+
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server})
+
+	// enough messages to overflow the slow client's queue
+	n := clientSendBufferSize + 10
+
+	received := make(chan int)
+	go func() {
+		reader := bufio.NewReader(client)
+		i := 0
+		for ; i < n; i++ {
+			if _, err := reader.ReadString('\n'); err != nil {
+				break
+			}
+		}
+		received <- i
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < n; i++ {
+			h.BroadcastToTCPClients(rotator.Heading{Azimuth: i})
+			time.Sleep(time.Millisecond)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcasts blocked by slow client")
+	}
+
+	select {
+	case i := <-received:
+		if i != n {
+			t.Fatalf("expected %d messages, got %d", n, i)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	waitForTCPClients(t, h, 1)
+}
+
+// waitForTCPClients waits until n tcp clients are connected to the hub.
+func waitForTCPClients(t *testing.T, h *Hub, n int) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		h.RLock()
+		clients := len(h.tcpClients)
+		h.RUnlock()
+		if clients == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d tcp clients, got %d", n, clients)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestTCPIdleTimeout(t *testing.T) {
+
+	if _, err := New(TCPIdleTimeout(-time.Second)); err == nil {
+		t.Fatal("expected error for negative idle timeout")
+	}
+
+	tt := []struct {
+		name          string
+		idleTimeout   time.Duration
+		active        bool
+		expDisconnect bool
+	}{
+		{"disabled", 0, false, false},
+		{"idle client", time.Millisecond * 50, false, true},
+		{"active client", time.Millisecond * 50, true, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(TCPIdleTimeout(tc.idleTimeout))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			r, err := dummy.New(dummy.Name("r1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			client, server := net.Pipe()
+			defer client.Close()
+			h.addTCPClient(&TCPClient{Conn: server, dialect: ARSVCOM})
+
+			// consume the replies of the queries
+			go func() {
+				buf := make([]byte, 64)
+				for {
+					if _, err := client.Read(buf); err != nil {
+						return
+					}
+				}
+			}()
+
+			end := time.Now().Add(time.Millisecond * 300)
+			for time.Now().Before(end) {
+				if tc.active {
+					client.Write([]byte("C\n"))
+				}
+				time.Sleep(time.Millisecond * 10)
+			}
+
+			h.RLock()
+			clients := len(h.tcpClients)
+			h.RUnlock()
+
+			if tc.expDisconnect && clients != 0 {
+				t.Fatal("idle client not disconnected")
+			}
+			if !tc.expDisconnect && clients != 1 {
+				t.Fatal("unexpected disconnect")
+			}
+		})
+	}
+}
+
+func TestTCPClientWriteUnsupported(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	c := &TCPClient{Conn: server}
+	if err := c.write(42); err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+}
@@ -0,0 +1,41 @@
+package hub
+
+import (
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// queueHeading stores h as the next heading of the rotator name to be
+// broadcasted. A heading which hasn't been broadcasted yet will be replaced.
+func (hub *Hub) queueHeading(name string, h rotator.Heading) {
+	hub.Lock()
+	defer hub.Unlock()
+
+	hub.pendingHeadings[name] = h
+}
+
+// throttleBroadcasts broadcasts the latest queued heading of each rotator
+// at most broadcastRate times per second until the hub is closed.
+// Intermediate headings are dropped, but the latest heading is always
+// delivered.
+func (hub *Hub) throttleBroadcasts() {
+	ticker := time.NewTicker(time.Second / time.Duration(hub.broadcastRate))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			hub.Lock()
+			pending := hub.pendingHeadings
+			hub.pendingHeadings = make(map[string]rotator.Heading)
+			hub.Unlock()
+
+			for name, h := range pending {
+				hub.broadcast(name, h)
+			}
+		case <-hub.closeCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package hub
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestBroadcastCoalescing(t *testing.T) {
+
+	h, err := New(BroadcastRate(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	r, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server, hasAzimuth: true})
+
+	frames := make(chan string, 100)
+	go func() {
+		reader := bufio.NewReader(client)
+		for {
+			msg, err := reader.ReadString('\n')
+			if err != nil {
+				close(frames)
+				return
+			}
+			frames <- msg
+		}
+	}()
+
+	for az := 0; az < 50; az++ {
+		h.Broadcast(rotator.Heading{Azimuth: az})
+	}
+
+	received := 0
+	for {
+		select {
+		case msg, ok := <-frames:
+			if !ok {
+				t.Fatal("connection closed")
+			}
+			received++
+			if msg != "+0049\r\n" {
+				continue
+			}
+			if received >= 50 {
+				t.Fatalf("expected intermediate frames to be dropped, got %d frames", received)
+			}
+			return
+		case <-time.After(time.Second):
+			t.Fatalf("final heading not delivered after %d frames", received)
+		}
+	}
+}
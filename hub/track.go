@@ -0,0 +1,175 @@
+package hub
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/astro"
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// TrackState describes the celestial body which is tracked by a rotator.
+type TrackState struct {
+	Body astro.Body `json:"body"`
+}
+
+type tracker struct {
+	body   astro.Body
+	stopCh chan struct{}
+	doneCh chan struct{} // closed when the tracker has returned
+}
+
+// Track points the rotator continuously at a celestial body (Sun / Moon).
+// The heading will be updated every tracking interval until StopTracking
+// is called or a stop command is received. While the body is below the
+// horizon, the rotator will not be moved.
+func (hub *Hub) Track(name string, body astro.Body) error {
+	hub.Lock()
+	defer hub.Unlock()
+
+	if hub.closed() {
+		return fmt.Errorf("hub closed")
+	}
+
+	if !hub.hasLocation {
+		return fmt.Errorf("station location not set")
+	}
+
+	body, err := astro.ParseBody(string(body))
+	if err != nil {
+		return err
+	}
+
+	r, ok := hub.rotators[name]
+	if !ok {
+		return fmt.Errorf("unknown rotator %s", name)
+	}
+
+	hub.startTracking(r, body)
+
+	return nil
+}
+
+// startTracking starts the tracker of r. The caller must hold the lock.
+func (hub *Hub) startTracking(r rotator.Rotator, body astro.Body) {
+	hub.stopTracking(r.Name())
+
+	t := &tracker{
+		body:   body,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+	hub.trackers[r.Name()] = t
+
+	hub.goRoutine(func() { hub.track(r, t) })
+
+	hub.broadcastTrackState(r.Name(), &TrackState{body})
+	hub.logger.Infof("rotator (%s) is tracking the %s", r.Name(), body)
+}
+
+// StopTracking stops tracking a celestial body. The rotator will
+// not be stopped. StopTracking returns once the tracker has returned,
+// so that a command issued afterwards (e.g. stop) can't be overtaken
+// by the tracker.
+func (hub *Hub) StopTracking(name string) {
+	hub.Lock()
+	t, ok := hub.trackers[name]
+	hub.stopTracking(name)
+	hub.Unlock()
+
+	if ok {
+		<-t.doneCh
+	}
+}
+
+func (hub *Hub) stopTracking(name string) {
+	t, ok := hub.trackers[name]
+	if !ok {
+		return
+	}
+	close(t.stopCh)
+	delete(hub.trackers, name)
+
+	hub.broadcastTrackState(name, nil)
+	hub.logger.Infof("rotator (%s) stopped tracking the %s", name, t.body)
+}
+
+// Tracking returns the celestial body a rotator is tracking. If the
+// rotator is not tracking, ("", false) will be returned.
+func (hub *Hub) Tracking(name string) (astro.Body, bool) {
+	hub.RLock()
+	defer hub.RUnlock()
+
+	t, ok := hub.trackers[name]
+	if !ok {
+		return "", false
+	}
+	return t.body, true
+}
+
+// track updates the rotator's heading until the tracker is stopped.
+// Since this function contains an endless loop, it should be executed
+// in a go routine.
+func (hub *Hub) track(r rotator.Rotator, t *tracker) {
+	defer close(t.doneCh)
+
+	ticker := time.NewTicker(hub.trackingInterval)
+	defer ticker.Stop()
+
+	belowHorizon := false
+
+	for {
+		az, el, err := astro.Position(t.body, time.Now(), hub.latitude, hub.longitude)
+		if err != nil {
+			hub.logger.Errorf("%v", err)
+			return
+		}
+
+		if el < 0 {
+			if !belowHorizon {
+				hub.logger.Infof("the %s is below the horizon; waiting until it rises", t.body)
+			}
+			belowHorizon = true
+		} else {
+			belowHorizon = false
+			if r.HasAzimuth() && !t.stopped() {
+				if err := hub.setAzimuth(r, int(math.Round(az))); err != nil {
+					hub.logger.Errorf("unable to track the %s with rotator %s: %v", t.body, r.Name(), err)
+				}
+			}
+			if r.HasElevation() && !t.stopped() {
+				if err := hub.setElevation(r, int(math.Round(el))); err != nil {
+					hub.logger.Errorf("unable to track the %s with rotator %s: %v", t.body, r.Name(), err)
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-t.stopCh:
+			return
+		}
+	}
+}
+
+// stopped returns true if the tracker has been stopped.
+func (t *tracker) stopped() bool {
+	select {
+	case <-t.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (hub *Hub) broadcastTrackState(name string, ts *TrackState) {
+	ev := Event{
+		Name:        UpdateTracking,
+		RotatorName: name,
+		Tracking:    ts,
+	}
+	if err := hub.broadcastToWsClients(ev); err != nil {
+		hub.logger.Errorf("%v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package hub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/astro"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestInvalidTrackingInterval(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		if _, err := New(TrackingInterval(d)); err == nil {
+			t.Fatalf("expected error for tracking interval %v", d)
+		}
+	}
+}
+
+func TestStopTrackingWaitsForTracker(t *testing.T) {
+	h, err := New(Location(40.4, -3.7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	r, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Track("r1", astro.Sun); err != nil {
+		t.Fatal(err)
+	}
+	h.RLock()
+	tr := h.trackers["r1"]
+	h.RUnlock()
+
+	h.StopTracking("r1")
+
+	select {
+	case <-tr.doneCh:
+	default:
+		t.Fatal("expected the tracker to have returned")
+	}
+	if _, ok := h.Tracking("r1"); ok {
+		t.Fatal("expected tracking to be stopped")
+	}
+}
@@ -0,0 +1,41 @@
+package hub
+
+import "github.com/dh1tw/remoteRotator/rotator"
+
+// stopIfUnattended stops all rotators if StopOnLastDisconnect is enabled
+// and no client which can send commands is connected anymore. The
+// rotators are stopped like with a stop command, which also ends their
+// ramps and tracking. This happens in a separate go routine, since
+// stopping a remote rotator might take a while. The caller must hold
+// the lock.
+func (hub *Hub) stopIfUnattended() {
+	if !hub.stopOnLastDisconnect || hub.closed() {
+		return
+	}
+
+	for c := range hub.tcpClients {
+		if !c.readOnly {
+			return
+		}
+	}
+	for c := range hub.wsClients {
+		if !c.readOnly {
+			return
+		}
+	}
+
+	rotators := make([]rotator.Rotator, 0, len(hub.rotators))
+	for _, r := range hub.rotators {
+		rotators = append(rotators, r)
+	}
+
+	hub.goRoutine(func() {
+		for _, r := range rotators {
+			if err := hub.stop(r); err != nil {
+				hub.logger.Errorf("unable to stop rotator (%s): %v", r.Name(), err)
+				continue
+			}
+			hub.logger.Infof("stopped rotator (%s); last controlling client disconnected", r.Name())
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package hub
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+// stopRecorder reports each call to Stop on stops
+type stopRecorder struct {
+	rotator.Rotator
+	stops chan struct{}
+}
+
+func (r *stopRecorder) Stop() error {
+	r.stops <- struct{}{}
+	return r.Rotator.Stop()
+}
+
+func TestStopOnLastDisconnect(t *testing.T) {
+
+	tt := []struct {
+		name       string
+		enabled    bool
+		readOnly   []bool // the first client disconnects
+		expStopped bool
+	}{
+		{"disabled", false, []bool{false}, false},
+		{"last client", true, []bool{false}, true},
+		{"controlling client left", true, []bool{false, false}, false},
+		{"only read-only client left", true, []bool{false, true}, true},
+		{"read-only client", true, []bool{true, false}, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(StopOnLastDisconnect(tc.enabled))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			d, err := dummy.New(dummy.Name("r1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &stopRecorder{Rotator: d, stops: make(chan struct{}, 1)}
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			conns := []net.Conn{}
+			for _, readOnly := range tc.readOnly {
+				client, server := net.Pipe()
+				defer client.Close()
+				h.addTCPClient(&TCPClient{Conn: server, readOnly: readOnly})
+				conns = append(conns, client)
+			}
+
+			conns[0].Close()
+			waitForTCPClients(t, h, len(tc.readOnly)-1)
+
+			select {
+			case <-r.stops:
+				if !tc.expStopped {
+					t.Fatal("unexpected stop")
+				}
+			case <-time.After(time.Millisecond * 100):
+				if tc.expStopped {
+					t.Fatal("expected rotator to be stopped")
+				}
+			}
+		})
+	}
+}
+
+func TestStopOnSlowClientDrop(t *testing.T) {
+
+	h, err := New(StopOnLastDisconnect(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &stopRecorder{Rotator: d, stops: make(chan struct{}, 1)}
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	// the client never reads (e.g. a half-open connection)
+	client, server := net.Pipe()
+	defer client.Close()
+	h.addTCPClient(&TCPClient{Conn: server})
+
+	for i := 0; i < clientSendBufferSize*2; i++ {
+		h.Broadcast(rotator.Heading{Azimuth: i % 360})
+	}
+	waitForTCPClients(t, h, 0)
+
+	select {
+	case <-r.stops:
+	case <-time.After(time.Second):
+		t.Fatal("expected rotator to be stopped")
+	}
+}
@@ -0,0 +1,62 @@
+package hub
+
+import (
+	"fmt"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// wsMaxMessageSize is the maximum size of a message received from a
+// websocket client. Requests are small JSON objects; clients sending
+// larger messages are disconnected.
+const wsMaxMessageSize = 4096
+
+// The ranges of plausible values in a request. Azimuths beyond 360° (or
+// below 0°) are valid mechanical positions of rotators with overlap;
+// the limits of the individual rotator are applied later.
+const (
+	minRequestAzimuth   = -360
+	maxRequestAzimuth   = 720
+	minRequestElevation = -90
+	maxRequestElevation = 180
+	maxRequestDelta     = 360
+)
+
+// validateRequest returns an error if req doesn't contain any command or
+// contains values which no rotator could be commanded to, so that
+// malformed requests are rejected before reaching the rotator.
+func validateRequest(req rotator.Request) error {
+	if !req.HasAzimuth && req.Azimuth != 0 {
+		return fmt.Errorf("azimuth set without has_azimuth")
+	}
+	if !req.HasElevation && req.Elevation != 0 {
+		return fmt.Errorf("elevation set without has_elevation")
+	}
+	if !req.HasSpeed && req.Speed != 0 {
+		return fmt.Errorf("speed set without has_speed")
+	}
+
+	if !req.HasAzimuth && !req.HasElevation && !req.HasSpeed &&
+		req.AzimuthDelta == 0 && req.ElevationDelta == 0 &&
+		!req.StopAzimuth && !req.StopElevation && !req.Stop {
+		return fmt.Errorf("request without command")
+	}
+
+	if req.HasAzimuth && (req.Azimuth < minRequestAzimuth || req.Azimuth > maxRequestAzimuth) {
+		return fmt.Errorf("azimuth %d out of range (%d...%d)", req.Azimuth, minRequestAzimuth, maxRequestAzimuth)
+	}
+	if req.HasElevation && (req.Elevation < minRequestElevation || req.Elevation > maxRequestElevation) {
+		return fmt.Errorf("elevation %d out of range (%d...%d)", req.Elevation, minRequestElevation, maxRequestElevation)
+	}
+	if req.HasSpeed && (req.Speed < rotator.SpeedMin || req.Speed > rotator.SpeedMax) {
+		return fmt.Errorf("speed %d out of range (%d...%d)", req.Speed, rotator.SpeedMin, rotator.SpeedMax)
+	}
+	if req.AzimuthDelta < -maxRequestDelta || req.AzimuthDelta > maxRequestDelta {
+		return fmt.Errorf("azimuth delta %d out of range (%d...%d)", req.AzimuthDelta, -maxRequestDelta, maxRequestDelta)
+	}
+	if req.ElevationDelta < -maxRequestDelta || req.ElevationDelta > maxRequestDelta {
+		return fmt.Errorf("elevation delta %d out of range (%d...%d)", req.ElevationDelta, -maxRequestDelta, maxRequestDelta)
+	}
+
+	return nil
+}
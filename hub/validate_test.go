@@ -0,0 +1,128 @@
+package hub
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/gorilla/websocket"
+)
+
+func TestValidateRequest(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		req    rotator.Request
+		expErr bool
+	}{
+		{"azimuth", rotator.Request{HasAzimuth: true, Azimuth: 90}, false},
+		{"overlap azimuth", rotator.Request{HasAzimuth: true, Azimuth: 450}, false},
+		{"azimuth 0°", rotator.Request{HasAzimuth: true}, false},
+		{"az/el and speed", rotator.Request{HasAzimuth: true, Azimuth: 90, HasElevation: true, Elevation: 45, HasSpeed: true, Speed: 2}, false},
+		{"jog", rotator.Request{AzimuthDelta: -10, ElevationDelta: 5}, false},
+		{"stop", rotator.Request{Stop: true}, false},
+		{"stop azimuth", rotator.Request{StopAzimuth: true}, false},
+		{"stop elevation", rotator.Request{StopElevation: true}, false},
+		{"no command", rotator.Request{Name: "r1"}, true},
+		{"azimuth without flag", rotator.Request{Azimuth: 90}, true},
+		{"elevation without flag", rotator.Request{Elevation: 45}, true},
+		{"speed without flag", rotator.Request{Speed: 2}, true},
+		{"azimuth too large", rotator.Request{HasAzimuth: true, Azimuth: 100000}, true},
+		{"azimuth too small", rotator.Request{HasAzimuth: true, Azimuth: -361}, true},
+		{"elevation too large", rotator.Request{HasElevation: true, Elevation: 181}, true},
+		{"elevation too small", rotator.Request{HasElevation: true, Elevation: -91}, true},
+		{"speed too large", rotator.Request{HasSpeed: true, Speed: rotator.SpeedMax + 1}, true},
+		{"speed too small", rotator.Request{HasSpeed: true, Speed: rotator.SpeedMin - 1}, true},
+		{"azimuth delta too large", rotator.Request{AzimuthDelta: 361}, true},
+		{"elevation delta too small", rotator.Request{ElevationDelta: -361}, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateRequest(tc.req)
+			if (err != nil) != tc.expErr {
+				t.Fatalf("expected error %v, got %v", tc.expErr, err)
+			}
+		})
+	}
+}
+
+func TestWsInvalidRequests(t *testing.T) {
+
+	h := newTestHub(t, "r1")
+	defer h.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	t.Run("malformed", func(t *testing.T) {
+		msgs := []string{
+			`{"name":"r1","has_azimuth":true`,
+			`{"name":"r1","azimuth":"north"}`,
+			`{"name":"r1"}`,
+			`{"name":"r1","has_azimuth":true,"azimuth":99999}`,
+			`{"name":"r1","has_speed":true,"speed":100}`,
+		}
+
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		readHello(t, conn)
+
+		for _, msg := range msgs {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		for errors := 0; errors < len(msgs); {
+			ev := Event{}
+			if err := conn.ReadJSON(&ev); err != nil {
+				t.Fatalf("expected %d error events, got %d: %v", len(msgs), errors, err)
+			}
+			if ev.Name == RequestError {
+				errors++
+			}
+		}
+
+		r, _ := h.Rotator("r1")
+		if r.Serialize().Heading.AzPreset != 0 {
+			t.Fatalf("unexpected azimuth preset %d", r.Serialize().Heading.AzPreset)
+		}
+	})
+
+	t.Run("oversized", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		readHello(t, conn)
+
+		msg := `{"name":"` + strings.Repeat("r", wsMaxMessageSize) + `","stop":true}`
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+
+		// the hub closes the connection
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		for {
+			_, _, err := conn.ReadMessage()
+			if err == nil {
+				continue
+			}
+			if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+				t.Fatalf("expected close error %d, got %v", websocket.CloseMessageTooBig, err)
+			}
+			break
+		}
+	})
+}
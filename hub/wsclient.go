@@ -3,25 +3,153 @@ package hub
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/compact"
 	"github.com/gorilla/websocket"
 )
 
+// Time allowed to write a message or a ping to the client.
+const wsWriteWait = 5 * time.Second
+
 //WsClient is a wrapper for clients connected through a Websocket
 type WsClient struct {
 	*websocket.Conn
+	// read-only clients receive all events but their requests are rejected
+	readOnly bool
+	// time at which the client has been added to the hub
+	connected time.Time
+	// websocket message type of the events (text or binary)
+	messageType int
+	// send the heading updates in the compact encoding
+	// (WsCompactSubprotocol)
+	compact bool
+	// name of the rotator the client has subscribed to; all rotators
+	// if empty
+	subscription string
+	// limits the rate of the client's requests; unlimited if nil
+	limiter *tokenBucket
+	// websocket connections support only one concurrent writer
+	writeMu sync.Mutex
+	// closed when the client stops listening
+	done chan struct{}
+	// events queued for the client; closed by the hub when the
+	// client is removed
+	send chan Event
 }
 
-// listen on the websocket. Despite that no data is read, this function
-// is necessary to reply to incoming ping messages.
-func (c *WsClient) listen(closer chan<- *WsClient) {
+// listen on the websocket for incoming requests (JSON encoded
+// rotator.Request). This function is also necessary to reply to incoming
+// ping messages.
+func (c *WsClient) listen(hub *Hub) {
+	defer close(c.done)
 	defer func() {
-		closer <- c
+		select {
+		case hub.closeWsClient <- c:
+		case <-hub.closeCh:
+		}
 	}()
 
+	// clients which don't answer the pings within two keep-alive
+	// periods are considered dead
+	pongWait := 2 * hub.wsKeepAlive
+	if hub.wsKeepAlive > 0 {
+		c.SetReadDeadline(time.Now().Add(pongWait))
+		c.SetPongHandler(func(string) error {
+			c.SetReadDeadline(time.Now().Add(pongWait))
+			return nil
+		})
+	}
+
 	for {
 		// in case of an error just return and signal closing down of the ws
-		if _, _, err := c.ReadMessage(); err != nil {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if hub.wsKeepAlive > 0 {
+			c.SetReadDeadline(time.Now().Add(pongWait))
+		}
+
+		req := rotator.Request{}
+		if jsonErr := json.Unmarshal(msg, &req); jsonErr != nil {
+			hub.logger.Warnf("invalid request (%v): %v", c.RemoteAddr(), jsonErr)
+			err = fmt.Errorf("invalid request: %v", jsonErr)
+		} else if c.readOnly {
+			hub.logger.Warnf("rejected request from read-only websocket client (%v)", c.RemoteAddr())
+			err = fmt.Errorf("read-only client")
+		} else if !c.limiter.allow(time.Now()) {
+			hub.logger.Warnf("dropped request from websocket client (%v): %v", c.RemoteAddr(), errRateLimit)
+			err = errRateLimit
+		} else {
+			err = hub.executeRequest(requestSource(ProtocolWebsocket, c.RemoteAddr().String()), req)
+		}
+
+		if err != nil {
+			ev := Event{
+				Name:        RequestError,
+				RotatorName: req.Name,
+				Error:       err.Error(),
+			}
+			if err := c.write(ev); err != nil {
+				hub.logger.Errorf("%v", err)
+				return
+			}
+		}
+	}
+}
+
+// ping sends a ping to the client every period until the client stops
+// listening or the hub shuts down.
+func (c *WsClient) ping(hub *Hub, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.done:
+			return
+		case <-hub.closeCh:
+			return
+		}
+		if err := c.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(wsWriteWait)); err != nil {
+			return
+		}
+	}
+}
+
+// queue queues event for the client without blocking. It returns false
+// if the client's queue is full. The caller must hold the hub's lock.
+func (c *WsClient) queue(event Event) bool {
+	select {
+	case c.send <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// writePump writes the queued events to the websocket until the hub
+// removes the client or shuts down. If a write fails, the connection is
+// closed which in turn removes the client from the hub.
+func (c *WsClient) writePump(hub *Hub) {
+	for {
+		select {
+		case event, ok := <-c.send:
+			if !ok {
+				return
+			}
+			if err := c.write(event); err != nil {
+				hub.logger.Errorf("error writing to client %v: %v", c.RemoteAddr(), err)
+				hub.broadcastError(ProtocolWebsocket)
+				c.Close()
+				return
+			}
+		case <-hub.closeCh:
 			return
 		}
 	}
@@ -29,13 +157,37 @@ func (c *WsClient) listen(closer chan<- *WsClient) {
 
 func (c *WsClient) write(event Event) error {
 
+	if c.compact && event.Name == UpdateHeading && event.RotatorID > 0 {
+		b, err := compact.EncodeHeading(event.RotatorID, event.Heading)
+		if err == nil {
+			c.writeMu.Lock()
+			defer c.writeMu.Unlock()
+			c.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			return c.WriteMessage(websocket.BinaryMessage, b)
+		}
+		// values which can't be encoded are sent as JSON
+	}
+
 	b, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("unable to serialize msg %v: %v", event, err)
 	}
-	if err := c.WriteMessage(websocket.TextMessage, b); err != nil {
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// a stalled client must not block the writer forever
+	c.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := c.WriteMessage(c.messageType, b); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// subscribed returns true if the client has subscribed to the rotator
+// with the given name. Events which don't belong to a particular rotator
+// (empty name) are sent to all clients.
+func (c *WsClient) subscribed(name string) bool {
+	return c.subscription == "" || name == "" || name == c.subscription
+}
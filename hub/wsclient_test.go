@@ -0,0 +1,275 @@
+package hub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+	"github.com/gorilla/websocket"
+)
+
+func TestWsKeepAlive(t *testing.T) {
+
+	tt := []struct {
+		name          string
+		responsive    bool
+		expDisconnect bool
+	}{
+		{"responsive client", true, false},
+		{"unresponsive client", false, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			evCh := make(chan ClientEvent, 10)
+			h, err := New(WsKeepAlive(time.Millisecond*50),
+				ClientEventHandler(func(ev ClientEvent) {
+					evCh <- ev
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+			defer srv.Close()
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			// pings are only answered while reading from the connection
+			if tc.responsive {
+				go func() {
+					for {
+						if _, _, err := conn.ReadMessage(); err != nil {
+							return
+						}
+					}
+				}()
+			}
+
+			if ev := <-evCh; ev.Type != ClientConnected {
+				t.Fatalf("expected connect event, got %+v", ev)
+			}
+
+			select {
+			case ev := <-evCh:
+				if !tc.expDisconnect {
+					t.Fatalf("unexpected event %+v", ev)
+				}
+				if ev.Type != ClientDisconnected {
+					t.Fatalf("expected disconnect event, got %+v", ev)
+				}
+			case <-time.After(time.Millisecond * 500):
+				if tc.expDisconnect {
+					t.Fatal("unresponsive client not disconnected")
+				}
+			}
+		})
+	}
+}
+
+func TestWsMessageType(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		opts    []func(*Hub)
+		expType int
+	}{
+		{"text by default", nil, websocket.TextMessage},
+		{"binary", []func(*Hub){WsBinary(true)}, websocket.BinaryMessage},
+		{"binary disabled", []func(*Hub){WsBinary(false)}, websocket.TextMessage},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			r, err := dummy.New(dummy.Name("r1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+			defer srv.Close()
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			// the hub greets the client right after the upgrade
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			msgType, msg, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msgType != tc.expType {
+				t.Fatalf("expected message type %d, got %d", tc.expType, msgType)
+			}
+
+			ev := Event{}
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Name != Hello {
+				t.Fatalf("expected %s event, got %s", Hello, ev.Name)
+			}
+		})
+	}
+}
+
+func TestWsPath(t *testing.T) {
+
+	if _, err := New(WsPath("ws")); err == nil {
+		t.Fatal("expected error for relative websocket path")
+	}
+
+	h, err := New(WsPath("/shack1/ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	tt := []struct {
+		path   string
+		expErr bool
+	}{
+		{"/shack1/ws", false},
+		{"/shack1/ws-readonly", false},
+		{"/ws", true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			conn, _, err := websocket.DefaultDialer.Dial(url+tc.path, nil)
+			if tc.expErr {
+				if err == nil {
+					conn.Close()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Close()
+		})
+	}
+}
+
+func TestWsCompression(t *testing.T) {
+
+	tt := []struct {
+		name       string
+		server     bool
+		client     bool
+		expDeflate bool
+	}{
+		{"enabled", true, true, true},
+		{"disabled on the hub", false, true, false},
+		{"not supported by the client", true, false, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := New(WsCompression(tc.server))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer h.Close()
+
+			r, err := dummy.New(dummy.Name("r1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if err := h.AddRotator(r); err != nil {
+				t.Fatal(err)
+			}
+
+			srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+			defer srv.Close()
+
+			dialer := websocket.Dialer{EnableCompression: tc.client}
+			conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			deflate := strings.Contains(resp.Header.Get("Sec-Websocket-Extensions"), "permessage-deflate")
+			if deflate != tc.expDeflate {
+				t.Fatalf("expected permessage-deflate=%v, got %v", tc.expDeflate, deflate)
+			}
+
+			// the events are readable either way
+			readHello(t, conn)
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			ev := Event{}
+			if err := conn.ReadJSON(&ev); err != nil {
+				t.Fatal(err)
+			}
+			if ev.Name != AddRotator || ev.Rotator == nil || ev.Rotator.Name != "r1" {
+				t.Fatalf("unexpected event %+v", ev)
+			}
+		})
+	}
+}
+
+func TestWsInitialEvents(t *testing.T) {
+
+	// more rotators than fit into the client's send queue
+	names := []string{}
+	for i := 0; i < clientSendBufferSize+10; i++ {
+		names = append(names, fmt.Sprintf("r%d", i))
+	}
+	h := newTestHub(t, names...)
+	defer h.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(h.wsHandler))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	readHello(t, conn)
+
+	added := map[string]bool{}
+	for len(added) < len(names) {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		ev := Event{}
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatalf("received %d of %d rotators: %v", len(added), len(names), err)
+		}
+		if ev.Name != AddRotator {
+			t.Fatalf("expected %s event, got %+v", AddRotator, ev)
+		}
+		added[ev.RotatorName] = true
+	}
+}
@@ -0,0 +1,250 @@
+package influx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// Exporter writes the heading of rotators as InfluxDB line protocol
+// to an InfluxDB endpoint. Points are queued and written in batches
+// from a separate go routine, so that a slow or unreachable database
+// never blocks the caller.
+type Exporter struct {
+	address       string
+	database      string
+	measurement   string
+	batchSize     int
+	bufferSize    int
+	flushInterval time.Duration
+	timeout       time.Duration
+	writer        io.Writer
+	send          func([]byte) error
+	conn          net.Conn
+	points        chan point
+	closeCh       chan struct{}
+	doneCh        chan struct{}
+	closer        sync.Once
+	dropped       uint64
+}
+
+type point struct {
+	name    string
+	heading rotator.Heading
+	ts      time.Time
+}
+
+// New returns the pointer to an initialized InfluxDB Exporter. Options
+// can be injected through functional options.
+// Default settings are:
+// address: udp://localhost:8089,
+// database: rotators,
+// measurement: rotator,
+// batchSize: 50,
+// bufferSize: 1000,
+// flushInterval: 1sec,
+// timeout: 5sec.
+func New(opts ...func(*Exporter)) (*Exporter, error) {
+
+	e := &Exporter{
+		address:       "udp://localhost:8089",
+		database:      "rotators",
+		measurement:   "rotator",
+		batchSize:     50,
+		bufferSize:    1000,
+		flushInterval: time.Second,
+		timeout:       time.Second * 5,
+		closeCh:       make(chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.batchSize < 1 {
+		return nil, fmt.Errorf("batch size must be > 0")
+	}
+
+	if e.bufferSize < 0 {
+		return nil, fmt.Errorf("buffer size must be >= 0")
+	}
+
+	if e.flushInterval <= 0 {
+		return nil, fmt.Errorf("flush interval must be > 0")
+	}
+
+	if e.writer != nil {
+		e.send = func(data []byte) error {
+			_, err := e.writer.Write(data)
+			return err
+		}
+	} else if err := e.dial(); err != nil {
+		return nil, err
+	}
+
+	e.points = make(chan point, e.bufferSize)
+
+	go e.start()
+
+	return e, nil
+}
+
+// dial sets up the transport to the InfluxDB endpoint at e.address.
+func (e *Exporter) dial() error {
+	u, err := url.Parse(e.address)
+	if err != nil {
+		return fmt.Errorf("invalid influxdb address %s: %v", e.address, err)
+	}
+
+	switch u.Scheme {
+	case "udp":
+		conn, err := net.Dial("udp", u.Host)
+		if err != nil {
+			return err
+		}
+		e.conn = conn
+		e.send = func(data []byte) error {
+			conn.SetWriteDeadline(time.Now().Add(e.timeout))
+			_, err := conn.Write(data)
+			return err
+		}
+	case "http", "https":
+		c := &http.Client{Timeout: e.timeout}
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/write"
+		q := u.Query()
+		q.Set("db", e.database)
+		q.Set("precision", "ns")
+		u.RawQuery = q.Encode()
+		writeURL := u.String()
+		e.send = func(data []byte) error {
+			resp, err := c.Post(writeURL, "text/plain; charset=utf-8", bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode/100 != 2 {
+				return fmt.Errorf("influxdb write failed. http error code is %v", resp.StatusCode)
+			}
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported influxdb scheme (%s)", u.Scheme)
+	}
+
+	return nil
+}
+
+// Write queues the heading of a rotator for export. Write never blocks;
+// if the queue is full (e.g. because InfluxDB is not reachable), the
+// point will be dropped.
+func (e *Exporter) Write(name string, h rotator.Heading) {
+	select {
+	case e.points <- point{name, h, time.Now()}:
+	default:
+		if n := atomic.AddUint64(&e.dropped, 1); n%100 == 1 {
+			log.Printf("influxdb queue full; %d point(s) dropped\n", n)
+		}
+	}
+}
+
+// Close flushes the remaining points and shuts down the exporter. The
+// connection to a UDP endpoint is closed afterwards.
+func (e *Exporter) Close() {
+	e.closer.Do(func() {
+		close(e.closeCh)
+	})
+	<-e.doneCh
+}
+
+// start the batching loop
+func (e *Exporter) start() {
+	defer close(e.doneCh)
+	if e.conn != nil {
+		defer e.conn.Close()
+	}
+
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]point, 0, e.batchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(e.encode(batch)); err != nil {
+			log.Printf("unable to write %d point(s) to influxdb: %v\n", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case p := <-e.points:
+			batch = append(batch, p)
+			if len(batch) >= e.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.closeCh:
+			for {
+				select {
+				case p := <-e.points:
+					batch = append(batch, p)
+					if len(batch) >= e.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// encode the points into InfluxDB's line protocol
+func (e *Exporter) encode(points []point) []byte {
+	buf := &bytes.Buffer{}
+	for _, p := range points {
+		moving := p.heading.Azimuth != p.heading.AzPreset ||
+			p.heading.Elevation != p.heading.ElPreset
+		fmt.Fprintf(buf, "%s,name=%s azimuth=%di,az_preset=%di,elevation=%di,el_preset=%di,moving=%t %d\n",
+			escapeMeasurement(e.measurement),
+			escape(p.name),
+			p.heading.Azimuth,
+			p.heading.AzPreset,
+			p.heading.Elevation,
+			p.heading.ElPreset,
+			moving,
+			p.ts.UnixNano())
+	}
+	return buf.Bytes()
+}
+
+var escaper = strings.NewReplacer(",", "\\,", " ", "\\ ", "=", "\\=")
+
+// escape commas, spaces and equal signs in tag values
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+var measurementEscaper = strings.NewReplacer(",", "\\,", " ", "\\ ")
+
+// escapeMeasurement escapes commas and spaces in measurements. Equal
+// signs are valid in measurements and must not be escaped.
+func escapeMeasurement(s string) string {
+	return measurementEscaper.Replace(s)
+}
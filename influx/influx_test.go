@@ -0,0 +1,157 @@
+package influx
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+func TestEncode(t *testing.T) {
+
+	ts := time.Unix(0, 1500000000000000000)
+
+	tt := []struct {
+		name   string
+		p      point
+		expMsg string
+	}{
+		{"parked", point{"myRotator", rotator.Heading{Azimuth: 120, AzPreset: 120}, ts},
+			"rotator,name=myRotator azimuth=120i,az_preset=120i,elevation=0i,el_preset=0i,moving=false 1500000000000000000\n"},
+		{"moving", point{"myRotator", rotator.Heading{Azimuth: 120, AzPreset: 300, Elevation: 10, ElPreset: 10}, ts},
+			"rotator,name=myRotator azimuth=120i,az_preset=300i,elevation=10i,el_preset=10i,moving=true 1500000000000000000\n"},
+		{"escaped name", point{"my Rotator,1", rotator.Heading{}, ts},
+			"rotator,name=my\\ Rotator\\,1 azimuth=0i,az_preset=0i,elevation=0i,el_preset=0i,moving=false 1500000000000000000\n"},
+	}
+
+	e := &Exporter{measurement: "rotator"}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res := string(e.encode([]point{tc.p}))
+			if res != tc.expMsg {
+				t.Fatalf("expected %q, got %q", tc.expMsg, res)
+			}
+		})
+	}
+}
+
+// batchWriter records the batches written by the exporter
+type batchWriter struct {
+	sync.Mutex
+	batches []string
+}
+
+func (w *batchWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	w.batches = append(w.batches, string(p))
+	return len(p), nil
+}
+
+func TestWriter(t *testing.T) {
+
+	tt := []struct {
+		name       string
+		batchSize  int
+		points     int
+		expBatches []int // points per batch
+	}{
+		{"single batch", 10, 3, []int{3}},
+		{"full batches", 2, 4, []int{2, 2}},
+		{"remainder flushed on close", 2, 5, []int{2, 2, 1}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &batchWriter{}
+
+			e, err := New(Writer(w), BatchSize(tc.batchSize), FlushInterval(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < tc.points; i++ {
+				e.Write("r1", rotator.Heading{Azimuth: i, AzPreset: i})
+			}
+			e.Close()
+
+			w.Lock()
+			defer w.Unlock()
+
+			if len(w.batches) != len(tc.expBatches) {
+				t.Fatalf("expected %d batches, got %d: %v", len(tc.expBatches), len(w.batches), w.batches)
+			}
+
+			i := 0
+			for n, batch := range w.batches {
+				lines := strings.Split(strings.TrimSuffix(batch, "\n"), "\n")
+				if len(lines) != tc.expBatches[n] {
+					t.Fatalf("expected %d points in batch %d, got %d", tc.expBatches[n], n, len(lines))
+				}
+				for _, line := range lines {
+					prefix := fmt.Sprintf("rotator,name=r1 azimuth=%di,az_preset=%di,", i, i)
+					if !strings.HasPrefix(line, prefix) {
+						t.Fatalf("expected point with prefix %q, got %q", prefix, line)
+					}
+					i++
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeMeasurement(t *testing.T) {
+
+	ts := time.Unix(0, 1500000000000000000)
+	e := &Exporter{measurement: "my rotator,az=el"}
+
+	exp := "my\\ rotator\\,az=el,name=r1 azimuth=0i,az_preset=0i,elevation=0i,el_preset=0i,moving=false 1500000000000000000\n"
+	if res := string(e.encode([]point{{"r1", rotator.Heading{}, ts}})); res != exp {
+		t.Fatalf("expected %q, got %q", exp, res)
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+
+	tt := []struct {
+		name string
+		opt  func(*Exporter)
+	}{
+		{"no batch size", BatchSize(0)},
+		{"negative buffer size", BufferSize(-1)},
+		{"no flush interval", FlushInterval(0)},
+		{"negative flush interval", FlushInterval(-time.Second)},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := New(tc.opt); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestCloseUDP(t *testing.T) {
+
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	e, err := New(Address("udp://" + l.LocalAddr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Close()
+
+	if _, err := e.conn.Write([]byte("rotator")); err == nil {
+		t.Fatal("expected the udp connection to be closed")
+	}
+}
@@ -0,0 +1,71 @@
+package influx
+
+import (
+	"io"
+	"time"
+)
+
+// Address is a functional option to set the URL of the InfluxDB endpoint.
+// Supported schemes are udp:// (e.g. udp://localhost:8089) and http(s)://
+// (e.g. http://localhost:8086).
+func Address(addr string) func(*Exporter) {
+	return func(e *Exporter) {
+		e.address = addr
+	}
+}
+
+// Database is a functional option to set the InfluxDB database to which
+// the points will be written. Only used for the HTTP transport.
+func Database(db string) func(*Exporter) {
+	return func(e *Exporter) {
+		e.database = db
+	}
+}
+
+// Measurement is a functional option to set the name of the measurement.
+func Measurement(m string) func(*Exporter) {
+	return func(e *Exporter) {
+		e.measurement = m
+	}
+}
+
+// BatchSize is a functional option to set the maximum amount of points
+// which will be sent to InfluxDB in one write.
+func BatchSize(size int) func(*Exporter) {
+	return func(e *Exporter) {
+		e.batchSize = size
+	}
+}
+
+// BufferSize is a functional option to set the amount of points which
+// can be queued before new points are dropped.
+func BufferSize(size int) func(*Exporter) {
+	return func(e *Exporter) {
+		e.bufferSize = size
+	}
+}
+
+// FlushInterval is a functional option to set the maximum time a point
+// will be held back before it gets written to InfluxDB.
+func FlushInterval(d time.Duration) func(*Exporter) {
+	return func(e *Exporter) {
+		e.flushInterval = d
+	}
+}
+
+// Timeout is a functional option to set the timeout for a single write
+// to InfluxDB.
+func Timeout(d time.Duration) func(*Exporter) {
+	return func(e *Exporter) {
+		e.timeout = d
+	}
+}
+
+// Writer is a functional option to write the batches of points (in line
+// protocol) to w instead of an InfluxDB endpoint, e.g. to a file or to
+// another time series database. Address and Database are ignored.
+func Writer(w io.Writer) func(*Exporter) {
+	return func(e *Exporter) {
+		e.writer = w
+	}
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// Collector exposes the metrics of a hub and the headings of its
+// rotators to Prometheus. It implements the hub.MetricsCollector
+// interface and is safe for concurrent use.
+type Collector struct {
+	namespace       string
+	registry        *prometheus.Registry
+	handler         http.Handler
+	azimuth         *prometheus.GaugeVec
+	azPreset        *prometheus.GaugeVec
+	elevation       *prometheus.GaugeVec
+	elPreset        *prometheus.GaugeVec
+	clients         *prometheus.GaugeVec
+	requests        *prometheus.CounterVec
+	rejected        *prometheus.CounterVec
+	broadcastErrors *prometheus.CounterVec
+}
+
+// New returns the pointer to an initialized Collector. Options can be
+// injected through functional options.
+// Default settings are:
+// namespace: remoterotator.
+func New(opts ...func(*Collector)) *Collector {
+
+	c := &Collector{
+		namespace: "remoterotator",
+		registry:  prometheus.NewRegistry(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	gauge := func(name, help string, labels ...string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: c.namespace,
+			Name:      name,
+			Help:      help,
+		}, labels)
+	}
+
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: c.namespace,
+			Name:      name,
+			Help:      help,
+		}, labels)
+	}
+
+	c.azimuth = gauge("azimuth_degrees", "Current azimuth of the rotator.", "rotator")
+	c.azPreset = gauge("azimuth_preset_degrees", "Azimuth preset of the rotator.", "rotator")
+	c.elevation = gauge("elevation_degrees", "Current elevation of the rotator.", "rotator")
+	c.elPreset = gauge("elevation_preset_degrees", "Elevation preset of the rotator.", "rotator")
+	c.clients = gauge("clients", "Number of connected clients.", "protocol")
+	c.requests = counter("requests_total", "Number of commands received from clients.", "rotator")
+	c.rejected = counter("requests_rejected_total", "Number of commands which could not be executed.", "rotator")
+	c.broadcastErrors = counter("broadcast_errors_total", "Number of clients disconnected because an update could not be delivered.", "protocol")
+
+	c.registry.MustRegister(c.azimuth, c.azPreset, c.elevation, c.elPreset,
+		c.clients, c.requests, c.rejected, c.broadcastErrors)
+
+	c.handler = promhttp.HandlerFor(c.registry, promhttp.HandlerOpts{})
+
+	return c
+}
+
+// Update sets the heading of the rotator with the given name.
+func (c *Collector) Update(name string, h rotator.Heading) {
+	c.azimuth.WithLabelValues(name).Set(float64(h.Azimuth))
+	c.azPreset.WithLabelValues(name).Set(float64(h.AzPreset))
+	c.elevation.WithLabelValues(name).Set(float64(h.Elevation))
+	c.elPreset.WithLabelValues(name).Set(float64(h.ElPreset))
+}
+
+// SetClients sets the number of clients connected through protocol.
+func (c *Collector) SetClients(protocol string, n int) {
+	c.clients.WithLabelValues(protocol).Set(float64(n))
+}
+
+// RequestReceived counts a command received for the rotator.
+func (c *Collector) RequestReceived(name string) {
+	c.requests.WithLabelValues(name).Inc()
+}
+
+// RequestRejected counts a command for the rotator which could not be
+// executed.
+func (c *Collector) RequestRejected(name string) {
+	c.rejected.WithLabelValues(name).Inc()
+}
+
+// BroadcastError counts a client which has been disconnected because an
+// update could not be delivered.
+func (c *Collector) BroadcastError(protocol string) {
+	c.broadcastErrors.WithLabelValues(protocol).Inc()
+}
+
+// ServeHTTP serves the metrics in the Prometheus exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c.handler.ServeHTTP(w, req)
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/hub"
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+var _ hub.MetricsCollector = &Collector{}
+
+func TestCollector(t *testing.T) {
+
+	c := New()
+
+	h, err := hub.New(hub.Metrics(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// the rotator doesn't move
+	r, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.AddRotator(r); err != nil {
+		t.Fatal(err)
+	}
+
+	azMax := 180
+	if err := h.SetSoftLimits("r1", hub.SoftLimits{AzimuthMax: &azMax, Policy: hub.RejectBeyondLimits}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	for _, body := range []string{`{"azimuth":90}`, `{"azimuth":270}`} {
+		req, err := http.NewRequest("PUT", srv.URL+"/api/rotator/r1/azimuth", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	c.Update("r1", r.Serialize().Heading)
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []string{
+		`remoterotator_requests_total{rotator="r1"} 2`,
+		`remoterotator_requests_rejected_total{rotator="r1"} 1`,
+		`remoterotator_azimuth_preset_degrees{rotator="r1"} 90`,
+		`remoterotator_azimuth_degrees{rotator="r1"} 0`,
+	}
+
+	for _, exp := range tt {
+		if !strings.Contains(string(data), exp) {
+			t.Fatalf("expected %q in\n%s", exp, data)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package metrics
+
+// Namespace is a functional option to set the prefix of the metric names.
+func Namespace(ns string) func(*Collector) {
+	return func(c *Collector) {
+		c.namespace = ns
+	}
+}
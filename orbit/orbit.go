@@ -0,0 +1,173 @@
+// Package orbit provides a low precision propagator for earth satellites
+// described by a two-line element set (TLE) and calculates the look
+// angles (azimuth / elevation) for an observer on earth.
+//
+// The propagator applies the secular perturbations caused by the earth's
+// oblateness (J2) and the decay of the orbit through the first derivative
+// of the mean motion. Periodic perturbations are neglected. For satellites
+// in low earth orbit and element sets which are not older than a few
+// days, the position is typically accurate to a few tens of kilometers,
+// which is well within the beamwidth of typical satellite antennas.
+package orbit
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// earth's gravitational parameter (km³/s²)
+	mu = 398600.4418
+	// WGS-84 equatorial radius (km) and flattening
+	earthRadius = 6378.137
+	flattening  = 1 / 298.257223563
+	// second zonal harmonic of the earth's gravity field
+	j2 = 1.08262668e-3
+)
+
+// LookAngles returns the azimuth (0° = North, clockwise) and elevation
+// (0° = horizon) in degrees and the range in km of the satellite at time
+// t for an observer at the given latitude and longitude (degrees, North
+// and East positive) and altitude (meters above the ellipsoid).
+func (tle TLE) LookAngles(t time.Time, lat, lon, alt float64) (az, el, rng float64) {
+
+	sat := tle.ecef(t)
+	obs := geodeticToECEF(lat, lon, alt/1000)
+
+	dx, dy, dz := sat[0]-obs[0], sat[1]-obs[1], sat[2]-obs[2]
+
+	// topocentric east / north / up
+	e := -sin(lon)*dx + cos(lon)*dy
+	n := -sin(lat)*cos(lon)*dx - sin(lat)*sin(lon)*dy + cos(lat)*dz
+	u := cos(lat)*cos(lon)*dx + cos(lat)*sin(lon)*dy + sin(lat)*dz
+
+	az = normalize(atan2(e, n))
+	el = atan2(u, math.Hypot(e, n))
+	rng = math.Sqrt(dx*dx + dy*dy + dz*dz)
+
+	return az, el, rng
+}
+
+// SubPoint returns the latitude and longitude (degrees) of the point on
+// the earth's surface directly below the satellite and the satellite's
+// altitude (km) above it at time t.
+func (tle TLE) SubPoint(t time.Time) (lat, lon, alt float64) {
+	p := tle.ecef(t)
+
+	e2 := flattening * (2 - flattening)
+	r := math.Hypot(p[0], p[1])
+
+	lon = atan2(p[1], p[0])
+	lat = atan2(p[2], r*(1-e2))
+	for i := 0; i < 5; i++ {
+		n := earthRadius / math.Sqrt(1-e2*sin(lat)*sin(lat))
+		alt = r/cos(lat) - n
+		lat = atan2(p[2], r*(1-e2*n/(n+alt)))
+	}
+
+	return lat, lon, alt
+}
+
+// ecef returns the position (km) of the satellite at time t in the
+// earth centered, earth fixed frame.
+func (tle TLE) ecef(t time.Time) [3]float64 {
+	x, y, z := tle.eci(t)
+
+	theta := gmst(t)
+
+	return [3]float64{
+		x*cos(theta) + y*sin(theta),
+		-x*sin(theta) + y*cos(theta),
+		z,
+	}
+}
+
+// eci returns the position (km) of the satellite at time t in the
+// earth centered inertial frame.
+func (tle TLE) eci(t time.Time) (x, y, z float64) {
+
+	dt := t.Sub(tle.Epoch).Hours() / 24 // days
+
+	i := tle.Inclination
+	e := tle.Eccentricity
+
+	// recover the semi-major axis from the (Kozai) mean motion as
+	// done by SGP4
+	n0 := tle.MeanMotion * 2 * math.Pi / 86400 // rad/s
+	k := 0.75 * j2 * (3*cos(i)*cos(i) - 1) / math.Pow(1-e*e, 1.5)
+	a1 := math.Cbrt(mu / (n0 * n0))
+	d1 := k * math.Pow(earthRadius/a1, 2)
+	a0 := a1 * (1 - d1/3 - d1*d1 - 134*d1*d1*d1/81)
+	d0 := k * math.Pow(earthRadius/a0, 2)
+	a := a0 / (1 - d0)
+	n := n0 / (1 + d0)
+
+	// secular drift of the ascending node and the perigee (deg/day)
+	p := a * (1 - e*e)
+	drift := 1.5 * j2 * math.Pow(earthRadius/p, 2) * deg(n) * 86400
+	raan := tle.RAAN - drift*cos(i)*dt
+	argp := tle.ArgPerigee + drift*(2-2.5*sin(i)*sin(i))*dt
+
+	// the orbit decays with the mean motion increasing
+	m := tle.MeanAnomaly + 360*(tle.MeanMotion*dt+tle.MeanMotionDot/2*dt*dt)
+	if nt := tle.MeanMotion + tle.MeanMotionDot*dt; nt > 0 {
+		a *= math.Pow(tle.MeanMotion/nt, 2.0/3)
+	}
+
+	// solve Kepler's equation for the eccentric anomaly
+	ma := rad(normalize(m))
+	ea := ma
+	for j := 0; j < 10; j++ {
+		ea -= (ea - e*math.Sin(ea) - ma) / (1 - e*math.Cos(ea))
+	}
+
+	nu := deg(math.Atan2(math.Sqrt(1-e*e)*math.Sin(ea), math.Cos(ea)-e))
+	r := a * (1 - e*math.Cos(ea))
+
+	// argument of latitude
+	u := argp + nu
+
+	x = r * (cos(raan)*cos(u) - sin(raan)*sin(u)*cos(i))
+	y = r * (sin(raan)*cos(u) + cos(raan)*sin(u)*cos(i))
+	z = r * sin(u) * sin(i)
+
+	return x, y, z
+}
+
+// geodeticToECEF converts a position on the WGS-84 ellipsoid (degrees,
+// km) into earth centered, earth fixed coordinates (km).
+func geodeticToECEF(lat, lon, alt float64) [3]float64 {
+	e2 := flattening * (2 - flattening)
+	n := earthRadius / math.Sqrt(1-e2*sin(lat)*sin(lat))
+
+	return [3]float64{
+		(n + alt) * cos(lat) * cos(lon),
+		(n + alt) * cos(lat) * sin(lon),
+		(n*(1-e2) + alt) * sin(lat),
+	}
+}
+
+// gmst returns the greenwich mean sidereal time in degrees
+func gmst(t time.Time) float64 {
+	j2000 := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := t.Sub(j2000).Hours() / 24
+	return normalize(280.46061837 + 360.98564736629*d)
+}
+
+// normalize maps an angle into the range 0...360°
+func normalize(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+// trigonometric helper functions working with degrees
+
+func rad(deg float64) float64 { return deg * math.Pi / 180 }
+func deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+func sin(d float64) float64      { return math.Sin(rad(d)) }
+func cos(d float64) float64      { return math.Cos(rad(d)) }
+func atan2(y, x float64) float64 { return deg(math.Atan2(y, x)) }
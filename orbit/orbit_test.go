@@ -0,0 +1,144 @@
+package orbit
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const issTLE = `ISS (ZARYA)
+1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927
+2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537`
+
+func parseISS(t *testing.T) TLE {
+	tle, err := ParseTLE(issTLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tle
+}
+
+func TestParseTLE(t *testing.T) {
+
+	tle := parseISS(t)
+
+	expEpoch := time.Date(2008, 9, 20, 12, 25, 40, 104000000, time.UTC)
+	if d := tle.Epoch.Sub(expEpoch); d < -time.Millisecond || d > time.Millisecond {
+		t.Fatalf("expected epoch %v, got %v", expEpoch, tle.Epoch)
+	}
+
+	tt := []struct {
+		name  string
+		value float64
+		exp   float64
+	}{
+		{"catalog number", float64(tle.CatalogNumber), 25544},
+		{"inclination", tle.Inclination, 51.6416},
+		{"raan", tle.RAAN, 247.4627},
+		{"eccentricity", tle.Eccentricity, 0.0006703},
+		{"argument of perigee", tle.ArgPerigee, 130.5360},
+		{"mean anomaly", tle.MeanAnomaly, 325.0288},
+		{"mean motion", tle.MeanMotion, 15.72125391},
+		{"mean motion derivative", tle.MeanMotionDot, -0.00004364},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if math.Abs(tc.value-tc.exp) > 1e-9 {
+				t.Fatalf("expected %v, got %v", tc.exp, tc.value)
+			}
+		})
+	}
+
+	if tle.Name != "ISS (ZARYA)" {
+		t.Fatalf("expected name ISS (ZARYA), got %s", tle.Name)
+	}
+}
+
+func TestParseTLEInvalid(t *testing.T) {
+
+	lines := strings.Split(issTLE, "\n")
+
+	tt := []struct {
+		name string
+		tle  string
+	}{
+		{"empty", ""},
+		{"single line", lines[1]},
+		{"checksum mismatch", lines[1] + "\n" + strings.Replace(lines[2], "51.6416", "51.6417", 1)},
+		{"too short", lines[1] + "\n" + lines[2][:60]},
+		{"swapped lines", lines[2] + "\n" + lines[1]},
+		{"catalog numbers differ", lines[1] + "\n" + "2 25545  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563538"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseTLE(tc.tle); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestSubPoint(t *testing.T) {
+
+	tle := parseISS(t)
+
+	// at the epoch, the ISS is close to the northernmost point of its orbit
+	lat, lon, alt := tle.SubPoint(tle.Epoch)
+	if math.Abs(lat-51.5) > 0.2 || math.Abs(lon-160.1) > 0.2 {
+		t.Fatalf("expected sub-point 51.5°/160.1°, got %.2f°/%.2f°", lat, lon)
+	}
+
+	// the sub-point never exceeds the inclination and the altitude
+	// stays within the (nearly circular) orbit
+	for ts := tle.Epoch; ts.Before(tle.Epoch.Add(time.Hour * 24)); ts = ts.Add(time.Minute) {
+		lat, _, alt = tle.SubPoint(ts)
+		if math.Abs(lat) > tle.Inclination+0.3 {
+			t.Fatalf("%v: latitude %.2f° exceeds the inclination", ts, lat)
+		}
+		if alt < 330 || alt > 380 {
+			t.Fatalf("%v: unexpected altitude %.1fkm", ts, alt)
+		}
+	}
+}
+
+func TestLookAngles(t *testing.T) {
+
+	tle := parseISS(t)
+	ts := tle.Epoch.Add(time.Minute * 42)
+
+	lat, lon, alt := tle.SubPoint(ts)
+
+	tt := []struct {
+		name   string
+		lat    float64
+		lon    float64
+		minEl  float64
+		maxEl  float64
+		maxRng float64
+	}{
+		{"below the satellite", lat, lon, 89.5, 90, alt + 1},
+		{"opposite side of the earth", -lat, normalize(lon+360) - 180, -90, -80, 14000},
+		{"near the horizon", lat - 20, lon, -5, 5, 2500},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, el, rng := tle.LookAngles(ts, tc.lat, tc.lon, 0)
+			if el < tc.minEl || el > tc.maxEl {
+				t.Fatalf("expected elevation between %.1f° and %.1f°, got %.2f°", tc.minEl, tc.maxEl, el)
+			}
+			if rng > tc.maxRng {
+				t.Fatalf("expected range below %.0fkm, got %.0fkm", tc.maxRng, rng)
+			}
+		})
+	}
+
+	// north of the satellite, the antenna points south
+	az, _, _ := tle.LookAngles(ts, lat+5, lon, 0)
+	if math.Abs(az-180) > 2 {
+		t.Fatalf("expected azimuth of 180°, got %.2f°", az)
+	}
+}
@@ -0,0 +1,147 @@
+package orbit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLE contains the mean orbital elements of a satellite as published in
+// the NORAD two-line element set format.
+type TLE struct {
+	Name          string
+	CatalogNumber int
+	Epoch         time.Time
+	// Inclination, RAAN (right ascension of the ascending node),
+	// ArgPerigee and MeanAnomaly are given in degrees
+	Inclination  float64
+	RAAN         float64
+	Eccentricity float64
+	ArgPerigee   float64
+	MeanAnomaly  float64
+	// MeanMotion in revolutions per day
+	MeanMotion float64
+	// MeanMotionDot is the first derivative of the mean motion in
+	// revolutions per day². Note that the TLE contains half this value.
+	MeanMotionDot float64
+}
+
+// ParseTLE parses a two-line element set. The element set may be
+// preceded by a line with the name of the satellite (three-line format).
+// The checksums of both lines are verified.
+func ParseTLE(s string) (TLE, error) {
+
+	lines := []string{}
+	for _, l := range strings.Split(strings.TrimSpace(s), "\n") {
+		l = strings.TrimRight(l, " \r\t")
+		if len(l) > 0 {
+			lines = append(lines, l)
+		}
+	}
+
+	tle := TLE{}
+
+	switch len(lines) {
+	case 2:
+	case 3:
+		tle.Name = strings.TrimSpace(strings.TrimPrefix(lines[0], "0 "))
+		lines = lines[1:]
+	default:
+		return TLE{}, fmt.Errorf("invalid tle (expected 2 or 3 lines, got %d)", len(lines))
+	}
+
+	l1, l2 := lines[0], lines[1]
+
+	for i, l := range []string{l1, l2} {
+		if len(l) < 69 {
+			return TLE{}, fmt.Errorf("invalid tle line %d (too short)", i+1)
+		}
+		if l[0] != byte('1'+i) {
+			return TLE{}, fmt.Errorf("invalid tle line %d (wrong line number)", i+1)
+		}
+		if checksum(l[:68]) != int(l[68]-'0') {
+			return TLE{}, fmt.Errorf("invalid tle line %d (checksum mismatch)", i+1)
+		}
+	}
+
+	p := parser{}
+
+	tle.CatalogNumber = p.int(l1[2:7])
+	if n := p.int(l2[2:7]); p.err == nil && n != tle.CatalogNumber {
+		return TLE{}, fmt.Errorf("invalid tle (catalog numbers %d and %d differ)", tle.CatalogNumber, n)
+	}
+
+	year := p.int(l1[18:20])
+	day := p.float(l1[20:32])
+	tle.MeanMotionDot = 2 * p.float(l1[33:43])
+
+	tle.Inclination = p.float(l2[8:16])
+	tle.RAAN = p.float(l2[17:25])
+	tle.Eccentricity = p.float("." + strings.TrimSpace(l2[26:33]))
+	tle.ArgPerigee = p.float(l2[34:42])
+	tle.MeanAnomaly = p.float(l2[43:51])
+	tle.MeanMotion = p.float(l2[52:63])
+
+	if p.err != nil {
+		return TLE{}, fmt.Errorf("invalid tle (%v)", p.err)
+	}
+
+	if tle.MeanMotion <= 0 {
+		return TLE{}, fmt.Errorf("invalid tle (mean motion %v)", tle.MeanMotion)
+	}
+
+	// two digit years 57...99 belong to the 20th century
+	if year < 57 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+	tle.Epoch = time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC).
+		Add(time.Duration((day - 1) * 24 * float64(time.Hour)))
+
+	return tle, nil
+}
+
+// checksum returns the modulo 10 checksum of a tle line. Digits count
+// with their value, minus signs count as 1.
+func checksum(l string) int {
+	sum := 0
+	for _, c := range l {
+		switch {
+		case c >= '0' && c <= '9':
+			sum += int(c - '0')
+		case c == '-':
+			sum++
+		}
+	}
+	return sum % 10
+}
+
+// parser converts the fixed width fields of a tle. The first error is
+// kept; subsequent conversions are ignored.
+type parser struct {
+	err error
+}
+
+func (p *parser) int(s string) int {
+	if p.err != nil {
+		return 0
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		p.err = err
+	}
+	return v
+}
+
+func (p *parser) float(s string) float64 {
+	if p.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		p.err = err
+	}
+	return v
+}
@@ -0,0 +1,98 @@
+// Package compact implements a compact binary encoding of the heading
+// updates which the hub broadcasts to its websocket clients. It is used
+// instead of JSON if the client negotiates the subprotocol
+// hub.WsCompactSubprotocol, e.g. for high rate telemetry to many clients.
+//
+// A frame has a fixed size of 12 bytes (big endian):
+//
+//	offset  size  field
+//	0       1     frame type (1: heading)
+//	1       1     rotator id (1...255), announced in the add events
+//	2       2     azimuth (signed)
+//	4       2     azimuth preset (signed)
+//	6       2     elevation (signed)
+//	8       2     elevation preset (signed)
+//	10      1     speed
+//	11      1     flags (reserved, 0)
+package compact
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// FrameSize is the size of an encoded heading in bytes.
+const FrameSize = 12
+
+// MaxID is the highest rotator id which can be encoded. Rotators without
+// an id (0) can't be encoded.
+const MaxID = math.MaxUint8
+
+// frameHeading is the type of frames containing a heading.
+const frameHeading byte = 1
+
+// EncodeHeading returns the frame containing the heading h of the rotator
+// with the given id. An error is returned if the id or a value of the
+// heading can't be represented.
+func EncodeHeading(id int, h rotator.Heading) ([]byte, error) {
+	if id < 1 || id > MaxID {
+		return nil, fmt.Errorf("invalid rotator id %d", id)
+	}
+
+	values := []struct {
+		name  string
+		value int
+	}{
+		{"azimuth", h.Azimuth},
+		{"azimuth preset", h.AzPreset},
+		{"elevation", h.Elevation},
+		{"elevation preset", h.ElPreset},
+	}
+
+	b := make([]byte, FrameSize)
+	b[0] = frameHeading
+	b[1] = byte(id)
+	for i, v := range values {
+		if v.value < math.MinInt16 || v.value > math.MaxInt16 {
+			return nil, fmt.Errorf("%s %d out of range", v.name, v.value)
+		}
+		binary.BigEndian.PutUint16(b[2+i*2:], uint16(int16(v.value)))
+	}
+	if h.Speed < 0 || h.Speed > math.MaxUint8 {
+		return nil, fmt.Errorf("speed %d out of range", h.Speed)
+	}
+	b[10] = byte(h.Speed)
+
+	return b, nil
+}
+
+// DecodeHeading returns the rotator id and the heading contained in the
+// frame b.
+func DecodeHeading(b []byte) (int, rotator.Heading, error) {
+	if len(b) != FrameSize {
+		return 0, rotator.Heading{}, fmt.Errorf("invalid frame size %d", len(b))
+	}
+	if b[0] != frameHeading {
+		return 0, rotator.Heading{}, fmt.Errorf("unknown frame type %d", b[0])
+	}
+	if b[1] == 0 {
+		return 0, rotator.Heading{}, fmt.Errorf("invalid rotator id 0")
+	}
+
+	value := func(offset int) int {
+		return int(int16(binary.BigEndian.Uint16(b[offset:])))
+	}
+
+	h := rotator.Heading{
+		Azimuth:   value(2),
+		AzPreset:  value(4),
+		Elevation: value(6),
+		ElPreset:  value(8),
+		Speed:     int(b[10]),
+	}
+
+	return int(b[1]), h, nil
+}
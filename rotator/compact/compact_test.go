@@ -0,0 +1,140 @@
+package compact
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+func TestRoundTrip(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		id      int
+		heading rotator.Heading
+	}{
+		{"zero", 1, rotator.Heading{}},
+		{"azimuth", 2, rotator.Heading{Azimuth: 123, AzPreset: 270, Speed: 4}},
+		{"overlap", 3, rotator.Heading{Azimuth: 450, AzPreset: 449}},
+		{"az/el", 4, rotator.Heading{Azimuth: 359, AzPreset: 0, Elevation: 45, ElPreset: 90, Speed: 1}},
+		{"negative", 5, rotator.Heading{Azimuth: -180, AzPreset: -1, Elevation: -5, ElPreset: -90}},
+		{"max id", MaxID, rotator.Heading{Azimuth: 180}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := EncodeHeading(tc.id, tc.heading)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b) != FrameSize {
+				t.Fatalf("expected %d bytes, got %d", FrameSize, len(b))
+			}
+			id, h, err := DecodeHeading(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tc.id || h != tc.heading {
+				t.Fatalf("expected %d / %+v, got %d / %+v", tc.id, tc.heading, id, h)
+			}
+		})
+	}
+}
+
+func TestEncodeHeadingErrors(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		id      int
+		heading rotator.Heading
+	}{
+		{"id 0", 0, rotator.Heading{}},
+		{"id too large", MaxID + 1, rotator.Heading{}},
+		{"azimuth too large", 1, rotator.Heading{Azimuth: 40000}},
+		{"elevation preset too small", 1, rotator.Heading{ElPreset: -40000}},
+		{"negative speed", 1, rotator.Heading{Speed: -1}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := EncodeHeading(tc.id, tc.heading); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestDecodeHeadingErrors(t *testing.T) {
+
+	valid, err := EncodeHeading(1, rotator.Heading{Azimuth: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknownType := append([]byte{}, valid...)
+	unknownType[0] = 2
+	noID := append([]byte{}, valid...)
+	noID[1] = 0
+
+	tt := []struct {
+		name  string
+		frame []byte
+	}{
+		{"empty", nil},
+		{"too short", valid[:FrameSize-1]},
+		{"too long", append(append([]byte{}, valid...), 0)},
+		{"unknown type", unknownType},
+		{"no id", noID},
+		{"json", []byte(`{"name":"heading"}`)},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := DecodeHeading(tc.frame); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+// BenchmarkEncoding compares the compact encoding of a heading update
+// with the JSON event which is sent otherwise.
+func BenchmarkEncoding(b *testing.B) {
+
+	h := rotator.Heading{Azimuth: 123, AzPreset: 270, Elevation: 45, ElPreset: 60, Speed: 4}
+
+	// mirrors hub.Event for a heading update without importing the hub
+	type event struct {
+		Name        string          `json:"name,omitempty"`
+		RotatorName string          `json:"rotator_name,omitempty"`
+		RotatorID   int             `json:"rotator_id,omitempty"`
+		Heading     rotator.Heading `json:"heading,omitempty"`
+	}
+
+	b.Run("compact", func(b *testing.B) {
+		b.ReportAllocs()
+		var size int
+		for i := 0; i < b.N; i++ {
+			frame, err := EncodeHeading(1, h)
+			if err != nil {
+				b.Fatal(err)
+			}
+			size = len(frame)
+		}
+		b.ReportMetric(float64(size), "bytes/msg")
+	})
+
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		var size int
+		for i := 0; i < b.N; i++ {
+			data, err := json.Marshal(event{"heading", "myRotator", 1, h})
+			if err != nil {
+				b.Fatal(err)
+			}
+			size = len(data)
+		}
+		b.ReportMetric(float64(size), "bytes/msg")
+	})
+}
@@ -9,7 +9,10 @@ import (
 )
 
 // Dummy is the implementation of a Dummy rotator which can be used
-// for testing purposes
+// for testing purposes and demos. It simulates the movement of a real
+// rotator: after SetAzimuth / SetElevation the heading moves with the
+// configured speed (deg/sec) towards the preset, respecting the limits
+// and the overlap. An event is emitted on every update of the heading.
 type Dummy struct {
 	sync.RWMutex
 	eventHandler   func(rotator.Rotator, rotator.Heading)
@@ -28,6 +31,7 @@ type Dummy struct {
 	hasElevation   bool
 	azSpeed        float32
 	elSpeed        float32
+	speed          int
 	ticker         *time.Ticker
 	tickerInterval float32 //ms
 	closeCh        chan struct{}
@@ -45,6 +49,7 @@ type Dummy struct {
 // elevationMax: 180,
 // azSpeed: 8, (deg/sec)
 // elSpeed: 5, (deg/sec)
+// speed: rotator.SpeedMax
 func New(options ...func(*Dummy)) (*Dummy, error) {
 
 	r := &Dummy{
@@ -53,6 +58,7 @@ func New(options ...func(*Dummy)) (*Dummy, error) {
 		elevationMax:   180,
 		azSpeed:        8,
 		elSpeed:        5,
+		speed:          rotator.SpeedMax,
 		tickerInterval: 100,
 		closeCh:        make(chan struct{}),
 	}
@@ -150,12 +156,15 @@ func (r *Dummy) SetAzimuth(az int) error {
 		return nil
 	}
 
-	if az > r.azimuthMax {
-		az = r.azimuthMax
-	}
+	// limits overlapping 0° are handled below
+	if r.azimuthMin <= r.azimuthMax {
+		if az > r.azimuthMax {
+			az = r.azimuthMax
+		}
 
-	if az < r.azimuthMin {
-		az = r.azimuthMin
+		if az < r.azimuthMin {
+			az = r.azimuthMin
+		}
 	}
 
 	abs := math.Abs(float64(r.azimuthMax - r.azimuthMin))
@@ -240,6 +249,43 @@ func (r *Dummy) SetElevation(el int) error {
 	return nil
 }
 
+// HasSpeed returns a boolean value indicating if the speed of this
+// rotator can be set
+func (r *Dummy) HasSpeed() bool {
+	return true
+}
+
+// Speed returns the current speed level of the rotator
+func (r *Dummy) Speed() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.speed
+}
+
+// SetSpeed sets the speed level of the rotator. Allowed values are
+// rotator.SpeedMin ... rotator.SpeedMax. Values outside of this range
+// will be clipped. At rotator.SpeedMax the rotator turns with the
+// configured azimuth and elevation speed.
+func (r *Dummy) SetSpeed(speed int) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if speed > rotator.SpeedMax {
+		speed = rotator.SpeedMax
+	}
+
+	if speed < rotator.SpeedMin {
+		speed = rotator.SpeedMin
+	}
+
+	r.speed = speed
+	if r.eventHandler != nil {
+		r.eventHandler(r, r.serialize().Heading)
+	}
+
+	return nil
+}
+
 // StopAzimuth stops horizontal rotator movement
 func (r *Dummy) StopAzimuth() error {
 	r.Lock()
@@ -295,6 +341,7 @@ func (r *Dummy) serialize() rotator.Object {
 			AzPreset:  int(r.azPreset),
 			Elevation: int(r.elevation),
 			ElPreset:  int(r.elPreset),
+			Speed:     r.speed,
 		},
 		Config: rotator.Config{
 			HasAzimuth:   r.hasAzimuth,
@@ -304,6 +351,7 @@ func (r *Dummy) serialize() rotator.Object {
 			AzimuthStop:  r.azimuthStop,
 			ElevationMax: r.elevationMax,
 			ElevationMin: r.elevationMin,
+			HasSpeed:     true,
 		},
 	}
 
@@ -321,7 +369,7 @@ func (r *Dummy) updateAzimuth() {
 
 	if r.hasAzimuth {
 		changed := r.calcNewAzHeading()
-		if changed {
+		if changed && r.eventHandler != nil {
 			r.eventHandler(r, r.serialize().Heading)
 		}
 	}
@@ -331,12 +379,17 @@ func (r *Dummy) updateElevation() {
 
 	if r.hasElevation {
 		changed := r.calcNewElHeading()
-		if changed {
+		if changed && r.eventHandler != nil {
 			r.eventHandler(r, r.serialize().Heading)
 		}
 	}
 }
 
+// speedFactor scales the simulated speed according to the speed level
+func (r *Dummy) speedFactor() float32 {
+	return float32(r.speed) / rotator.SpeedMax
+}
+
 func (r *Dummy) calcNewElHeading() bool {
 
 	if int(r.elevation) == int(r.elPreset) {
@@ -346,7 +399,7 @@ func (r *Dummy) calcNewElHeading() bool {
 	moveCCW := false
 	moveCW := false
 
-	delta := r.elSpeed / (r.tickerInterval / 10)
+	delta := r.elSpeed / (r.tickerInterval / 10) * r.speedFactor()
 
 	min := float32(r.elevationMin)
 	max := float32(r.elevationMax)
@@ -377,7 +430,7 @@ func (r *Dummy) calcNewAzHeading() bool {
 	moveCCW := false
 	moveCW := false
 
-	delta := r.azSpeed / (r.tickerInterval / 10)
+	delta := r.azSpeed / (r.tickerInterval / 10) * r.speedFactor()
 
 	abs := math.Abs(float64(r.azimuthMax - r.azimuthMin))
 
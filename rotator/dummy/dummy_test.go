@@ -0,0 +1,117 @@
+package dummy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+func TestMovement(t *testing.T) {
+
+	events := make(chan rotator.Heading, 100)
+
+	r, err := New(AzimuthSpeed(100), ElevationSpeed(50), HasElevation(true),
+		EventHandler(func(_ rotator.Rotator, h rotator.Heading) {
+			select {
+			case events <- h:
+			default:
+			}
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if err := r.SetAzimuth(90); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetElevation(30); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(time.Second * 3)
+	for r.Azimuth() != 90 || r.Elevation() != 30 {
+		select {
+		case <-events:
+		case <-timeout:
+			t.Fatalf("rotator didn't arrive (az: %d, el: %d)", r.Azimuth(), r.Elevation())
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		stop   func(r *Dummy) error
+		stopAz bool
+		stopEl bool
+	}{
+		{"stop", (*Dummy).Stop, true, true},
+		{"stop azimuth", (*Dummy).StopAzimuth, true, false},
+		{"stop elevation", (*Dummy).StopElevation, false, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// no event handler
+			r, err := New(AzimuthSpeed(20), ElevationSpeed(20), HasElevation(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			r.SetAzimuth(180)
+			r.SetElevation(90)
+			time.Sleep(time.Millisecond * 300)
+
+			if err := tc.stop(r); err != nil {
+				t.Fatal(err)
+			}
+
+			if stopped := r.AzPreset() == r.Azimuth(); stopped != tc.stopAz {
+				t.Fatalf("expected azimuth stopped: %v, got preset %d at %d", tc.stopAz, r.AzPreset(), r.Azimuth())
+			}
+			if stopped := r.ElPreset() == r.Elevation(); stopped != tc.stopEl {
+				t.Fatalf("expected elevation stopped: %v, got preset %d at %d", tc.stopEl, r.ElPreset(), r.Elevation())
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+
+	tt := []struct {
+		name       string
+		azimuthMin int
+		azimuthMax int
+		azimuth    int
+		expPreset  int
+	}{
+		{"within limits", 0, 360, 180, 180},
+		{"above max", 0, 360, 400, 360},
+		{"overlap", 0, 450, 420, 420},
+		{"below min", 10, 350, 5, 10},
+		{"limits overlapping 0°, within", 300, 60, 20, 20},
+		{"limits overlapping 0°, closer to min", 300, 60, 250, 300},
+		{"limits overlapping 0°, closer to max", 300, 60, 100, 60},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := New(AzimuthMin(tc.azimuthMin), AzimuthMax(tc.azimuthMax), AzimuthSpeed(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			if err := r.SetAzimuth(tc.azimuth); err != nil {
+				t.Fatal(err)
+			}
+			if r.AzPreset() != tc.expPreset {
+				t.Fatalf("expected preset %d, got %d", tc.expPreset, r.AzPreset())
+			}
+		})
+	}
+}
@@ -20,27 +20,63 @@ type ElevationPut struct {
 	Elevation *int `json:"elevation"`
 }
 
+// AzElPut sets the azimuth and the elevation of a rotator with a
+// single request.
+type AzElPut struct {
+	Azimuth   *int `json:"azimuth"`
+	Elevation *int `json:"elevation"`
+}
+
+// JogPut moves a rotator by the given number of degrees relative to its
+// current heading.
+type JogPut struct {
+	AzimuthDelta   *int `json:"azimuth_delta"`
+	ElevationDelta *int `json:"elevation_delta"`
+}
+
+type SpeedGet struct {
+	HasSpeed bool `json:"has_speed"`
+	Speed    int  `json:"speed"`
+}
+
+type SpeedPut struct {
+	Speed *int `json:"speed"`
+}
+
 type Object struct {
-	Name    string  `json:"name"`
+	Name string `json:"name"`
+	Metadata
 	Heading Heading `json:"heading"`
 	Config  Config  `json:"config"`
 }
 
+// Metadata describes a rotator for the users (e.g. to label it as
+// "Tribander @ Tower 1"). All fields are optional and omitted from the
+// JSON representation if empty, so that older clients are not affected.
+type Metadata struct {
+	Description string   `json:"description,omitempty"`
+	Location    string   `json:"location,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
 type Heading struct {
 	Azimuth   int `json:"azimuth"`
 	AzPreset  int `json:"az_preset"`
 	Elevation int `json:"elevation"`
 	ElPreset  int `json:"el_preset"`
+	Speed     int `json:"speed"`
 }
 
 type Objects map[string]Object
 
 type Config struct {
-	HasAzimuth   bool `json:"has_azimuth"`
-	AzimuthMin   int  `json:"azimuth_min"`
-	AzimuthMax   int  `json:"azimuth_max"`
-	AzimuthStop  int  `json:"azimuth_stop"`
-	HasElevation bool `json:"has_elevation"`
-	ElevationMin int  `json:"elevation_min"`
-	ElevationMax int  `json:"elevation_max"`
+	HasAzimuth    bool `json:"has_azimuth"`
+	AzimuthMin    int  `json:"azimuth_min"`
+	AzimuthMax    int  `json:"azimuth_max"`
+	AzimuthStop   int  `json:"azimuth_stop"`
+	AzimuthOffset int  `json:"azimuth_offset"`
+	HasElevation  bool `json:"has_elevation"`
+	ElevationMin  int  `json:"elevation_min"`
+	ElevationMax  int  `json:"elevation_max"`
+	HasSpeed      bool `json:"has_speed"`
 }
@@ -0,0 +1,97 @@
+package offset
+
+import (
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// Rotator wraps a rotator whose azimuth is referenced to another
+// direction than North (e.g. 0° = South). It translates between the raw
+// azimuth of the wrapped rotator and the azimuth referenced to North,
+// which is reported to and commanded by the clients:
+//
+//	azimuth = raw azimuth + offset (mod 360)
+//
+// The limits in the configuration (AzimuthMin, AzimuthMax, AzimuthStop)
+// remain the raw values of the wrapped rotator; the offset is reported
+// as AzimuthOffset. Azimuths commanded within an overlap are mapped to
+// the lower mechanical position.
+type Rotator struct {
+	rotator.Rotator
+	offset int
+}
+
+// New returns a Rotator which translates the azimuth of r by offset
+// degrees. A rotator referenced to South (raw 0° = 180°) requires an
+// offset of 180.
+func New(r rotator.Rotator, offset int) *Rotator {
+	return &Rotator{
+		Rotator: r,
+		offset:  normalize(offset),
+	}
+}
+
+// EventHandler returns an event handler for the wrapped rotator which
+// translates the headings by offset degrees before passing them to h.
+func EventHandler(offset int, h rotator.EventHandler) rotator.EventHandler {
+	offset = normalize(offset)
+	return func(r rotator.Rotator, heading rotator.Heading) {
+		h(r, translate(heading, offset))
+	}
+}
+
+// Azimuth returns the current azimuth referenced to North.
+func (r *Rotator) Azimuth() int {
+	return normalize(r.Rotator.Azimuth() + r.offset)
+}
+
+// AzPreset returns the azimuth preset referenced to North.
+func (r *Rotator) AzPreset() int {
+	return normalize(r.Rotator.AzPreset() + r.offset)
+}
+
+// SetAzimuth commands the wrapped rotator to the azimuth az (referenced
+// to North).
+func (r *Rotator) SetAzimuth(az int) error {
+	cfg := r.Rotator.Serialize().Config
+	return r.Rotator.SetAzimuth(raw(az, r.offset, cfg.AzimuthMin, cfg.AzimuthMax))
+}
+
+// Serialize returns the configuration of the wrapped rotator and the
+// heading referenced to North.
+func (r *Rotator) Serialize() rotator.Object {
+	obj := r.Rotator.Serialize()
+	obj.Heading = translate(obj.Heading, r.offset)
+	obj.Config.AzimuthOffset = r.offset
+	return obj
+}
+
+// translate returns the heading h of the wrapped rotator referenced
+// to North.
+func translate(h rotator.Heading, offset int) rotator.Heading {
+	h.Azimuth = normalize(h.Azimuth + offset)
+	h.AzPreset = normalize(h.AzPreset + offset)
+	return h
+}
+
+// raw returns the raw azimuth of a rotator with the limits min and max
+// for the azimuth az referenced to North. If the rotator can reach the
+// heading in two positions (overlap), the lower one is returned.
+func raw(az, offset, min, max int) int {
+	r := az - offset
+	for r < min {
+		r += 360
+	}
+	for r > max && r-360 >= min {
+		r -= 360
+	}
+	return r
+}
+
+// normalize returns az in the range [0, 360).
+func normalize(az int) int {
+	az %= 360
+	if az < 0 {
+		az += 360
+	}
+	return az
+}
@@ -0,0 +1,144 @@
+package offset
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+func TestNormalize(t *testing.T) {
+
+	tt := []struct {
+		az   int
+		want int
+	}{
+		{0, 0},
+		{359, 359},
+		{360, 0},
+		{450, 90},
+		{-1, 359},
+		{-180, 180},
+		{-720, 0},
+	}
+
+	for _, tc := range tt {
+		if got := normalize(tc.az); got != tc.want {
+			t.Errorf("normalize(%d): expected %d, got %d", tc.az, tc.want, got)
+		}
+	}
+}
+
+func TestRaw(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		az     int
+		offset int
+		min    int
+		max    int
+		want   int
+	}{
+		{"no offset", 90, 0, 0, 360, 90},
+		{"south center", 30, 180, 0, 360, 210},
+		{"south center north", 0, 180, 0, 360, 180},
+		{"south center across 0°", 200, 180, 0, 360, 20},
+		{"negative offset", 350, -20, 0, 360, 10},
+		{"negative offset across 360°", 10, -20, 0, 360, 30},
+		{"overlap takes lower position", 190, 180, 0, 450, 10},
+		{"negative limits", 0, 180, -180, 180, -180},
+		{"negative limits across 0°", 170, 180, -180, 180, -10},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := raw(tc.az, normalize(tc.offset), tc.min, tc.max)
+			if got != tc.want {
+				t.Fatalf("expected %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+
+	tt := []struct {
+		name   string
+		offset int
+		raw    rotator.Heading
+		want   rotator.Heading
+	}{
+		{"no offset", 0,
+			rotator.Heading{Azimuth: 90, AzPreset: 100, Elevation: 10},
+			rotator.Heading{Azimuth: 90, AzPreset: 100, Elevation: 10}},
+		{"south center", 180,
+			rotator.Heading{Azimuth: 210, AzPreset: 170, Elevation: 10},
+			rotator.Heading{Azimuth: 30, AzPreset: 350, Elevation: 10}},
+		{"overlap", 180,
+			rotator.Heading{Azimuth: 400, AzPreset: 450},
+			rotator.Heading{Azimuth: 220, AzPreset: 270}},
+		{"negative offset", -20,
+			rotator.Heading{Azimuth: 10, AzPreset: 30},
+			rotator.Heading{Azimuth: 350, AzPreset: 10}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := translate(tc.raw, normalize(tc.offset))
+			if got != tc.want {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRotator(t *testing.T) {
+
+	events := make(chan rotator.Heading, 100)
+	hdlr := EventHandler(180, func(_ rotator.Rotator, h rotator.Heading) {
+		select {
+		case events <- h:
+		default:
+		}
+	})
+
+	d, err := dummy.New(dummy.AzimuthSpeed(100), dummy.EventHandler(hdlr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	r := New(d, 180)
+
+	if err := r.SetAzimuth(30); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(time.Second * 5)
+	for r.Azimuth() != 30 {
+		select {
+		case h := <-events:
+			if h.AzPreset != 30 {
+				t.Fatalf("expected azimuth preset 30 in event, got %d", h.AzPreset)
+			}
+		case <-timeout:
+			t.Fatalf("rotator didn't arrive (az: %d)", r.Azimuth())
+		}
+	}
+
+	if d.Azimuth() != 210 {
+		t.Fatalf("expected raw azimuth 210, got %d", d.Azimuth())
+	}
+
+	obj := r.Serialize()
+	if obj.Heading.Azimuth != 30 {
+		t.Errorf("expected serialized azimuth 30, got %d", obj.Heading.Azimuth)
+	}
+	if obj.Config.AzimuthOffset != 180 {
+		t.Errorf("expected azimuth offset 180, got %d", obj.Config.AzimuthOffset)
+	}
+	if obj.Config.AzimuthMax != d.Serialize().Config.AzimuthMax {
+		t.Errorf("expected unchanged azimuth limits")
+	}
+}
@@ -0,0 +1,32 @@
+package polling
+
+import (
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// Interval is a functional option to set the interval in which the
+// heading of the rotator is polled.
+func Interval(d time.Duration) func(*Rotator) {
+	return func(p *Rotator) {
+		p.interval = d
+	}
+}
+
+// EventHandler is a functional option to set a callback which is
+// executed whenever the polled heading changes.
+func EventHandler(h rotator.EventHandler) func(*Rotator) {
+	return func(p *Rotator) {
+		p.eventHandler = h
+	}
+}
+
+// Query is a functional option to set the function which reads the
+// current heading from the hardware. It takes precedence over the
+// Query method of the wrapped rotator.
+func Query(q func() (rotator.Heading, error)) func(*Rotator) {
+	return func(p *Rotator) {
+		p.query = q
+	}
+}
@@ -0,0 +1,161 @@
+// Package polling provides a wrapper for rotators whose drivers have to
+// poll the hardware for their position. Instead of implementing the
+// polling loop themselves, drivers only have to read the current heading
+// from the hardware.
+package polling
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// Querier is implemented by rotators which can read their current
+// heading from the hardware.
+type Querier interface {
+	Query() (rotator.Heading, error)
+}
+
+// Rotator wraps a rotator and polls its heading every interval. The
+// last heading is cached and reported by Azimuth, Elevation and
+// Serialize. Whenever the heading changes, the event handler is called,
+// so that the hub can broadcast the new heading.
+//
+// If the wrapped rotator implements Querier, the heading is read with
+// Query; otherwise the heading reported by Serialize is polled.
+type Rotator struct {
+	rotator.Rotator
+	sync.RWMutex
+	query        func() (rotator.Heading, error)
+	interval     time.Duration
+	eventHandler rotator.EventHandler
+	heading      *rotator.Heading
+	closeCh      chan struct{}
+	doneCh       chan struct{}
+	closer       sync.Once
+}
+
+// New returns a Rotator which polls the heading of r until ctx is done
+// or Close is called.
+// Default settings are:
+// interval: 1sec.
+func New(ctx context.Context, r rotator.Rotator, opts ...func(*Rotator)) (*Rotator, error) {
+
+	p := &Rotator{
+		Rotator:  r,
+		interval: time.Second,
+		closeCh:  make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+
+	p.query = func() (rotator.Heading, error) {
+		return r.Serialize().Heading, nil
+	}
+
+	if q, ok := r.(Querier); ok {
+		p.query = q.Query
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.interval <= 0 {
+		return nil, fmt.Errorf("invalid polling interval %v", p.interval)
+	}
+
+	go p.run(ctx)
+
+	return p, nil
+}
+
+// run polls the heading every interval until ctx is done or the
+// Rotator is closed. Since this function contains an endless loop,
+// it should be executed in a go routine.
+func (p *Rotator) run(ctx context.Context) {
+	defer close(p.doneCh)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.poll()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-p.closeCh:
+			return
+		}
+	}
+}
+
+// poll queries the heading once and calls the event handler if it has
+// changed. A failed query is skipped; the heading will be queried again
+// after the next interval.
+func (p *Rotator) poll() {
+	h, err := p.query()
+	if err != nil {
+		return
+	}
+
+	p.Lock()
+	changed := p.heading == nil || *p.heading != h
+	p.heading = &h
+	eventHandler := p.eventHandler
+	p.Unlock()
+
+	if changed && eventHandler != nil {
+		eventHandler(p, h)
+	}
+}
+
+// Close stops polling and closes the wrapped rotator.
+func (p *Rotator) Close() {
+	p.closer.Do(func() {
+		close(p.closeCh)
+		<-p.doneCh
+		p.Rotator.Close()
+	})
+}
+
+// Heading returns the last polled heading and false if the heading
+// has not been polled yet.
+func (p *Rotator) Heading() (rotator.Heading, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	if p.heading == nil {
+		return rotator.Heading{}, false
+	}
+	return *p.heading, true
+}
+
+// Azimuth returns the last polled azimuth.
+func (p *Rotator) Azimuth() int {
+	if h, ok := p.Heading(); ok {
+		return h.Azimuth
+	}
+	return p.Rotator.Azimuth()
+}
+
+// Elevation returns the last polled elevation.
+func (p *Rotator) Elevation() int {
+	if h, ok := p.Heading(); ok {
+		return h.Elevation
+	}
+	return p.Rotator.Elevation()
+}
+
+// Serialize returns the configuration of the wrapped rotator and the
+// last polled heading.
+func (p *Rotator) Serialize() rotator.Object {
+	obj := p.Rotator.Serialize()
+	if h, ok := p.Heading(); ok {
+		obj.Heading = h
+	}
+	return obj
+}
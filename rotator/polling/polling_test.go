@@ -0,0 +1,162 @@
+package polling
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+// fakeHardware returns the headings in the given order; once all
+// headings have been returned, the last one is repeated.
+type fakeHardware struct {
+	sync.Mutex
+	headings []rotator.Heading
+	errs     []error
+	queries  int
+}
+
+func (f *fakeHardware) query() (rotator.Heading, error) {
+	f.Lock()
+	defer f.Unlock()
+	i := f.queries
+	if i >= len(f.headings) {
+		i = len(f.headings) - 1
+	}
+	f.queries++
+	var err error
+	if i < len(f.errs) {
+		err = f.errs[i]
+	}
+	return f.headings[i], err
+}
+
+func TestPolling(t *testing.T) {
+
+	tt := []struct {
+		name      string
+		headings  []rotator.Heading
+		errs      []error
+		expEvents []rotator.Heading
+	}{
+		{"unchanged heading",
+			[]rotator.Heading{{Azimuth: 90}, {Azimuth: 90}},
+			nil,
+			[]rotator.Heading{{Azimuth: 90}}},
+		{"moving",
+			[]rotator.Heading{{Azimuth: 90}, {Azimuth: 95, Elevation: 10}, {Azimuth: 95, Elevation: 10}},
+			nil,
+			[]rotator.Heading{{Azimuth: 90}, {Azimuth: 95, Elevation: 10}}},
+		{"failed query",
+			[]rotator.Heading{{Azimuth: 90}, {}, {Azimuth: 100}},
+			[]error{nil, fmt.Errorf("timeout")},
+			[]rotator.Heading{{Azimuth: 90}, {Azimuth: 100}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hw := &fakeHardware{headings: tc.headings, errs: tc.errs}
+			evCh := make(chan rotator.Heading, 10)
+
+			d, err := dummy.New(dummy.Name("r1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := New(context.Background(), d,
+				Interval(time.Millisecond*10),
+				Query(hw.query),
+				EventHandler(func(r rotator.Rotator, h rotator.Heading) {
+					if r.Name() != "r1" {
+						t.Errorf("unexpected rotator %s", r.Name())
+					}
+					evCh <- h
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+
+			for _, exp := range tc.expEvents {
+				select {
+				case h := <-evCh:
+					if h != exp {
+						t.Fatalf("expected heading %+v, got %+v", exp, h)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("timeout waiting for heading %+v", exp)
+				}
+			}
+
+			last := tc.expEvents[len(tc.expEvents)-1]
+			if p.Azimuth() != last.Azimuth || p.Elevation() != last.Elevation {
+				t.Fatalf("expected cached heading %d°/%d°, got %d°/%d°",
+					last.Azimuth, last.Elevation, p.Azimuth(), p.Elevation())
+			}
+			if h := p.Serialize().Heading; h != last {
+				t.Fatalf("expected serialized heading %+v, got %+v", last, h)
+			}
+
+			select {
+			case h := <-evCh:
+				t.Fatalf("unexpected event %+v", h)
+			case <-time.After(time.Millisecond * 50):
+			}
+		})
+	}
+}
+
+func TestPollingStop(t *testing.T) {
+
+	hw := &fakeHardware{headings: []rotator.Heading{{Azimuth: 90}}}
+
+	d, err := dummy.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p, err := New(ctx, d, Interval(time.Millisecond*10), Query(hw.query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	cancel()
+
+	select {
+	case <-p.doneCh:
+	case <-time.After(time.Second):
+		t.Fatal("polling not stopped")
+	}
+
+	hw.Lock()
+	n := hw.queries
+	hw.Unlock()
+
+	time.Sleep(time.Millisecond * 50)
+
+	hw.Lock()
+	defer hw.Unlock()
+	if hw.queries != n {
+		t.Fatalf("expected no more queries after the context is done, got %d", hw.queries-n)
+	}
+}
+
+func TestPollingInvalidInterval(t *testing.T) {
+
+	d, err := dummy.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if _, err := New(context.Background(), d, Interval(0)); err == nil {
+		t.Fatal("expected error for invalid interval")
+	}
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"github.com/dh1tw/remoteRotator/discovery"
+)
+
+// Endpoint is a remote rotator which has been discovered via mDNS.
+type Endpoint struct {
+	Name         string
+	Host         string
+	Port         int
+	HasAzimuth   bool
+	HasElevation bool
+}
+
+// Options returns the functional options to create a Proxy for the
+// endpoint with New.
+func (e Endpoint) Options() []func(*Proxy) {
+	return []func(*Proxy){Host(e.Host), Port(e.Port)}
+}
+
+// Discover browses the local network via mDNS for hubs and returns
+// the rotators they advertise.
+func Discover() ([]Endpoint, error) {
+	entries, err := discovery.LookupRotators()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]Endpoint, 0, len(entries))
+	for _, e := range entries {
+		host := e.Host
+		if e.AddrV4 != nil {
+			host = e.AddrV4.String()
+		}
+		endpoints = append(endpoints, Endpoint{
+			Name:         e.Name,
+			Host:         host,
+			Port:         e.Port,
+			HasAzimuth:   e.HasAzimuth,
+			HasElevation: e.HasElevation,
+		})
+	}
+
+	return endpoints, nil
+}
@@ -1,6 +1,12 @@
 package proxy
 
-import "github.com/dh1tw/remoteRotator/rotator"
+import (
+	"net/http"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/hub"
+	"github.com/dh1tw/remoteRotator/rotator"
+)
 
 // Host is a functional option to set IP / dns name of the remote Rotators host.
 func Host(host string) func(*Proxy) {
@@ -25,9 +31,225 @@ func DoneCh(ch chan struct{}) func(*Proxy) {
 }
 
 // EventHandler sets a callback function through which the proxy rotator
-// will report Events
+// will report Events. Further callbacks can be registered with
+// Proxy.AddListener. The handler may call Proxy.Close.
 func EventHandler(h func(rotator.Rotator, rotator.Heading)) func(*Proxy) {
 	return func(r *Proxy) {
 		r.eventHandler = h
 	}
 }
+
+// HubEventHandler sets a callback function through which the proxy reports
+// when rotators are added to (hub.AddRotator) or removed from
+// (hub.RemoveRotator) the remote hub. Right after connecting, an
+// add event is reported for every rotator on the remote hub. The handler
+// is also called with a hub.PresetReached event when the proxied rotator
+// has reached the heading commanded through the remote hub, with a
+// hub.RotatorStopped event when it has come to rest after a stop and with a
+// hub.RotatorFault event when its controller reports a fault or has
+// recovered from it. The events are reported in order from a separate
+// go routine; the handler may call Proxy.Close.
+func HubEventHandler(h func(hub.Event)) func(*Proxy) {
+	return func(r *Proxy) {
+		r.hubHandler = h
+	}
+}
+
+// ConnectionStateHandler sets a callback function through which the proxy
+// reports each transition of the connection to the remote rotator
+// (Connected, Disconnected, Reconnecting). The transitions are reported in
+// order from a separate go routine and independently of the EventHandler.
+// The handler may call Proxy.Close, e.g. on Disconnected.
+func ConnectionStateHandler(h func(ConnState)) func(*Proxy) {
+	return func(r *Proxy) {
+		r.stateHandler = h
+	}
+}
+
+// WaitForConnection is a functional option to let the commands which move
+// the rotator (SetAzimuth, SetElevation, SetSpeed, ...) wait up to d for
+// the websocket connection to be reestablished (see Reconnect) if it is
+// down. If the connection isn't reestablished in time, ErrNotConnected is
+// returned. By default, these commands are rejected immediately. Stop
+// commands are always sent to the remote rotator.
+func WaitForConnection(d time.Duration) func(*Proxy) {
+	return func(r *Proxy) {
+		r.connWait = d
+	}
+}
+
+// Reconnect is a functional option to enable the automatic reconnection
+// to the remote rotator when the websocket connection drops. While
+// reconnecting, the proxy retries with an exponential backoff. The DoneCh
+// will only be closed after the proxy has been closed.
+func Reconnect(enabled bool) func(*Proxy) {
+	return func(r *Proxy) {
+		r.reconnect = enabled
+	}
+}
+
+// MaxBackoff is a functional option to set the maximum time between two
+// reconnection attempts. The backoff must be positive.
+func MaxBackoff(d time.Duration) func(*Proxy) {
+	return func(r *Proxy) {
+		r.maxBackoff = d
+	}
+}
+
+// PingInterval is a functional option to set the period in which pings
+// are sent to the remote rotator to keep the websocket connection alive
+// (e.g. through NATs and firewalls). Must be less than the PongTimeout.
+func PingInterval(d time.Duration) func(*Proxy) {
+	return func(r *Proxy) {
+		r.pingInterval = d
+	}
+}
+
+// PongTimeout is a functional option to set the time after which the
+// websocket connection is considered dead if no pong (or any other
+// message) has been received from the remote rotator. The connection
+// is then closed and, if enabled, re-established.
+func PongTimeout(d time.Duration) func(*Proxy) {
+	return func(r *Proxy) {
+		r.pongTimeout = d
+	}
+}
+
+// UseTLS is a functional option to connect to the remote rotator through
+// HTTPS and secure websockets (wss://).
+func UseTLS(enabled bool) func(*Proxy) {
+	return func(r *Proxy) {
+		r.useTLS = enabled
+	}
+}
+
+// SkipTLSVerify is a functional option to accept any certificate presented
+// by the remote rotator (e.g. self-signed certificates in a lab). This
+// makes the connection susceptible to man-in-the-middle attacks.
+func SkipTLSVerify(skip bool) func(*Proxy) {
+	return func(r *Proxy) {
+		r.skipTLSVerify = skip
+	}
+}
+
+// AuthToken is a functional option to set the token which is required
+// by the remote rotator's hub to access its API and websocket.
+func AuthToken(token string) func(*Proxy) {
+	return func(r *Proxy) {
+		r.authToken = token
+	}
+}
+
+// InfoTimeout is a functional option to set the maximum time for
+// retrieving the rotator's information from the remote hub (e.g. over
+// high latency links). The default is 3 seconds. If the context passed
+// to NewWithContext has a deadline, the deadline takes precedence.
+func InfoTimeout(d time.Duration) func(*Proxy) {
+	return func(r *Proxy) {
+		r.infoTimeout = d
+	}
+}
+
+// DialTimeout is a functional option to set the maximum time for
+// establishing the websocket connection to the remote hub (including
+// reconnects). The default is 5 seconds; 0 disables the timeout. If the
+// context passed to NewWithContext has an earlier deadline, the deadline
+// takes precedence.
+func DialTimeout(d time.Duration) func(*Proxy) {
+	return func(r *Proxy) {
+		r.dialTimeout = d
+	}
+}
+
+// WsPath is a functional option to set the path of the remote hub's
+// websocket endpoint (e.g. /shack1/ws if the hub is mounted under a sub
+// path by a reverse proxy). The default is /ws.
+func WsPath(path string) func(*Proxy) {
+	return func(r *Proxy) {
+		r.wsPath = path
+	}
+}
+
+// WsCompression is a functional option to negotiate permessage-deflate
+// compression on the websocket connection to the remote hub. The hub has
+// to support compression as well (see hub.WsCompression).
+func WsCompression(enabled bool) func(*Proxy) {
+	return func(r *Proxy) {
+		r.wsCompression = enabled
+	}
+}
+
+// CompactHeadings is a functional option to request the compact binary
+// encoding of the heading updates (see hub.WsCompactSubprotocol). Hubs
+// which don't support it keep sending JSON.
+func CompactHeadings(enabled bool) func(*Proxy) {
+	return func(r *Proxy) {
+		r.compactHeadings = enabled
+	}
+}
+
+// InfoPath is a functional option to set the path from which the
+// rotator's information is retrieved. The default is /api/rotators.
+func InfoPath(path string) func(*Proxy) {
+	return func(r *Proxy) {
+		r.infoPath = path
+	}
+}
+
+// HTTPClient is a functional option to set the http client through which
+// the proxy talks to the remote hub's API (e.g. with a transport going
+// through a http proxy). The TLS settings of the proxy are not applied
+// to a custom client.
+func HTTPClient(c *http.Client) func(*Proxy) {
+	return func(r *Proxy) {
+		r.httpClient = c
+	}
+}
+
+// RotatorName is a functional option to select the rotator with the given
+// name if the remote hub provides more than one rotator.
+func RotatorName(name string) func(*Proxy) {
+	return func(r *Proxy) {
+		r.rotatorName = name
+	}
+}
+
+// Logger is a functional option to route the proxy's log messages
+// (connection losses, reconnects and errors) through l.
+func Logger(l hub.Logger) func(*Proxy) {
+	return func(r *Proxy) {
+		r.logger = l
+	}
+}
+
+// Deadband is a functional option to suppress the events of heading
+// changes up to the given number of degrees (e.g. of noisy analog
+// rotators). A change is emitted once it exceeds the deadband relative
+// to the last emitted heading or, if smaller, once no further heading
+// has been received for a second. Changes of the presets and the speed
+// are always emitted. The heading returned by Azimuth and Elevation is
+// always up to date.
+func Deadband(deg int) func(*Proxy) {
+	return func(r *Proxy) {
+		r.deadband = deg
+	}
+}
+
+// StrictProtocol is a functional option to refuse the connection to a
+// remote hub which speaks a different version of the wire protocol (see
+// hub.ProtocolVersion) with ErrProtocolMismatch. By default, a mismatch
+// is only logged.
+func StrictProtocol(strict bool) func(*Proxy) {
+	return func(r *Proxy) {
+		r.strictProtocol = strict
+	}
+}
+
+// SkipRangeCheck is a functional option to forward headings to the remote
+// rotator even if they exceed its limits. By default SetAzimuth and
+// SetElevation return a RangeError in this case.
+func SkipRangeCheck(skip bool) func(*Proxy) {
+	return func(r *Proxy) {
+		r.skipRangeCheck = skip
+	}
+}
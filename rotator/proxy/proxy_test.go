@@ -0,0 +1,1978 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/hub"
+	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer returns a server which mimics the HTTP and websocket
+// endpoints of a hub with a single rotator. The first dropConns websocket
+// connections will be closed right after the upgrade.
+func newTestServer(t *testing.T, dropConns int, useTLS bool) (*httptest.Server, string, int) {
+	var mu sync.Mutex
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/rotators", func(w http.ResponseWriter, req *http.Request) {
+		objs := rotator.Objects{
+			"myRotator": rotator.Object{
+				Name:   "myRotator",
+				Config: rotator.Config{HasAzimuth: true, AzimuthMax: 360},
+			},
+		}
+		json.NewEncoder(w).Encode(objs)
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, req *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		mu.Lock()
+		drop := dropConns > 0
+		dropConns--
+		mu.Unlock()
+		if drop {
+			return
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	srv := httptest.NewUnstartedServer(mux)
+	if useTLS {
+		srv.StartTLS()
+	} else {
+		srv.Start()
+	}
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return srv, host, port
+}
+
+func TestProxyCloseNoLeak(t *testing.T) {
+
+	srv, host, port := newTestServer(t, 0, false)
+	defer srv.Close()
+
+	base := runtime.NumGoroutine()
+
+	for i := 0; i < 20; i++ {
+		r, err := New(Host(host), Port(port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Name() != "myRotator" {
+			t.Fatalf("expected name myRotator, got %s", r.Name())
+		}
+		r.Close()
+		// Close is idempotent
+		r.Close()
+	}
+
+	// the server side connection handlers and idle http
+	// connections need some time to shut down
+	srv.CloseClientConnections()
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+
+	deadline := time.Now().Add(time.Second * 2)
+	for runtime.NumGoroutine() > base {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			n := runtime.Stack(buf, true)
+			t.Fatalf("goroutine leak: expected <= %d, got %d\n%s",
+				base, runtime.NumGoroutine(), buf[:n])
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestProxyCloseConcurrently(t *testing.T) {
+
+	srv, host, port := newTestServer(t, 0, false)
+	defer srv.Close()
+
+	r, err := New(Host(host), Port(port), Reconnect(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-r.Done():
+		t.Fatal("done channel closed while connected")
+	default:
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Close()
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-r.Done():
+	case <-time.After(time.Second):
+		t.Fatal("done channel not closed after Close")
+	}
+
+	if r.Connected() {
+		t.Fatal("expected proxy to be disconnected")
+	}
+}
+
+func TestNewWithContext(t *testing.T) {
+
+	// the listener accepts connections but never responds, like a
+	// remote host behind a half-open network
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+
+	tt := []struct {
+		name   string
+		ctx    func() (context.Context, context.CancelFunc)
+		expErr error
+	}{
+		{"deadline", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), time.Millisecond*100)
+		}, context.DeadlineExceeded},
+		{"cancel", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(time.Millisecond*100, cancel)
+			return ctx, cancel
+		}, context.Canceled},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := tc.ctx()
+			defer cancel()
+
+			start := time.Now()
+			_, err := NewWithContext(ctx, Host(addr.IP.String()), Port(addr.Port))
+			if err != tc.expErr {
+				t.Fatalf("expected %v, got %v", tc.expErr, err)
+			}
+			if d := time.Since(start); d > time.Second {
+				t.Fatalf("connection attempt not aborted in time (%v)", d)
+			}
+		})
+	}
+}
+
+func TestInfoTimeout(t *testing.T) {
+
+	srv, host, port := newTestServer(t, 0, false)
+	defer srv.Close()
+
+	// the remote hub responds after 200ms
+	slow := &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			time.Sleep(time.Millisecond * 200)
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+
+	tt := []struct {
+		name    string
+		timeout time.Duration
+		expErr  bool
+	}{
+		{"too short", time.Millisecond * 50, true},
+		{"long enough", time.Second * 2, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := New(Host(host), Port(port), HTTPClient(slow), InfoTimeout(tc.timeout))
+			if tc.expErr {
+				if err == nil {
+					r.Close()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Close()
+		})
+	}
+}
+
+func TestProxyWsSubprotocol(t *testing.T) {
+
+	tt := []struct {
+		name      string
+		supported []string
+		expErr    bool
+	}{
+		{"negotiated", []string{hub.WsSubprotocol}, false},
+		{"hub without negotiation", nil, false},
+		{"unsupported", []string{"rotator-cbor-v1"}, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			selected := make(chan string, 1)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/rotators", func(w http.ResponseWriter, req *http.Request) {
+				json.NewEncoder(w).Encode(rotator.Objects{"r1": rotator.Object{Name: "r1"}})
+			})
+			mux.HandleFunc("/ws", func(w http.ResponseWriter, req *http.Request) {
+				// a misbehaving hub selects a subprotocol
+				// which has not been requested
+				u := websocket.Upgrader{Subprotocols: tc.supported}
+				var header http.Header
+				if len(tc.supported) > 0 && tc.supported[0] != hub.WsSubprotocol {
+					u.Subprotocols = nil
+					header = http.Header{"Sec-Websocket-Protocol": tc.supported}
+				}
+				conn, err := u.Upgrade(w, req, header)
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				selected <- conn.Subprotocol()
+				for {
+					if _, _, err := conn.ReadMessage(); err != nil {
+						return
+					}
+				}
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			addr := srv.Listener.Addr().(*net.TCPAddr)
+			r, err := New(Host(addr.IP.String()), Port(addr.Port))
+			if tc.expErr {
+				if err == nil {
+					r.Close()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			exp := ""
+			if len(tc.supported) > 0 {
+				exp = tc.supported[0]
+			}
+			if p := <-selected; p != exp {
+				t.Fatalf("expected subprotocol %q, got %q", exp, p)
+			}
+		})
+	}
+}
+
+func TestProxyProtocolVersion(t *testing.T) {
+
+	srv, host, port := newTestServer(t, 0, false)
+	defer srv.Close()
+
+	tt := []struct {
+		name        string
+		version     string
+		strict      bool
+		expErr      error
+		expProtocol int
+	}{
+		{"current", strconv.Itoa(hub.ProtocolVersion), true, nil, hub.ProtocolVersion},
+		{"newer", "99", false, nil, 99},
+		{"newer strict", "99", true, ErrProtocolMismatch, 0},
+		{"unversioned", "", false, nil, 0},
+		{"unversioned strict", "", true, ErrProtocolMismatch, 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// the remote hub announces tc.version
+			client := &http.Client{
+				Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					resp, err := http.DefaultTransport.RoundTrip(req)
+					if err == nil && len(tc.version) > 0 {
+						resp.Header.Set(hub.ProtocolHeader, tc.version)
+					}
+					return resp, err
+				}),
+			}
+
+			r, err := New(Host(host), Port(port), HTTPClient(client), StrictProtocol(tc.strict))
+			if tc.expErr != nil {
+				if err != tc.expErr {
+					if err == nil {
+						r.Close()
+					}
+					t.Fatalf("expected error %v, got %v", tc.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			if v := r.RemoteProtocol(); v != tc.expProtocol {
+				t.Fatalf("expected remote protocol %d, got %d", tc.expProtocol, v)
+			}
+		})
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+
+	if _, err := New(DialTimeout(-time.Second)); err == nil {
+		t.Fatal("expected error for negative dial timeout")
+	}
+
+	// the websocket handshake never completes
+	release := make(chan struct{})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/rotators", func(w http.ResponseWriter, req *http.Request) {
+		objs := rotator.Objects{
+			"myRotator": rotator.Object{
+				Name:   "myRotator",
+				Config: rotator.Config{HasAzimuth: true, AzimuthMax: 360},
+			},
+		}
+		json.NewEncoder(w).Encode(objs)
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, req *http.Request) {
+		<-release
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	// the handler must return before the server can be closed
+	defer close(release)
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if _, err := New(Host(host), Port(port), DialTimeout(time.Millisecond*100)); err == nil {
+		t.Fatal("expected error")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("dial not aborted in time (%v)", d)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestProxyInvalidOptions(t *testing.T) {
+
+	tt := []struct {
+		name string
+		opts []func(*Proxy)
+	}{
+		{"no maximum backoff", []func(*Proxy){MaxBackoff(0)}},
+		{"negative maximum backoff", []func(*Proxy){MaxBackoff(-time.Second)}},
+		{"no ping interval", []func(*Proxy){PingInterval(0)}},
+		{"negative ping interval", []func(*Proxy){PingInterval(-time.Second)}},
+		{"no pong timeout", []func(*Proxy){PongTimeout(0)}},
+		{"pong timeout below ping interval", []func(*Proxy){
+			PingInterval(time.Second * 5), PongTimeout(time.Second * 2)}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := New(tc.opts...); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestProxyReconnect(t *testing.T) {
+
+	srv, host, port := newTestServer(t, 1, false)
+	defer srv.Close()
+
+	doneCh := make(chan struct{})
+	events := make(chan rotator.Heading, 10)
+	eh := func(r rotator.Rotator, h rotator.Heading) {
+		events <- h
+	}
+
+	r, err := New(Host(host), Port(port), DoneCh(doneCh),
+		EventHandler(eh), Reconnect(true), MaxBackoff(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first connection is dropped by the server; after reconnecting
+	// the proxy re-emits the current heading
+	select {
+	case <-events:
+	case <-doneCh:
+		t.Fatal("proxy gave up instead of reconnecting")
+	case <-time.After(time.Second * 5):
+		t.Fatal("timeout while waiting for reconnect")
+	}
+
+	r.Close()
+
+	select {
+	case <-doneCh:
+	case <-time.After(time.Second):
+		t.Fatal("doneCh not closed after Close")
+	}
+}
+
+func TestProxyConnectionState(t *testing.T) {
+
+	tt := []struct {
+		name      string
+		reconnect bool
+		expStates []ConnState
+	}{
+		{"without reconnect", false,
+			[]ConnState{Connected, Disconnected}},
+		{"with reconnect", true,
+			[]ConnState{Connected, Disconnected, Reconnecting, Connected, Disconnected}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// the server drops the first websocket connection
+			srv, host, port := newTestServer(t, 1, false)
+			defer srv.Close()
+
+			states := make(chan ConnState, 10)
+			r, err := New(Host(host), Port(port), Reconnect(tc.reconnect),
+				ConnectionStateHandler(func(s ConnState) { states <- s }))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, exp := range tc.expStates {
+				// the proxy is closed after it has reconnected
+				if tc.reconnect && i == len(tc.expStates)-1 {
+					r.Close()
+				}
+				select {
+				case s := <-states:
+					if s != exp {
+						t.Fatalf("expected state %d to be %s, got %s", i, exp, s)
+					}
+				case <-time.After(time.Second * 3):
+					t.Fatalf("timeout while waiting for state %s", exp)
+				}
+			}
+			r.Close()
+
+			if s := r.State(); s != Disconnected {
+				t.Fatalf("expected state %s, got %s", Disconnected, s)
+			}
+
+			select {
+			case s := <-states:
+				t.Fatalf("unexpected state %s", s)
+			default:
+			}
+		})
+	}
+}
+
+func TestProxyCloseFromHandler(t *testing.T) {
+
+	t.Run("connection state", func(t *testing.T) {
+		// the server drops the websocket connection
+		srv, host, port := newTestServer(t, 1, false)
+		defer srv.Close()
+
+		proxyCh := make(chan *Proxy, 1)
+		closed := make(chan struct{})
+		r, err := New(Host(host), Port(port),
+			ConnectionStateHandler(func(s ConnState) {
+				if s == Disconnected {
+					(<-proxyCh).Close()
+					close(closed)
+				}
+			}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		proxyCh <- r
+
+		select {
+		case <-closed:
+		case <-time.After(time.Second * 3):
+			t.Fatal("Close called from the handler didn't return")
+		}
+	})
+
+	t.Run("heading", func(t *testing.T) {
+		h, err := hub.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer h.Close()
+
+		d, err := dummy.New(dummy.Name("myRotator"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+		if err := h.AddRotator(d); err != nil {
+			t.Fatal(err)
+		}
+
+		srv := httptest.NewServer(h.Handler())
+		defer srv.Close()
+		addr := srv.Listener.Addr().(*net.TCPAddr)
+
+		proxyCh := make(chan *Proxy, 1)
+		closed := make(chan struct{})
+		var once sync.Once
+		r, err := New(Host(addr.IP.String()), Port(addr.Port),
+			EventHandler(func(p rotator.Rotator, _ rotator.Heading) {
+				once.Do(func() {
+					(<-proxyCh).Close()
+					close(closed)
+				})
+			}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		proxyCh <- r
+
+		deadline := time.Now().Add(time.Second)
+		for len(h.Clients()) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("proxy not connected")
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		h.Broadcast(rotator.Heading{Azimuth: 42})
+
+		select {
+		case <-closed:
+		case <-time.After(time.Second * 3):
+			t.Fatal("Close called from the handler didn't return")
+		}
+	})
+}
+
+func TestProxyConnected(t *testing.T) {
+
+	// the server drops the first websocket connection
+	srv, host, port := newTestServer(t, 1, false)
+	defer srv.Close()
+
+	before := time.Now()
+
+	doneCh := make(chan struct{})
+	r, err := New(Host(host), Port(port), DoneCh(doneCh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.LastUpdate().Before(before) {
+		t.Fatalf("expected last update after %v, got %v", before, r.LastUpdate())
+	}
+
+	select {
+	case <-doneCh:
+	case <-time.After(time.Second):
+		t.Fatal("timeout while waiting for the connection to drop")
+	}
+
+	if r.Connected() {
+		t.Fatal("expected disconnected proxy")
+	}
+
+	// the next connection is kept open
+	r2, err := New(Host(host), Port(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r2.Connected() {
+		t.Fatal("expected connected proxy")
+	}
+	r2.Close()
+	if r2.Connected() {
+		t.Fatal("expected disconnected proxy after Close")
+	}
+}
+
+func TestProxyNotConnected(t *testing.T) {
+
+	tt := []struct {
+		name      string
+		reconnect bool
+		wait      time.Duration
+		expErr    bool
+	}{
+		{"rejected", false, 0, true},
+		{"wait too short", true, time.Millisecond * 100, true},
+		{"wait for reconnect", true, time.Second * 3, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// the server drops the first websocket connection
+			srv, host, port := newTestServer(t, 1, false)
+			defer srv.Close()
+
+			states := make(chan ConnState, 10)
+			r, err := New(Host(host), Port(port), Reconnect(tc.reconnect),
+				WaitForConnection(tc.wait),
+				ConnectionStateHandler(func(s ConnState) { states <- s }))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			// wait until the connection has been dropped
+			for s := range states {
+				if s == Disconnected {
+					break
+				}
+			}
+
+			// the test server doesn't implement the commands, so an
+			// error other than ErrNotConnected means that the command
+			// has been sent
+			err = r.SetAzimuth(90)
+			if tc.expErr && err != ErrNotConnected {
+				t.Fatalf("expected ErrNotConnected, got %v", err)
+			}
+			if !tc.expErr && (err == ErrNotConnected || !r.Connected()) {
+				t.Fatalf("expected command to be sent after reconnecting, got %v", err)
+			}
+
+			// stop commands are never rejected
+			if err := r.Stop(); err == ErrNotConnected {
+				t.Fatal("unexpected ErrNotConnected for stop command")
+			}
+		})
+	}
+}
+
+func TestProxyTLS(t *testing.T) {
+
+	srv, host, port := newTestServer(t, 0, true)
+	defer srv.Close()
+
+	tt := []struct {
+		name   string
+		opts   []func(*Proxy)
+		expErr bool
+	}{
+		{"plain http", []func(*Proxy){}, true},
+		{"self-signed certificate", []func(*Proxy){UseTLS(true)}, true},
+		{"skip verify", []func(*Proxy){UseTLS(true), SkipTLSVerify(true)}, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]func(*Proxy){Host(host), Port(port)}, tc.opts...)
+			r, err := New(opts...)
+			if tc.expErr {
+				if err == nil {
+					r.Close()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Close()
+		})
+	}
+}
+
+func TestProxyAgainstHub(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("myRotator"), dummy.HasElevation(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(Host(host), Port(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.Name() != "myRotator" {
+		t.Fatalf("expected name myRotator, got %s", r.Name())
+	}
+	if !r.HasElevation() {
+		t.Fatal("expected rotator with elevation")
+	}
+	if r.Speed() != d.Speed() {
+		t.Fatalf("expected speed %d, got %d", d.Speed(), r.Speed())
+	}
+}
+
+func TestProxyPaths(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("myRotator"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	// the hub is mounted under a sub path, like behind a reverse proxy
+	mux := http.NewServeMux()
+	mux.Handle("/shack1/", http.StripPrefix("/shack1", h.Handler()))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name   string
+		opts   []func(*Proxy)
+		expErr bool
+	}{
+		{"default paths", nil, true},
+		{"info path only", []func(*Proxy){InfoPath("/shack1/api/rotators")}, true},
+		{"sub paths", []func(*Proxy){InfoPath("/shack1/api/rotators"), WsPath("/shack1/ws")}, false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]func(*Proxy){Host(host), Port(port)}, tc.opts...)
+			r, err := New(opts...)
+			if tc.expErr {
+				if err == nil {
+					r.Close()
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if !r.Connected() {
+				t.Fatal("expected connected proxy")
+			}
+		})
+	}
+}
+
+func TestProxyCompression(t *testing.T) {
+
+	h, err := hub.New(hub.WsCompression(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("myRotator"), dummy.HasElevation(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan rotator.Heading, 10)
+	eh := func(r rotator.Rotator, h rotator.Heading) {
+		events <- h
+	}
+
+	r, err := New(Host(host), Port(port), WsCompression(true), EventHandler(eh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// wait until the proxy has been registered as websocket client
+	deadline := time.Now().Add(time.Second)
+	for len(h.Clients()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("proxy not connected")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	exp := rotator.Heading{Azimuth: 123, AzPreset: 200, Elevation: 45, ElPreset: 60, Speed: 3}
+	h.Broadcast(exp)
+
+	select {
+	case heading := <-events:
+		if heading != exp {
+			t.Fatalf("expected heading %+v, got %+v", exp, heading)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout while waiting for heading")
+	}
+}
+
+func TestProxyCompactHeadings(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	for _, name := range []string{"r1", "r2"} {
+		d, err := dummy.New(dummy.Name(name), dummy.HasElevation(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+		if err := h.AddRotator(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+
+	events := make(chan rotator.Heading, 10)
+	eh := func(r rotator.Rotator, h rotator.Heading) {
+		events <- h
+	}
+
+	r, err := New(Host(addr.IP.String()), Port(addr.Port), RotatorName("r2"),
+		CompactHeadings(true), EventHandler(eh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// wait until the proxy has been registered as websocket client
+	deadline := time.Now().Add(time.Second)
+	for len(h.Clients()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("proxy not connected")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// the headings of other rotators are ignored
+	h.BroadcastHeading("r1", rotator.Heading{Azimuth: 10})
+
+	exp := rotator.Heading{Azimuth: 123, AzPreset: 200, Elevation: 45, ElPreset: 60, Speed: 3}
+	h.BroadcastHeading("r2", exp)
+
+	select {
+	case heading := <-events:
+		if heading != exp {
+			t.Fatalf("expected heading %+v, got %+v", exp, heading)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout while waiting for heading")
+	}
+}
+
+func TestProxyElevationOnly(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// 45° per tick
+	d, err := dummy.New(dummy.Name("el"), dummy.HasAzimuth(false),
+		dummy.HasElevation(true), dummy.ElevationSpeed(450),
+		dummy.EventHandler(func(r rotator.Rotator, heading rotator.Heading) {
+			h.Broadcast(heading)
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan rotator.Heading, 10)
+	eh := func(r rotator.Rotator, h rotator.Heading) {
+		events <- h
+	}
+
+	r, err := New(Host(host), Port(port), EventHandler(eh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.HasAzimuth() || !r.HasElevation() {
+		t.Fatal("expected elevation only rotator")
+	}
+
+	if err := r.SetAzimuth(90); err == nil {
+		t.Fatal("expected error when setting the azimuth")
+	}
+	if err := r.SetAzEl(90, 45); err == nil {
+		t.Fatal("expected error when setting azimuth and elevation")
+	}
+
+	// a heading with a phantom azimuth doesn't trigger an event
+	h.Broadcast(rotator.Heading{Azimuth: 123, AzPreset: 123, Speed: 4})
+
+	if err := r.SetElevation(45); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		select {
+		case heading := <-events:
+			if heading.Azimuth != 0 || heading.AzPreset != 0 {
+				t.Fatalf("unexpected event with azimuth %d", heading.Azimuth)
+			}
+			if heading.Elevation == 45 {
+				if r.Azimuth() != 0 {
+					t.Fatalf("unexpected azimuth %d", r.Azimuth())
+				}
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout while waiting for the elevation")
+		}
+	}
+}
+
+func TestProxyMultipleRotators(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	for _, name := range []string{"r1", "r2"} {
+		d, err := dummy.New(dummy.Name(name), dummy.HasElevation(name == "r2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+		if err := h.AddRotator(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+
+	tt := []struct {
+		name         string
+		rotatorName  string
+		expErr       error
+		hasElevation bool
+	}{
+		{"no name", "", ErrMultipleRotators, false},
+		{"r1", "r1", nil, false},
+		{"r2", "r2", nil, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := New(Host(addr.IP.String()), Port(addr.Port), RotatorName(tc.rotatorName))
+			if err != tc.expErr {
+				t.Fatalf("expected error %v, got %v", tc.expErr, err)
+			}
+			if err != nil {
+				return
+			}
+			defer r.Close()
+
+			if r.Name() != tc.rotatorName {
+				t.Fatalf("expected name %s, got %s", tc.rotatorName, r.Name())
+			}
+			if r.HasElevation() != tc.hasElevation {
+				t.Fatalf("expected has elevation %v, got %v", tc.hasElevation, r.HasElevation())
+			}
+		})
+	}
+
+	if _, err := New(Host(addr.IP.String()), Port(addr.Port), RotatorName("r3")); err == nil {
+		t.Fatal("expected error for unknown rotator")
+	}
+}
+
+func TestListRotators(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	for _, name := range []string{"r2", "r1"} {
+		d, err := dummy.New(dummy.Name(name), dummy.HasElevation(name == "r2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+		if err := h.AddRotator(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+	opts := []func(*Proxy){Host(addr.IP.String()), Port(addr.Port)}
+
+	rotators, err := ListRotators(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotators) != 2 || rotators[0].Name != "r1" || rotators[1].Name != "r2" {
+		t.Fatalf("expected rotators r1 and r2, got %+v", rotators)
+	}
+	if !rotators[1].Config.HasElevation {
+		t.Fatal("expected r2 to have elevation")
+	}
+
+	// connect to the chosen rotator
+	r, err := New(append(opts, RotatorName(rotators[1].Name))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Name() != "r2" {
+		t.Fatalf("expected r2, got %s", r.Name())
+	}
+
+	if _, err := ListRotators(context.Background(), Host(addr.IP.String()), Port(1)); err == nil {
+		t.Fatal("expected error for unreachable hub")
+	}
+}
+
+func TestProxyHubEvents(t *testing.T) {
+
+	h, err := hub.New(hub.SettleTime(time.Millisecond * 50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	r1, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r1.Close()
+	if err := h.AddRotator(r1); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+
+	events := make(chan hub.Event, 10)
+	r, err := New(Host(addr.IP.String()), Port(addr.Port), RotatorName("r1"),
+		HubEventHandler(func(ev hub.Event) { events <- ev }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r2, err := dummy.New(dummy.Name("r2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name         string
+		action       func()
+		expEvent     hub.RotatorEvent
+		expRotator   string
+		expHasObject bool
+	}{
+		{"initial add", func() {}, hub.AddRotator, "r1", true},
+		{"add", func() { h.AddRotator(r2) }, hub.AddRotator, "r2", true},
+		{"remove", func() { h.RemoveRotator(r2) }, hub.RemoveRotator, "r2", false},
+		{"preset reached", func() { h.SetAzimuth("r1", 10) }, hub.PresetReached, "r1", false},
+		{"stopped", func() { h.Stop("r1") }, hub.RotatorStopped, "r1", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.action()
+			select {
+			case ev := <-events:
+				if ev.Name != tc.expEvent || ev.RotatorName != tc.expRotator {
+					t.Fatalf("expected %s %s, got %s %s", tc.expEvent, tc.expRotator, ev.Name, ev.RotatorName)
+				}
+				if (ev.Rotator != nil) != tc.expHasObject {
+					t.Fatalf("expected rotator object %v, got %+v", tc.expHasObject, ev.Rotator)
+				}
+				if ev.Rotator != nil && ev.Rotator.Name != tc.expRotator {
+					t.Fatalf("expected rotator object %s, got %s", tc.expRotator, ev.Rotator.Name)
+				}
+			case <-time.After(time.Second * 2):
+				t.Fatal("timeout")
+			}
+		})
+	}
+}
+
+func TestProxyRefresh(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// the hub doesn't broadcast the changes of this rotator
+	d, err := dummy.New(dummy.Name("myRotator"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+
+	events := make(chan rotator.Heading, 10)
+	r, err := New(Host(addr.IP.String()), Port(addr.Port),
+		EventHandler(func(_ rotator.Rotator, h rotator.Heading) { events <- h }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if err := d.SetSpeed(rotator.SpeedMin); err != nil {
+		t.Fatal(err)
+	}
+	if r.Speed() == rotator.SpeedMin {
+		t.Fatal("expected stale speed before refresh")
+	}
+
+	// Refresh must be safe to call concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Refresh(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if r.Speed() != rotator.SpeedMin {
+		t.Fatalf("expected speed %d, got %d", rotator.SpeedMin, r.Speed())
+	}
+
+	select {
+	case h := <-events:
+		if h.Speed != rotator.SpeedMin {
+			t.Fatalf("expected event with speed %d, got %d", rotator.SpeedMin, h.Speed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected event")
+	}
+}
+
+func TestProxyPongTimeout(t *testing.T) {
+
+	block := make(chan struct{})
+	defer close(block)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/rotators", func(w http.ResponseWriter, req *http.Request) {
+		objs := rotator.Objects{
+			"myRotator": rotator.Object{Name: "myRotator"},
+		}
+		json.NewEncoder(w).Encode(objs)
+	})
+	// the connection stays open, but pings are never answered
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, req *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		<-block
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+
+	done := make(chan struct{})
+	r, err := New(Host(addr.IP.String()), Port(addr.Port), DoneCh(done),
+		PingInterval(time.Millisecond*20), PongTimeout(time.Millisecond*100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 2):
+		t.Fatal("dead connection not detected")
+	}
+}
+
+func TestProxyRangeCheck(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("myRotator"), dummy.AzimuthMax(450),
+		dummy.HasElevation(true), dummy.ElevationMax(90), dummy.AzimuthSpeed(0),
+		dummy.ElevationSpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name      string
+		skip      bool
+		azimuth   bool
+		value     int
+		expErr    bool
+		expPreset int
+	}{
+		{"azimuth within range", false, true, 400, false, 400},
+		{"azimuth out of range", false, true, 9999, true, 400},
+		{"negative azimuth", false, true, -10, true, 400},
+		{"elevation within range", false, false, 45, false, 45},
+		{"elevation out of range", false, false, 100, true, 45},
+		{"azimuth check skipped", true, true, 700, false, 450},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := New(Host(host), Port(port), SkipRangeCheck(tc.skip))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			set, preset := r.SetElevation, d.ElPreset
+			if tc.azimuth {
+				set, preset = r.SetAzimuth, d.AzPreset
+			}
+
+			err = set(tc.value)
+			if tc.expErr {
+				if _, ok := err.(*RangeError); !ok {
+					t.Fatalf("expected RangeError, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if preset() != tc.expPreset {
+				t.Fatalf("expected preset %d, got %d", tc.expPreset, preset())
+			}
+		})
+	}
+}
+
+func TestProxySetAzEl(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("myRotator"), dummy.AzimuthMax(450),
+		dummy.HasElevation(true), dummy.ElevationMax(90), dummy.AzimuthSpeed(0),
+		dummy.ElevationSpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var reqs []rotator.Request
+	h.SetRequestHandler(func(source string, req rotator.Request) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		reqs = append(reqs, req)
+		return true
+	})
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(Host(host), Port(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tt := []struct {
+		name       string
+		azimuth    int
+		elevation  int
+		expErr     bool
+		expReqs    int
+		expAzimuth int
+		expElev    int
+	}{
+		{"within range", 400, 45, false, 1, 400, 45},
+		{"azimuth out of range", 9999, 10, true, 0, 400, 45},
+		{"elevation out of range", 10, 100, true, 0, 400, 45},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mu.Lock()
+			reqs = nil
+			mu.Unlock()
+
+			err := r.SetAzEl(tc.azimuth, tc.elevation)
+			if tc.expErr {
+				if _, ok := err.(*RangeError); !ok {
+					t.Fatalf("expected RangeError, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(reqs) != tc.expReqs {
+				t.Fatalf("expected %d requests, got %d", tc.expReqs, len(reqs))
+			}
+			if tc.expReqs > 0 && (!reqs[0].HasAzimuth || !reqs[0].HasElevation) {
+				t.Fatalf("expected request with azimuth and elevation, got %+v", reqs[0])
+			}
+			if d.AzPreset() != tc.expAzimuth {
+				t.Fatalf("expected azimuth preset %d, got %d", tc.expAzimuth, d.AzPreset())
+			}
+			if d.ElPreset() != tc.expElev {
+				t.Fatalf("expected elevation preset %d, got %d", tc.expElev, d.ElPreset())
+			}
+		})
+	}
+}
+
+func TestProxyExecuteRequest(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("myRotator"), dummy.AzimuthMax(360),
+		dummy.HasElevation(true), dummy.ElevationMax(90), dummy.AzimuthSpeed(0),
+		dummy.ElevationSpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var reqs []rotator.Request
+	h.SetRequestHandler(func(source string, req rotator.Request) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		reqs = append(reqs, req)
+		return true
+	})
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(Host(host), Port(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	tt := []struct {
+		name     string
+		req      rotator.Request
+		expErr   bool
+		expReqs  int
+		expAz    int
+		expEl    int
+		expSpeed int
+	}{
+		{"azimuth, elevation and speed",
+			rotator.Request{HasAzimuth: true, Azimuth: 120, HasElevation: true, Elevation: 30, HasSpeed: true, Speed: 2},
+			false, 1, 120, 30, 2},
+		{"elevation out of range",
+			rotator.Request{HasAzimuth: true, Azimuth: 200, HasElevation: true, Elevation: 100},
+			true, 0, 120, 30, 2},
+		{"stop is never rejected",
+			rotator.Request{Stop: true, HasElevation: true, Elevation: 100},
+			false, 1, 0, 0, 2},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mu.Lock()
+			reqs = nil
+			mu.Unlock()
+
+			err := r.ExecuteRequest(tc.req)
+			if tc.expErr && err == nil {
+				t.Fatal("expected error")
+			}
+			if !tc.expErr && err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(reqs) != tc.expReqs {
+				t.Fatalf("expected %d requests, got %d", tc.expReqs, len(reqs))
+			}
+			if tc.expReqs > 0 && reqs[0].Name != "myRotator" {
+				t.Fatalf("expected request for myRotator, got %q", reqs[0].Name)
+			}
+			if d.AzPreset() != tc.expAz {
+				t.Fatalf("expected azimuth preset %d, got %d", tc.expAz, d.AzPreset())
+			}
+			if d.ElPreset() != tc.expEl {
+				t.Fatalf("expected elevation preset %d, got %d", tc.expEl, d.ElPreset())
+			}
+			if d.Speed() != tc.expSpeed {
+				t.Fatalf("expected speed %d, got %d", tc.expSpeed, d.Speed())
+			}
+		})
+	}
+}
+
+func TestProxyJog(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// the dummy doesn't move; jogs are relative to 0°
+	d, err := dummy.New(dummy.Name("myRotator"), dummy.AzimuthSpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(Host(host), Port(port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if err := r.JogAzimuth(-5); err != nil {
+		t.Fatal(err)
+	}
+	if d.AzPreset() != 355 {
+		t.Fatalf("expected azimuth preset 355°, got %d°", d.AzPreset())
+	}
+
+	// the rotator has no elevation
+	if err := r.JogElevation(5); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestProxyDeadband(t *testing.T) {
+
+	if _, err := New(Deadband(-1)); err == nil {
+		t.Fatal("expected error for negative deadband")
+	}
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("myRotator"), dummy.AzimuthSpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan rotator.Heading, 10)
+	eh := func(r rotator.Rotator, h rotator.Heading) {
+		events <- h
+	}
+
+	r, err := New(Host(host), Port(port), Deadband(3), EventHandler(eh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.Lock()
+	r.settleTime = time.Millisecond * 200
+	r.Unlock()
+
+	deadline := time.Now().Add(time.Second)
+	for len(h.Clients()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("proxy not connected")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	heading := func(az int) rotator.Heading {
+		return rotator.Heading{Azimuth: az, AzPreset: 200, Speed: d.Speed()}
+	}
+
+	expEvent := func(az int) {
+		select {
+		case h := <-events:
+			if h.Azimuth != az {
+				t.Fatalf("expected event with azimuth %d°, got %d°", az, h.Azimuth)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout while waiting for azimuth %d°", az)
+		}
+	}
+
+	// preset changed
+	h.Broadcast(heading(100))
+	expEvent(100)
+
+	// within the deadband
+	h.Broadcast(heading(101))
+	h.Broadcast(heading(102))
+	h.Broadcast(heading(104))
+	expEvent(104)
+
+	select {
+	case h := <-events:
+		t.Fatalf("unexpected event %+v", h)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	// the settled heading is eventually reported
+	h.Broadcast(heading(105))
+	expEvent(105)
+
+	if r.Azimuth() != 105 {
+		t.Fatalf("expected azimuth 105°, got %d°", r.Azimuth())
+	}
+}
+
+func TestProxyListeners(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	// the dummy doesn't report its own headings
+	d, err := dummy.New(dummy.Name("r1"), dummy.AzimuthSpeed(0),
+		dummy.EventHandler(func(rotator.Rotator, rotator.Heading) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+
+	// a slow event handler must not stall the listeners
+	block := make(chan struct{})
+	slow := func(rotator.Rotator, rotator.Heading) { <-block }
+
+	r, err := New(Host(addr.IP.String()), Port(addr.Port), EventHandler(slow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer close(block)
+
+	events1 := make(chan rotator.Heading, 10)
+	events2 := make(chan rotator.Heading, 10)
+	r.AddListener(func(_ rotator.Rotator, h rotator.Heading) { events1 <- h })
+	unsubscribe := r.AddListener(func(_ rotator.Rotator, h rotator.Heading) { events2 <- h })
+
+	deadline := time.Now().Add(time.Second)
+	for len(h.Clients()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("proxy not connected")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	expEvent := func(events chan rotator.Heading, az int) {
+		t.Helper()
+		select {
+		case h := <-events:
+			if h.Azimuth != az {
+				t.Fatalf("expected event with azimuth %d°, got %d°", az, h.Azimuth)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout while waiting for azimuth %d°", az)
+		}
+	}
+
+	h.Broadcast(rotator.Heading{Azimuth: 100, Speed: d.Speed()})
+	expEvent(events1, 100)
+	expEvent(events2, 100)
+
+	unsubscribe()
+	unsubscribe()
+
+	h.Broadcast(rotator.Heading{Azimuth: 110, Speed: d.Speed()})
+	expEvent(events1, 110)
+
+	select {
+	case h := <-events2:
+		t.Fatalf("unexpected event %+v after unsubscribing", h)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestProxyMetadata(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	md := rotator.Metadata{Description: "Tribander", Location: "Tower 1", Tags: []string{"hf", "20m"}}
+	if err := h.SetMetadata("r1", md); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+
+	r, err := New(Host(addr.IP.String()), Port(addr.Port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if m := r.Metadata(); !reflect.DeepEqual(m, md) {
+		t.Fatalf("expected metadata %+v, got %+v", md, m)
+	}
+	if m := r.Serialize().Metadata; !reflect.DeepEqual(m, md) {
+		t.Fatalf("expected serialized metadata %+v, got %+v", md, m)
+	}
+
+	// changes are picked up on refresh
+	md.Location = "Tower 2"
+	if err := h.SetMetadata("r1", md); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if loc := r.Metadata().Location; loc != "Tower 2" {
+		t.Fatalf("expected location Tower 2, got %s", loc)
+	}
+}
+
+func TestProxyFault(t *testing.T) {
+
+	h, err := hub.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	d, err := dummy.New(dummy.Name("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if err := h.AddRotator(d); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h.Handler())
+	defer srv.Close()
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	faults := make(chan hub.Event, 10)
+	hh := func(ev hub.Event) {
+		if ev.Name == hub.RotatorFault {
+			faults <- ev
+		}
+	}
+
+	r, err := New(Host(host), Port(port), HubEventHandler(hh))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// wait until the proxy's websocket client has been added
+	deadline := time.Now().Add(time.Second)
+	for len(h.Clients()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("proxy not connected")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	for _, fault := range []string{"controller not responding", ""} {
+		var err error
+		if fault != "" {
+			err = errors.New(fault)
+		}
+		h.ReportFault("r1", err)
+
+		select {
+		case ev := <-faults:
+			if ev.Error != fault {
+				t.Fatalf("expected fault %q, got %q", fault, ev.Error)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+
+		if r.Fault() != fault {
+			t.Fatalf("expected fault %q, got %q", fault, r.Fault())
+		}
+	}
+}
@@ -3,10 +3,12 @@ package proxy
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
@@ -14,19 +16,54 @@ import (
 
 	"github.com/dh1tw/remoteRotator/hub"
 	"github.com/dh1tw/remoteRotator/rotator"
+	"github.com/dh1tw/remoteRotator/rotator/compact"
 )
 
-const (
-	// Time allowed to write a message to the peer.
-	wsWriteWait = 5 * time.Second
+// Time allowed to write a message to the peer.
+const wsWriteWait = 5 * time.Second
+
+// Time after which a heading change within the deadband is emitted if
+// no further heading has been received.
+const deadbandSettleTime = time.Second
+
+// ErrMultipleRotators is returned when the remote hub provides more than
+// one rotator, but no rotator has been selected with the RotatorName option.
+// The available rotators can be retrieved with ListRotators.
+var ErrMultipleRotators = errors.New("remote hub provides more than one rotator")
+
+// ErrNotConnected is returned by the commands which move the rotator
+// while the websocket connection to the remote rotator is down (see
+// WaitForConnection).
+var ErrNotConnected = errors.New("not connected to the remote rotator")
 
-	// Time allowed to read the next pong message from the peer.
-	wsPongWait = 10 * time.Second
+// ErrProtocolMismatch is returned if the remote hub speaks a different
+// version of the wire protocol (see hub.ProtocolVersion) and the
+// StrictProtocol option has been set.
+var ErrProtocolMismatch = errors.New("incompatible protocol version")
 
-	// Send pings to peer with this period. Must be less than pongWait.
-	wsPingPeriod = 3 * time.Second
+// ConnState is the state of the connection to the remote rotator.
+type ConnState string
+
+// States of the connection reported through the ConnectionStateHandler.
+const (
+	Disconnected ConnState = "disconnected"
+	Reconnecting ConnState = "reconnecting"
+	Connected    ConnState = "connected"
 )
 
+// RangeError is returned by SetAzimuth and SetElevation if the heading
+// exceeds the limits reported by the remote rotator.
+type RangeError struct {
+	Axis  string
+	Value int
+	Min   int
+	Max   int
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("%s %d out of range (%d...%d)", e.Axis, e.Value, e.Min, e.Max)
+}
+
 // Proxy is a proxy object representing a remote rotator. It implements
 // the rotator.Rotator interface. Behind the scenes it sychronizes itself
 // with the real rotator through a websocket.
@@ -37,188 +74,898 @@ type Proxy struct {
 	conn           *websocket.Conn
 	wsTxTimeout    time.Duration
 	wsRxTimeout    time.Duration
+	pingInterval   time.Duration
+	pongTimeout    time.Duration
 	eventHandler   func(rotator.Rotator, rotator.Heading)
+	listeners      map[int]func(rotator.Rotator, rotator.Heading)
+	nextListener   int
+	hubHandler     func(hub.Event)
+	stateHandler   func(ConnState)
+	logger         hub.Logger
 	name           string
+	rotatorName    string
+	skipRangeCheck bool
+	strictProtocol bool
+	protocol       int
 	azimuthMin     int
 	azimuthMax     int
 	azimuthStop    int
+	azimuthOffset  int
 	azimuthOverlap bool
 	elevationMin   int
 	elevationMax   int
 	hasAzimuth     bool
 	hasElevation   bool
+	hasSpeed       bool
 	azimuth        int
 	azPreset       int
 	elevation      int
 	elPreset       int
+	speed          int
+	connected      bool
+	connState      ConnState
+	connCh         chan struct{}
+	connWait       time.Duration
+	lastUpdate     time.Time
+	fault          string
+	metadata       rotator.Metadata
 	closeCh        chan struct{}
 	doneCh         chan struct{}
+	closer         sync.Once
+	reconnect      bool
+	maxBackoff     time.Duration
+	useTLS         bool
+	skipTLSVerify  bool
+	authToken      string
+	httpClient     *http.Client
+	infoTimeout    time.Duration
+	dialTimeout    time.Duration
+	wsPath         string
+	infoPath       string
+	wsCompression  bool
+	deadband       int
+	settleTime     time.Duration
+	settleTimer    *time.Timer
+	lastEmitted    rotator.Heading
+	// compactHeadings requests the compact encoding of the heading
+	// updates; rotatorID identifies them once the hub announced it
+	compactHeadings bool
+	rotatorID       int
+	// the handlers run outside of wg, so that they may call Close
+	callbacks callbacks
+	// wg tracks all go routines spawned by the proxy
+	wg sync.WaitGroup
 }
 
 // New returns the pointer to an initalized Rotator proxy object.
+// Default settings are:
+// reconnect: false,
+// maxBackoff: 30sec,
+// infoTimeout: 3sec,
+// dialTimeout: 5sec,
+// pingInterval: 3sec,
+// pongTimeout: 10sec,
+// wsPath: /ws,
+// infoPath: /api/rotators,
+// logger: hub.StdLogger.
 func New(opts ...func(*Proxy)) (*Proxy, error) {
+	return NewWithContext(context.Background(), opts...)
+}
+
+// NewWithContext is like New but aborts the connection attempt to the
+// remote rotator when ctx is cancelled or expires. In this case
+// ctx.Err() is returned. The context is only used for establishing the
+// connection; it doesn't affect the lifetime of the proxy.
+func NewWithContext(ctx context.Context, opts ...func(*Proxy)) (*Proxy, error) {
+
+	r := newProxy(opts...)
+
+	if r.maxBackoff <= 0 {
+		return nil, fmt.Errorf("invalid maximum backoff %v", r.maxBackoff)
+	}
+
+	if r.pingInterval <= 0 {
+		return nil, fmt.Errorf("invalid ping interval %v", r.pingInterval)
+	}
+
+	if r.pongTimeout <= r.pingInterval {
+		return nil, fmt.Errorf("pong timeout %v must exceed the ping interval %v",
+			r.pongTimeout, r.pingInterval)
+	}
+
+	if r.doneCh == nil {
+		r.doneCh = make(chan struct{})
+	}
+
+	if r.deadband < 0 {
+		return nil, fmt.Errorf("invalid deadband %d", r.deadband)
+	}
+
+	if r.dialTimeout < 0 {
+		return nil, fmt.Errorf("invalid dial timeout %v", r.dialTimeout)
+	}
+
+	if err := r.getObject(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	conn, err := r.dial(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	r.wg.Add(1)
+	go r.run(conn)
+
+	return r, nil
+}
+
+// ListRotators returns the rotators provided by a remote hub, sorted by
+// name. It accepts the same options as New (e.g. Host, Port, UseTLS,
+// AuthToken), but doesn't connect to the websocket. A client can present
+// the rotators to the user and connect to the chosen one with New and
+// the RotatorName option.
+func ListRotators(ctx context.Context, opts ...func(*Proxy)) ([]rotator.Object, error) {
+	r := newProxy(opts...)
+	defer r.httpClient.CloseIdleConnections()
+
+	objs, err := r.getObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rotators := make([]rotator.Object, 0, len(objs))
+	for _, o := range objs {
+		rotators = append(rotators, o)
+	}
+	sort.Slice(rotators, func(i, j int) bool {
+		return rotators[i].Name < rotators[j].Name
+	})
+
+	return rotators, nil
+}
+
+// newProxy returns a Proxy with the default settings and the options
+// applied.
+func newProxy(opts ...func(*Proxy)) *Proxy {
 
 	r := &Proxy{
-		name:    "rotatorProxy",
-		closeCh: make(chan struct{}),
+		name:         "rotatorProxy",
+		closeCh:      make(chan struct{}),
+		maxBackoff:   time.Second * 30,
+		infoTimeout:  time.Second * 3,
+		dialTimeout:  time.Second * 5,
+		pingInterval: time.Second * 3,
+		pongTimeout:  time.Second * 10,
+		wsPath:       "/ws",
+		infoPath:     "/api/rotators",
+		settleTime:   deadbandSettleTime,
+		logger:       hub.StdLogger{},
+		connState:    Disconnected,
+		protocol:     hub.ProtocolVersion,
+		connCh:       make(chan struct{}),
+		listeners:    make(map[int]func(rotator.Rotator, rotator.Heading)),
 	}
 
 	for _, opt := range opts {
 		opt(r)
 	}
 
-	if err := r.getObject(); err != nil {
-		return nil, err
+	if r.httpClient == nil {
+		r.httpClient = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: r.tlsConfig()},
+		}
 	}
 
-	wsDialer := &websocket.Dialer{}
+	return r
+}
 
-	wsURL := fmt.Sprintf("ws://%s:%d/ws", r.host, r.port)
-	conn, _, err := wsDialer.Dial(wsURL, nil)
+// Close closes the websocket connection to the remote rotator and waits
+// until all go routines spawned by the proxy have returned. Close also
+// terminates a pending reconnect. Close doesn't wait for the handlers
+// (EventHandler, HubEventHandler, ConnectionStateHandler and the
+// listeners); they may call Close themselves, e.g. on Disconnected.
+func (r *Proxy) Close() {
+	r.closer.Do(func() {
+		r.Lock()
+		close(r.closeCh)
+		if r.conn != nil {
+			r.conn.Close()
+		}
+		r.setDisconnected()
+		if r.settleTimer != nil {
+			r.settleTimer.Stop()
+		}
+		r.Unlock()
+		r.httpClient.CloseIdleConnections()
+	})
+	r.wg.Wait()
+}
+
+// Done returns a channel which is closed once the proxy has been closed
+// or has given up on the connection to the remote rotator. It is the
+// channel passed with the DoneCh option (if any).
+func (r *Proxy) Done() <-chan struct{} {
+	return r.doneCh
+}
+
+// Refresh retrieves the current heading and configuration from the remote
+// rotator, regardless of the heading updates received through the
+// websocket. If the heading has changed, an event is emitted.
+func (r *Proxy) Refresh() error {
+	r.RLock()
+	old := r.serialize().Heading
+	r.RUnlock()
+
+	if err := r.getObject(context.Background()); err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if h := r.serialize().Heading; h != old {
+		r.emit(h)
+	}
+
+	return nil
+}
+
+// dial opens the websocket connection to the remote rotator.
+func (r *Proxy) dial(ctx context.Context) (*websocket.Conn, error) {
+
+	subprotocols := []string{hub.WsSubprotocol}
+	if r.compactHeadings {
+		subprotocols = []string{hub.WsCompactSubprotocol, hub.WsSubprotocol}
+	}
+
+	wsDialer := &websocket.Dialer{
+		TLSClientConfig:   r.tlsConfig(),
+		EnableCompression: r.wsCompression,
+		HandshakeTimeout:  r.dialTimeout,
+		Subprotocols:      subprotocols,
+	}
+
+	scheme := "ws"
+	if r.useTLS {
+		scheme = "wss"
+	}
+
+	wsURL := fmt.Sprintf("%s://%s:%d%s", scheme, r.host, r.port, r.wsPath)
+	conn, _, err := wsDialer.DialContext(ctx, wsURL, r.header())
 	if err != nil {
 		return nil, err
 	}
 
-	conn.SetReadDeadline(time.Now().Add(wsPongWait))
-	// Pong handler extends the read deadline by wsPongWait whenever a
+	// hubs which predate the negotiation don't select a subprotocol
+	if p := conn.Subprotocol(); p != "" && p != hub.WsSubprotocol &&
+		(p != hub.WsCompactSubprotocol || !r.compactHeadings) {
+		conn.Close()
+		return nil, fmt.Errorf("unsupported websocket subprotocol %q selected by %v:%v", p, r.host, r.port)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(r.pongTimeout))
+	// Pong handler extends the read deadline by pongTimeout whenever a
 	// pong has been received
 	conn.SetPongHandler(func(string) error {
-		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetReadDeadline(time.Now().Add(r.pongTimeout))
 		return nil
 	})
 
+	r.Lock()
+	defer r.Unlock()
+
+	// the proxy might have been closed in the meantime
+	select {
+	case <-r.closeCh:
+		conn.Close()
+		return nil, fmt.Errorf("proxy closed")
+	default:
+	}
+
 	r.conn = conn
+	r.connected = true
+	close(r.connCh)
+
+	return conn, nil
+}
+
+// run listens on the websocket connection. If the connection drops and
+// reconnect is enabled, run tries to reconnect with an exponential backoff.
+// Once the proxy gives up (or has been closed), the doneCh will be closed.
+func (r *Proxy) run(conn *websocket.Conn) {
+	defer r.wg.Done()
+
+	// Signal the object holder that we are going to shutdown so
+	// that this object can be disposed.
+	defer close(r.doneCh)
+
+	for {
+		r.setConnState(Connected)
+		r.listen(conn)
+		r.setConnState(Disconnected)
+
+		if !r.reconnect {
+			return
+		}
+
+		select {
+		case <-r.closeCh:
+			return
+		default:
+		}
+
+		r.setConnState(Reconnecting)
+		conn = r.redial()
+		if conn == nil {
+			r.setConnState(Disconnected)
+			return
+		}
+	}
+}
+
+// setConnState records the state of the connection and reports a
+// transition to the ConnectionStateHandler.
+func (r *Proxy) setConnState(s ConnState) {
+	r.Lock()
+	if r.connState == s {
+		r.Unlock()
+		return
+	}
+	r.connState = s
+	handler := r.stateHandler
+	r.Unlock()
+
+	if handler != nil {
+		r.callbacks.queue(func() { handler(s) })
+	}
+}
+
+// reportHubEvent passes ev to the HubEventHandler (if any).
+func (r *Proxy) reportHubEvent(ev hub.Event) {
+	if r.hubHandler != nil {
+		r.callbacks.queue(func() { r.hubHandler(ev) })
+	}
+}
+
+// listen on the websocket for incoming messages until the connection
+// drops or the readTimeout kicks in. This shouldn't happen as long as the
+// counterpart responds to the pings.
+func (r *Proxy) listen(conn *websocket.Conn) {
+
+	stopPing := make(chan struct{})
+	defer close(stopPing)
 
-	// this function sends every wsPingPeriod a ping to the other side.
+	// this function sends every pingInterval a ping to the other side.
 	// if this fails, the function terminates. No further signaling needed,
-	// since the readTimeout will kick in eventually and start the object
-	// disposal.
+	// since the readTimeout will kick in eventually.
+	r.wg.Add(1)
 	go func() {
-		ping := time.NewTicker(wsPingPeriod)
+		defer r.wg.Done()
+		ping := time.NewTicker(r.pingInterval)
+		defer ping.Stop()
 		for {
-			<-ping.C
-			r.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
-			if err := r.conn.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+			select {
+			case <-ping.C:
+			case <-stopPing:
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
 				return
 			}
 		}
 	}()
 
-	// this function listen on the websocket for incoming messages or until
-	// readTimeout kicks in. This shouldn't happen as long as the counterpart
-	// responds to the pings.
-	go func() {
-		for {
-			_, msg, err := conn.ReadMessage()
-			if err != nil {
+	compactHeadings := conn.Subprotocol() == hub.WsCompactSubprotocol
+
+	for {
+		msgType, msg, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-r.closeCh:
+				// the proxy has been closed
+			default:
 				if websocket.IsUnexpectedCloseError(err,
 					websocket.CloseAbnormalClosure,
 					websocket.CloseNormalClosure) {
-					log.Println("websocket error:", err)
+					r.logger.Errorf("websocket error: %v", err)
 				}
-				// Signal the object holder that we are going to shutdown so
-				// that this object can be disposed.
-				close(r.doneCh)
-				return
 			}
+			conn.Close()
+			r.Lock()
+			r.setDisconnected()
+			r.Unlock()
+			return
+		}
+
+		// the connection is alive
+		conn.SetReadDeadline(time.Now().Add(r.pongTimeout))
 
-			data := hub.Event{}
-			if err := json.Unmarshal(msg, &data); err != nil {
-				log.Println(err)
+		data := hub.Event{}
+		if compactHeadings && msgType == websocket.BinaryMessage {
+			id, h, err := compact.DecodeHeading(msg)
+			if err != nil {
+				r.logger.Errorf("%v", err)
+				continue
+			}
+			r.RLock()
+			own := id == r.rotatorID
+			r.RUnlock()
+			if !own {
+				continue
 			}
+			data = hub.Event{Name: hub.UpdateHeading, RotatorName: r.Name(), Heading: h}
+		} else if err := json.Unmarshal(msg, &data); err != nil {
+			r.logger.Errorf("%v", err)
+		}
 
-			switch data.Name {
-			case "add":
-				// pass
-			case "remove":
-				// pass
-			case "heading":
+		switch data.Name {
+		case hub.Hello:
+			// the connection is dropped; a reconnect is refused by getObject
+			if err := r.checkProtocol(data.Protocol); err != nil {
+				conn.Close()
+			}
+		case "add", "remove":
+			// the id of the rotator in the compact heading updates
+			if data.Name == hub.AddRotator && data.RotatorName == r.Name() {
 				r.Lock()
-				changed := false
+				r.rotatorID = data.RotatorID
+				r.Unlock()
+			}
+			r.reportHubEvent(data)
+		case hub.PresetReached, hub.RotatorStopped:
+			if data.RotatorName != "" && data.RotatorName != r.Name() {
+				continue
+			}
+			r.reportHubEvent(data)
+		case hub.RotatorFault:
+			if data.RotatorName != "" && data.RotatorName != r.Name() {
+				continue
+			}
+			r.Lock()
+			r.fault = data.Error
+			r.Unlock()
+			r.reportHubEvent(data)
+		case "heading":
+			// a hub may provide several rotators
+			if data.RotatorName != "" && data.RotatorName != r.Name() {
+				continue
+			}
+			r.Lock()
+			r.lastUpdate = time.Now()
+			changed := false
+
+			// the values of a missing axis are meaningless and
+			// must not trigger events
+			s := data.Heading
+			if r.hasAzimuth && r.azimuth != s.Azimuth {
+				r.azimuth = s.Azimuth
+				changed = true
+			}
+			if r.hasAzimuth && r.azPreset != s.AzPreset {
+				r.azPreset = s.AzPreset
+				changed = true
+			}
+			if r.hasElevation && r.elevation != s.Elevation {
+				r.elevation = s.Elevation
+				changed = true
+			}
+			if r.hasElevation && r.elPreset != s.ElPreset {
+				r.elPreset = s.ElPreset
+				changed = true
+			}
+			if r.speed != s.Speed {
+				r.speed = s.Speed
+				changed = true
+			}
 
-				s := data.Heading
-				if r.azimuth != s.Azimuth {
-					r.azimuth = s.Azimuth
-					changed = true
-				}
-				if r.azPreset != s.AzPreset {
-					r.azPreset = s.AzPreset
-					changed = true
-				}
-				if r.elevation != s.Elevation {
-					r.elevation = s.Elevation
-					changed = true
-				}
-				if r.elPreset != s.ElPreset {
-					r.elPreset = s.ElPreset
-					changed = true
+			if changed {
+				if h := r.serialize().Heading; r.significant(h) {
+					r.emit(h)
+				} else {
+					r.emitSettled()
 				}
+			}
+			r.Unlock()
+		}
+	}
+}
 
-				if changed {
-					if r.eventHandler != nil {
-						go r.eventHandler(r, s)
-					}
-				}
+// redial tries to reconnect to the remote rotator with an exponential
+// backoff (starting at 1s, doubling up to maxBackoff). On success the
+// state of the rotator is resynchronized and the current heading is
+// emitted through the eventHandler. If the proxy is closed while
+// reconnecting, nil is returned.
+func (r *Proxy) redial() *websocket.Conn {
+
+	// abort a pending connection attempt when the proxy is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-r.closeCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	backoff := time.Second
+
+	for {
+		r.logger.Warnf("connection to rotator %s lost; reconnecting in %v", r.Name(), backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-r.closeCh:
+			return nil
+		}
+
+		err := r.getObject(ctx)
+		if err == nil {
+			var conn *websocket.Conn
+			conn, err = r.dial(ctx)
+			if err == nil {
+				r.logger.Infof("reconnected to rotator %s", r.Name())
+				r.Lock()
+				r.emit(r.serialize().Heading)
 				r.Unlock()
+				return conn
 			}
 		}
-	}()
 
-	return r, nil
+		select {
+		case <-r.closeCh:
+			return nil
+		default:
+		}
+
+		r.logger.Errorf("unable to reconnect to rotator %s: %v", r.Name(), err)
+
+		backoff *= 2
+		if backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
 }
 
-func (r *Proxy) Close() {
-	// if r.conn != nil {
-	// 	r.conn.Close()
-	// }
+// significant returns true if the heading h differs from the last
+// emitted heading by more than the deadband or if a preset or the speed
+// has changed. The caller must hold the lock.
+func (r *Proxy) significant(h rotator.Heading) bool {
+	last := r.lastEmitted
+	if r.deadband == 0 || h.AzPreset != last.AzPreset ||
+		h.ElPreset != last.ElPreset || h.Speed != last.Speed {
+		return true
+	}
+
+	azDelta := (h.Azimuth - last.Azimuth) % 360
+	if azDelta < 0 {
+		azDelta = -azDelta
+	}
+	if azDelta > 180 {
+		azDelta = 360 - azDelta
+	}
+	elDelta := h.Elevation - last.Elevation
+	if elDelta < 0 {
+		elDelta = -elDelta
+	}
+
+	return azDelta > r.deadband || elDelta > r.deadband
+}
+
+// emitSettled emits the current heading once no further heading has been
+// received within the settle time, so that the settled heading is
+// reported even if it is within the deadband. The caller must hold the
+// lock.
+func (r *Proxy) emitSettled() {
+	if r.settleTimer != nil {
+		r.settleTimer.Stop()
+	}
+	r.settleTimer = time.AfterFunc(r.settleTime, func() {
+		r.Lock()
+		defer r.Unlock()
+		select {
+		case <-r.closeCh:
+			return
+		default:
+		}
+		if h := r.serialize().Heading; h != r.lastEmitted {
+			r.emit(h)
+		}
+	})
+}
+
+// AddListener registers h to receive the heading updates of the remote
+// rotator, in addition to the EventHandler and any other listener. Each
+// listener is called in its own go routine, so that a slow listener
+// neither stalls the proxy nor the other listeners. The returned function
+// unsubscribes h; it may be called more than once.
+func (r *Proxy) AddListener(h func(rotator.Rotator, rotator.Heading)) func() {
+	r.Lock()
+	defer r.Unlock()
+
+	id := r.nextListener
+	r.nextListener++
+	r.listeners[id] = h
+
+	return func() {
+		r.Lock()
+		defer r.Unlock()
+		delete(r.listeners, id)
+	}
+}
+
+// emit passes the heading asynchronously to the eventHandler and all
+// listeners. The caller must hold the lock.
+func (r *Proxy) emit(h rotator.Heading) {
+	r.lastEmitted = h
+	if r.settleTimer != nil {
+		r.settleTimer.Stop()
+	}
+	if r.eventHandler != nil {
+		r.notify(r.eventHandler, h)
+	}
+	for _, l := range r.listeners {
+		r.notify(l, h)
+	}
+}
+
+// notify calls the handler with the heading h in a new go routine. The
+// go routine isn't tracked by wg (see Close).
+func (r *Proxy) notify(handler func(rotator.Rotator, rotator.Heading), h rotator.Heading) {
+	go handler(r, h)
+}
+
+// callbacks calls the queued functions one at a time in the order in
+// which they have been queued. Its go routine only exists while
+// functions are pending.
+type callbacks struct {
+	sync.Mutex
+	pending []func()
+	running bool
+}
+
+func (c *callbacks) queue(f func()) {
+	c.Lock()
+	defer c.Unlock()
+	c.pending = append(c.pending, f)
+	if !c.running {
+		c.running = true
+		go c.run()
+	}
+}
+
+func (c *callbacks) run() {
+	for {
+		c.Lock()
+		if len(c.pending) == 0 {
+			c.running = false
+			c.Unlock()
+			return
+		}
+		f := c.pending[0]
+		c.pending = c.pending[1:]
+		c.Unlock()
+
+		f()
+	}
 }
 
 // get the serialized representation of the local rotator object and set the
 // same parameters in our proxy Object
-func (r *Proxy) getObject() error {
-
-	url := fmt.Sprintf("http://%s:%d/api/rotators", r.host, r.port)
+func (r *Proxy) getObject(ctx context.Context) error {
 
-	c := &http.Client{Timeout: 3 * time.Second}
-	resp, err := c.Get(url)
+	rotators, err := r.getObjects(ctx)
 	if err != nil {
 		return err
 	}
+
+	if len(rotators) == 0 {
+		return fmt.Errorf("incompatible rotator at %v:%v", r.host, r.port)
+	}
+
+	var pr rotator.Object
+
+	switch {
+	case r.rotatorName != "":
+		var ok bool
+		pr, ok = rotators[r.rotatorName]
+		if !ok {
+			return fmt.Errorf("rotator %s not found at %v:%v", r.rotatorName, r.host, r.port)
+		}
+	case len(rotators) > 1:
+		return ErrMultipleRotators
+	default:
+		// there is only one rotator in the dict
+		for _, o := range rotators {
+			pr = o
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.name = pr.Name
+	r.hasAzimuth = pr.Config.HasAzimuth
+	r.hasElevation = pr.Config.HasElevation
+	r.hasSpeed = pr.Config.HasSpeed
+	r.azimuthMin = pr.Config.AzimuthMin
+	r.azimuthMax = pr.Config.AzimuthMax
+	r.azimuthStop = pr.Config.AzimuthStop
+	r.azimuthOffset = pr.Config.AzimuthOffset
+	r.elevationMin = pr.Config.ElevationMin
+	r.elevationMax = pr.Config.ElevationMax
+	r.azimuth = pr.Heading.Azimuth
+	r.azPreset = pr.Heading.AzPreset
+	r.elevation = pr.Heading.Elevation
+	r.elPreset = pr.Heading.ElPreset
+	r.speed = pr.Heading.Speed
+	r.metadata = pr.Metadata
+	r.lastUpdate = time.Now()
+
+	return nil
+}
+
+// getObjects retrieves all rotators from the remote hub.
+func (r *Proxy) getObjects(ctx context.Context) (rotator.Objects, error) {
+
+	// fall back to a default timeout if the caller hasn't set a deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.infoTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequest("GET", r.url(r.infoPath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = r.header()
+
+	resp, err := r.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
+	v, err := hub.ParseProtocolVersion(resp.Header)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkProtocol(v); err != nil {
+		return nil, err
+	}
+
 	rotators := rotator.Objects{}
 
 	if err := json.NewDecoder(resp.Body).Decode(&rotators); err != nil {
-		return err
+		return nil, err
 	}
 
-	if len(rotators) == 0 {
-		return fmt.Errorf("incompatible rotator at %v:%v", r.host, r.port)
+	return rotators, nil
+}
+
+// checkProtocol records the protocol version v announced by the remote
+// hub. A mismatch with hub.ProtocolVersion is logged once; if
+// StrictProtocol has been set, ErrProtocolMismatch is returned.
+func (r *Proxy) checkProtocol(v int) error {
+	r.Lock()
+	changed := r.protocol != v
+	r.protocol = v
+	strict := r.strictProtocol
+	r.Unlock()
+
+	if v == hub.ProtocolVersion {
+		return nil
 	}
 
-	if len(rotators) > 1 {
-		return fmt.Errorf("expected information of 1 rotator, but got %d", len(rotators))
+	if changed {
+		r.logger.Warnf("remote hub at %v:%v speaks protocol version %d, expected %d",
+			r.host, r.port, v, hub.ProtocolVersion)
 	}
 
-	// there is only one rotator in the dict
-	for _, pr := range rotators {
-		r.name = pr.Name
-		r.hasAzimuth = pr.Config.HasAzimuth
-		r.hasElevation = pr.Config.HasElevation
-		r.azimuthMin = pr.Config.AzimuthMin
-		r.azimuthMax = pr.Config.AzimuthMax
-		r.azimuthStop = pr.Config.AzimuthStop
-		r.elevationMin = pr.Config.ElevationMin
-		r.elevationMax = pr.Config.ElevationMax
-		r.azimuth = pr.Heading.Azimuth
-		r.azPreset = pr.Heading.AzPreset
-		r.elevation = pr.Heading.Elevation
-		r.elPreset = pr.Heading.ElPreset
+	if strict {
+		return ErrProtocolMismatch
 	}
 
 	return nil
 }
 
+// RemoteProtocol returns the protocol version of the remote hub. Hubs
+// which predate the versioning report 0.
+func (r *Proxy) RemoteProtocol() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.protocol
+}
+
+// Connected returns true while the websocket connection to the remote
+// rotator is established. Without a connection, the heading won't be
+// updated and might be stale.
+func (r *Proxy) Connected() bool {
+	r.RLock()
+	defer r.RUnlock()
+	return r.connected
+}
+
+// setDisconnected marks the websocket connection as down. The caller
+// must hold the lock.
+func (r *Proxy) setDisconnected() {
+	if r.connected {
+		r.connCh = make(chan struct{})
+	}
+	r.connected = false
+}
+
+// awaitConnection returns ErrNotConnected if the websocket connection to
+// the remote rotator is down and isn't reestablished within the time set
+// with WaitForConnection.
+func (r *Proxy) awaitConnection() error {
+	r.RLock()
+	connected, connCh, wait := r.connected, r.connCh, r.connWait
+	r.RUnlock()
+
+	if connected {
+		return nil
+	}
+	if wait <= 0 {
+		return ErrNotConnected
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-connCh:
+		return nil
+	case <-timer.C:
+	case <-r.closeCh:
+	}
+
+	return ErrNotConnected
+}
+
+// State returns the state of the connection to the remote rotator.
+func (r *Proxy) State() ConnState {
+	r.RLock()
+	defer r.RUnlock()
+	return r.connState
+}
+
+// Fault returns the fault of the remote rotator's controller which has
+// been reported by the remote hub or an empty string if there is none.
+func (r *Proxy) Fault() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.fault
+}
+
+// Metadata returns the description, location and tags of the remote
+// rotator. They are retrieved from the remote hub when connecting (and
+// on Refresh); remote hubs which don't provide metadata return empty
+// values.
+func (r *Proxy) Metadata() rotator.Metadata {
+	r.RLock()
+	defer r.RUnlock()
+	return r.metadata
+}
+
+// LastUpdate returns the time at which the heading of the remote rotator
+// has been received last (through the websocket or Refresh).
+func (r *Proxy) LastUpdate() time.Time {
+	r.RLock()
+	defer r.RUnlock()
+	return r.lastUpdate
+}
+
 func (r *Proxy) Name() string {
 	r.RLock()
 	defer r.RUnlock()
@@ -251,13 +998,29 @@ func (r *Proxy) AzPreset() int {
 
 func (r *Proxy) SetAzimuth(az int) error {
 
+	if err := r.awaitConnection(); err != nil {
+		return err
+	}
+
+	r.RLock()
+	err := r.checkAxes(true, false)
+	// the limits of rotators with an azimuth offset are mechanical
+	// positions; the remote hub maps az into them
+	if err == nil && r.azimuthOffset == 0 {
+		err = r.checkRange("azimuth", az, r.azimuthMin, r.azimuthMax)
+	}
+	r.RUnlock()
+	if err != nil {
+		return err
+	}
+
 	azPut := rotator.AzimuthPut{
 		Azimuth: &az,
 	}
 
-	url := fmt.Sprintf("http://%s:%d/api/rotator/%s/azimuth", r.host, r.port, r.name)
+	url := r.url(fmt.Sprintf("/api/rotator/%s/azimuth", r.Name()))
 
-	return putRequest(url, &azPut)
+	return r.putRequest(url, &azPut)
 }
 
 func (r *Proxy) Elevation() int {
@@ -274,32 +1037,237 @@ func (r *Proxy) ElPreset() int {
 
 func (r *Proxy) SetElevation(el int) error {
 
+	if err := r.awaitConnection(); err != nil {
+		return err
+	}
+
+	r.RLock()
+	err := r.checkAxes(false, true)
+	if err == nil {
+		err = r.checkRange("elevation", el, r.elevationMin, r.elevationMax)
+	}
+	r.RUnlock()
+	if err != nil {
+		return err
+	}
+
 	elPut := rotator.ElevationPut{
 		Elevation: &el,
 	}
 
-	url := fmt.Sprintf("http://%s:%d/api/rotator/%s/elevation", r.host, r.port, r.name)
+	url := r.url(fmt.Sprintf("/api/rotator/%s/elevation", r.Name()))
+
+	return r.putRequest(url, &elPut)
+}
+
+// SetAzEl sets the azimuth and the elevation with a single request, so
+// that the remote hub commands both axes together. This is useful for
+// tracking fast moving objects (e.g. LEO satellites) with az/el mounts.
+func (r *Proxy) SetAzEl(az, el int) error {
+
+	if err := r.awaitConnection(); err != nil {
+		return err
+	}
+
+	r.RLock()
+	err := r.checkAxes(true, true)
+	if err == nil && r.azimuthOffset == 0 {
+		err = r.checkRange("azimuth", az, r.azimuthMin, r.azimuthMax)
+	}
+	if err == nil {
+		err = r.checkRange("elevation", el, r.elevationMin, r.elevationMax)
+	}
+	r.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	azElPut := rotator.AzElPut{
+		Azimuth:   &az,
+		Elevation: &el,
+	}
+
+	url := r.url(fmt.Sprintf("/api/rotator/%s/azel", r.Name()))
+
+	return r.putRequest(url, &azElPut)
+}
+
+// JogAzimuth moves the rotator delta degrees (positive: clockwise)
+// relative to its current azimuth. The remote hub wraps the azimuth
+// around 0° or clamps it to the rotator's limits.
+func (r *Proxy) JogAzimuth(delta int) error {
+
+	if err := r.awaitConnection(); err != nil {
+		return err
+	}
+
+	r.RLock()
+	err := r.checkAxes(true, false)
+	r.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	jogPut := rotator.JogPut{
+		AzimuthDelta: &delta,
+	}
+
+	url := r.url(fmt.Sprintf("/api/rotator/%s/jog", r.Name()))
+
+	return r.putRequest(url, &jogPut)
+}
+
+// JogElevation moves the rotator delta degrees relative to its current
+// elevation. The remote hub clamps the elevation to the rotator's limits.
+func (r *Proxy) JogElevation(delta int) error {
+
+	if err := r.awaitConnection(); err != nil {
+		return err
+	}
+
+	r.RLock()
+	err := r.checkAxes(false, true)
+	r.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	jogPut := rotator.JogPut{
+		ElevationDelta: &delta,
+	}
+
+	url := r.url(fmt.Sprintf("/api/rotator/%s/jog", r.Name()))
 
-	return putRequest(url, &elPut)
+	return r.putRequest(url, &jogPut)
+}
+
+// checkAxes returns an error if the remote rotator doesn't support one
+// of the requested axes. The caller must hold the lock.
+func (r *Proxy) checkAxes(azimuth, elevation bool) error {
+	if azimuth && !r.hasAzimuth {
+		return fmt.Errorf("rotator %s does not support azimuth", r.name)
+	}
+	if elevation && !r.hasElevation {
+		return fmt.Errorf("rotator %s does not support elevation", r.name)
+	}
+	return nil
+}
+
+// checkRange returns a RangeError if v is not within [min, max]. Limits
+// with min > max overlap 0°. If the limits are unknown (min == max) or
+// the check is disabled, nil is returned. The caller must hold the lock.
+func (r *Proxy) checkRange(axis string, v, min, max int) error {
+	if r.skipRangeCheck || min == max {
+		return nil
+	}
+	if min < max && v >= min && v <= max {
+		return nil
+	}
+	if min > max && ((v >= min && v < 360) || (v >= 0 && v <= max)) {
+		return nil
+	}
+	return &RangeError{axis, v, min, max}
+}
+
+func (r *Proxy) HasSpeed() bool {
+	r.RLock()
+	defer r.RUnlock()
+	return r.hasSpeed
+}
+
+func (r *Proxy) Speed() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.speed
+}
+
+func (r *Proxy) SetSpeed(speed int) error {
+
+	if err := r.awaitConnection(); err != nil {
+		return err
+	}
+
+	speedPut := rotator.SpeedPut{
+		Speed: &speed,
+	}
+
+	url := r.url(fmt.Sprintf("/api/rotator/%s/speed", r.Name()))
+
+	return r.putRequest(url, &speedPut)
+}
+
+// ExecuteRequest sends req to the remote hub, which executes all of its
+// fields with a single command (e.g. azimuth, elevation and speed). The
+// name of the request is set to the name of the remote rotator. See
+// rotator.Request for the order in which the fields are applied.
+func (r *Proxy) ExecuteRequest(req rotator.Request) error {
+
+	req.Name = r.Name()
+
+	// stop requests are never rejected
+	if !req.Stop && !req.StopAzimuth && !req.StopElevation {
+		if err := r.awaitConnection(); err != nil {
+			return err
+		}
+	}
+
+	r.RLock()
+	err := r.checkRequest(req)
+	r.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	return r.sendRequest("POST", r.url("/api/command"), &req)
+}
+
+// checkRequest returns an error if the remote rotator doesn't support
+// the fields set in req or a heading is out of range. Stop requests are
+// never rejected. The caller must hold the lock.
+func (r *Proxy) checkRequest(req rotator.Request) error {
+	if req.Stop {
+		return nil
+	}
+
+	moveAz := (req.HasAzimuth || req.AzimuthDelta != 0) && !req.StopAzimuth
+	moveEl := (req.HasElevation || req.ElevationDelta != 0) && !req.StopElevation
+
+	if err := r.checkAxes(moveAz, moveEl); err != nil {
+		return err
+	}
+	if req.HasSpeed && !r.hasSpeed {
+		return fmt.Errorf("rotator %s does not support setting the speed", r.name)
+	}
+	if moveAz && req.HasAzimuth && r.azimuthOffset == 0 {
+		if err := r.checkRange("azimuth", req.Azimuth, r.azimuthMin, r.azimuthMax); err != nil {
+			return err
+		}
+	}
+	if moveEl && req.HasElevation {
+		if err := r.checkRange("elevation", req.Elevation, r.elevationMin, r.elevationMax); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (r *Proxy) StopAzimuth() error {
 
-	url := fmt.Sprintf("http://%s:%d/api/rotator/%s/stop_azimuth", r.host, r.port, r.name)
+	url := r.url(fmt.Sprintf("/api/rotator/%s/stop_azimuth", r.Name()))
 
-	return putRequest(url, struct{}{})
+	return r.putRequest(url, struct{}{})
 }
 
 func (r *Proxy) StopElevation() error {
-	url := fmt.Sprintf("http://%s:%d/api/rotator/%s/stop_elevation", r.host, r.port, r.name)
+	url := r.url(fmt.Sprintf("/api/rotator/%s/stop_elevation", r.Name()))
 
-	return putRequest(url, struct{}{})
+	return r.putRequest(url, struct{}{})
 }
 
 func (r *Proxy) Stop() error {
-	url := fmt.Sprintf("http://%s:%d/api/rotator/%s/stop", r.host, r.port, r.name)
+	url := r.url(fmt.Sprintf("/api/rotator/%s/stop", r.Name()))
 
-	return putRequest(url, struct{}{})
+	return r.putRequest(url, struct{}{})
 }
 
 // Serialize the data of the rotator
@@ -312,29 +1280,64 @@ func (r *Proxy) Serialize() rotator.Object {
 func (r *Proxy) serialize() rotator.Object {
 
 	obj := rotator.Object{
-		Name: r.name,
+		Name:     r.name,
+		Metadata: r.metadata,
 		Heading: rotator.Heading{
 			Azimuth:   int(r.azimuth),
 			AzPreset:  int(r.azPreset),
 			Elevation: int(r.elevation),
 			ElPreset:  int(r.elPreset),
+			Speed:     r.speed,
 		},
 		Config: rotator.Config{
-			HasAzimuth:   r.hasAzimuth,
-			HasElevation: r.hasElevation,
-			AzimuthMax:   r.azimuthMax,
-			AzimuthMin:   r.azimuthMin,
-			AzimuthStop:  r.azimuthStop,
-			ElevationMax: r.elevationMax,
-			ElevationMin: r.elevationMin,
+			HasAzimuth:    r.hasAzimuth,
+			HasElevation:  r.hasElevation,
+			AzimuthMax:    r.azimuthMax,
+			AzimuthMin:    r.azimuthMin,
+			AzimuthStop:   r.azimuthStop,
+			AzimuthOffset: r.azimuthOffset,
+			ElevationMax:  r.elevationMax,
+			ElevationMin:  r.elevationMin,
+			HasSpeed:      r.hasSpeed,
 		},
 	}
 
 	return obj
 }
 
+// url returns the URL of the remote rotator's endpoint at path
+func (r *Proxy) url(path string) string {
+	scheme := "http"
+	if r.useTLS {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%d%s", scheme, r.host, r.port, path)
+}
+
+// header returns the HTTP header for the requests to the remote rotator
+func (r *Proxy) header() http.Header {
+	h := http.Header{}
+	if len(r.authToken) > 0 {
+		h.Set("Authorization", "Bearer "+r.authToken)
+	}
+	return h
+}
+
+// tlsConfig returns the TLS configuration for the connections to the
+// remote rotator
+func (r *Proxy) tlsConfig() *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify: r.skipTLSVerify,
+	}
+}
+
 // putRequest executes an HTTP put request.
-func putRequest(url string, data interface{}) error {
+func (r *Proxy) putRequest(url string, data interface{}) error {
+	return r.sendRequest("PUT", url, data)
+}
+
+// sendRequest executes an HTTP request with data encoded as JSON body.
+func (r *Proxy) sendRequest(method, url string, data interface{}) error {
 
 	b := new(bytes.Buffer)
 	json.NewEncoder(b).Encode(data)
@@ -342,24 +1345,24 @@ func putRequest(url string, data interface{}) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
 
-	req, err := http.NewRequest("PUT", url, b)
+	req, err := http.NewRequest(method, url, b)
 	if err != nil {
 		return err
 	}
 
 	req = req.WithContext(ctx)
 
+	req.Header = r.header()
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := r.httpClient.Do(req)
 	if err != nil {
 		return (err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unable to set azimuth. http error code is %v", resp.StatusCode)
+		return fmt.Errorf("unable to execute request. http error code is %v", resp.StatusCode)
 	}
 
 	return nil
@@ -0,0 +1,24 @@
+package rotator
+
+// Request is a command for a rotator, independent of the protocol
+// through which it has been received. Several fields may be set to
+// command the rotator with a single request (e.g. azimuth, elevation
+// and speed); the speed is set before the axes are moved. Stop
+// overrides all other fields, StopAzimuth and StopElevation override
+// new headings of the same axis. The deltas move the rotator relative
+// to its current heading; they are ignored if an absolute heading is
+// set for the same axis.
+type Request struct {
+	Name           string `json:"name"`
+	HasAzimuth     bool   `json:"has_azimuth,omitempty"`
+	Azimuth        int    `json:"azimuth,omitempty"`
+	AzimuthDelta   int    `json:"azimuth_delta,omitempty"`
+	HasElevation   bool   `json:"has_elevation,omitempty"`
+	Elevation      int    `json:"elevation,omitempty"`
+	ElevationDelta int    `json:"elevation_delta,omitempty"`
+	HasSpeed       bool   `json:"has_speed,omitempty"`
+	Speed          int    `json:"speed,omitempty"`
+	StopAzimuth    bool   `json:"stop_azimuth,omitempty"`
+	StopElevation  bool   `json:"stop_elevation,omitempty"`
+	Stop           bool   `json:"stop,omitempty"`
+}
@@ -11,6 +11,9 @@ type Rotator interface {
 	Elevation() int
 	ElPreset() int
 	SetElevation(el int) error
+	HasSpeed() bool
+	Speed() int
+	SetSpeed(speed int) error
 	StopAzimuth() error
 	StopElevation() error
 	Stop() error
@@ -18,5 +21,12 @@ type Rotator interface {
 	Close()
 }
 
+// SpeedMin and SpeedMax are the slowest and the fastest speed level
+// of a rotator. They correspond to the GS-232 commands X1 to X4.
+const (
+	SpeedMin = 1
+	SpeedMax = 4
+)
+
 // EventHandler is called whenever a variable of a rotator changes
 type EventHandler func(Rotator, Heading)
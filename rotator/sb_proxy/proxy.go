@@ -177,6 +177,20 @@ func (r *SbProxy) SetElevation(el int) error {
 	return err
 }
 
+// HasSpeed returns false since the speed can not be set through the
+// service bus
+func (r *SbProxy) HasSpeed() bool {
+	return false
+}
+
+func (r *SbProxy) Speed() int {
+	return 0
+}
+
+func (r *SbProxy) SetSpeed(speed int) error {
+	return fmt.Errorf("rotator %s does not support setting the speed", r.Name())
+}
+
 func (r *SbProxy) StopAzimuth() error {
 	_, err := r.rcli.StopAzimuth(context.Background(), &sbRotator.None{})
 	return err
@@ -0,0 +1,159 @@
+package tcpproxy
+
+import (
+	"time"
+
+	"github.com/dh1tw/remoteRotator/hub"
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// Host is a functional option to set the host of the remote rotator.
+func Host(host string) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.host = host
+	}
+}
+
+// Port is a functional option to set the TCP port of the remote rotator.
+func Port(port int) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.port = port
+	}
+}
+
+// Dialect is a functional option to set the protocol spoken by the
+// remote rotator (hub.ARSVCOM, hub.GS232A, hub.GS232B or hub.JSON).
+func Dialect(d hub.Dialect) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.dialect = d
+	}
+}
+
+// Name is a functional option to set the name of the rotator.
+func Name(name string) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.name = name
+	}
+}
+
+// HasAzimuth is a functional option to indicate if the remote rotator
+// supports horizontal rotation.
+func HasAzimuth(set bool) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.hasAzimuth = set
+	}
+}
+
+// HasElevation is a functional option to indicate if the remote rotator
+// supports vertical rotation.
+func HasElevation(set bool) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.hasElevation = set
+	}
+}
+
+// AzimuthMin is a functional option to set the minimum azimuth of the
+// remote rotator.
+func AzimuthMin(min int) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.azimuthMin = min
+	}
+}
+
+// AzimuthMax is a functional option to set the maximum azimuth of the
+// remote rotator.
+func AzimuthMax(max int) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.azimuthMax = max
+	}
+}
+
+// AzimuthStop is a functional option to set the mechanical stop of the
+// remote rotator.
+func AzimuthStop(stop int) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.azimuthStop = stop
+	}
+}
+
+// ElevationMin is a functional option to set the minimum elevation of
+// the remote rotator.
+func ElevationMin(min int) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.elevationMin = min
+	}
+}
+
+// ElevationMax is a functional option to set the maximum elevation of
+// the remote rotator.
+func ElevationMax(max int) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.elevationMax = max
+	}
+}
+
+// PollInterval is a functional option to set the interval in which the
+// position of the remote rotator is queried. Polling is disabled if d
+// is 0, e.g. if the remote side sends position updates on its own.
+func PollInterval(d time.Duration) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.pollInterval = d
+	}
+}
+
+// DialTimeout is a functional option to set the timeout for establishing
+// the TCP connection.
+func DialTimeout(d time.Duration) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.dialTimeout = d
+	}
+}
+
+// WriteTimeout is a functional option to set the maximum time for
+// writing a command to the remote rotator.
+func WriteTimeout(d time.Duration) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.writeTimeout = d
+	}
+}
+
+// DoneCh is a functional option allows you to pass a channel to the proxy
+// object. This channel will be closed by this object. It serves as a
+// notification that the object can be deleted.
+func DoneCh(ch chan struct{}) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.doneCh = ch
+	}
+}
+
+// EventHandler sets a callback function through which the proxy rotator
+// will report Events
+func EventHandler(h func(rotator.Rotator, rotator.Heading)) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.eventHandler = h
+	}
+}
+
+// Reconnect is a functional option to let the proxy reconnect to the
+// remote rotator with an exponential backoff if the connection drops.
+func Reconnect(enabled bool) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.reconnect = enabled
+	}
+}
+
+// MaxBackoff is a functional option to set the maximum time between two
+// reconnect attempts. The backoff must be positive.
+func MaxBackoff(d time.Duration) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.maxBackoff = d
+	}
+}
+
+// Logger is a functional option to route the proxy's log messages
+// through l.
+func Logger(l hub.Logger) func(*TCPProxy) {
+	return func(r *TCPProxy) {
+		r.logger = l
+	}
+}
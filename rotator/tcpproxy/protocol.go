@@ -0,0 +1,149 @@
+package tcpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dh1tw/remoteRotator/hub"
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// position is a heading update parsed from a message of the remote
+// rotator. Only the axes contained in the message are set.
+type position struct {
+	azimuth      int
+	elevation    int
+	hasAzimuth   bool
+	hasElevation bool
+}
+
+// parsePosition parses a position message (without line termination)
+// in the dialect d. Single value messages (+0aaa) belong to the
+// azimuth, unless the rotator only supports elevation.
+func parsePosition(d hub.Dialect, msg string, elevationOnly bool) (position, error) {
+	msg = strings.TrimSpace(msg)
+	// the prompt (?>) is sent without line termination
+	msg = strings.TrimSpace(strings.Replace(msg, "?>", "", -1))
+	if len(msg) == 0 {
+		return position{}, fmt.Errorf("empty message")
+	}
+
+	if d == hub.JSON {
+		return parseJSONPosition(msg)
+	}
+
+	// AZ=aaa  EL=eee (GS-232A)
+	if strings.HasPrefix(msg, "AZ=") || strings.HasPrefix(msg, "EL=") {
+		p := position{}
+		for _, f := range strings.Fields(msg) {
+			if len(f) < 4 {
+				return position{}, fmt.Errorf("invalid position (%s)", msg)
+			}
+			v, err := strconv.Atoi(f[3:])
+			if err != nil {
+				return position{}, fmt.Errorf("invalid position (%s)", msg)
+			}
+			switch f[:3] {
+			case "AZ=":
+				p.azimuth, p.hasAzimuth = v, true
+			case "EL=":
+				p.elevation, p.hasElevation = v, true
+			default:
+				return position{}, fmt.Errorf("invalid position (%s)", msg)
+			}
+		}
+		return p, nil
+	}
+
+	// +0aaa+0eee or +0aaa
+	values := strings.Split(msg, "+")
+	if len(values) < 2 || len(values) > 3 || values[0] != "" {
+		return position{}, fmt.Errorf("invalid position (%s)", msg)
+	}
+
+	v := make([]int, 0, 2)
+	for _, s := range values[1:] {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return position{}, fmt.Errorf("invalid position (%s)", msg)
+		}
+		v = append(v, i)
+	}
+
+	if len(v) == 2 {
+		return position{azimuth: v[0], elevation: v[1], hasAzimuth: true, hasElevation: true}, nil
+	}
+	if elevationOnly {
+		return position{elevation: v[0], hasElevation: true}, nil
+	}
+	return position{azimuth: v[0], hasAzimuth: true}, nil
+}
+
+// parseJSONPosition parses a JSON encoded rotator.Heading. Error
+// messages of the remote hub are returned as error.
+func parseJSONPosition(msg string) (position, error) {
+	var v struct {
+		rotator.Heading
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(msg), &v); err != nil {
+		return position{}, fmt.Errorf("invalid position (%s): %v", msg, err)
+	}
+	if len(v.Error) > 0 {
+		return position{}, fmt.Errorf("remote error: %s", v.Error)
+	}
+	return position{
+		azimuth:      v.Azimuth,
+		elevation:    v.Elevation,
+		hasAzimuth:   true,
+		hasElevation: true,
+	}, nil
+}
+
+// command returns the message for the request in the dialect d. Commands
+// are terminated with CR LF, which is accepted by GS-232 controllers as
+// well as by the hub's TCP server. For requests which can't be expressed
+// in the dialect, an error is returned.
+// Setting the elevation with GS-232 requires the azimuth preset azPreset.
+func command(d hub.Dialect, req rotator.Request, azPreset int) (string, error) {
+	if d == hub.JSON {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return "", err
+		}
+		return string(data) + "\n", nil
+	}
+
+	gs232 := d == hub.GS232A || d == hub.GS232B
+
+	switch {
+	case req.Stop:
+		return "S\r\n", nil
+	case req.StopAzimuth:
+		return "A\r\n", nil
+	case req.StopElevation:
+		return "E\r\n", nil
+	case req.HasElevation && gs232:
+		return fmt.Sprintf("W%03d %03d\r\n", azPreset, req.Elevation), nil
+	case req.HasSpeed && gs232:
+		return fmt.Sprintf("X%d\r\n", req.Speed), nil
+	case req.HasAzimuth:
+		return fmt.Sprintf("M%03d\r\n", req.Azimuth), nil
+	}
+
+	return "", fmt.Errorf("request not supported by the %s dialect", d)
+}
+
+// query returns the message to poll the position in the dialect d.
+func query(d hub.Dialect, hasElevation bool) string {
+	switch {
+	case d == hub.JSON:
+		return "{}\n"
+	case hasElevation:
+		return "C2\r\n"
+	default:
+		return "C\r\n"
+	}
+}
@@ -0,0 +1,84 @@
+package tcpproxy
+
+import (
+	"testing"
+
+	"github.com/dh1tw/remoteRotator/hub"
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+func TestParsePosition(t *testing.T) {
+
+	tt := []struct {
+		name          string
+		dialect       hub.Dialect
+		msg           string
+		elevationOnly bool
+		expPos        position
+		expErr        bool
+	}{
+		{"azimuth", hub.ARSVCOM, "+0123", false, position{azimuth: 123, hasAzimuth: true}, false},
+		{"azimuth and elevation", hub.ARSVCOM, "+0123+0045", false,
+			position{azimuth: 123, elevation: 45, hasAzimuth: true, hasElevation: true}, false},
+		{"elevation only rotator", hub.GS232B, "+0045", true, position{elevation: 45, hasElevation: true}, false},
+		{"with prompt", hub.ARSVCOM, "?>+0123", false, position{azimuth: 123, hasAzimuth: true}, false},
+		{"gs232a azimuth", hub.GS232A, "AZ=123", false, position{azimuth: 123, hasAzimuth: true}, false},
+		{"gs232a elevation", hub.GS232A, "EL=045", false, position{elevation: 45, hasElevation: true}, false},
+		{"gs232a azimuth and elevation", hub.GS232A, "AZ=123  EL=045", false,
+			position{azimuth: 123, elevation: 45, hasAzimuth: true, hasElevation: true}, false},
+		{"json", hub.JSON, `{"azimuth":123,"az_preset":200,"elevation":45}`, false,
+			position{azimuth: 123, elevation: 45, hasAzimuth: true, hasElevation: true}, false},
+		{"json error", hub.JSON, `{"error":"read-only client"}`, false, position{}, true},
+		{"empty", hub.ARSVCOM, "  ", false, position{}, true},
+		{"prompt only", hub.ARSVCOM, "?>", false, position{}, true},
+		{"garbage", hub.ARSVCOM, "+0abc", false, position{}, true},
+		{"too many values", hub.ARSVCOM, "+0001+0002+0003", false, position{}, true},
+		{"invalid gs232a", hub.GS232A, "AZ=", false, position{}, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := parsePosition(tc.dialect, tc.msg, tc.elevationOnly)
+			if (err != nil) != tc.expErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p != tc.expPos {
+				t.Fatalf("expected %+v, got %+v", tc.expPos, p)
+			}
+		})
+	}
+}
+
+func TestCommand(t *testing.T) {
+
+	tt := []struct {
+		name    string
+		dialect hub.Dialect
+		req     rotator.Request
+		expMsg  string
+		expErr  bool
+	}{
+		{"azimuth", hub.ARSVCOM, rotator.Request{HasAzimuth: true, Azimuth: 90}, "M090\r\n", false},
+		{"stop", hub.ARSVCOM, rotator.Request{Stop: true}, "S\r\n", false},
+		{"stop azimuth", hub.GS232B, rotator.Request{StopAzimuth: true}, "A\r\n", false},
+		{"stop elevation", hub.GS232B, rotator.Request{StopElevation: true}, "E\r\n", false},
+		{"elevation", hub.GS232A, rotator.Request{HasElevation: true, Elevation: 30}, "W200 030\r\n", false},
+		{"speed", hub.GS232B, rotator.Request{HasSpeed: true, Speed: 2}, "X2\r\n", false},
+		{"arsvcom elevation", hub.ARSVCOM, rotator.Request{HasElevation: true, Elevation: 30}, "", true},
+		{"arsvcom speed", hub.ARSVCOM, rotator.Request{HasSpeed: true, Speed: 2}, "", true},
+		{"json", hub.JSON, rotator.Request{Name: "r1", HasAzimuth: true, Azimuth: 90},
+			`{"name":"r1","has_azimuth":true,"azimuth":90}` + "\n", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := command(tc.dialect, tc.req, 200)
+			if (err != nil) != tc.expErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msg != tc.expMsg {
+				t.Fatalf("expected %q, got %q", tc.expMsg, msg)
+			}
+		})
+	}
+}
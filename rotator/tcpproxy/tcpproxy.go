@@ -0,0 +1,526 @@
+package tcpproxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/hub"
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// TCPProxy is a proxy object representing a remote rotator which is
+// reachable through a plain TCP connection speaking ARSVCOM, GS-232 or
+// the hub's JSON dialect (e.g. the TCP server of a hub or legacy
+// equipment). It implements the rotator.Rotator interface. Behind the
+// scenes it polls the position of the rotator and parses the position
+// frames sent by the remote side.
+type TCPProxy struct {
+	sync.RWMutex
+	host         string
+	port         int
+	dialect      hub.Dialect
+	conn         net.Conn
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+	pollInterval time.Duration
+	eventHandler func(rotator.Rotator, rotator.Heading)
+	logger       hub.Logger
+	name         string
+	hasAzimuth   bool
+	hasElevation bool
+	azimuthMin   int
+	azimuthMax   int
+	azimuthStop  int
+	elevationMin int
+	elevationMax int
+	azimuth      int
+	azPreset     int
+	elevation    int
+	elPreset     int
+	speed        int
+	closeCh      chan struct{}
+	doneCh       chan struct{}
+	closer       sync.Once
+	reconnect    bool
+	maxBackoff   time.Duration
+	resync       bool
+	wg           sync.WaitGroup
+}
+
+// New returns the pointer to an initialized TCPProxy object which is
+// connected to the remote rotator. Since the protocols don't provide
+// the configuration of the rotator, it has to be set through functional
+// options.
+// Default settings are:
+// name: tcpProxy,
+// host: localhost,
+// port: 7373,
+// dialect: arsvcom,
+// hasAzimuth: true,
+// azimuthMax: 360,
+// elevationMax: 180,
+// pollInterval: 1sec,
+// dialTimeout: 3sec,
+// writeTimeout: 3sec,
+// reconnect: false,
+// maxBackoff: 30sec.
+func New(opts ...func(*TCPProxy)) (*TCPProxy, error) {
+
+	r := &TCPProxy{
+		name:         "tcpProxy",
+		host:         "localhost",
+		port:         7373,
+		dialect:      hub.ARSVCOM,
+		hasAzimuth:   true,
+		azimuthMax:   360,
+		elevationMax: 180,
+		speed:        rotator.SpeedMax,
+		pollInterval: time.Second,
+		dialTimeout:  time.Second * 3,
+		writeTimeout: time.Second * 3,
+		maxBackoff:   time.Second * 30,
+		logger:       hub.StdLogger{},
+		closeCh:      make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if r.doneCh == nil {
+		r.doneCh = make(chan struct{})
+	}
+
+	if _, err := hub.ParseDialect(string(r.dialect)); err != nil {
+		return nil, err
+	}
+	if r.dialect == hub.DCU1 {
+		return nil, fmt.Errorf("tcp dialect %s not supported by the tcp proxy", r.dialect)
+	}
+	if r.maxBackoff <= 0 {
+		return nil, fmt.Errorf("invalid maximum backoff %v", r.maxBackoff)
+	}
+
+	conn, err := r.dial(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	r.wg.Add(1)
+	go r.run(conn)
+
+	return r, nil
+}
+
+// Close closes the connection to the remote rotator and waits until all
+// go routines spawned by the proxy have returned. Close also terminates
+// a pending reconnect.
+func (r *TCPProxy) Close() {
+	r.closer.Do(func() {
+		r.Lock()
+		close(r.closeCh)
+		if r.conn != nil {
+			r.conn.Close()
+		}
+		r.Unlock()
+	})
+	r.wg.Wait()
+}
+
+// dial opens the TCP connection to the remote rotator.
+func (r *TCPProxy) dial(ctx context.Context) (net.Conn, error) {
+	addr := net.JoinHostPort(r.host, strconv.Itoa(r.port))
+
+	d := net.Dialer{Timeout: r.dialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	select {
+	case <-r.closeCh:
+		conn.Close()
+		return nil, fmt.Errorf("proxy closed")
+	default:
+	}
+	r.conn = conn
+	r.resync = true
+
+	return conn, nil
+}
+
+// run listens on the TCP connection. If the connection drops and
+// reconnect is enabled, run tries to reconnect with an exponential backoff.
+// Once the proxy gives up (or has been closed), the doneCh will be closed.
+func (r *TCPProxy) run(conn net.Conn) {
+	defer r.wg.Done()
+	defer close(r.doneCh)
+
+	for {
+		r.listen(conn)
+
+		if !r.reconnect {
+			return
+		}
+
+		conn = r.redial()
+		if conn == nil {
+			return
+		}
+	}
+}
+
+// listen parses the position frames received from the remote rotator
+// until the connection drops. The position is queried right after
+// (re)connecting, so that the clients don't keep showing a stale heading
+// until the next frame arrives. Meanwhile the position is polled every
+// pollInterval.
+func (r *TCPProxy) listen(conn net.Conn) {
+
+	if err := r.write(query(r.dialect, r.hasElevation)); err != nil {
+		r.logger.Errorf("unable to query the position of rotator %s: %v", r.Name(), err)
+	}
+
+	stopPoll := make(chan struct{})
+	defer close(stopPoll)
+
+	if r.pollInterval > 0 {
+		r.wg.Add(1)
+		go r.poll(stopPoll)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Split(scanLines)
+
+	for scanner.Scan() {
+		p, err := parsePosition(r.dialect, scanner.Text(), !r.hasAzimuth && r.hasElevation)
+		if err != nil {
+			r.logger.Debugf("%v", err)
+			continue
+		}
+		r.update(p)
+	}
+
+	select {
+	case <-r.closeCh:
+	default:
+		if err := scanner.Err(); err != nil {
+			r.logger.Errorf("tcp error: %v", err)
+		}
+	}
+
+	conn.Close()
+}
+
+// scanLines is a bufio.SplitFunc which splits the input at CR and / or
+// LF. Empty lines are omitted.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := 0
+	for start < len(data) && (data[start] == '\r' || data[start] == '\n') {
+		start++
+	}
+	for i := start; i < len(data); i++ {
+		if data[i] == '\r' || data[i] == '\n' {
+			return i + 1, data[start:i], nil
+		}
+	}
+	if atEOF && start < len(data) {
+		return len(data), data[start:], nil
+	}
+	return start, nil, nil
+}
+
+// poll queries the position of the remote rotator every pollInterval
+// until stop is closed.
+func (r *TCPProxy) poll(stop chan struct{}) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+		if err := r.write(query(r.dialect, r.hasElevation)); err != nil {
+			return
+		}
+	}
+}
+
+// update applies a position received from the remote rotator and emits
+// an event if the heading has changed or if it is the first position
+// after (re)connecting.
+func (r *TCPProxy) update(p position) {
+	r.Lock()
+	defer r.Unlock()
+
+	changed := r.resync
+	r.resync = false
+
+	if p.hasAzimuth && p.azimuth != r.azimuth {
+		r.azimuth = p.azimuth
+		changed = true
+	}
+
+	if p.hasElevation && p.elevation != r.elevation {
+		r.elevation = p.elevation
+		changed = true
+	}
+
+	if changed {
+		r.emit(r.serialize().Heading)
+	}
+}
+
+// redial tries to reconnect to the remote rotator with an exponential
+// backoff (starting at 1s, doubling up to maxBackoff). If the proxy is
+// closed while reconnecting, nil is returned.
+func (r *TCPProxy) redial() net.Conn {
+
+	// abort a pending connection attempt when the proxy is closed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-r.closeCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	backoff := time.Second
+
+	for {
+		r.logger.Warnf("connection to rotator %s lost; reconnecting in %v", r.Name(), backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-r.closeCh:
+			return nil
+		}
+
+		conn, err := r.dial(ctx)
+		if err == nil {
+			r.logger.Infof("reconnected to rotator %s", r.Name())
+			return conn
+		}
+
+		select {
+		case <-r.closeCh:
+			return nil
+		default:
+		}
+
+		r.logger.Errorf("unable to reconnect to rotator %s: %v", r.Name(), err)
+
+		backoff *= 2
+		if backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
+}
+
+// emit passes the heading asynchronously to the eventHandler.
+// The caller must hold the lock.
+func (r *TCPProxy) emit(h rotator.Heading) {
+	if r.eventHandler == nil {
+		return
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.eventHandler(r, h)
+	}()
+}
+
+// write sends msg to the remote rotator.
+func (r *TCPProxy) write(msg string) error {
+	r.RLock()
+	conn := r.conn
+	r.RUnlock()
+
+	if r.writeTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(r.writeTimeout))
+	}
+	_, err := conn.Write([]byte(msg))
+	return err
+}
+
+// execute sends the request in the dialect of the remote rotator.
+func (r *TCPProxy) execute(req rotator.Request) error {
+	r.RLock()
+	msg, err := command(r.dialect, req, r.azPreset)
+	r.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	return r.write(msg)
+}
+
+// Name returns the name of the rotator
+func (r *TCPProxy) Name() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.name
+}
+
+// HasAzimuth returns a boolean value indicating if this rotator supports
+// horizontal rotation
+func (r *TCPProxy) HasAzimuth() bool {
+	r.RLock()
+	defer r.RUnlock()
+	return r.hasAzimuth
+}
+
+// HasElevation returns a boolean value indicating if this rotator supports
+// vertical rotation
+func (r *TCPProxy) HasElevation() bool {
+	r.RLock()
+	defer r.RUnlock()
+	return r.hasElevation
+}
+
+// Azimuth returns the last azimuth reported by the remote rotator
+func (r *TCPProxy) Azimuth() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.azimuth
+}
+
+// AzPreset returns the azimuth to which the rotator has been commanded
+// through this proxy
+func (r *TCPProxy) AzPreset() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.azPreset
+}
+
+// SetAzimuth commands the remote rotator to the azimuth az.
+func (r *TCPProxy) SetAzimuth(az int) error {
+	req := rotator.Request{Name: r.Name(), HasAzimuth: true, Azimuth: az}
+	if err := r.execute(req); err != nil {
+		return err
+	}
+
+	r.Lock()
+	r.azPreset = az
+	r.Unlock()
+
+	return nil
+}
+
+// Elevation returns the last elevation reported by the remote rotator
+func (r *TCPProxy) Elevation() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.elevation
+}
+
+// ElPreset returns the elevation to which the rotator has been commanded
+// through this proxy
+func (r *TCPProxy) ElPreset() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.elPreset
+}
+
+// SetElevation commands the remote rotator to the elevation el. The
+// ARSVCOM dialect doesn't support setting the elevation.
+func (r *TCPProxy) SetElevation(el int) error {
+	req := rotator.Request{Name: r.Name(), HasElevation: true, Elevation: el}
+	if err := r.execute(req); err != nil {
+		return err
+	}
+
+	r.Lock()
+	r.elPreset = el
+	r.Unlock()
+
+	return nil
+}
+
+// HasSpeed returns a boolean value indicating if the speed of the remote
+// rotator can be set. The ARSVCOM dialect doesn't support setting the speed.
+func (r *TCPProxy) HasSpeed() bool {
+	r.RLock()
+	defer r.RUnlock()
+	return r.dialect != hub.ARSVCOM
+}
+
+// Speed returns the speed level which has been set through this proxy
+func (r *TCPProxy) Speed() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.speed
+}
+
+// SetSpeed sets the speed level of the remote rotator.
+func (r *TCPProxy) SetSpeed(speed int) error {
+	req := rotator.Request{Name: r.Name(), HasSpeed: true, Speed: speed}
+	if err := r.execute(req); err != nil {
+		return err
+	}
+
+	r.Lock()
+	r.speed = speed
+	r.Unlock()
+
+	return nil
+}
+
+// StopAzimuth stops the horizontal movement of the remote rotator
+func (r *TCPProxy) StopAzimuth() error {
+	return r.execute(rotator.Request{Name: r.Name(), StopAzimuth: true})
+}
+
+// StopElevation stops the vertical movement of the remote rotator
+func (r *TCPProxy) StopElevation() error {
+	return r.execute(rotator.Request{Name: r.Name(), StopElevation: true})
+}
+
+// Stop stops all movement of the remote rotator
+func (r *TCPProxy) Stop() error {
+	return r.execute(rotator.Request{Name: r.Name(), Stop: true})
+}
+
+// Serialize returns the rotator's configuration and heading
+func (r *TCPProxy) Serialize() rotator.Object {
+	r.RLock()
+	defer r.RUnlock()
+	return r.serialize()
+}
+
+func (r *TCPProxy) serialize() rotator.Object {
+	return rotator.Object{
+		Name: r.name,
+		Heading: rotator.Heading{
+			Azimuth:   r.azimuth,
+			AzPreset:  r.azPreset,
+			Elevation: r.elevation,
+			ElPreset:  r.elPreset,
+			Speed:     r.speed,
+		},
+		Config: rotator.Config{
+			HasAzimuth:   r.hasAzimuth,
+			HasElevation: r.hasElevation,
+			HasSpeed:     r.dialect != hub.ARSVCOM,
+			AzimuthMin:   r.azimuthMin,
+			AzimuthMax:   r.azimuthMax,
+			AzimuthStop:  r.azimuthStop,
+			ElevationMin: r.elevationMin,
+			ElevationMax: r.elevationMax,
+		},
+	}
+}
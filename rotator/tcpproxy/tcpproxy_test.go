@@ -0,0 +1,234 @@
+package tcpproxy
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/hub"
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// newTestServer returns a listener which emulates a remote rotator. It
+// answers position queries with position and forwards all other
+// commands to the returned channel.
+func newTestServer(t *testing.T, position string) (net.Listener, int, chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := make(chan string, 10)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			msg := strings.TrimSpace(scanner.Text())
+			switch {
+			case msg == "C" || msg == "C2" || msg == "{}":
+				conn.Write([]byte(position))
+			case len(msg) > 0:
+				commands <- msg
+			}
+		}
+	}()
+
+	_, p, err := net.SplitHostPort(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l, port, commands
+}
+
+func TestTCPProxy(t *testing.T) {
+
+	tt := []struct {
+		name         string
+		dialect      hub.Dialect
+		position     string
+		hasElevation bool
+		expAzimuth   int
+		expElevation int
+		expCommand   string
+	}{
+		{"arsvcom", hub.ARSVCOM, "+0123\r\n", false, 123, 0, "M200"},
+		{"gs232a", hub.GS232A, "AZ=123  EL=045\r", true, 123, 45, "M200"},
+		{"gs232b", hub.GS232B, "+0123+0045\r", true, 123, 45, "M200"},
+		{"json", hub.JSON, `{"azimuth":123,"elevation":45}` + "\n", true, 123, 45,
+			`{"name":"tcpProxy","has_azimuth":true,"azimuth":200}`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			l, port, commands := newTestServer(t, tc.position)
+			defer l.Close()
+
+			events := make(chan rotator.Heading, 10)
+
+			r, err := New(Host("127.0.0.1"), Port(port), Dialect(tc.dialect),
+				HasElevation(tc.hasElevation), PollInterval(time.Millisecond*10),
+				EventHandler(func(_ rotator.Rotator, h rotator.Heading) {
+					events <- h
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			select {
+			case h := <-events:
+				if h.Azimuth != tc.expAzimuth || h.Elevation != tc.expElevation {
+					t.Fatalf("expected %d/%d, got %d/%d", tc.expAzimuth, tc.expElevation, h.Azimuth, h.Elevation)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("no heading received")
+			}
+
+			if r.Azimuth() != tc.expAzimuth || r.Elevation() != tc.expElevation {
+				t.Fatalf("expected %d/%d, got %d/%d", tc.expAzimuth, tc.expElevation, r.Azimuth(), r.Elevation())
+			}
+
+			if err := r.SetAzimuth(200); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case cmd := <-commands:
+				if cmd != tc.expCommand {
+					t.Fatalf("expected command %q, got %q", tc.expCommand, cmd)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("no command received")
+			}
+
+			if r.AzPreset() != 200 {
+				t.Fatalf("expected azimuth preset 200, got %d", r.AzPreset())
+			}
+		})
+	}
+}
+
+func TestTCPProxyReconnect(t *testing.T) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// drop every connection right away
+	accepted := make(chan struct{}, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+			accepted <- struct{}{}
+		}
+	}()
+
+	port := l.Addr().(*net.TCPAddr).Port
+	doneCh := make(chan struct{})
+
+	r, err := New(Host("127.0.0.1"), Port(port), Reconnect(true), DoneCh(doneCh))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-accepted:
+		case <-doneCh:
+			t.Fatal("proxy gave up")
+		case <-time.After(time.Second * 3):
+			t.Fatal("proxy didn't reconnect")
+		}
+	}
+
+	r.Close()
+
+	select {
+	case <-doneCh:
+	case <-time.After(time.Second):
+		t.Fatal("proxy didn't shut down")
+	}
+}
+
+func TestTCPProxyResync(t *testing.T) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// answer the position query and drop the first connection afterwards
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn, drop bool) {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					if strings.TrimSpace(scanner.Text()) != "C" {
+						continue
+					}
+					conn.Write([]byte("+0123\r\n"))
+					if drop {
+						return
+					}
+				}
+			}(conn, i == 0)
+		}
+	}()
+
+	events := make(chan rotator.Heading, 10)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	// without polling, the position is only queried after (re)connecting
+	r, err := New(Host("127.0.0.1"), Port(port), PollInterval(0), Reconnect(true),
+		EventHandler(func(_ rotator.Rotator, h rotator.Heading) {
+			events <- h
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// the unchanged heading is emitted again after the reconnect
+	for i := 0; i < 2; i++ {
+		select {
+		case h := <-events:
+			if h.Azimuth != 123 {
+				t.Fatalf("expected azimuth 123, got %d", h.Azimuth)
+			}
+		case <-time.After(time.Second * 3):
+			t.Fatalf("no heading received after connection %d", i+1)
+		}
+	}
+}
+
+func TestTCPProxyInvalidBackoff(t *testing.T) {
+	if _, err := New(MaxBackoff(0)); err == nil {
+		t.Fatal("expected error for maximum backoff 0")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/dh1tw/remoteRotator/rotator"
 )
@@ -212,6 +213,94 @@ func TestSetElevationButNotEnabled(t *testing.T) {
 	}
 }
 
+func TestSetAzEl(t *testing.T) {
+
+	tt := []struct {
+		name         string
+		hasElevation bool
+		az, el       int
+		expAz, expEl int
+		expMsg       []byte
+	}{
+		{"150/30 deg", true, 150, 30, 150, 30, []byte("W150 030\r\n")},
+		{"clipped", true, 500, -10, 450, 0, []byte("W450 000\r\n")},
+		{"azimuth only", false, 150, 30, 150, 0, []byte("M150\r\n")},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dp := dummyPort{
+				sendBuf: &bytes.Buffer{},
+				rxBuf:   &bytes.Buffer{},
+			}
+
+			yaesu := Yaesu{
+				hasAzimuth:   true,
+				hasElevation: tc.hasElevation,
+				sp:           &dp,
+			}
+
+			if err := yaesu.SetAzEl(tc.az, tc.el); err != nil {
+				t.Fatal(err)
+			}
+			res := dp.sendBuf.Bytes()
+			if bytes.Compare(tc.expMsg, res) != 0 {
+				t.Fatalf("expecting '%s' to be sent to the serial port. Instead got '%s'",
+					replaceLineBreaks(tc.expMsg), replaceLineBreaks(res))
+			}
+			if yaesu.AzPreset() != tc.expAz || yaesu.ElPreset() != tc.expEl {
+				t.Fatalf("expecting presets %d/%d, got %d/%d",
+					tc.expAz, tc.expEl, yaesu.AzPreset(), yaesu.ElPreset())
+			}
+		})
+	}
+}
+
+func TestSetSpeed(t *testing.T) {
+
+	tt := []struct {
+		name     string
+		value    int
+		expValue int
+		expMsg   []byte
+	}{
+		{"speed 1", 1, 1, []byte("X1\r\n")},
+		{"speed 4", 4, 4, []byte("X4\r\n")},
+		{"speed 0", 0, 1, []byte("X1\r\n")},
+		{"speed 10", 10, 4, []byte("X4\r\n")},
+	}
+
+	for _, tc := range tt {
+
+		dp := dummyPort{
+			sendBuf: &bytes.Buffer{},
+			rxBuf:   &bytes.Buffer{},
+		}
+
+		yaesu := Yaesu{
+			sp: &dp,
+		}
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := yaesu.SetSpeed(tc.value)
+			if err != nil {
+				t.Fatalf("unable to set speed to %v; got error: %q", tc.name, err)
+			}
+			res := dp.sendBuf.Bytes()
+			if bytes.Compare(tc.expMsg, res) != 0 {
+				t.Fatalf("expecting '%s' (Hex: % 02x) to be sent to the serial port. Instead got '%s' (Hex: % 02x)",
+					replaceLineBreaks(tc.expMsg),
+					replaceLineBreaks(tc.expMsg),
+					replaceLineBreaks(res),
+					replaceLineBreaks(res))
+			}
+			if yaesu.Speed() != tc.expValue {
+				t.Fatalf("expecting speed %v, but got %v", tc.expValue, yaesu.Speed())
+			}
+		})
+	}
+}
+
 func TestRotatorStop(t *testing.T) {
 
 	tt := []struct {
@@ -335,25 +424,89 @@ func TestParseMsg(t *testing.T) {
 }
 
 func TestQuery(t *testing.T) {
-	dp := &dummyPort{
-		sendBuf: &bytes.Buffer{},
-		rxBuf:   &bytes.Buffer{},
+
+	tt := []struct {
+		name         string
+		hasElevation bool
+		expMsg       []byte
+	}{
+		{"azimuth only", false, []byte("C\r\n")},
+		{"azimuth + elevation", true, []byte("C2\r\n")},
 	}
 
-	yaesu := &Yaesu{
-		sp: dp,
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dp := &dummyPort{
+				sendBuf: &bytes.Buffer{},
+				rxBuf:   &bytes.Buffer{},
+			}
+
+			yaesu := &Yaesu{
+				hasAzimuth:   true,
+				hasElevation: tc.hasElevation,
+				sp:           dp,
+			}
+
+			if err := yaesu.query(); err != nil {
+				t.Fatalf("unable to send query; %v", err)
+			}
+
+			value := dp.sendBuf.Bytes()
+			if bytes.Compare(value, tc.expMsg) != 0 {
+				v := replaceLineBreaks(value)
+				exp := replaceLineBreaks(tc.expMsg)
+				t.Fatalf("expected '%s', got %s", exp, v)
+			}
+		})
 	}
+}
 
-	if err := yaesu.query(); err != nil {
-		t.Fatalf("unable to send query; %v", err)
+func TestParseMsgEvents(t *testing.T) {
+
+	headingPattern, err := regexp.Compile("[\\d]{4}")
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	value := dp.sendBuf.Bytes()
-	expValue := []byte("C2\r\n")
-	if bytes.Compare(value, expValue) != 0 {
-		v := replaceLineBreaks(value)
-		exp := replaceLineBreaks(expValue)
-		t.Fatalf("expected '%s', got %s", exp, v)
+	tt := []struct {
+		name      string
+		inputs    []string
+		expEvents int
+	}{
+		{"azimuth only", []string{"+0030\r\n", "+0030\r\n", "+0040\r\n"}, 2},
+		{"azimuth + elevation", []string{"+0030+0010\r\n", "+0030+0010\r\n", "+0040+0010\r\n"}, 2},
+		{"elevation change", []string{"+0030+0010\r\n", "+0030+0020\r\n"}, 2},
+		{"prompt", []string{"?>\r\n"}, 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			evCh := make(chan rotator.Heading, len(tc.inputs))
+			yaesu := &Yaesu{
+				headingPattern: headingPattern,
+				eventHandler: func(r rotator.Rotator, h rotator.Heading) {
+					evCh <- h
+				},
+			}
+
+			for _, input := range tc.inputs {
+				yaesu.parseMsg(input)
+			}
+
+			for i := 0; i < tc.expEvents; i++ {
+				select {
+				case <-evCh:
+				case <-time.After(time.Second):
+					t.Fatalf("expected %d events, got %d", tc.expEvents, i)
+				}
+			}
+
+			select {
+			case h := <-evCh:
+				t.Fatalf("unexpected event %+v", h)
+			case <-time.After(time.Millisecond * 50):
+			}
+		})
 	}
 }
 
@@ -14,7 +14,9 @@ import (
 	"github.com/dh1tw/remoteRotator/rotator"
 )
 
-// Yaesu is the implementation of the Yaesu GS232A/B rotator protocol
+// Yaesu is the implementation of the Yaesu GS232A/B rotator protocol.
+// It also supports controllers emulating this protocol, like the EA4TX
+// ARS-USB, which report the heading as "+0aaa+0eee".
 type Yaesu struct {
 	sync.RWMutex
 	name            string
@@ -28,6 +30,7 @@ type Yaesu struct {
 	azPreset        int
 	elevation       int
 	elPreset        int
+	speed           int
 	hasAzimuth      bool
 	hasElevation    bool
 	azInitialized   bool
@@ -215,10 +218,18 @@ func (r *Yaesu) read() (string, error) {
 	return bufio.NewReader(r.sp).ReadString('\n')
 }
 
-// request Azimuth + Elevation from Yaesu rotator
+// request the heading from the Yaesu rotator. Controllers without
+// elevation are queried with "C" (azimuth only), all others with "C2"
+// (azimuth + elevation).
 func (r *Yaesu) query() error {
-	//query azimuth + elevation
-	_, err := r.write([]byte("C2\r\n"))
+	r.RLock()
+	cmd := "C\r\n"
+	if r.hasElevation {
+		cmd = "C2\r\n"
+	}
+	r.RUnlock()
+
+	_, err := r.write([]byte(cmd))
 	return err
 }
 
@@ -266,16 +277,18 @@ func (r *Yaesu) parseMsg(msg string) {
 		if !r.elInitialized {
 			r.elPreset = el
 			r.elInitialized = true
+			gotNewValue = true
 		}
 
 		if r.elevation != el {
 			r.elevation = el
+			gotNewValue = true
 		}
+	}
 
-		if r.eventHandler != nil && gotNewValue {
-			// cb launched async to avoid deadlock on yaesu.*()
-			go r.eventHandler(r, r.serialize().Heading)
-		}
+	if r.eventHandler != nil && gotNewValue {
+		// cb launched async to avoid deadlock on yaesu.*()
+		go r.eventHandler(r, r.serialize().Heading)
 	}
 }
 
@@ -389,6 +402,88 @@ func (r *Yaesu) SetElevation(el int) error {
 	return nil
 }
 
+// SetAzEl sets the azimuth and elevation to which the rotator shall
+// turn to with a single "W" command. Values outside of the allowed
+// ranges will be clipped. Rotators without elevation or azimuth
+// fall back to SetAzimuth or SetElevation. The hub uses SetAzEl for
+// requests which set both axes.
+func (r *Yaesu) SetAzEl(az, el int) error {
+	if !r.HasElevation() {
+		return r.SetAzimuth(az)
+	}
+
+	if !r.HasAzimuth() {
+		return r.SetElevation(el)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if az > 450 {
+		az = 450
+	}
+
+	if az < 0 {
+		az = 0
+	}
+
+	if el > 180 {
+		el = 180
+	}
+
+	if el < 0 {
+		el = 0
+	}
+
+	r.azPreset = az
+	r.elPreset = el
+
+	if _, err := r.write([]byte(fmt.Sprintf("W%.3d %.3d\r\n", az, el))); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// HasSpeed returns a boolean value indicating if the speed of this
+// rotator can be set
+func (r *Yaesu) HasSpeed() bool {
+	return true
+}
+
+// Speed returns the speed level which has been set last. Since the
+// controller can not be queried for its speed, 0 is returned until
+// the speed has been set.
+func (r *Yaesu) Speed() int {
+	r.RLock()
+	defer r.RUnlock()
+	return r.speed
+}
+
+// SetSpeed sets the speed level of the rotator. Allowed values are
+// rotator.SpeedMin ... rotator.SpeedMax. Values outside of this range
+// will be clipped.
+func (r *Yaesu) SetSpeed(speed int) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if speed > rotator.SpeedMax {
+		speed = rotator.SpeedMax
+	}
+
+	if speed < rotator.SpeedMin {
+		speed = rotator.SpeedMin
+	}
+
+	r.speed = speed
+
+	if _, err := r.write([]byte(fmt.Sprintf("X%d\r\n", speed))); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // Stop stops all rotator movement
 func (r *Yaesu) Stop() error {
 	r.Lock()
@@ -449,6 +544,7 @@ func (r *Yaesu) serialize() rotator.Object {
 			AzPreset:  r.azPreset,
 			Elevation: r.elevation,
 			ElPreset:  r.elPreset,
+			Speed:     r.speed,
 		},
 		Config: rotator.Config{
 			HasAzimuth:   r.hasAzimuth,
@@ -458,6 +554,7 @@ func (r *Yaesu) serialize() rotator.Object {
 			HasElevation: r.hasElevation,
 			ElevationMax: r.elevationMax,
 			ElevationMin: r.elevationMin,
+			HasSpeed:     true,
 		},
 	}
 
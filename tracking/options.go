@@ -0,0 +1,49 @@
+package tracking
+
+import (
+	"time"
+
+	"github.com/dh1tw/remoteRotator/hub"
+)
+
+// Location is a functional option to set the position of the observer
+// (latitude / longitude in degrees, North and East positive).
+func Location(lat, lon float64) func(*Tracker) {
+	return func(t *Tracker) {
+		t.latitude = lat
+		t.longitude = lon
+		t.hasLocation = true
+	}
+}
+
+// Altitude is a functional option to set the altitude of the observer
+// in meters above the ellipsoid.
+func Altitude(alt float64) func(*Tracker) {
+	return func(t *Tracker) {
+		t.altitude = alt
+	}
+}
+
+// Interval is a functional option to set the interval in which the
+// rotator will be updated with a new heading.
+func Interval(d time.Duration) func(*Tracker) {
+	return func(t *Tracker) {
+		t.interval = d
+	}
+}
+
+// FlipElevation is a functional option to set the maximum elevation
+// (degrees) of a pass above which rotators supporting elevations beyond
+// 90° are operated in flip mode.
+func FlipElevation(el float64) func(*Tracker) {
+	return func(t *Tracker) {
+		t.flipElevation = el
+	}
+}
+
+// Logger is a functional option to set the logger of the tracker.
+func Logger(l hub.Logger) func(*Tracker) {
+	return func(t *Tracker) {
+		t.logger = l
+	}
+}
@@ -0,0 +1,293 @@
+// Package tracking points a rotator at an earth satellite. The look
+// angles are calculated from the satellite's two-line element set (TLE)
+// with the orbit package.
+package tracking
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/hub"
+	"github.com/dh1tw/remoteRotator/orbit"
+	"github.com/dh1tw/remoteRotator/rotator"
+)
+
+// azElSetter is implemented by rotators which can set azimuth and
+// elevation with a single command (e.g. the rotator proxy).
+type azElSetter interface {
+	SetAzEl(az, el int) error
+}
+
+// Tracker periodically calculates the azimuth and elevation of a
+// satellite and points the rotator at it while the satellite is above
+// the horizon.
+//
+// During a pass through (or close to) the zenith, the azimuth of the
+// satellite changes by up to 180° within seconds. If the rotator supports
+// elevations beyond 90°, passes with a maximum elevation above the flip
+// elevation are tracked in flip mode: the azimuth is kept at the azimuth
+// where the satellite rose and the rotator follows the satellite over
+// the zenith by moving the elevation from 0° up to 180°. The pointing
+// error is roughly limited to 90° minus the maximum elevation of the pass.
+type Tracker struct {
+	sync.Mutex
+	r             rotator.Rotator
+	tle           orbit.TLE
+	latitude      float64
+	longitude     float64
+	altitude      float64
+	hasLocation   bool
+	interval      time.Duration
+	flipElevation float64
+	logger        hub.Logger
+	now           func() time.Time
+	running       bool
+	stopCh        chan struct{}
+	doneCh        chan struct{}
+}
+
+// pass contains the state of the satellite pass which is currently
+// tracked.
+type pass struct {
+	visible   bool
+	flip      bool
+	riseAz    float64
+	commanded bool
+	az        int
+	el        int
+}
+
+// New returns the pointer to an initialized Tracker which points the
+// rotator r at the satellite described by tle. The location of the
+// observer must be set with the Location option.
+// Default settings are:
+// interval: 1sec,
+// flipElevation: 75°,
+// logger: hub.StdLogger.
+func New(r rotator.Rotator, tle orbit.TLE, opts ...func(*Tracker)) (*Tracker, error) {
+
+	t := &Tracker{
+		r:             r,
+		tle:           tle,
+		interval:      time.Second,
+		flipElevation: 75,
+		logger:        hub.StdLogger{},
+		now:           time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if !t.hasLocation {
+		return nil, fmt.Errorf("observer location not set")
+	}
+
+	if t.latitude < -90 || t.latitude > 90 || t.longitude < -180 || t.longitude > 180 {
+		return nil, fmt.Errorf("invalid observer location %v/%v", t.latitude, t.longitude)
+	}
+
+	if t.interval <= 0 {
+		return nil, fmt.Errorf("invalid tracking interval %v", t.interval)
+	}
+
+	return t, nil
+}
+
+// Start starts tracking the satellite. The heading of the rotator will
+// be updated every interval until Stop is called.
+func (t *Tracker) Start() error {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.running {
+		return fmt.Errorf("tracker already running")
+	}
+
+	t.running = true
+	t.stopCh = make(chan struct{})
+	t.doneCh = make(chan struct{})
+
+	go t.run(t.stopCh, t.doneCh)
+
+	t.logger.Infof("rotator (%s) is tracking %s", t.r.Name(), t.satellite())
+
+	return nil
+}
+
+// Stop stops tracking the satellite and waits until the tracker has
+// sent its last command. The rotator will not be stopped.
+func (t *Tracker) Stop() {
+	t.Lock()
+	defer t.Unlock()
+
+	if !t.running {
+		return
+	}
+
+	close(t.stopCh)
+	<-t.doneCh
+	t.running = false
+
+	t.logger.Infof("rotator (%s) stopped tracking %s", t.r.Name(), t.satellite())
+}
+
+// Running returns true if the tracker has been started.
+func (t *Tracker) Running() bool {
+	t.Lock()
+	defer t.Unlock()
+	return t.running
+}
+
+// run updates the rotator's heading until stopCh is closed. Since this
+// function contains an endless loop, it should be executed in a go routine.
+func (t *Tracker) run(stopCh, doneCh chan struct{}) {
+	defer close(doneCh)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	p := &pass{}
+
+	for {
+		t.update(p)
+
+		select {
+		case <-ticker.C:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// update points the rotator at the current position of the satellite.
+// While the satellite is below the horizon, the rotator will not be moved.
+func (t *Tracker) update(p *pass) {
+	now := t.now()
+
+	az, el, _ := t.tle.LookAngles(now, t.latitude, t.longitude, t.altitude)
+
+	if el < 0 {
+		if p.visible {
+			t.logger.Infof("%s has set; waiting for the next pass", t.satellite())
+		}
+		p.visible = false
+		return
+	}
+
+	cfg := t.r.Serialize().Config
+
+	if !p.visible {
+		riseAz, maxEl := t.predictPass(now)
+		p.visible = true
+		p.riseAz = riseAz
+		p.flip = cfg.HasElevation && cfg.ElevationMax >= 180 && maxEl >= t.flipElevation
+		if p.flip {
+			t.logger.Infof("%s has risen; tracking the pass in flip mode", t.satellite())
+		} else {
+			t.logger.Infof("%s has risen", t.satellite())
+		}
+	}
+
+	if p.flip {
+		az, el = overTheTop(az, el, p.riseAz)
+	}
+
+	azimuth := int(math.Round(az)) % 360
+	elevation := clamp(int(math.Round(el)), cfg.ElevationMin, cfg.ElevationMax)
+
+	if p.commanded && azimuth == p.az && elevation == p.el {
+		return
+	}
+
+	if err := t.point(cfg, azimuth, elevation); err != nil {
+		t.logger.Errorf("unable to track %s with rotator %s: %v", t.satellite(), t.r.Name(), err)
+		return
+	}
+
+	p.commanded, p.az, p.el = true, azimuth, elevation
+}
+
+// point commands the rotator to the given heading. Azimuth and elevation
+// are set with a single command if the rotator supports it.
+func (t *Tracker) point(cfg rotator.Config, az, el int) error {
+	if s, ok := t.r.(azElSetter); ok && cfg.HasAzimuth && cfg.HasElevation {
+		return s.SetAzEl(az, el)
+	}
+
+	if cfg.HasAzimuth {
+		if err := t.r.SetAzimuth(az); err != nil {
+			return err
+		}
+	}
+
+	if cfg.HasElevation {
+		if err := t.r.SetElevation(el); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// predictPass returns the azimuth at which the satellite rose and the
+// highest elevation of the pass which is in progress at the time ts.
+func (t *Tracker) predictPass(ts time.Time) (riseAz, maxEl float64) {
+	maxEl = -90.0
+
+	// even high LEO passes last less than 30 minutes
+	step := time.Second * 10
+	for dir := -1; dir <= 1; dir += 2 {
+		for i := 0; i < 180; i++ {
+			az, el, _ := t.tle.LookAngles(ts.Add(time.Duration(dir*i)*step), t.latitude, t.longitude, t.altitude)
+			if el < 0 {
+				break
+			}
+			if dir < 0 {
+				riseAz = az
+			}
+			if el > maxEl {
+				maxEl = el
+			}
+		}
+	}
+
+	return riseAz, maxEl
+}
+
+// overTheTop projects the direction az / el into the vertical plane
+// through the azimuth ref and returns the heading of a rotator with an
+// elevation range of 0°...180° which points along the projection.
+func overTheTop(az, el, ref float64) (float64, float64) {
+	// horizontal and vertical component within the plane
+	h := math.Cos(rad(el)) * math.Cos(rad(az-ref))
+	v := math.Sin(rad(el))
+	return ref, math.Atan2(v, h) * 180 / math.Pi
+}
+
+func rad(deg float64) float64 { return deg * math.Pi / 180 }
+
+// satellite returns the name of the satellite for log messages.
+func (t *Tracker) satellite() string {
+	if len(t.tle.Name) > 0 {
+		return t.tle.Name
+	}
+	return fmt.Sprintf("satellite %d", t.tle.CatalogNumber)
+}
+
+// clamp limits v to [min, max]. If the limits are unknown (min >= max),
+// v is returned unchanged.
+func clamp(v, min, max int) int {
+	if min >= max {
+		return v
+	}
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,225 @@
+package tracking
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dh1tw/remoteRotator/orbit"
+	"github.com/dh1tw/remoteRotator/rotator/dummy"
+)
+
+const issTLE = `ISS (ZARYA)
+1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927
+2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537`
+
+// azElRotator records the commands received through SetAzEl.
+type azElRotator struct {
+	*dummy.Dummy
+	sync.Mutex
+	cmds [][2]int
+}
+
+func (r *azElRotator) SetAzEl(az, el int) error {
+	r.Lock()
+	r.cmds = append(r.cmds, [2]int{az, el})
+	r.Unlock()
+	if err := r.Dummy.SetAzimuth(az); err != nil {
+		return err
+	}
+	return r.Dummy.SetElevation(el)
+}
+
+// overheadPass returns the ISS element set and the time at which it
+// passes through the zenith of the observer at lat / lon.
+func overheadPass(t *testing.T) (tle orbit.TLE, zenith time.Time, lat, lon float64) {
+	tle, err := orbit.ParseTLE(issTLE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zenith = tle.Epoch.Add(time.Minute * 42)
+	lat, lon, _ = tle.SubPoint(zenith)
+	return tle, zenith, lat, lon
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func TestNew(t *testing.T) {
+
+	tle, _, _, _ := overheadPass(t)
+
+	d, err := dummy.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	tt := []struct {
+		name   string
+		opts   []func(*Tracker)
+		expErr bool
+	}{
+		{"valid", []func(*Tracker){Location(48, 11)}, false},
+		{"location not set", nil, true},
+		{"invalid latitude", []func(*Tracker){Location(91, 11)}, true},
+		{"invalid longitude", []func(*Tracker){Location(48, 181)}, true},
+		{"invalid interval", []func(*Tracker){Location(48, 11), Interval(0)}, true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(d, tle, tc.opts...)
+			if tc.expErr && err == nil {
+				t.Fatal("expected error")
+			}
+			if !tc.expErr && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestTrackPass(t *testing.T) {
+
+	tle, zenith, lat, lon := overheadPass(t)
+
+	tt := []struct {
+		name       string
+		elMin      int
+		elMax      int
+		flipEl     float64
+		expFlip    bool
+		expMinEl   int
+		expMaxJump int
+	}{
+		{"elevation up to 90°", 0, 90, 75, false, 0, 360},
+		{"flip mode", 0, 180, 75, true, 0, 0},
+		{"flip elevation not reached", 0, 180, 91, false, 0, 360},
+		{"minimum elevation", 10, 90, 75, false, 10, 360},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := dummy.New(dummy.HasElevation(true), dummy.ElevationMin(tc.elMin),
+				dummy.ElevationMax(tc.elMax), dummy.AzimuthSpeed(0), dummy.ElevationSpeed(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer d.Close()
+			r := &azElRotator{Dummy: d}
+
+			tr, err := New(r, tle, Location(lat, lon), FlipElevation(tc.flipEl))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// start well before the satellite rises
+			ts := zenith.Add(-time.Minute * 10)
+			tr.now = func() time.Time { return ts }
+
+			p := &pass{}
+			for ; ts.Before(zenith.Add(time.Minute * 10)); ts = ts.Add(time.Second * 5) {
+				tr.update(p)
+			}
+
+			if len(r.cmds) == 0 {
+				t.Fatal("no commands received")
+			}
+
+			maxEl, maxJump := 0, 0
+			for i, c := range r.cmds {
+				if c[1] < tc.expMinEl {
+					t.Fatalf("elevation %d below %d", c[1], tc.expMinEl)
+				}
+				if c[1] > maxEl {
+					maxEl = c[1]
+				}
+				if i > 0 {
+					jump := abs(c[0] - r.cmds[i-1][0])
+					if jump > 180 {
+						jump = 360 - jump
+					}
+					if jump > maxJump {
+						maxJump = jump
+					}
+				}
+			}
+
+			if tc.expFlip && maxEl <= 90 {
+				t.Fatalf("expected elevation beyond 90° in flip mode, got %d°", maxEl)
+			}
+			if !tc.expFlip && maxEl > 90 {
+				t.Fatalf("unexpected elevation of %d°", maxEl)
+			}
+			if maxJump > tc.expMaxJump {
+				t.Fatalf("expected azimuth changes up to %d°, got %d°", tc.expMaxJump, maxJump)
+			}
+		})
+	}
+}
+
+func TestStartStop(t *testing.T) {
+
+	tle, zenith, lat, lon := overheadPass(t)
+
+	d, err := dummy.New(dummy.HasElevation(true), dummy.ElevationMax(90),
+		dummy.AzimuthSpeed(0), dummy.ElevationSpeed(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	tr, err := New(d, tle, Location(lat, lon), Interval(time.Millisecond*10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := zenith.Add(-time.Minute * 2)
+	tr.now = func() time.Time { return ts }
+	expAz, expEl, _ := tle.LookAngles(ts, lat, lon, 0)
+
+	if err := tr.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Start(); err == nil {
+		t.Fatal("expected error when starting a running tracker")
+	}
+	if !tr.Running() {
+		t.Fatal("expected running tracker")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for d.ElPreset() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	tr.Stop()
+	if tr.Running() {
+		t.Fatal("expected stopped tracker")
+	}
+
+	if abs(d.AzPreset()-int(expAz+0.5)) > 1 || abs(d.ElPreset()-int(expEl+0.5)) > 1 {
+		t.Fatalf("expected heading %.0f°/%.0f°, got %d°/%d°", expAz, expEl, d.AzPreset(), d.ElPreset())
+	}
+
+	// no further commands after Stop
+	d.SetElevation(0)
+	time.Sleep(time.Millisecond * 50)
+	if d.ElPreset() != 0 {
+		t.Fatalf("unexpected command after Stop")
+	}
+
+	// the tracker can be restarted
+	if err := tr.Start(); err != nil {
+		t.Fatal(err)
+	}
+	tr.Stop()
+}